@@ -0,0 +1,95 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/FactomProject/factomd/database/archive"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/hybridDB"
+)
+
+const level string = "level"
+const bolt string = "bolt"
+
+func main() {
+	fmt.Println("Usage:")
+	fmt.Println("BlockArchive export level/bolt DBFileLocation ArchiveFile [StartHeight]")
+	fmt.Println("BlockArchive import level/bolt DBFileLocation ArchiveFile")
+	fmt.Println("Export appends the blocks of the database to the archive, from StartHeight if the archive is new")
+	fmt.Println("Import validates the blocks of the archive and adds them to an empty database, or one holding the start of the archive")
+	fmt.Println("The last directory block of the archive is not imported, as it is signed by the next one")
+
+	if len(os.Args) < 5 {
+		fmt.Println("\nNot enough arguments passed")
+		os.Exit(1)
+	}
+	if len(os.Args) > 6 {
+		fmt.Println("\nToo many arguments passed")
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	if command != "export" && command != "import" {
+		fmt.Println("\nFirst argument should be `export` or `import`")
+		os.Exit(1)
+	}
+	levelBolt := os.Args[2]
+	if levelBolt != level && levelBolt != bolt {
+		fmt.Println("\nSecond argument should be `level` or `bolt`")
+		os.Exit(1)
+	}
+	path := os.Args[3]
+	archivePath := os.Args[4]
+
+	start := uint64(0)
+	if len(os.Args) == 6 {
+		if command != "export" {
+			fmt.Println("\nOnly export takes a start height")
+			os.Exit(1)
+		}
+		var err error
+		start, err = strconv.ParseUint(os.Args[5], 10, 32)
+		if err != nil {
+			fmt.Printf("\nBad start height: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var dbase *hybridDB.HybridDB
+	var err error
+	if levelBolt == bolt {
+		dbase = hybridDB.NewBoltMapHybridDB(nil, path)
+	} else {
+		dbase, err = hybridDB.NewLevelMapHybridDB(path, command == "import")
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	dbo := databaseOverlay.NewOverlay(dbase)
+	defer dbo.Close()
+
+	progress := func(height uint32) {
+		if height%1000 == 0 {
+			fmt.Printf("Directory block %v\n", height)
+		}
+	}
+
+	var count int
+	if command == "export" {
+		count, err = archive.Export(dbo, archivePath, uint32(start), progress)
+	} else {
+		count, err = archive.Import(dbo, archivePath, progress)
+	}
+	if err != nil {
+		fmt.Printf("ERROR after %v directory blocks: %v\n", count, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%v directory blocks %ved\n", count, command)
+}
@@ -39,6 +39,7 @@ func main() {
 	fmt.Println("Usage:")
 	fmt.Println("CorrectChainHeads level/bolt/api DBFileLocation")
 	fmt.Println("Program will fix chainheads")
+	fmt.Println("factomd checks and repairs the block heads when it starts, this is only needed for databases it can't repair")
 
 	if len(flag.Args()) < 2 {
 		fmt.Println("\nNot enough arguments passed")
@@ -144,36 +144,48 @@ func CheckDatabase(dbo interfaces.DBOverlay) {
 
 	fmt.Printf("\tChecking block indexes\n")
 
-	hashes, keys, err := dbo.GetAll(databaseOverlay.DIRECTORYBLOCK_NUMBER, primitives.NewZeroHash())
-	for i, v := range hashes {
+	err = dbo.ForEachInBucket(databaseOverlay.DIRECTORYBLOCK_NUMBER, nil, primitives.NewZeroHash(), func(key []byte, v interfaces.BinaryMarshallableAndCopyable) error {
 		h := v.(*primitives.Hash)
 		if hashMap[h.String()] != "OK" {
-			fmt.Printf("Invalid DBlock indexed at height 0x%x - %v\n", keys[i], h)
+			fmt.Printf("Invalid DBlock indexed at height 0x%x - %v\n", key, h)
 		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
 
-	hashes, keys, err = dbo.GetAll(databaseOverlay.FACTOIDBLOCK_NUMBER, primitives.NewZeroHash())
-	for i, v := range hashes {
+	err = dbo.ForEachInBucket(databaseOverlay.FACTOIDBLOCK_NUMBER, nil, primitives.NewZeroHash(), func(key []byte, v interfaces.BinaryMarshallableAndCopyable) error {
 		h := v.(*primitives.Hash)
 		if hashMap[h.String()] != "OK" {
-			fmt.Printf("Invalid FBlock indexed at height 0x%x - %v\n", keys[i], h)
+			fmt.Printf("Invalid FBlock indexed at height 0x%x - %v\n", key, h)
 		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
 
-	hashes, keys, err = dbo.GetAll(databaseOverlay.ADMINBLOCK_NUMBER, primitives.NewZeroHash())
-	for i, v := range hashes {
+	err = dbo.ForEachInBucket(databaseOverlay.ADMINBLOCK_NUMBER, nil, primitives.NewZeroHash(), func(key []byte, v interfaces.BinaryMarshallableAndCopyable) error {
 		h := v.(*primitives.Hash)
 		if hashMap[h.String()] != "OK" {
-			fmt.Printf("Invalid ABlock indexed at height 0x%x - %v\n", keys[i], h)
+			fmt.Printf("Invalid ABlock indexed at height 0x%x - %v\n", key, h)
 		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
 
-	hashes, keys, err = dbo.GetAll(databaseOverlay.ENTRYCREDITBLOCK_NUMBER, primitives.NewZeroHash())
-	for i, v := range hashes {
+	err = dbo.ForEachInBucket(databaseOverlay.ENTRYCREDITBLOCK_NUMBER, nil, primitives.NewZeroHash(), func(key []byte, v interfaces.BinaryMarshallableAndCopyable) error {
 		h := v.(*primitives.Hash)
 		if hashMap[h.String()] != "OK" {
-			fmt.Printf("Invalid ECBlock indexed at height 0x%x - %v\n", keys[i], h)
+			fmt.Printf("Invalid ECBlock indexed at height 0x%x - %v\n", key, h)
 		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
 
 	fmt.Printf("\tFinished checking block indexes\n")
@@ -20,6 +20,7 @@ func main() {
 	fmt.Println("Usage:")
 	fmt.Println("FixBlockHeads level/bolt DBFileLocation")
 	fmt.Println("Program will reset the block heads to the highest valid DBlock")
+	fmt.Println("factomd checks and repairs the block heads when it starts, this is only needed for databases it can't repair")
 
 	if len(os.Args) < 3 {
 		fmt.Println("\nNot enough arguments passed")
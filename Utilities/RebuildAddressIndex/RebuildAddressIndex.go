@@ -0,0 +1,61 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/hybridDB"
+)
+
+const level string = "level"
+const bolt string = "bolt"
+
+func main() {
+	fmt.Println("Usage:")
+	fmt.Println("RebuildAddressIndex level/bolt DBFileLocation")
+	fmt.Println("Program will index every factoid and entry credit transaction in the database by address")
+	fmt.Println("Set AddressIndex = true in factomd.conf to keep the index up to date afterwards")
+
+	if len(os.Args) < 3 {
+		fmt.Println("\nNot enough arguments passed")
+		os.Exit(1)
+	}
+	if len(os.Args) > 3 {
+		fmt.Println("\nToo many arguments passed")
+		os.Exit(1)
+	}
+
+	levelBolt := os.Args[1]
+	if levelBolt != level && levelBolt != bolt {
+		fmt.Println("\nFirst argument should be `level` or `bolt`")
+		os.Exit(1)
+	}
+
+	path := os.Args[2]
+
+	var dbase *hybridDB.HybridDB
+	var err error
+	if levelBolt == bolt {
+		dbase = hybridDB.NewBoltMapHybridDB(nil, path)
+	} else {
+		dbase, err = hybridDB.NewLevelMapHybridDB(path, false)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	dbo := databaseOverlay.NewOverlay(dbase)
+	defer dbo.Close()
+
+	err = dbo.RebuildAddressIndex()
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Address index rebuilt")
+}
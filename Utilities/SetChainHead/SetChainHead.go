@@ -20,6 +20,7 @@ func main() {
 	fmt.Println("Usage:")
 	fmt.Println("SetChainHead level/bolt NewMaxHeight DBFileLocation")
 	fmt.Println("Program will reset the highest directory block to the specified height")
+	fmt.Println("factomd checks and repairs the block heads when it starts, this is only needed for databases it can't repair")
 
 	if len(os.Args) < 4 {
 		fmt.Println("\nNot enough arguments passed")
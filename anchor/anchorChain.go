@@ -0,0 +1,41 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package anchor
+
+import (
+	"errors"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// The chains an anchor record has a part for
+const (
+	ChainBitcoin  = "bitcoin"
+	ChainEthereum = "ethereum"
+)
+
+// ErrTransactionNotFound is returned by GetTransaction for transactions the chain dropped, the
+// anchor is submitted again
+var ErrTransactionNotFound = errors.New("Anchor transaction not found")
+
+// AnchorChain is a blockchain directory block KeyMRs are anchored into
+type AnchorChain interface {
+	// Name is ChainBitcoin or ChainEthereum, it decides which part of the anchor record is filled in
+	Name() string
+	// Submit writes the KeyMR of the directory block into a transaction and returns its id
+	Submit(dbHeight uint32, keyMR interfaces.IHash) (string, error)
+	// GetTransaction returns where the transaction is in the chain, or nil if it is not in a block yet
+	GetTransaction(txID string) (*ChainTransaction, error)
+}
+
+// ChainTransaction is an anchor transaction that made it into a block of the target chain
+type ChainTransaction struct {
+	Address       string
+	TXID          string
+	BlockHeight   int64
+	BlockHash     string
+	Offset        int64
+	Confirmations int64
+}
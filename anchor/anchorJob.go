@@ -0,0 +1,316 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package anchor
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// ANCHOR_JOBS is the database bucket the anchorer tracks its anchors in
+var ANCHOR_JOBS = []byte("AnchorJobs")
+
+// ANCHOR_OPEN_JOBS holds a copy of every job that is not published or failed yet, under the same
+// key, so processing the jobs does not have to read every job ever anchored
+var ANCHOR_OPEN_JOBS = []byte("AnchorOpenJobs")
+
+const (
+	// AnchorJobQueued is waiting to be submitted, or resubmitted after a failure
+	AnchorJobQueued = iota
+	// AnchorJobSubmitted is in the target chain, waiting for confirmations
+	AnchorJobSubmitted
+	// AnchorJobConfirmed has enough confirmations, but the signed record is not published yet
+	AnchorJobConfirmed
+	// AnchorJobCommitted has its signed record committed to the anchor chain, waiting for it to
+	// show up in a saved entry block
+	AnchorJobCommitted
+	// AnchorJobPublished has its signed anchor record in a saved entry block of the anchor chain
+	AnchorJobPublished
+	// AnchorJobFailed ran out of attempts
+	AnchorJobFailed
+)
+
+var anchorJobStatusNames = []string{"queued", "submitted", "confirmed", "committed", "published", "failed"}
+
+// AnchorJobStatusName returns the name of a job status, as shown in the API
+func AnchorJobStatusName(status int) string {
+	if status < 0 || status >= len(anchorJobStatusNames) {
+		return "unknown"
+	}
+	return anchorJobStatusNames[status]
+}
+
+// AnchorJob tracks anchoring one directory block into one target chain
+type AnchorJob struct {
+	Chain    string
+	DBHeight uint32
+	KeyMR    string
+
+	Status      int
+	Attempts    uint32
+	LastAttempt int64 // Unix time in seconds
+	LastError   string
+
+	// Filled in once the transaction is in a block of the target chain
+	Transaction *ChainTransaction
+
+	// The signed anchor record, filled in once the job is confirmed
+	Record    []byte
+	Signature []byte
+	// Unix time in seconds the signed record was last committed to the anchor chain
+	Committed int64
+}
+
+var _ interfaces.BinaryMarshallable = (*AnchorJob)(nil)
+
+// AnchorJobKey is the key of a job in the ANCHOR_JOBS bucket, sorted by height first
+func AnchorJobKey(chain string, dbHeight uint32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, dbHeight)
+	return append(key, []byte(chain)...)
+}
+
+func (e *AnchorJob) DatabaseKey() []byte {
+	return AnchorJobKey(e.Chain, e.DBHeight)
+}
+
+// IsFinished is true once the job is published or failed, and will not be processed again
+func (e *AnchorJob) IsFinished() bool {
+	return e.Status == AnchorJobPublished || e.Status == AnchorJobFailed
+}
+
+func (e *AnchorJob) StatusName() string {
+	return AnchorJobStatusName(e.Status)
+}
+
+// AnchorRecord returns the anchor record for the job, with the part of the target chain filled in
+// if the transaction is in a block
+func (e *AnchorJob) AnchorRecord() *AnchorRecord {
+	ar := new(AnchorRecord)
+	ar.AnchorRecordVer = 1
+	ar.DBHeight = e.DBHeight
+	ar.KeyMR = e.KeyMR
+	ar.RecordHeight = e.DBHeight
+
+	tx := e.Transaction
+	if tx == nil {
+		return ar
+	}
+	switch e.Chain {
+	case ChainBitcoin:
+		ar.Bitcoin = new(BitcoinStruct)
+		ar.Bitcoin.Address = tx.Address
+		ar.Bitcoin.TXID = tx.TXID
+		ar.Bitcoin.BlockHeight = int32(tx.BlockHeight)
+		ar.Bitcoin.BlockHash = tx.BlockHash
+		ar.Bitcoin.Offset = int32(tx.Offset)
+	case ChainEthereum:
+		ar.Ethereum = new(EthereumStruct)
+		ar.Ethereum.Address = tx.Address
+		ar.Ethereum.TXID = tx.TXID
+		ar.Ethereum.BlockHeight = tx.BlockHeight
+		ar.Ethereum.BlockHash = tx.BlockHash
+		ar.Ethereum.Offset = tx.Offset
+	}
+	return ar
+}
+
+func (e *AnchorJob) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+
+	err := buf.PushString(e.Chain)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushUInt32(e.DBHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushString(e.KeyMR)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushVarInt(uint64(e.Status))
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushUInt32(e.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushInt64(e.LastAttempt)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushString(e.LastError)
+	if err != nil {
+		return nil, err
+	}
+
+	err = buf.PushBool(e.Transaction != nil)
+	if err != nil {
+		return nil, err
+	}
+	if e.Transaction != nil {
+		err = e.Transaction.push(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = buf.PushBytes(e.Record)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushBytes(e.Signature)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushInt64(e.Committed)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (e *AnchorJob) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling AnchorJob: %v", r)
+		}
+	}()
+	buf := primitives.NewBuffer(data)
+
+	e.Chain, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+	e.DBHeight, err = buf.PopUInt32()
+	if err != nil {
+		return nil, err
+	}
+	e.KeyMR, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+	status, err := buf.PopVarInt()
+	if err != nil {
+		return nil, err
+	}
+	e.Status = int(status)
+	e.Attempts, err = buf.PopUInt32()
+	if err != nil {
+		return nil, err
+	}
+	e.LastAttempt, err = buf.PopInt64()
+	if err != nil {
+		return nil, err
+	}
+	e.LastError, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+
+	hasTx, err := buf.PopBool()
+	if err != nil {
+		return nil, err
+	}
+	e.Transaction = nil
+	if hasTx {
+		e.Transaction = new(ChainTransaction)
+		err = e.Transaction.pop(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	e.Record, err = buf.PopBytes()
+	if err != nil {
+		return nil, err
+	}
+	e.Signature, err = buf.PopBytes()
+	if err != nil {
+		return nil, err
+	}
+	e.Committed, err = buf.PopInt64()
+	if err != nil {
+		return nil, err
+	}
+
+	newData = buf.DeepCopyBytes()
+	return
+}
+
+func (e *AnchorJob) UnmarshalBinary(data []byte) error {
+	_, err := e.UnmarshalBinaryData(data)
+	return err
+}
+
+func (e *AnchorJob) JSONByte() ([]byte, error) {
+	return primitives.EncodeJSON(e)
+}
+
+func (e *AnchorJob) JSONString() (string, error) {
+	return primitives.EncodeJSONString(e)
+}
+
+func (e *AnchorJob) String() string {
+	str, _ := e.JSONString()
+	return str
+}
+
+func (e *ChainTransaction) push(buf *primitives.Buffer) error {
+	err := buf.PushString(e.Address)
+	if err != nil {
+		return err
+	}
+	err = buf.PushString(e.TXID)
+	if err != nil {
+		return err
+	}
+	err = buf.PushInt64(e.BlockHeight)
+	if err != nil {
+		return err
+	}
+	err = buf.PushString(e.BlockHash)
+	if err != nil {
+		return err
+	}
+	err = buf.PushInt64(e.Offset)
+	if err != nil {
+		return err
+	}
+	return buf.PushInt64(e.Confirmations)
+}
+
+func (e *ChainTransaction) pop(buf *primitives.Buffer) error {
+	var err error
+	e.Address, err = buf.PopString()
+	if err != nil {
+		return err
+	}
+	e.TXID, err = buf.PopString()
+	if err != nil {
+		return err
+	}
+	e.BlockHeight, err = buf.PopInt64()
+	if err != nil {
+		return err
+	}
+	e.BlockHash, err = buf.PopString()
+	if err != nil {
+		return err
+	}
+	e.Offset, err = buf.PopInt64()
+	if err != nil {
+		return err
+	}
+	e.Confirmations, err = buf.PopInt64()
+	return err
+}
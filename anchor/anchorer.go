@@ -0,0 +1,365 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package anchor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/util"
+)
+
+// JobStore is the part of the database the anchorer keeps its jobs in
+type JobStore interface {
+	Put(bucket, key []byte, data interfaces.BinaryMarshallable) error
+	Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error)
+	Delete(bucket, key []byte) error
+	ListAllKeys(bucket []byte) ([][]byte, error)
+}
+
+// Anchorer anchors directory blocks in process, instead of leaving it to an external anchor
+// service. Every directory block gets a job per target chain, the job is submitted, retried until
+// it makes it into a block, and once it has enough confirmations the anchor record is signed and
+// handed to Publish. The job is published once IsPublished finds the record, and handed to Publish
+// again if it does not within PublishTimeout. The jobs are kept in the database, so a restart picks
+// up where it left off.
+type Anchorer struct {
+	Chains []AnchorChain
+	Signer interfaces.Signer
+	DB     JobStore
+
+	// Confirmations needed before an anchor record is signed and published
+	Confirmations int64
+	// RetryDelay is the time between attempts to submit a job
+	RetryDelay time.Duration
+	// MaxAttempts fails a job after this many submissions, 0 retries forever
+	MaxAttempts uint32
+
+	// Publish is handed every signed anchor record, the signature is the V2 external ID
+	Publish func(record *AnchorRecord, data []byte, sig []byte) error
+	// IsPublished returns true once a record handed to Publish is in a saved entry block. If it is
+	// nil, records are published as soon as Publish returns.
+	IsPublished func(data []byte, sig []byte) (bool, error)
+	// PublishTimeout is the time a record has to show up before it is handed to Publish again
+	PublishTimeout time.Duration
+
+	// mutex guards the database, processMutex keeps a single Process running. Process holds only
+	// processMutex across the calls to the chains.
+	mutex        sync.Mutex
+	processMutex sync.Mutex
+	quit         chan struct{}
+}
+
+var _ interfaces.IAnchor = (*Anchorer)(nil)
+
+func NewAnchorer(db JobStore, signer interfaces.Signer, chains ...AnchorChain) *Anchorer {
+	a := new(Anchorer)
+	a.DB = db
+	a.Signer = signer
+	a.Chains = chains
+	a.Confirmations = 6
+	a.RetryDelay = time.Minute
+	a.PublishTimeout = 30 * time.Minute
+	return a
+}
+
+// InitRPCClient checks the anchorer is usable, the chains connect on their own
+func (a *Anchorer) InitRPCClient() error {
+	if len(a.Chains) == 0 {
+		return fmt.Errorf("No anchor chains configured")
+	}
+	if a.Signer == nil {
+		return fmt.Errorf("No key to sign anchor records with")
+	}
+	if a.DB == nil {
+		return fmt.Errorf("No database to keep anchor jobs in")
+	}
+	return nil
+}
+
+// UpdateDirBlockInfoMap queues a newly saved directory block to be anchored
+func (a *Anchorer) UpdateDirBlockInfoMap(dirBlockInfo interfaces.IDirBlockInfo) {
+	err := a.Queue(dirBlockInfo.GetDBHeight(), dirBlockInfo.GetDBMerkleRoot())
+	if err != nil {
+		fmt.Printf("Error queueing anchor for directory block %v: %v\n", dirBlockInfo.GetDBHeight(), err)
+	}
+}
+
+// AnchorDBlock queues a directory block to be anchored into every chain
+func (a *Anchorer) AnchorDBlock(dBlock interfaces.IDirectoryBlock) error {
+	return a.Queue(dBlock.GetDatabaseHeight(), dBlock.GetKeyMR())
+}
+
+// Queue adds a job for every chain the directory block is not anchored into yet
+func (a *Anchorer) Queue(dbHeight uint32, keyMR interfaces.IHash) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, chain := range a.Chains {
+		job, err := a.getJob(chain.Name(), dbHeight)
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			continue
+		}
+		job = new(AnchorJob)
+		job.Chain = chain.Name()
+		job.DBHeight = dbHeight
+		job.KeyMR = keyMR.String()
+		job.Status = AnchorJobQueued
+		err = a.saveJob(job)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveJob saves the job, and keeps it in ANCHOR_OPEN_JOBS until it is finished
+func (a *Anchorer) saveJob(job *AnchorJob) error {
+	err := a.DB.Put(ANCHOR_JOBS, job.DatabaseKey(), job)
+	if err != nil {
+		return err
+	}
+	if job.IsFinished() {
+		return a.DB.Delete(ANCHOR_OPEN_JOBS, job.DatabaseKey())
+	}
+	return a.DB.Put(ANCHOR_OPEN_JOBS, job.DatabaseKey(), job)
+}
+
+func (a *Anchorer) getJob(chain string, dbHeight uint32) (*AnchorJob, error) {
+	job, err := a.DB.Get(ANCHOR_JOBS, AnchorJobKey(chain, dbHeight), new(AnchorJob))
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, nil
+	}
+	return job.(*AnchorJob), nil
+}
+
+// GetJob returns the job anchoring the directory block into the chain, or nil if there is none
+func (a *Anchorer) GetJob(chain string, dbHeight uint32) (*AnchorJob, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.getJob(chain, dbHeight)
+}
+
+func (a *Anchorer) getJobs(bucket []byte) ([]*AnchorJob, error) {
+	keys, err := a.DB.ListAllKeys(bucket)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(util.ByByteArray(keys))
+
+	jobs := []*AnchorJob{}
+	for _, k := range keys {
+		job, err := a.DB.Get(bucket, k, new(AnchorJob))
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			continue
+		}
+		jobs = append(jobs, job.(*AnchorJob))
+	}
+	return jobs, nil
+}
+
+// GetJobs returns every job, ordered by directory block height
+func (a *Anchorer) GetJobs() ([]*AnchorJob, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.getJobs(ANCHOR_JOBS)
+}
+
+func (a *Anchorer) getChain(name string) AnchorChain {
+	for _, chain := range a.Chains {
+		if chain.Name() == name {
+			return chain
+		}
+	}
+	return nil
+}
+
+// Process moves every unfinished job one step forward. Errors are saved in the job and retried on
+// a later call, only database errors are returned.
+func (a *Anchorer) Process(now time.Time) error {
+	a.processMutex.Lock()
+	defer a.processMutex.Unlock()
+
+	a.mutex.Lock()
+	jobs, err := a.getJobs(ANCHOR_OPEN_JOBS)
+	a.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.IsFinished() {
+			continue
+		}
+		chain := a.getChain(job.Chain)
+		if chain == nil {
+			continue
+		}
+		if a.processJob(chain, job, now) == false {
+			continue
+		}
+		a.mutex.Lock()
+		err = a.saveJob(job)
+		a.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processJob returns true if the job changed
+func (a *Anchorer) processJob(chain AnchorChain, job *AnchorJob, now time.Time) bool {
+	switch job.Status {
+	case AnchorJobQueued:
+		if job.Attempts > 0 && now.Before(time.Unix(job.LastAttempt, 0).Add(a.RetryDelay)) {
+			return false
+		}
+		keyMR, err := primitives.NewShaHashFromStr(job.KeyMR)
+		if err != nil {
+			job.Status = AnchorJobFailed
+			job.LastError = err.Error()
+			return true
+		}
+		job.Attempts++
+		job.LastAttempt = now.Unix()
+		txID, err := chain.Submit(job.DBHeight, keyMR)
+		if err != nil {
+			job.LastError = err.Error()
+			if a.MaxAttempts > 0 && job.Attempts >= a.MaxAttempts {
+				job.Status = AnchorJobFailed
+			}
+			return true
+		}
+		job.Transaction = &ChainTransaction{TXID: txID}
+		job.LastError = ""
+		job.Status = AnchorJobSubmitted
+		return true
+
+	case AnchorJobSubmitted:
+		tx, err := chain.GetTransaction(job.Transaction.TXID)
+		if err == ErrTransactionNotFound {
+			// Dropped by the chain, submit it again
+			job.Transaction = nil
+			job.LastError = err.Error()
+			job.Status = AnchorJobQueued
+			return true
+		}
+		if err != nil {
+			job.LastError = err.Error()
+			return true
+		}
+		if tx == nil {
+			return false
+		}
+		job.Transaction = tx
+		if tx.Confirmations < a.Confirmations {
+			return true
+		}
+		record := job.AnchorRecord()
+		job.Record, job.Signature, err = record.MarshalAndSignV2(a.Signer)
+		if err != nil {
+			job.LastError = err.Error()
+			return true
+		}
+		job.LastError = ""
+		job.Status = AnchorJobConfirmed
+		a.publish(job, now)
+		return true
+
+	case AnchorJobConfirmed:
+		a.publish(job, now)
+		return true
+
+	case AnchorJobCommitted:
+		found, err := a.IsPublished(job.Record, job.Signature)
+		if err != nil {
+			job.LastError = err.Error()
+			return true
+		}
+		if found {
+			job.LastError = ""
+			job.Status = AnchorJobPublished
+			return true
+		}
+		if now.Before(time.Unix(job.Committed, 0).Add(a.PublishTimeout)) {
+			return false
+		}
+		// Lost on the way to the anchor chain, publish it again
+		job.LastError = "Anchor record not in a saved entry block"
+		job.Status = AnchorJobConfirmed
+		return true
+	}
+	return false
+}
+
+func (a *Anchorer) publish(job *AnchorJob, now time.Time) {
+	if a.Publish != nil {
+		record := new(AnchorRecord)
+		err := record.Unmarshal(job.Record)
+		if err == nil {
+			err = a.Publish(record, job.Record, job.Signature)
+		}
+		if err != nil {
+			job.LastError = err.Error()
+			return
+		}
+	}
+	job.LastError = ""
+	job.Status = AnchorJobPublished
+	if a.Publish != nil && a.IsPublished != nil {
+		job.Committed = now.Unix()
+		job.Status = AnchorJobCommitted
+	}
+}
+
+// Start processes the jobs every interval until Stop is called
+func (a *Anchorer) Start(interval time.Duration) {
+	a.mutex.Lock()
+	if a.quit != nil {
+		a.mutex.Unlock()
+		return
+	}
+	quit := make(chan struct{})
+	a.quit = quit
+	a.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case now := <-ticker.C:
+				err := a.Process(now)
+				if err != nil {
+					fmt.Printf("Error processing anchor jobs: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+func (a *Anchorer) Stop() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.quit != nil {
+		close(a.quit)
+		a.quit = nil
+	}
+}
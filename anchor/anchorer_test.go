@@ -0,0 +1,294 @@
+package anchor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/FactomProject/factomd/anchor"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/mapdb"
+	. "github.com/FactomProject/factomd/testHelper"
+)
+
+func TestMarshalUnmarshalAnchorJob(t *testing.T) {
+	job := new(AnchorJob)
+	job.Chain = ChainBitcoin
+	job.DBHeight = 12
+	job.KeyMR = "980ab6d50d9fad574ad4df6dba06a8c02b1c67288ee5beab3fbfde2723f73ef6"
+	job.Status = AnchorJobSubmitted
+	job.Attempts = 3
+	job.LastAttempt = 1234567
+	job.LastError = "error"
+	job.Transaction = &ChainTransaction{Address: "a", TXID: "b", BlockHeight: 5, BlockHash: "c", Offset: 2, Confirmations: 4}
+	job.Record = []byte("record")
+	job.Signature = []byte("sig")
+	job.Committed = 7654321
+
+	data, err := job.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job2 := new(AnchorJob)
+	rest, err := job2.UnmarshalBinaryData(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(rest) > 0 {
+		t.Errorf("Returned too much data")
+	}
+	data2, err := job2.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if primitives.AreBytesEqual(data, data2) == false {
+		t.Errorf("Jobs are not equal - %v vs %v", job, job2)
+	}
+	if *job2.Transaction != *job.Transaction {
+		t.Errorf("Transactions are not equal - %v vs %v", job.Transaction, job2.Transaction)
+	}
+}
+
+func TestMockChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockchain")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := NewMockChain(ChainBitcoin, filepath.Join(dir, "chain.json"))
+	keyMR := primitives.Sha([]byte("dblock"))
+
+	txID, err := chain.Submit(1, keyMR)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	tx, err := chain.GetTransaction(txID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if tx != nil {
+		t.Errorf("Transaction is in a block before one was mined")
+	}
+
+	for i := 0; i < 3; i++ {
+		err = chain.Mine()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// A new MockChain reads the same file
+	chain = NewMockChain(ChainBitcoin, filepath.Join(dir, "chain.json"))
+	tx, err = chain.GetTransaction(txID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if tx == nil {
+		t.Fatalf("Transaction not found")
+	}
+	if tx.Confirmations != 3 {
+		t.Errorf("Expected 3 confirmations, got %v", tx.Confirmations)
+	}
+	if tx.BlockHeight != 0 || len(tx.BlockHash) != 64 {
+		t.Errorf("Wrong block - %v", tx)
+	}
+
+	_, err = chain.GetTransaction(primitives.Sha([]byte("nothing")).String())
+	if err != ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+	}
+}
+
+// failingChain fails to submit a given number of times
+type failingChain struct {
+	*MockChain
+	failures int
+}
+
+func (c *failingChain) Submit(dbHeight uint32, keyMR interfaces.IHash) (string, error) {
+	if c.failures > 0 {
+		c.failures--
+		return "", os.ErrInvalid
+	}
+	return c.MockChain.Submit(dbHeight, keyMR)
+}
+
+func TestAnchorer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anchorer")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := &failingChain{NewMockChain(ChainBitcoin, filepath.Join(dir, "chain.json")), 1}
+	db := new(mapdb.MapDB)
+	db.Init(nil)
+	priv := NewPrimitivesPrivateKey(0)
+
+	anchorer := NewAnchorer(db, priv, chain)
+	anchorer.Confirmations = 2
+	anchorer.RetryDelay = time.Minute
+
+	published := []*AnchorRecord{}
+	anchorer.Publish = func(record *AnchorRecord, data []byte, sig []byte) error {
+		_, valid, err := UnmarshalAndValidateAnchorRecordV2(data, [][]byte{sig}, []interfaces.Verifier{priv.Pub})
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+		if valid == false {
+			t.Errorf("Published anchor record has an invalid signature")
+		}
+		published = append(published, record)
+		return nil
+	}
+	inBlock := false
+	anchorer.IsPublished = func(data []byte, sig []byte) (bool, error) {
+		return inBlock, nil
+	}
+	anchorer.PublishTimeout = 10 * time.Minute
+
+	dBlock := CreateTestDirectoryBlock(nil)
+	err = anchorer.AnchorDBlock(dBlock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// Queueing the same block twice keeps one job
+	err = anchorer.AnchorDBlock(dBlock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	jobStatus := func() *AnchorJob {
+		job, err := anchorer.GetJob(ChainBitcoin, dBlock.GetDatabaseHeight())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if job == nil {
+			t.Fatalf("Job not found")
+		}
+		return job
+	}
+
+	now := time.Now()
+	// The first submission fails
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job := jobStatus()
+	if job.Status != AnchorJobQueued || job.Attempts != 1 || job.LastError == "" {
+		t.Errorf("Expected a failed attempt, got %v", job)
+	}
+
+	// Not retried before the delay is over
+	err = anchorer.Process(now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if jobStatus().Attempts != 1 {
+		t.Errorf("Job was retried too early")
+	}
+
+	now = now.Add(2 * time.Minute)
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job = jobStatus()
+	if job.Status != AnchorJobSubmitted || job.Attempts != 2 {
+		t.Errorf("Expected a submitted job, got %v", job)
+	}
+	open, err := db.ListAllKeys(ANCHOR_OPEN_JOBS)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(open) != 1 {
+		t.Errorf("Expected 1 open job, got %v", len(open))
+	}
+
+	chain.Mine()
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if jobStatus().Status != AnchorJobSubmitted {
+		t.Errorf("Job confirmed with too few confirmations")
+	}
+
+	chain.Mine()
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job = jobStatus()
+	if job.Status != AnchorJobCommitted {
+		t.Errorf("Expected a committed job, got %v", job)
+	}
+	if len(published) != 1 {
+		t.Fatalf("Expected 1 published record, got %v", len(published))
+	}
+
+	// Not published again before the timeout is over
+	err = anchorer.Process(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if jobStatus().Status != AnchorJobCommitted || len(published) != 1 {
+		t.Errorf("Job was published again too early")
+	}
+
+	// Published again once the record did not show up in time
+	now = now.Add(20 * time.Minute)
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job = jobStatus()
+	if job.Status != AnchorJobConfirmed || job.LastError == "" {
+		t.Errorf("Expected a job to publish again, got %v", job)
+	}
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if jobStatus().Status != AnchorJobCommitted || len(published) != 2 {
+		t.Errorf("Job was not published again")
+	}
+
+	inBlock = true
+	err = anchorer.Process(now)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	job = jobStatus()
+	if job.Status != AnchorJobPublished {
+		t.Errorf("Expected a published job, got %v", job)
+	}
+	record := published[0]
+	if record.DBHeight != dBlock.GetDatabaseHeight() || record.KeyMR != dBlock.GetKeyMR().String() {
+		t.Errorf("Wrong anchor record - %v", record)
+	}
+	if record.Bitcoin == nil || record.Bitcoin.TXID != job.Transaction.TXID {
+		t.Errorf("Wrong bitcoin anchor - %v", record)
+	}
+
+	jobs, err := anchorer.GetJobs()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("Expected 1 job, got %v", len(jobs))
+	}
+	// Published jobs are not processed again
+	open, err = db.ListAllKeys(ANCHOR_OPEN_JOBS)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("Expected no open jobs, got %v", len(open))
+	}
+}
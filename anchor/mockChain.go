@@ -0,0 +1,209 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package anchor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// MockChain is an AnchorChain kept in a JSON file, for tests and private networks that have no
+// real chain to anchor into. Submitted transactions wait in a mempool until a block is mined.
+type MockChain struct {
+	ChainName string
+	Filename  string
+	Address   string
+	// AutoMine mines a block on every Submit and GetTransaction, so anchors confirm without
+	// anything else driving the chain
+	AutoMine bool
+
+	mutex sync.Mutex
+}
+
+var _ AnchorChain = (*MockChain)(nil)
+
+type mockChainState struct {
+	Blocks  []*mockBlock
+	Mempool []*mockTransaction
+}
+
+type mockBlock struct {
+	Height       int64
+	Hash         string
+	PrevHash     string
+	Transactions []*mockTransaction
+}
+
+type mockTransaction struct {
+	TXID string
+	Data string
+}
+
+func NewMockChain(name, filename string) *MockChain {
+	c := new(MockChain)
+	c.ChainName = name
+	c.Filename = filename
+	c.Address = "mock-" + name
+	return c
+}
+
+func (c *MockChain) Name() string {
+	return c.ChainName
+}
+
+func (c *MockChain) load() (*mockChainState, error) {
+	state := new(mockChainState)
+	data, err := ioutil.ReadFile(c.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (c *MockChain) save(state *mockChainState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.Filename)
+	if dir != "" {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(c.Filename, data, 0644)
+}
+
+func (state *mockChainState) mine() *mockBlock {
+	b := new(mockBlock)
+	b.Transactions = state.Mempool
+	state.Mempool = nil
+
+	prev := primitives.NewZeroHash()
+	if len(state.Blocks) > 0 {
+		last := state.Blocks[len(state.Blocks)-1]
+		b.Height = last.Height + 1
+		b.PrevHash = last.Hash
+		h, err := primitives.NewShaHashFromStr(last.Hash)
+		if err == nil {
+			prev = h
+		}
+	}
+
+	data := prev.Bytes()
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, uint64(b.Height))
+	data = append(data, height...)
+	for _, tx := range b.Transactions {
+		data = append(data, []byte(tx.TXID)...)
+	}
+	b.Hash = primitives.Sha(data).String()
+
+	state.Blocks = append(state.Blocks, b)
+	return b
+}
+
+// Mine puts the mempool into a new block, it mines an empty block if the mempool is empty
+func (c *MockChain) Mine() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	state, err := c.load()
+	if err != nil {
+		return err
+	}
+	state.mine()
+	return c.save(state)
+}
+
+func (c *MockChain) Submit(dbHeight uint32, keyMR interfaces.IHash) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	state, err := c.load()
+	if err != nil {
+		return "", err
+	}
+
+	// The same KeyMR can be submitted more than once, so the id also covers the chain's length
+	data := append([]byte{}, keyMR.Bytes()...)
+	counts := make([]byte, 16)
+	binary.BigEndian.PutUint64(counts[0:8], uint64(len(state.Blocks)))
+	binary.BigEndian.PutUint64(counts[8:16], uint64(len(state.Mempool)))
+	data = append(data, counts...)
+
+	tx := new(mockTransaction)
+	tx.TXID = primitives.Sha(data).String()
+	tx.Data = fmt.Sprintf("FA%x", keyMR.Bytes())
+	state.Mempool = append(state.Mempool, tx)
+
+	if c.AutoMine {
+		state.mine()
+	}
+	err = c.save(state)
+	if err != nil {
+		return "", err
+	}
+	return tx.TXID, nil
+}
+
+func (c *MockChain) GetTransaction(txID string) (*ChainTransaction, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	state, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	if c.AutoMine {
+		state.mine()
+		err = c.save(state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tx := range state.Mempool {
+		if tx.TXID == txID {
+			return nil, nil
+		}
+	}
+	if len(state.Blocks) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	tip := state.Blocks[len(state.Blocks)-1].Height
+	for _, b := range state.Blocks {
+		for i, tx := range b.Transactions {
+			if tx.TXID != txID {
+				continue
+			}
+			answer := new(ChainTransaction)
+			answer.Address = c.Address
+			answer.TXID = tx.TXID
+			answer.BlockHeight = b.Height
+			answer.BlockHash = b.Hash
+			answer.Offset = int64(i)
+			answer.Confirmations = tip - b.Height + 1
+			return answer, nil
+		}
+	}
+	return nil, ErrTransactionNotFound
+}
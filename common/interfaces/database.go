@@ -4,7 +4,9 @@
 
 package interfaces
 
-import ()
+import (
+	"bytes"
+)
 
 type IDatabase interface {
 	Close() error
@@ -18,6 +20,107 @@ type IDatabase interface {
 	ListAllBuckets() ([][]byte, error)
 	Trim()
 	DoesKeyExist(bucket, key []byte) (bool, error)
+	// NewIterator walks the bucket in key order without loading it into memory. A nil range
+	// walks the whole bucket.
+	NewIterator(bucket []byte, r *IteratorRange) (IIterator, error)
+	// GetSnapshot returns a read only view of the database as it is now
+	GetSnapshot() (ISnapshot, error)
+}
+
+// ISnapshot is a consistent view of a database, writes made after the snapshot was taken are not
+// seen. Release must be called once the snapshot is no longer needed, as an open snapshot can keep
+// the database from reclaiming space. A snapshot is not safe for use by several goroutines at once.
+type ISnapshot interface {
+	Get(bucket, key []byte, destination BinaryMarshallable) (BinaryMarshallable, error)
+	ListAllKeys(bucket []byte) ([][]byte, error)
+	GetAll(bucket []byte, sample BinaryMarshallableAndCopyable) ([]BinaryMarshallableAndCopyable, [][]byte, error)
+	DoesKeyExist(bucket, key []byte) (bool, error)
+	NewIterator(bucket []byte, r *IteratorRange) (IIterator, error)
+	Release()
+}
+
+// IAtomicBatcher is implemented by databases that know whether PutInBatch writes either all of a
+// batch or none of it, even if the process stops part way through
+type IAtomicBatcher interface {
+	AtomicBatches() bool
+}
+
+// AtomicBatches returns true if the database's PutInBatch is known to be atomic. Databases that do
+// not say are assumed not to be.
+func AtomicBatches(db IDatabase) bool {
+	a, ok := db.(IAtomicBatcher)
+	return ok && a.AtomicBatches()
+}
+
+// IIterator is a cursor over the keys of one bucket, in byte order. It starts before the first key,
+// so Next has to be called before Key and Value. The slices returned by Key and Value are only
+// valid until the next call to Next or Seek, and Release must always be called.
+type IIterator interface {
+	// Next moves to the next key, it returns false once there are no more keys in the range
+	Next() bool
+	// Seek moves to the first key in the range that is >= key, it returns false if there is none
+	Seek(key []byte) bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+// IteratorRange limits an iterator to the keys >= Start and < Limit that begin with Prefix.
+// Empty fields do not limit the iterator.
+type IteratorRange struct {
+	Start  []byte
+	Limit  []byte
+	Prefix []byte
+}
+
+// Bounds returns the lowest key of the range and the key the range ends before, a nil limit
+// is the end of the bucket
+func (r *IteratorRange) Bounds() (start []byte, limit []byte) {
+	if r == nil {
+		return nil, nil
+	}
+	start = r.Start
+	limit = r.Limit
+	if len(limit) == 0 {
+		limit = nil
+	}
+	if len(r.Prefix) > 0 {
+		if bytes.Compare(r.Prefix, start) > 0 {
+			start = r.Prefix
+		}
+		prefixLimit := PrefixLimit(r.Prefix)
+		if prefixLimit != nil && (limit == nil || bytes.Compare(prefixLimit, limit) < 0) {
+			limit = prefixLimit
+		}
+	}
+	return start, limit
+}
+
+// Contains returns true if the key is in the range
+func (r *IteratorRange) Contains(key []byte) bool {
+	start, limit := r.Bounds()
+	if bytes.Compare(key, start) < 0 {
+		return false
+	}
+	if limit != nil && bytes.Compare(key, limit) >= 0 {
+		return false
+	}
+	return true
+}
+
+// PrefixLimit returns the first key after every key that begins with the prefix, or nil if there
+// is no such key (the prefix is all 0xff)
+func PrefixLimit(prefix []byte) []byte {
+	limit := make([]byte, len(prefix))
+	copy(limit, prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
 }
 
 type Record struct {
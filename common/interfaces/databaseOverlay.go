@@ -32,6 +32,15 @@ type DBOverlaySimple interface {
 	FetchIncludedIn(hash IHash) (IHash, error)
 	FetchPaidFor(hash IHash) (IHash, error)
 	FetchAllEBlocksByChain(IHash) ([]IEntryBlock, error)
+	FetchEBlockHeightsByChain(chainID IHash) ([]uint32, error)
+	FetchEBlocksByChainInHeightRange(chainID IHash, startHeight, endHeight uint32, limit int) ([]IEntryBlock, error)
+	FetchAddressTransactions(address []byte, startHeight uint32, cursor []byte, limit int) ([]AddressTransaction, []byte, error)
+	FetchFactoidBalanceAtHeight(address [32]byte, height uint32) (int64, error)
+	FetchECBalanceAtHeight(address [32]byte, height uint32) (int64, error)
+	FetchAnchorEntriesByDBHeight(dbHeight uint32) ([]AnchorEntry, error)
+	InitAnchorRecordIndex() error
+	SaveEthereumAnchorRecord(dbHeight uint32, record IAnchorRecord) error
+	FetchEthereumAnchorRecord(dbHeight uint32) (IAnchorRecord, error)
 	InsertEntryMultiBatch(entry IEBEntry) error
 	ProcessABlockMultiBatch(block DatabaseBatchable) error
 	ProcessDBlockMultiBatch(block DatabaseBlockWithEntries) error
@@ -41,7 +50,10 @@ type DBOverlaySimple interface {
 	ProcessECBlockMultiBatch(IEntryCreditBlock, bool) (err error)
 	ProcessFBlockMultiBatch(DatabaseBlockWithEntries) error
 	FetchDirBlockInfoByKeyMR(hash IHash) (IDirBlockInfo, error)
+	SaveDirBlockInfo(block IDirBlockInfo) error
 	SetExportData(path string)
+	SetAddressIndex(enabled bool)
+	GetAddressIndex() bool
 	StartMultiBatch()
 	Trim()
 	FetchAllEntriesByChainID(chainID IHash) ([]IEBEntry, error)
@@ -49,6 +61,13 @@ type DBOverlaySimple interface {
 	FetchKeyValueStore(key []byte, dst BinaryMarshallable) (BinaryMarshallable, error)
 	SaveDatabaseEntryHeight(height uint32) error
 	FetchDatabaseEntryHeight() (uint32, error)
+	SaveEntryPruneHeight(height uint32) error
+	FetchEntryPruneHeight() (uint32, error)
+	PruneEntries(pruneHeight uint32, keep func(chainID IHash) bool) (int, error)
+	IsEntryPruned(hash IHash) (bool, error)
+	RecoverMultiBatch() (bool, error)
+	CheckBlockHeads(depth uint32) (int, error)
+	SetBlockCacheSize(size int)
 }
 
 // Db defines a generic interface that is used to request and insert data into db
@@ -79,6 +98,12 @@ type DBOverlay interface {
 
 	FetchAllEntryIDs() ([]IHash, error)
 
+	// ForEachEntryID streams the hashes of every entry, where FetchAllEntryIDs loads them all
+	ForEachEntryID(f func(entryID IHash) error) error
+
+	ForEachInBucket(bucket []byte, r *IteratorRange, sample BinaryMarshallableAndCopyable, f func(key []byte, value BinaryMarshallableAndCopyable) error) error
+	ForEachBlockKeyInBucket(bucket []byte, f func(key IHash) error) error
+
 	//**********************************EBlock**********************************//
 
 	// ProcessEBlockBatche inserts the EBlock and update all it's ebentries in DB
@@ -101,6 +126,12 @@ type DBOverlay interface {
 	// FetchAllEBlocksByChain gets all of the blocks by chain id
 	FetchAllEBlocksByChain(IHash) ([]IEntryBlock, error)
 
+	// FetchEBlockHeightsByChain gets the directory block heights of a chain's entry blocks
+	FetchEBlockHeightsByChain(chainID IHash) ([]uint32, error)
+
+	// FetchEBlocksByChainInHeightRange gets a chain's entry blocks within a directory block height range
+	FetchEBlocksByChainInHeightRange(chainID IHash, startHeight, endHeight uint32, limit int) ([]IEntryBlock, error)
+
 	SaveEBlockHead(block DatabaseBlockWithEntries, checkForDuplicateEntries bool) error
 
 	FetchEBlockHead(chainID IHash) (IEntryBlock, error)
@@ -262,6 +293,52 @@ type DBOverlay interface {
 	FetchKeyValueStore(key []byte, dst BinaryMarshallable) (BinaryMarshallable, error)
 	SaveDatabaseEntryHeight(height uint32) error
 	FetchDatabaseEntryHeight() (uint32, error)
+
+	//******************************AddressIndex**********************************//
+	SetAddressIndex(enabled bool)
+	GetAddressIndex() bool
+	SaveAddressIndexFromBlock(block interface{}, multiBatch bool) error
+	RebuildAddressIndex() error
+	FetchAddressTransactions(address []byte, startHeight uint32, cursor []byte, limit int) ([]AddressTransaction, []byte, error)
+
+	//******************************Balances**********************************//
+	FetchFactoidBalanceAtHeight(address [32]byte, height uint32) (int64, error)
+	FetchECBalanceAtHeight(address [32]byte, height uint32) (int64, error)
+
+	//******************************AnchorRecords**********************************//
+	RebuildAnchorRecordIndex() error
+	InitAnchorRecordIndex() error
+	FetchAnchorEntriesByDBHeight(dbHeight uint32) ([]AnchorEntry, error)
+	SaveEthereumAnchorRecord(dbHeight uint32, record IAnchorRecord) error
+	FetchEthereumAnchorRecord(dbHeight uint32) (IAnchorRecord, error)
+
+	//******************************Pruning**********************************//
+	SaveEntryPruneHeight(height uint32) error
+	FetchEntryPruneHeight() (uint32, error)
+	PruneEntries(pruneHeight uint32, keep func(chainID IHash) bool) (int, error)
+	IsEntryPruned(hash IHash) (bool, error)
+
+	//******************************Recovery**********************************//
+	RecoverMultiBatch() (bool, error)
+	CheckBlockHeads(depth uint32) (int, error)
+
+	//******************************BlockCache**********************************//
+	SetBlockCacheSize(size int)
+}
+
+// AddressTransaction is an entry of the address index, a factoid transaction or entry credit
+// commit that involved the address
+type AddressTransaction struct {
+	TxID        IHash
+	DBHeight    uint32
+	EntryCredit bool
+}
+
+// AnchorEntry is an anchor chain entry holding an anchor record, Valid is set if it is signed by
+// one of the anchor keys
+type AnchorEntry struct {
+	Entry IEBEntry
+	Valid bool
 }
 
 type ISCDatabaseOverlay interface {
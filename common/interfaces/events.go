@@ -0,0 +1,16 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package interfaces
+
+// IEventSink is told what the state does, eg by the API, which passes it on to the clients
+// subscribed to it.  It is set with IState.SetEventSink, so the state needn't call into the API.
+type IEventSink interface {
+	// NotifyNewDBlock is called once a directory block and its entry blocks have been written to the database
+	NotifyNewDBlock(state IState, dblock IDirectoryBlock, eblocks []IEntryBlock)
+	// NotifyAck is called when a transaction message has been acknowledged
+	NotifyAck(state IState, ack IMsg, m IMsg)
+	// NotifyMinute is called when the state moves to a new minute
+	NotifyMinute(state IState, leaderHeight uint32, minute int)
+}
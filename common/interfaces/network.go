@@ -14,3 +14,9 @@ type INetwork interface {
 	GetMLog() IMLog
 	SetMLog(IMLog)
 }
+
+// INetworkController is the p2p network controller of a node, a *p2p.Controller.  The API
+// works with the controller itself through a type assertion, as the p2p types can't be named here.
+type INetworkController interface {
+	GetNumberConnections() int
+}
@@ -171,10 +171,14 @@ type IState interface {
 
 	GetEBlockKeyMRFromEntryHash(entryHash IHash) IHash
 	GetAnchor() IAnchor
+	GetNetworkController() INetworkController // nil when the node is not on the network
+	SetEventSink(sink IEventSink)
 
 	// Database
 	GetAndLockDB() DBOverlaySimple
 	UnlockDB()
+	// GetDBSnapshot returns a read only view of the database as it is now, Close releases it
+	GetDBSnapshot() (DBOverlaySimple, error)
 
 	// Web Services
 	// ============
@@ -0,0 +1,478 @@
+package archive_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/database/archive"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+	. "github.com/FactomProject/factomd/testHelper"
+)
+
+func tempArchive(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return filepath.Join(dir, "blocks.archive"), func() { os.RemoveAll(dir) }
+}
+
+// startFrom saves the first block set of the source into the target. The test blocks do not start
+// with a genesis block, so the target holds it already, like a node that started on its own.
+func startFrom(t *testing.T, source, target *databaseOverlay.Overlay) {
+	bs, err := source.FetchBlockSetByHeightWithEntries(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = SaveBlockSet(target, bs)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	path, cleanup := tempArchive(t)
+	defer cleanup()
+
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+	head, err := source.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	count, err := Export(source, path, 0, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != int(head.GetDatabaseHeight())+1 {
+		t.Errorf("Exported %v block sets, expected %v", count, head.GetDatabaseHeight()+1)
+	}
+
+	r, err := OpenReader(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	last, ok, err := r.LastHeight()
+	r.Close()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok == false || last != head.GetDatabaseHeight() {
+		t.Errorf("The last block set of the archive is %v, expected %v", last, head.GetDatabaseHeight())
+	}
+
+	// Nothing new to export
+	count, err = Export(source, path, 0, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != 0 {
+		t.Errorf("Exported %v block sets again", count)
+	}
+
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	startFrom(t, source, target)
+	count, err = Import(target, path, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// The last block set is not signed by anything in the archive, so it is left out
+	if count != int(head.GetDatabaseHeight())-1 {
+		t.Errorf("Imported %v block sets, expected %v", count, head.GetDatabaseHeight()-1)
+	}
+
+	targetHead, err := target.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if targetHead == nil || targetHead.GetKeyMR().IsSameAs(head.GetHeader().GetPrevKeyMR()) == false {
+		t.Fatalf("The imported head is not the block before the head of the source")
+	}
+	for h := uint32(0); h < head.GetDatabaseHeight(); h++ {
+		a, err := source.FetchBlockSetByHeightWithEntries(h)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		b, err := target.FetchBlockSetByHeightWithEntries(h)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(a.EBlocks) != len(b.EBlocks) || len(a.Entries) != len(b.Entries) {
+			t.Errorf("Block set %v differs after the import", h)
+		}
+	}
+
+	// Importing again continues after the head
+	count, err = Import(target, path, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != 0 {
+		t.Errorf("Imported %v block sets again", count)
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	path, cleanup := tempArchive(t)
+	defer cleanup()
+
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+
+	_, err := Export(source, path, 0, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	complete, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// An export interrupted in the middle of the last block set
+	err = os.Truncate(path, int64(len(complete)-10))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	r, err := OpenReader(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	last, ok, err := r.LastHeight()
+	r.Close()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	head, err := source.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok == false || last != head.GetDatabaseHeight()-1 {
+		t.Errorf("The last complete block set of the archive is %v, expected %v", last, head.GetDatabaseHeight()-1)
+	}
+	count, err := Export(source, path, 0, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != 1 {
+		t.Errorf("Exported %v block sets, expected the last one again", count)
+	}
+	resumed, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if string(resumed) != string(complete) {
+		t.Errorf("The resumed archive differs from the complete one")
+	}
+}
+
+func TestExportFromHeight(t *testing.T) {
+	path, cleanup := tempArchive(t)
+	defer cleanup()
+
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+
+	_, err := Export(source, path, 5, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	r, err := OpenReader(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer r.Close()
+	if r.Header.StartHeight != 5 {
+		t.Errorf("Archive starts at %v", r.Header.StartHeight)
+	}
+	bs, err := r.Next()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if bs.DBHeight != 5 {
+		t.Errorf("First block set is %v", bs.DBHeight)
+	}
+
+	// An empty database can't start from there
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	_, err = Import(target, path, nil)
+	if err == nil {
+		t.Errorf("Imported an archive starting at 5 into an empty database")
+	}
+}
+
+func TestImportDamagedArchive(t *testing.T) {
+	path, cleanup := tempArchive(t)
+	defer cleanup()
+
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+
+	_, err := Export(source, path, 0, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	startFrom(t, source, target)
+	count, err := Import(target, path, nil)
+	if err == nil {
+		t.Errorf("Imported a damaged archive")
+	}
+	// Everything before the damaged block set is imported
+	head, err := target.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if head == nil || int(head.GetDatabaseHeight()) != count {
+		t.Errorf("Expected %v block sets in the database", count)
+	}
+}
+
+func TestValidateBlockSet(t *testing.T) {
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+
+	first, err := source.FetchBlockSetByHeightWithEntries(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	networkID := first.DBlock.GetHeader().GetNetworkID()
+	err = ValidateBlockSet(target, first, nil, networkID)
+	if err == nil {
+		t.Errorf("A block set that is not the genesis block is valid")
+	}
+
+	dBlock, aBlock, fBlock, ecBlock := directoryBlock.GenerateGenesisBlocks(networkID)
+	genesis := &databaseOverlay.BlockSet{DBlock: dBlock, ABlock: aBlock, ECBlock: ecBlock, FBlock: fBlock}
+	err = ValidateBlockSet(target, genesis, nil, networkID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	err = ValidateBlockSet(target, genesis, nil, networkID+1)
+	if err == nil {
+		t.Errorf("A genesis block of another network is valid")
+	}
+
+	startFrom(t, source, target)
+	bs, err := source.FetchBlockSetByHeightWithEntries(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entries := []interfaces.IEBEntry{}
+	for _, e := range bs.Entries {
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	bs.Entries = entries
+
+	err = ValidateBlockSet(target, bs, first.DBlock, networkID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = ValidateBlockSet(target, bs, first.DBlock, networkID+1)
+	if err == nil {
+		t.Errorf("A block set of another network is valid")
+	}
+
+	bs.Entries = entries[1:]
+	err = ValidateBlockSet(target, bs, first.DBlock, networkID)
+	if err == nil {
+		t.Errorf("A block set missing an entry is valid")
+	}
+	bs.Entries = entries
+
+	next, err := source.FetchBlockSetByHeightWithEntries(3)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = ValidateBlockSet(target, next, bs.DBlock, networkID)
+	if err == nil {
+		t.Errorf("A block set not following the previous one is valid")
+	}
+}
+
+// blockSet fetches a block set of the test database, without the entries the test blocks do not
+// have
+func blockSet(t *testing.T, dbo *databaseOverlay.Overlay, height uint32) *databaseOverlay.BlockSet {
+	bs, err := dbo.FetchBlockSetByHeightWithEntries(height)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entries := []interfaces.IEBEntry{}
+	for _, e := range bs.Entries {
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	bs.Entries = entries
+	return bs
+}
+
+func TestValidatorDBSignatures(t *testing.T) {
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	startFrom(t, source, target)
+
+	first := blockSet(t, source, 0)
+	networkID := first.DBlock.GetHeader().GetNetworkID()
+	prevHeader, err := first.DBlock.GetHeader().MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// The server the first test admin block adds, with the key of the local network
+	server, _ := primitives.HexToHash("38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9")
+	serverKey, err := primitives.NewPrivateKeyFromHex("4c38c72fc5cdad68f13b74674d3ffb1f3d63a112710868c9b08946553448d26d")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	otherKey := NewPrimitivesPrivateKey(5)
+
+	signed := func(ids []interfaces.IHash, keys []*primitives.PrivateKey) *databaseOverlay.BlockSet {
+		bs := blockSet(t, source, 1)
+		for i := range ids {
+			err := bs.ABlock.AddDBSig(ids[i], keys[i].Sign(prevHeader))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+		err := bs.DBlock.SetABlockHash(bs.ABlock)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return bs
+	}
+
+	validator, err := NewValidator(target, networkID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = validator.Validate(signed([]interfaces.IHash{server}, []*primitives.PrivateKey{serverKey}))
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	err = validator.Validate(signed([]interfaces.IHash{server}, []*primitives.PrivateKey{otherKey}))
+	if err == nil {
+		t.Errorf("A DBSignature not by the signing key of the server is valid")
+	}
+	err = validator.Validate(signed([]interfaces.IHash{primitives.Sha([]byte("other"))}, []*primitives.PrivateKey{otherKey}))
+	if err == nil {
+		t.Errorf("A DBSignature not by a federated server is valid")
+	}
+	err = validator.Validate(signed([]interfaces.IHash{server, server}, []*primitives.PrivateKey{serverKey, serverKey}))
+	if err == nil {
+		t.Errorf("Two DBSignatures of a server are valid")
+	}
+}
+
+func TestValidatorBalances(t *testing.T) {
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	startFrom(t, source, target)
+
+	networkID := blockSet(t, source, 0).DBlock.GetHeader().GetNetworkID()
+	validator, err := NewValidator(target, networkID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	bs := blockSet(t, source, 1)
+	err = validator.Validate(bs)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// Address 1 has never been paid anything
+	tx := new(factoid.Transaction)
+	tx.AddInput(NewFactoidAddress(1), 1000)
+	tx.AddOutput(NewFactoidAddress(2), 1000)
+	tx.SetTimestamp(primitives.NewTimestampFromSeconds(60 * 10 * bs.DBHeight))
+	fee, err := tx.CalculateFee(1000)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	in, err := tx.GetInput(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	in.SetAmount(in.GetAmount() + fee)
+	SignFactoidTransaction(1, tx)
+	err = bs.FBlock.AddTransaction(tx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = bs.DBlock.SetFBlockHash(bs.FBlock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = validator.Validate(bs)
+	if err == nil {
+		t.Errorf("A transaction spending more than its balance is valid")
+	}
+}
+
+func TestValidatorUnsignedTransaction(t *testing.T) {
+	source := CreateAndPopulateTestDatabaseOverlay()
+	defer source.Close()
+	target := databaseOverlay.NewOverlay(new(mapdb.MapDB))
+	defer target.Close()
+	startFrom(t, source, target)
+
+	networkID := blockSet(t, source, 0).DBlock.GetHeader().GetNetworkID()
+	validator, err := NewValidator(target, networkID)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	bs := blockSet(t, source, 1)
+
+	// Address 0 is paid the coinbase, but the transfer out of it is not signed
+	tx := new(factoid.Transaction)
+	tx.AddInput(NewFactoidAddress(0), 1000)
+	tx.AddOutput(NewFactoidAddress(2), 1000)
+	tx.AddAuthorization(NewFactoidRCDAddress(0))
+	tx.SetTimestamp(primitives.NewTimestampFromSeconds(60 * 10 * bs.DBHeight))
+	fee, err := tx.CalculateFee(bs.FBlock.GetExchRate())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	in, err := tx.GetInput(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	in.SetAmount(in.GetAmount() + fee)
+	fBlock := bs.FBlock.(*factoid.FBlock)
+	fBlock.Transactions = append(fBlock.Transactions, tx)
+	err = bs.DBlock.SetFBlockHash(bs.FBlock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = validator.Validate(bs)
+	if err == nil {
+		t.Errorf("A transaction without the signature of its input is valid")
+	}
+}
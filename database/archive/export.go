@@ -0,0 +1,175 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// Export appends the block sets of the database to the archive, up to the head of the database. A
+// new archive starts at the start height. An existing one continues after its last complete block
+// set, so exporting to the same archive again brings it up to date, and a block set cut short by
+// an interrupted export is written again. It returns the number of block sets written.
+func Export(dbo *databaseOverlay.Overlay, path string, start uint32, progress func(height uint32)) (int, error) {
+	head, err := dbo.FetchDBlockHead()
+	if err != nil {
+		return 0, err
+	}
+	if head == nil {
+		return 0, fmt.Errorf("The database is empty")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	next, err := resumeExport(dbo, file, head.GetHeader().GetNetworkID(), start)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := bufio.NewWriterSize(file, 1024*1024)
+	count := 0
+	for height := next; height <= head.GetDatabaseHeight(); height++ {
+		bs, err := exportBlockSet(dbo, height)
+		if err != nil {
+			return count, err
+		}
+		body, err := MarshalBlockSet(bs)
+		if err != nil {
+			return count, err
+		}
+		err = writeChunk(writer, body)
+		if err != nil {
+			return count, err
+		}
+		count++
+		if progress != nil {
+			progress(height)
+		}
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return count, err
+	}
+	return count, file.Sync()
+}
+
+// resumeExport writes the header of a new archive, or finds the end of the last complete block
+// set of an existing one and cuts off what follows. It returns the height to export next.
+func resumeExport(dbo *databaseOverlay.Overlay, file *os.File, networkID uint32, start uint32) (uint32, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		header := Header{Version: Version, NetworkID: networkID, StartHeight: start}
+		data, err := header.MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		_, err = file.Write(data)
+		return start, err
+	}
+
+	reader := bufio.NewReaderSize(file, 1024*1024)
+	data := make([]byte, HeaderSize)
+	_, err = io.ReadFull(reader, data)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the archive header: %v", err)
+	}
+	header := new(Header)
+	err = header.UnmarshalBinary(data)
+	if err != nil {
+		return 0, err
+	}
+	if header.NetworkID != networkID {
+		return 0, fmt.Errorf("The archive is of network %x, the database of network %x", header.NetworkID, networkID)
+	}
+
+	// Only the height of the block sets is read, the last one is checked against the database
+	next := header.StartHeight
+	end := int64(HeaderSize)
+	var last []byte
+	for {
+		body, err := readChunk(reader)
+		if err != nil || len(body) < 4 || binary.BigEndian.Uint32(body) != next {
+			// The end of the archive, or what an interrupted export left
+			break
+		}
+		last = body
+		next++
+		end += int64(chunkPrefixSize + len(body))
+	}
+
+	if last != nil {
+		bs, err := UnmarshalBlockSet(last)
+		if err != nil {
+			return 0, err
+		}
+		keyMR, err := dbo.FetchDBKeyMRByHeight(bs.DBHeight)
+		if err != nil {
+			return 0, err
+		}
+		if keyMR == nil || keyMR.IsSameAs(bs.DBlock.GetKeyMR()) == false {
+			return 0, fmt.Errorf("Directory block %v of the archive is not the one of the database", bs.DBHeight)
+		}
+	}
+
+	err = file.Truncate(end)
+	if err != nil {
+		return 0, err
+	}
+	_, err = file.Seek(end, io.SeekStart)
+	return next, err
+}
+
+// exportBlockSet fetches the block set with its entries, all of which must be in the database
+func exportBlockSet(dbo *databaseOverlay.Overlay, height uint32) (*databaseOverlay.BlockSet, error) {
+	bs, err := dbo.FetchBlockSetByHeightWithEntries(height)
+	if err != nil {
+		return nil, err
+	}
+	if bs == nil {
+		return nil, fmt.Errorf("Directory block %v is missing from the database", height)
+	}
+	if bs.ABlock == nil || bs.ECBlock == nil || bs.FBlock == nil {
+		return nil, fmt.Errorf("Directory block %v is missing its admin, entry credit or factoid block", height)
+	}
+
+	expected := 0
+	for _, eBlock := range bs.EBlocks {
+		if eBlock == nil {
+			return nil, fmt.Errorf("An entry block of directory block %v is missing", height)
+		}
+		for _, hash := range eBlock.GetEntryHashes() {
+			if hash.IsMinuteMarker() == false {
+				expected++
+			}
+		}
+	}
+	// Minute markers and pruned entries are fetched as nil
+	entries := make([]interfaces.IEBEntry, 0, expected)
+	for _, entry := range bs.Entries {
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) != expected {
+		return nil, fmt.Errorf("Directory block %v is missing %v entries, a pruned database can't be exported", height, expected-len(entries))
+	}
+	bs.Entries = entries
+	return bs, nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+// Package archive reads and writes block archives, a single file holding the block sets of a
+// range of directory blocks, so a node can be bootstrapped from local disk.
+//
+// The file starts with a header, followed by one chunk per directory block, in height order:
+//
+//	header: magic (8 bytes) | version (4) | network id (4) | start height (4) | sha256 of the above (32)
+//	chunk:  length of the body (4) | sha256 of the body (32) | body
+//	body:   height (4) | DBlock | ABlock | ECBlock | FBlock | count | EBlocks | count | Entries
+//
+// Blocks and entries in the body are in their binary form, each behind its length as a varint.
+// Counts are varints. Numbers in the header and in front of the chunks are big endian.
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+const (
+	// Version is the version of the archive format written
+	Version uint32 = 1
+
+	// HeaderSize is the size of the header on disk
+	HeaderSize = 8 + 4 + 4 + 4 + sha256.Size
+
+	// MaxChunkSize bounds the size of a block set, a larger length is taken as a damaged chunk
+	MaxChunkSize = 256 * 1024 * 1024
+
+	chunkPrefixSize = 4 + sha256.Size
+)
+
+// Magic starts every archive file
+var Magic = []byte("FCTARCHV")
+
+// Header describes the archive
+type Header struct {
+	Version     uint32
+	NetworkID   uint32
+	StartHeight uint32
+}
+
+func (h *Header) MarshalBinary() ([]byte, error) {
+	data := make([]byte, HeaderSize-sha256.Size)
+	copy(data, Magic)
+	binary.BigEndian.PutUint32(data[8:], h.Version)
+	binary.BigEndian.PutUint32(data[12:], h.NetworkID)
+	binary.BigEndian.PutUint32(data[16:], h.StartHeight)
+	sum := sha256.Sum256(data)
+	return append(data, sum[:]...), nil
+}
+
+func (h *Header) UnmarshalBinary(data []byte) error {
+	if len(data) < HeaderSize {
+		return fmt.Errorf("The archive header is too short")
+	}
+	if primitives.AreBytesEqual(data[:8], Magic) == false {
+		return fmt.Errorf("Not a block archive")
+	}
+	sum := sha256.Sum256(data[:HeaderSize-sha256.Size])
+	if primitives.AreBytesEqual(sum[:], data[HeaderSize-sha256.Size:HeaderSize]) == false {
+		return fmt.Errorf("The archive header checksum does not match")
+	}
+	h.Version = binary.BigEndian.Uint32(data[8:])
+	h.NetworkID = binary.BigEndian.Uint32(data[12:])
+	h.StartHeight = binary.BigEndian.Uint32(data[16:])
+	if h.Version != Version {
+		return fmt.Errorf("Unsupported archive version %v", h.Version)
+	}
+	return nil
+}
+
+// writeChunk writes the body behind its length and checksum, in a single write
+func writeChunk(w io.Writer, body []byte) error {
+	data := make([]byte, chunkPrefixSize, chunkPrefixSize+len(body))
+	binary.BigEndian.PutUint32(data, uint32(len(body)))
+	sum := sha256.Sum256(body)
+	copy(data[4:], sum[:])
+	_, err := w.Write(append(data, body...))
+	return err
+}
+
+// readChunk returns the body of the next chunk. It returns io.EOF at the end of the archive, and
+// io.ErrUnexpectedEOF if the chunk is cut short.
+func readChunk(r io.Reader) ([]byte, error) {
+	prefix := make([]byte, chunkPrefixSize)
+	_, err := io.ReadFull(r, prefix)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(prefix)
+	if length > MaxChunkSize {
+		return nil, fmt.Errorf("Chunk of %v bytes is too large", length)
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if primitives.AreBytesEqual(sum[:], prefix[4:]) == false {
+		return nil, fmt.Errorf("Chunk checksum does not match")
+	}
+	return body, nil
+}
+
+// skipChunks moves past the chunks of the block sets below the height without reading their
+// bodies. It stops at the first chunk it can't tell the height of, for readChunk to report.
+func skipChunks(r *bufio.Reader, height uint32) error {
+	for {
+		prefix, err := r.Peek(chunkPrefixSize + 4)
+		if err != nil {
+			return nil
+		}
+		length := binary.BigEndian.Uint32(prefix)
+		if length < 4 || length > MaxChunkSize || binary.BigEndian.Uint32(prefix[chunkPrefixSize:]) >= height {
+			return nil
+		}
+		_, err = r.Discard(chunkPrefixSize + int(length))
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// MarshalBlockSet returns the body of the chunk of the block set
+func MarshalBlockSet(bs *databaseOverlay.BlockSet) ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+
+	err := buf.PushUInt32(bs.DBHeight)
+	if err != nil {
+		return nil, err
+	}
+	push := func(b interfaces.BinaryMarshallable) error {
+		data, err := b.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return buf.PushBytes(data)
+	}
+
+	for _, b := range []interfaces.BinaryMarshallable{bs.DBlock, bs.ABlock, bs.ECBlock, bs.FBlock} {
+		err = push(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = buf.PushVarInt(uint64(len(bs.EBlocks)))
+	if err != nil {
+		return nil, err
+	}
+	for _, eBlock := range bs.EBlocks {
+		err = push(eBlock)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = buf.PushVarInt(uint64(len(bs.Entries)))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range bs.Entries {
+		err = push(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+// UnmarshalBlockSet decodes the body of a chunk
+func UnmarshalBlockSet(data []byte) (bs *databaseOverlay.BlockSet, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling the block set: %v", r)
+		}
+	}()
+
+	if len(data) < 4 {
+		return nil, fmt.Errorf("The block set is too short")
+	}
+	bs = new(databaseOverlay.BlockSet)
+	bs.DBHeight = binary.BigEndian.Uint32(data)
+	rest := data[4:]
+
+	// The buffer copies what is left on every pop, which is too slow for a block set with many
+	// entries
+	pop := func(b interfaces.BinaryMarshallable) error {
+		l, r := primitives.DecodeVarInt(rest)
+		if l > uint64(len(r)) {
+			return fmt.Errorf("End of the block set")
+		}
+		rest = r[l:]
+		return b.UnmarshalBinary(r[:l])
+	}
+	count := func() (int, error) {
+		c, r := primitives.DecodeVarInt(rest)
+		if c > uint64(len(r)) {
+			return 0, fmt.Errorf("Count of %v is larger than the block set", c)
+		}
+		rest = r
+		return int(c), nil
+	}
+
+	bs.DBlock = directoryBlock.NewDirectoryBlock(nil)
+	bs.ABlock = new(adminBlock.AdminBlock)
+	bs.ECBlock = entryCreditBlock.NewECBlock()
+	bs.FBlock = new(factoid.FBlock)
+	for _, b := range []interfaces.BinaryMarshallable{bs.DBlock, bs.ABlock, bs.ECBlock, bs.FBlock} {
+		err = pop(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := count()
+	if err != nil {
+		return nil, err
+	}
+	bs.EBlocks = make([]interfaces.IEntryBlock, n)
+	for i := range bs.EBlocks {
+		eBlock := entryBlock.NewEBlock()
+		err = pop(eBlock)
+		if err != nil {
+			return nil, err
+		}
+		bs.EBlocks[i] = eBlock
+	}
+
+	n, err = count()
+	if err != nil {
+		return nil, err
+	}
+	bs.Entries = make([]interfaces.IEBEntry, n)
+	for i := range bs.Entries {
+		entry := entryBlock.NewEntry()
+		err = pop(entry)
+		if err != nil {
+			return nil, err
+		}
+		bs.Entries[i] = entry
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%v bytes left after the block set", len(rest))
+	}
+	return bs, nil
+}
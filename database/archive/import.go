@@ -0,0 +1,159 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// Import adds the block sets of the archive to the database, each one validated against the
+// database before it is saved. A directory block is signed by the DBSignatures in the admin block
+// of the next one, so each block set is only saved once the next set of the archive signs it, and
+// the last block set of the archive is left for the node to sync. The database must be empty, or
+// hold the start of the chain of the archive: the import continues after its head, so an
+// interrupted import is resumed by importing the same archive again. It returns the number of
+// block sets imported.
+func Import(dbo *databaseOverlay.Overlay, path string, progress func(height uint32)) (int, error) {
+	r, err := OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	head, err := dbo.FetchDBlockHead()
+	if err != nil {
+		return 0, err
+	}
+	next := uint32(0)
+	if head != nil {
+		if head.GetHeader().GetNetworkID() != r.Header.NetworkID {
+			return 0, fmt.Errorf("The archive is of network %x, the database of network %x", r.Header.NetworkID, head.GetHeader().GetNetworkID())
+		}
+		next = head.GetDatabaseHeight() + 1
+	}
+	if r.Header.StartHeight > next {
+		return 0, fmt.Errorf("The archive starts at directory block %v, the database needs directory block %v first", r.Header.StartHeight, next)
+	}
+
+	// An archive imported already is left alone, without replaying the database to validate it.
+	// Its last block set is never imported, as nothing in the archive signs it.
+	last, ok, err := r.LastHeight()
+	if err != nil {
+		return 0, err
+	}
+	if ok == false || last <= next {
+		return 0, nil
+	}
+
+	validator, err := NewValidator(dbo, r.Header.NetworkID)
+	if err != nil {
+		return 0, err
+	}
+	// The block sets already in the database are skipped without being read
+	err = skipChunks(r.reader, next)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the archive up to directory block %v: %v", next, err)
+	}
+	count := 0
+	var pending *databaseOverlay.BlockSet // validated, waiting for the next set to sign it
+	for {
+		body, err := readChunk(r.reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("Error reading directory block %v from the archive: %v", next, err)
+		}
+
+		bs, err := UnmarshalBlockSet(body)
+		if err != nil {
+			return count, err
+		}
+		if bs.DBHeight != next {
+			return count, fmt.Errorf("Expected directory block %v in the archive, found %v", next, bs.DBHeight)
+		}
+		// The pending set is saved first, as the entry blocks of this one follow its chain heads
+		if pending != nil {
+			err = validator.checkDBSignatures(bs)
+			if err != nil {
+				return count, err
+			}
+			err = SaveBlockSet(dbo, pending)
+			if err != nil {
+				return count, err
+			}
+			count++
+			if progress != nil {
+				progress(pending.DBHeight)
+			}
+		}
+		err = validator.validateUnsigned(bs)
+		if err != nil {
+			return count, err
+		}
+		err = validator.Apply(bs)
+		if err != nil {
+			return count, err
+		}
+		pending = bs
+		next++
+	}
+
+	if count > 0 {
+		err = dbo.RebuildDirBlockInfo()
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// SaveBlockSet writes the blocks and entries of the block set in one multi batch, as the node
+// saves a directory block
+func SaveBlockSet(dbo *databaseOverlay.Overlay, bs *databaseOverlay.BlockSet) error {
+	dbo.StartMultiBatch()
+
+	err := saveBlockSet(dbo, bs)
+	if err != nil {
+		dbo.AbortMultiBatch()
+		return err
+	}
+	return dbo.ExecuteMultiBatch()
+}
+
+func saveBlockSet(dbo *databaseOverlay.Overlay, bs *databaseOverlay.BlockSet) error {
+	err := dbo.ProcessDBlockMultiBatch(bs.DBlock)
+	if err != nil {
+		return err
+	}
+	err = dbo.ProcessABlockMultiBatch(bs.ABlock)
+	if err != nil {
+		return err
+	}
+	err = dbo.ProcessFBlockMultiBatch(bs.FBlock)
+	if err != nil {
+		return err
+	}
+	err = dbo.ProcessECBlockMultiBatch(bs.ECBlock, false)
+	if err != nil {
+		return err
+	}
+	for _, eBlock := range bs.EBlocks {
+		err = dbo.ProcessEBlockMultiBatch(eBlock, true)
+		if err != nil {
+			return err
+		}
+	}
+	for _, entry := range bs.Entries {
+		err = dbo.InsertEntryMultiBatch(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
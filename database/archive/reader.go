@@ -0,0 +1,88 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// Reader reads the block sets of an archive in order
+type Reader struct {
+	Header Header
+
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// OpenReader opens the archive and reads its header
+func OpenReader(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := new(Reader)
+	r.file = file
+	r.reader = bufio.NewReaderSize(file, 1024*1024)
+
+	data := make([]byte, HeaderSize)
+	_, err = io.ReadFull(r.reader, data)
+	if err == nil {
+		err = r.Header.UnmarshalBinary(data)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Next returns the next block set, or io.EOF after the last one
+func (r *Reader) Next() (*databaseOverlay.BlockSet, error) {
+	body, err := readChunk(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalBlockSet(body)
+}
+
+// LastHeight returns the height of the last complete block set of the archive, and false if it
+// has none. Only the start of every chunk is read, and the position of the reader does not change.
+func (r *Reader) LastHeight() (uint32, bool, error) {
+	info, err := r.file.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	size := info.Size()
+
+	prefix := make([]byte, chunkPrefixSize+4)
+	offset := int64(HeaderSize)
+	last := uint32(0)
+	found := false
+	for offset+int64(len(prefix)) <= size {
+		_, err = r.file.ReadAt(prefix, offset)
+		if err != nil {
+			return 0, false, err
+		}
+		length := binary.BigEndian.Uint32(prefix)
+		end := offset + chunkPrefixSize + int64(length)
+		if length < 4 || length > MaxChunkSize || end > size {
+			// A chunk cut short ends what can be imported
+			break
+		}
+		last = binary.BigEndian.Uint32(prefix[chunkPrefixSize:])
+		found = true
+		offset = end
+	}
+	return last, found, nil
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
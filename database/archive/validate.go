@@ -0,0 +1,411 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// bootstrapKeys are the keys that sign the first directory blocks of the networks, as returned by
+// the state's GetNetworkBootStrapKey. The key of a custom network is not known here.
+var bootstrapKeys = map[uint32]string{
+	constants.MAIN_NETWORK_ID:  "0426a802617848d4d16d87830fc521f4d136bb2d0c352850919c2679f189613a",
+	constants.TEST_NETWORK_ID:  "49b6edd274e7d07c94d4831eca2f073c207248bde1bf989d2183a8cebca227b7",
+	constants.LOCAL_NETWORK_ID: "cc1985cdfae4e32b5a454dfda8ce5e1361558482684f3367649c3ad852c8e31a",
+}
+
+// Validator checks the block sets following the head of the database the way a syncing node
+// checks the blocks it is sent. On top of ValidateBlockSet, it verifies the DBSignatures of the
+// admin blocks against the federated servers and their signing keys, the factoid transactions as
+// a factoid block checks them, signatures and fees included, and the signatures of the entry
+// credit commits. No factoid transaction may spend more than the balance of its inputs, nor a
+// commit more than the entry credits of its key. It keeps the federated servers and the factoid
+// and entry credit balances of every block set it is given with Apply.
+type Validator struct {
+	dbo          *databaseOverlay.Overlay
+	networkID    uint32
+	bootstrapKey string
+	prev         interfaces.IDirectoryBlock
+	authorities  *identity.IdentityManager
+	balances     map[[32]byte]int64
+	ecBalances   map[[32]byte]int64
+}
+
+// NewValidator returns a validator for the block set following the head of the database. It
+// replays the admin and factoid blocks already in the database.
+func NewValidator(dbo *databaseOverlay.Overlay, networkID uint32) (*Validator, error) {
+	v := new(Validator)
+	v.dbo = dbo
+	v.networkID = networkID
+	v.bootstrapKey = bootstrapKeys[networkID]
+	v.authorities = new(identity.IdentityManager)
+	v.balances = map[[32]byte]int64{}
+	v.ecBalances = map[[32]byte]int64{}
+
+	head, err := dbo.FetchDBlockHead()
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return v, nil
+	}
+	for h := uint32(0); h <= head.GetDatabaseHeight(); h++ {
+		aBlock, err := dbo.FetchABlockByHeight(h)
+		if err != nil {
+			return nil, err
+		}
+		fBlock, err := dbo.FetchFBlockByHeight(h)
+		if err != nil {
+			return nil, err
+		}
+		ecBlock, err := dbo.FetchECBlockByHeight(h)
+		if err != nil {
+			return nil, err
+		}
+		if aBlock == nil || fBlock == nil || ecBlock == nil {
+			return nil, fmt.Errorf("Blocks of directory block %v are not in the database", h)
+		}
+		// The blocks of the database were checked when they were saved
+		err = v.apply(h, aBlock, fBlock, ecBlock, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v.prev = head
+	return v, nil
+}
+
+// Validate checks the block set is the next one, as ValidateBlockSet does, that its admin block
+// is signed by the federated servers and that its transactions are signed and funded
+func (v *Validator) Validate(bs *databaseOverlay.BlockSet) error {
+	err := v.checkDBSignatures(bs)
+	if err != nil {
+		return err
+	}
+	return v.validateUnsigned(bs)
+}
+
+// validateUnsigned is Validate without the DBSignatures, which Import checks before it saves the
+// previous block set
+func (v *Validator) validateUnsigned(bs *databaseOverlay.BlockSet) error {
+	err := ValidateBlockSet(v.dbo, bs, v.prev, v.networkID)
+	if err != nil {
+		return err
+	}
+	err = v.checkTransactions(bs)
+	if err != nil {
+		return err
+	}
+	_, _, err = v.balanceChanges(bs.DBHeight, bs.FBlock, bs.ECBlock, true)
+	return err
+}
+
+// Apply moves the validator past a block set that was validated
+func (v *Validator) Apply(bs *databaseOverlay.BlockSet) error {
+	err := v.apply(bs.DBHeight, bs.ABlock, bs.FBlock, bs.ECBlock, true)
+	if err != nil {
+		return err
+	}
+	v.prev = bs.DBlock
+	return nil
+}
+
+func (v *Validator) apply(height uint32, aBlock interfaces.IAdminBlock, fBlock interfaces.IFBlock, ecBlock interfaces.IEntryCreditBlock, check bool) error {
+	for _, entry := range aBlock.GetABEntries() {
+		switch entry.Type() {
+		case constants.TYPE_ADD_FED_SERVER, constants.TYPE_ADD_AUDIT_SERVER, constants.TYPE_REMOVE_FED_SERVER:
+		case constants.TYPE_ADD_FED_SERVER_KEY:
+			// A key can be added before the server is promoted
+			e := entry.(*adminBlock.AddFederatedServerSigningKey)
+			if v.authorities.GetAuthority(e.IdentityChainID) == nil {
+				auth := new(identity.Authority)
+				auth.AuthorityChainID = primitives.NewHash(e.IdentityChainID.Bytes())
+				auth.Status = constants.IDENTITY_UNASSIGNED
+				v.authorities.SetAuthority(e.IdentityChainID, auth)
+			}
+		default:
+			continue
+		}
+		err := v.authorities.ProcessABlockEntry(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, ecChanges, err := v.balanceChanges(height, fBlock, ecBlock, check)
+	if err != nil {
+		return err
+	}
+	for address, change := range changes {
+		v.balances[address] += change
+	}
+	for address, change := range ecChanges {
+		v.ecBalances[address] += change
+	}
+	return nil
+}
+
+// checkDBSignatures verifies the DBSignatures of the admin block, which sign the header of the
+// previous directory block, with the rules of IdentityManager.CheckDBSignatureEntries. Each must
+// be by a federated server with its signing key, or by the bootstrap key of the network, and at
+// least half of the federated servers must have signed. Servers promoted or given a key in the
+// same admin block may sign already.
+func (v *Validator) checkDBSignatures(bs *databaseOverlay.BlockSet) error {
+	if v.prev == nil {
+		return nil
+	}
+	height := bs.DBHeight
+	prevHeader, err := v.prev.GetHeader().MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	promoted := map[string]bool{}
+	keys := map[string][]byte{}
+	for _, entry := range bs.ABlock.GetABEntries() {
+		switch e := entry.(type) {
+		case *adminBlock.AddFederatedServer:
+			promoted[e.IdentityChainID.String()] = true
+		case *adminBlock.AddFederatedServerSigningKey:
+			keys[e.IdentityChainID.String()] = e.PublicKey[:]
+		}
+	}
+
+	signed := map[string]bool{}
+	for _, entry := range bs.ABlock.GetABEntries() {
+		dbs, ok := entry.(*adminBlock.DBSignatureEntry)
+		if ok == false {
+			continue
+		}
+		id := dbs.IdentityAdminChainID.String()
+		if signed[id] {
+			return fmt.Errorf("Admin block of directory block %v has two DBSignatures of %v", height, id)
+		}
+		key := dbs.PrevDBSig.GetKey()
+		if hex.EncodeToString(key) != v.bootstrapKey {
+			auth := v.authorities.GetAuthority(dbs.IdentityAdminChainID)
+			federated := promoted[id] || (auth != nil && auth.Status == constants.IDENTITY_FEDERATED_SERVER)
+			if federated == false {
+				return fmt.Errorf("DBSignature of %v in directory block %v is not by a federated server", id, height)
+			}
+			if bytes.Equal(keys[id], key) == false && (auth == nil || bytes.Equal(auth.SigningKey[:], key) == false) {
+				return fmt.Errorf("DBSignature of %v in directory block %v is not by its signing key", id, height)
+			}
+		}
+		// As in CheckDBSignatureEntries, a signature that does not verify is not counted
+		if dbs.PrevDBSig.Verify(prevHeader) {
+			signed[id] = true
+		}
+	}
+	fedServerCount := v.authorities.FedServerCount()
+	if len(signed) < fedServerCount/2 {
+		return fmt.Errorf("Directory block %v is signed by %v of %v federated servers", height, len(signed), fedServerCount)
+	}
+	return nil
+}
+
+// checkTransactions checks the factoid transactions of the block set as FBlock.ValidateTransaction
+// does: every one but the coinbase must have its inputs signed by their RCDs and pay the fee at the
+// exchange rate of the block. The commits of the entry credit block must be signed by their key.
+func (v *Validator) checkTransactions(bs *databaseOverlay.BlockSet) error {
+	for i, tx := range bs.FBlock.GetTransactions() {
+		var err error
+		if i == 0 {
+			// The coinbase pays no fee, so it is only checked to have no inputs
+			err = tx.Validate(0)
+			if err == nil && len(tx.GetECOutputs()) != 0 {
+				err = fmt.Errorf("The coinbase transaction cannot buy Entry Credits")
+			}
+		} else {
+			err = bs.FBlock.ValidateTransaction(i, tx)
+		}
+		if err != nil {
+			return fmt.Errorf("Transaction %v of directory block %v is not valid: %v", tx.GetSigHash(), bs.DBHeight, err)
+		}
+	}
+
+	for _, entry := range bs.ECBlock.GetBody().GetEntries() {
+		var err error
+		switch e := entry.(type) {
+		case *entryCreditBlock.CommitChain:
+			err = e.ValidateSignatures()
+		case *entryCreditBlock.CommitEntry:
+			err = e.ValidateSignatures()
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Commit %v of directory block %v is not signed: %v", entry.Hash(), bs.DBHeight, err)
+		}
+	}
+	return nil
+}
+
+// balanceChanges works out how the factoid transactions and then the entry credit commits of a
+// block change the balances, as FactoidState's AddTransactionBlock and AddECBlock apply them. The
+// transactions are applied in order, so one can spend the outputs of an earlier one. With check
+// set, an input spending more than the balance of its address, or a commit more than the entry
+// credits of its key, fails.
+func (v *Validator) balanceChanges(height uint32, fBlock interfaces.IFBlock, ecBlock interfaces.IEntryCreditBlock, check bool) (map[[32]byte]int64, map[[32]byte]int64, error) {
+	changes := map[[32]byte]int64{}
+	ecChanges := map[[32]byte]int64{}
+	for _, tx := range fBlock.GetTransactions() {
+		for _, input := range tx.GetInputs() {
+			address := input.GetAddress().Fixed()
+			changes[address] -= int64(input.GetAmount())
+			if check && v.balances[address]+changes[address] < 0 {
+				return nil, nil, fmt.Errorf("Transaction %v of directory block %v spends more than the balance of %v", tx.GetSigHash(), height, input.GetAddress())
+			}
+		}
+		for _, output := range tx.GetOutputs() {
+			changes[output.GetAddress().Fixed()] += int64(output.GetAmount())
+		}
+		for _, ecOutput := range tx.GetECOutputs() {
+			if fBlock.GetExchRate() == 0 {
+				return nil, nil, fmt.Errorf("Transaction %v of directory block %v buys entry credits without an exchange rate", tx.GetSigHash(), height)
+			}
+			ecChanges[ecOutput.GetAddress().Fixed()] += int64(ecOutput.GetAmount() / fBlock.GetExchRate())
+		}
+	}
+
+	// As in UpdateECTransaction, commits could overdraw on mainnet up to block 97886
+	overdraw := height <= 97886 && v.networkID == constants.MAIN_NETWORK_ID
+	for _, entry := range ecBlock.GetBody().GetEntries() {
+		var key [32]byte
+		var credits uint8
+		switch e := entry.(type) {
+		case *entryCreditBlock.CommitChain:
+			key, credits = e.ECPubKey.Fixed(), e.Credits
+		case *entryCreditBlock.CommitEntry:
+			key, credits = e.ECPubKey.Fixed(), e.Credits
+		default:
+			continue
+		}
+		ecChanges[key] -= int64(credits)
+		if check && overdraw == false && v.ecBalances[key]+ecChanges[key] < 0 {
+			return nil, nil, fmt.Errorf("Commit %v of directory block %v spends more than the entry credits of %x", entry.Hash(), height, key)
+		}
+	}
+	return changes, ecChanges, nil
+}
+
+// ValidateBlockSet checks the block set is the next one of the database. The directory block must
+// follow prev, the directory block of the database's head, or be the genesis block of the network if
+// prev is nil.
+// Every block it lists must be in the set with the KeyMR it lists, every entry block must follow
+// the head of its chain in the database, and every entry of the entry blocks must be in the set.
+// Nothing else may be. The signatures and balances are checked by Validator.
+func ValidateBlockSet(dbo *databaseOverlay.Overlay, bs *databaseOverlay.BlockSet, prev interfaces.IDirectoryBlock, networkID uint32) error {
+	height := bs.DBHeight
+	header := bs.DBlock.GetHeader()
+	if header.GetDBHeight() != height {
+		return fmt.Errorf("Directory block %v has height %v", height, header.GetDBHeight())
+	}
+	if header.GetNetworkID() != networkID {
+		return fmt.Errorf("Directory block %v is of network %x, not %x", height, header.GetNetworkID(), networkID)
+	}
+	if prev == nil {
+		genesis, _, _, _ := directoryBlock.GenerateGenesisBlocks(networkID)
+		if height != 0 || bs.DBlock.GetKeyMR().IsSameAs(genesis.GetKeyMR()) == false {
+			return fmt.Errorf("Directory block %v is not the genesis block of network %x", height, networkID)
+		}
+	} else if header.GetPrevKeyMR().IsSameAs(prev.GetKeyMR()) == false {
+		return fmt.Errorf("Directory block %v does not follow directory block %v %v", height, prev.GetDatabaseHeight(), prev.GetKeyMR())
+	}
+
+	eBlocks := map[[32]byte]interfaces.IEntryBlock{}
+	for _, eBlock := range bs.EBlocks {
+		keyMR, err := eBlock.KeyMR()
+		if err != nil {
+			return err
+		}
+		eBlocks[keyMR.Fixed()] = eBlock
+	}
+	if len(eBlocks) != len(bs.EBlocks) {
+		return fmt.Errorf("Directory block %v has an entry block twice", height)
+	}
+
+	blocks := map[string]interface {
+		DatabasePrimaryIndex() interfaces.IHash
+	}{
+		string(constants.ADMIN_CHAINID):   bs.ABlock,
+		string(constants.EC_CHAINID):      bs.ECBlock,
+		string(constants.FACTOID_CHAINID): bs.FBlock,
+	}
+	for _, dbEntry := range bs.DBlock.GetDBEntries() {
+		chainID := dbEntry.GetChainID().Bytes()
+		block, ok := blocks[string(chainID)]
+		if ok {
+			if block.DatabasePrimaryIndex().IsSameAs(dbEntry.GetKeyMR()) == false {
+				return fmt.Errorf("Block of chain %x of directory block %v does not have KeyMR %v", chainID, height, dbEntry.GetKeyMR())
+			}
+			delete(blocks, string(chainID))
+			continue
+		}
+
+		eBlock, ok := eBlocks[dbEntry.GetKeyMR().Fixed()]
+		if ok == false {
+			return fmt.Errorf("Entry block %v of directory block %v is missing", dbEntry.GetKeyMR(), height)
+		}
+		delete(eBlocks, dbEntry.GetKeyMR().Fixed())
+		if bytes.Equal(eBlock.GetChainID().Bytes(), chainID) == false {
+			return fmt.Errorf("Entry block %v of directory block %v is not of chain %x", dbEntry.GetKeyMR(), height, chainID)
+		}
+		if eBlock.GetHeader().GetDBHeight() != height {
+			return fmt.Errorf("Entry block %v of directory block %v has height %v", dbEntry.GetKeyMR(), height, eBlock.GetHeader().GetDBHeight())
+		}
+		chainHead, err := dbo.FetchHeadIndexByChainID(eBlock.GetChainID())
+		if err != nil {
+			return err
+		}
+		prevKeyMR := eBlock.GetHeader().GetPrevKeyMR()
+		if (chainHead == nil && prevKeyMR.IsZero() == false) || (chainHead != nil && prevKeyMR.IsSameAs(chainHead) == false) {
+			return fmt.Errorf("Entry block %v of directory block %v does not follow the head of chain %x", dbEntry.GetKeyMR(), height, chainID)
+		}
+	}
+	if len(blocks) != 0 {
+		return fmt.Errorf("Directory block %v does not list its admin, entry credit and factoid blocks", height)
+	}
+	if len(eBlocks) != 0 {
+		return fmt.Errorf("Directory block %v does not list %v entry blocks of the set", height, len(eBlocks))
+	}
+
+	entries := map[[32]byte]interfaces.IEBEntry{}
+	for _, entry := range bs.Entries {
+		entries[entry.GetHash().Fixed()] = entry
+	}
+	// An entry can be in an entry block more than once
+	listed := map[[32]byte]bool{}
+	for _, eBlock := range bs.EBlocks {
+		for _, hash := range eBlock.GetEntryHashes() {
+			if hash.IsMinuteMarker() {
+				continue
+			}
+			entry, ok := entries[hash.Fixed()]
+			if ok == false {
+				return fmt.Errorf("Entry %v of directory block %v is missing", hash, height)
+			}
+			if entry.GetChainID().IsSameAs(eBlock.GetChainID()) == false {
+				return fmt.Errorf("Entry %v of directory block %v is not of chain %v", hash, height, eBlock.GetChainID())
+			}
+			listed[hash.Fixed()] = true
+		}
+	}
+	if len(listed) != len(entries) {
+		return fmt.Errorf("Directory block %v does not list %v entries of the set", height, len(entries)-len(listed))
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package badgerdb
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/dgraph-io/badger"
+)
+
+// BadgerDB stores the buckets in a Badger database. Badger keeps values in a separate log from its
+// LSM tree of keys, so compactions only rewrite keys, which keeps write amplification low for the
+// large blocks an archival node stores.
+//
+// Every key is stored as the length of its bucket (4 bytes), the bucket and the key, so buckets
+// never overlap and can be listed.
+type BadgerDB struct {
+	Sem sync.RWMutex
+	db  *badger.DB
+
+	// ValueLogGCInterval is the least time between two value log garbage collections started by Trim
+	ValueLogGCInterval time.Duration
+
+	gcMutex sync.Mutex
+	lastGC  time.Time
+	inGC    bool
+}
+
+var _ interfaces.IDatabase = (*BadgerDB)(nil)
+
+func NewBadgerDB(dirname string, create bool) (*BadgerDB, error) {
+	if create == true {
+		err := os.MkdirAll(dirname, 0750)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		_, err := os.Stat(dirname)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts := badger.DefaultOptions
+	opts.Dir = dirname
+	opts.ValueDir = dirname
+	opts.MaxTableSize = MaxTableSize
+	bdb, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	db := new(BadgerDB)
+	db.db = bdb
+	db.ValueLogGCInterval = 10 * time.Minute
+	db.lastGC = time.Now()
+	return db, nil
+}
+
+// MaxTableSize is the size of the LSM tables of the database. Badger limits a transaction to 15%
+// of it, which at 256MB lets a batch hold about 400,000 records, enough for the block set of a
+// full directory block. Values of more than 32 bytes are kept in the value log, so only their
+// keys count towards the limit.
+const MaxTableSize = 256 << 20
+
+// BucketPrefix returns what every key of the bucket starts with
+func BucketPrefix(bucket []byte) []byte {
+	prefix := make([]byte, 4, 4+len(bucket))
+	binary.BigEndian.PutUint32(prefix, uint32(len(bucket)))
+	return append(prefix, bucket...)
+}
+
+func CombineBucketAndKey(bucket []byte, key []byte) []byte {
+	return append(BucketPrefix(bucket), key...)
+}
+
+/***************************************
+ *       Methods
+ ***************************************/
+
+func (db *BadgerDB) ListAllBuckets() ([][]byte, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	answer := [][]byte{}
+	it.Rewind()
+	for it.Valid() {
+		k := it.Item().Key()
+		if len(k) < 4 {
+			it.Next()
+			continue
+		}
+		l := binary.BigEndian.Uint32(k[:4])
+		if uint32(len(k)-4) < l {
+			it.Next()
+			continue
+		}
+		bucket := make([]byte, l)
+		copy(bucket, k[4:4+l])
+		answer = append(answer, bucket)
+
+		// Skip the rest of the bucket
+		limit := interfaces.PrefixLimit(BucketPrefix(bucket))
+		if limit == nil {
+			break
+		}
+		it.Seek(limit)
+	}
+
+	return answer, nil
+}
+
+func (db *BadgerDB) Delete(bucket []byte, key []byte) error {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(CombineBucketAndKey(bucket, key))
+	})
+}
+
+// Trim starts a garbage collection of the value log, at most once every ValueLogGCInterval. Badger
+// does not reclaim the space of overwritten and deleted values on its own.
+func (db *BadgerDB) Trim() {
+	db.Sem.RLock()
+	if db.db == nil {
+		db.Sem.RUnlock()
+		return
+	}
+	lsm, vlog := db.db.Size()
+	db.Sem.RUnlock()
+	BadgerDBLSMSize.Set(float64(lsm))
+	BadgerDBValueLogSize.Set(float64(vlog))
+
+	db.gcMutex.Lock()
+	defer db.gcMutex.Unlock()
+
+	if db.inGC || time.Since(db.lastGC) < db.ValueLogGCInterval {
+		return
+	}
+	db.inGC = true
+	go func() {
+		// Every successful run rewrites one log file, keep going until there is nothing to rewrite.
+		// Each run holds the read lock, so Close waits for it and the collection stops once the
+		// database is closed.
+		for db.runValueLogGC() {
+		}
+
+		db.gcMutex.Lock()
+		db.inGC = false
+		db.lastGC = time.Now()
+		db.gcMutex.Unlock()
+	}()
+}
+
+// runValueLogGC rewrites one value log file, returning false once there is nothing left to rewrite
+// or the database is closed
+func (db *BadgerDB) runValueLogGC() bool {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	if db.db == nil {
+		return false
+	}
+	return db.db.RunValueLogGC(0.5) == nil
+}
+
+// Close can be called more than once, Badger can't
+func (db *BadgerDB) Close() error {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	if db.db == nil {
+		return nil
+	}
+	err := db.db.Close()
+	db.db = nil
+	return err
+}
+
+func (db *BadgerDB) Get(bucket []byte, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	BadgerDBGets.Inc()
+
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return get(txn, bucket, key, destination)
+}
+
+func (db *BadgerDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshallable) error {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	BadgerDBPuts.Inc()
+
+	hex, err := data.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(CombineBucketAndKey(bucket, key), hex)
+	})
+}
+
+// AtomicBatches is true, a batch is written in one transaction or not at all
+func (db *BadgerDB) AtomicBatches() bool {
+	return true
+}
+
+// PutInBatch writes the records in one transaction, so they are seen all at once and a crash
+// leaves none of them written. A batch too big for a transaction is not written, and
+// badger.ErrTxnTooBig is returned.
+func (db *BadgerDB) PutInBatch(records []interfaces.Record) error {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	txn := db.db.NewTransaction(true)
+	defer txn.Discard()
+
+	for _, v := range records {
+		hex, err := v.Data.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		BadgerDBPuts.Inc()
+		err = txn.Set(CombineBucketAndKey(v.Bucket, v.Key), hex)
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit(nil)
+}
+
+// Clear deletes the keys of the bucket. A bucket too big to delete in one transaction is deleted
+// over several.
+func (db *BadgerDB) Clear(bucket []byte) error {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	txn := db.db.NewTransaction(false)
+	keys, err := listAllKeys(txn, bucket)
+	txn.Discard()
+	if err != nil {
+		return err
+	}
+
+	txn = db.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for _, key := range keys {
+		err = txn.Delete(CombineBucketAndKey(bucket, key))
+		if err == badger.ErrTxnTooBig {
+			err = txn.Commit(nil)
+			if err != nil {
+				return err
+			}
+			txn = db.db.NewTransaction(true)
+			err = txn.Delete(CombineBucketAndKey(bucket, key))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit(nil)
+}
+
+func (db *BadgerDB) ListAllKeys(bucket []byte) ([][]byte, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return listAllKeys(txn, bucket)
+}
+
+func (db *BadgerDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return getAll(txn, bucket, sample)
+}
+
+func (db *BadgerDB) DoesKeyExist(bucket, key []byte) (bool, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return doesKeyExist(txn, bucket, key)
+}
+
+func get(txn *badger.Txn, bucket []byte, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	item, err := txn.Get(CombineBucketAndKey(bucket, key))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = destination.UnmarshalBinaryData(v)
+	if err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
+
+func doesKeyExist(txn *badger.Txn, bucket, key []byte) (bool, error) {
+	_, err := txn.Get(CombineBucketAndKey(bucket, key))
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func listAllKeys(txn *badger.Txn, bucket []byte) ([][]byte, error) {
+	prefix := BucketPrefix(bucket)
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	answer := [][]byte{}
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Item().Key()
+		tmp := make([]byte, len(k)-len(prefix))
+		copy(tmp, k[len(prefix):])
+		answer = append(answer, tmp)
+	}
+	return answer, nil
+}
+
+func getAll(txn *badger.Txn, bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	prefix := BucketPrefix(bucket)
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	answer := []interfaces.BinaryMarshallableAndCopyable{}
+	keys := [][]byte{}
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		tmp := sample.New()
+		err = tmp.UnmarshalBinary(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		k := item.Key()
+		key := make([]byte, len(k)-len(prefix))
+		copy(key, k[len(prefix):])
+		keys = append(keys, key)
+		answer = append(answer, tmp)
+	}
+	return answer, keys, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package badgerdb_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	. "github.com/FactomProject/factomd/database/badgerdb"
+	"github.com/FactomProject/factomd/util"
+)
+
+type TestData struct {
+	Str string
+}
+
+func (t *TestData) New() interfaces.BinaryMarshallableAndCopyable {
+	return new(TestData)
+}
+
+func (t *TestData) MarshalBinary() ([]byte, error) {
+	return []byte(t.Str), nil
+}
+
+func (t *TestData) UnmarshalBinaryData(data []byte) ([]byte, error) {
+	t.Str = string(data)
+	return nil, nil
+}
+
+func (t *TestData) UnmarshalBinary(data []byte) (err error) {
+	_, err = t.UnmarshalBinaryData(data)
+	return
+}
+
+var _ interfaces.BinaryMarshallable = (*TestData)(nil)
+
+func TestBuckets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerTest")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := NewBadgerDB(dir, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer m.Close()
+
+	// Buckets that are prefixes of each other must not see each other's keys
+	buckets := [][]byte{[]byte("a"), []byte("ab"), []byte("b")}
+	for _, b := range buckets {
+		for i := 0; i < 3; i++ {
+			err = m.Put(b, []byte(fmt.Sprintf("%v", i)), &TestData{Str: string(b)})
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+	}
+
+	all, err := m.ListAllBuckets()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sort.Sort(util.ByByteArray(all))
+	if fmt.Sprintf("%s", all) != "[a ab b]" {
+		t.Errorf("Wrong buckets - %s", all)
+	}
+
+	for _, b := range buckets {
+		values, keys, err := m.GetAll(b, new(TestData))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(keys) != 3 {
+			t.Errorf("Expected 3 keys in bucket %s, got %v", b, len(keys))
+		}
+		for _, v := range values {
+			if v.(*TestData).Str != string(b) {
+				t.Errorf("Bucket %s holds a value of bucket %v", b, v.(*TestData).Str)
+			}
+		}
+	}
+
+	err = m.Clear([]byte("a"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	keys, err := m.ListAllKeys([]byte("a"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Bucket was not cleared - %s", keys)
+	}
+	keys, err = m.ListAllKeys([]byte("ab"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("Clearing a bucket cleared another one - %s", keys)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package badgerdb
+
+import (
+	"bytes"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/dgraph-io/badger"
+)
+
+// BadgerDBIterator walks the keys of one bucket inside a read transaction, so it sees the bucket
+// as it was when the iterator was made
+type BadgerDBIterator struct {
+	txn    *badger.Txn
+	ownsTx bool // false for iterators of a snapshot, which discards the transaction itself
+	iter   *badger.Iterator
+	prefix []byte // the bucket prefix every key starts with
+	start  []byte
+	limit  []byte
+
+	started bool
+	valid   bool
+	value   []byte
+	err     error
+}
+
+var _ interfaces.IIterator = (*BadgerDBIterator)(nil)
+
+func (db *BadgerDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	it := newIterator(db.db.NewTransaction(false), bucket, r)
+	it.ownsTx = true
+	return it, nil
+}
+
+func newIterator(txn *badger.Txn, bucket []byte, r *interfaces.IteratorRange) *BadgerDBIterator {
+	it := new(BadgerDBIterator)
+	it.txn = txn
+	it.prefix = BucketPrefix(bucket)
+
+	start, limit := r.Bounds()
+	it.start = CombineBucketAndKey(bucket, start)
+	if limit != nil {
+		it.limit = CombineBucketAndKey(bucket, limit)
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it.iter = txn.NewIterator(opts)
+	return it
+}
+
+// check keeps the iterator valid only while it is in the bucket and the range
+func (it *BadgerDBIterator) check() bool {
+	it.value = nil
+	it.valid = it.iter.ValidForPrefix(it.prefix)
+	if it.valid && it.limit != nil && bytes.Compare(it.iter.Item().Key(), it.limit) >= 0 {
+		it.valid = false
+	}
+	return it.valid
+}
+
+func (it *BadgerDBIterator) Next() bool {
+	if it.started == false {
+		it.started = true
+		it.iter.Seek(it.start)
+		return it.check()
+	}
+	if it.valid == false {
+		return false
+	}
+	it.iter.Next()
+	return it.check()
+}
+
+func (it *BadgerDBIterator) Seek(key []byte) bool {
+	it.started = true
+	bKey := append(append([]byte{}, it.prefix...), key...)
+	if bytes.Compare(bKey, it.start) < 0 {
+		bKey = it.start
+	}
+	it.iter.Seek(bKey)
+	return it.check()
+}
+
+func (it *BadgerDBIterator) Key() []byte {
+	if it.valid == false {
+		return nil
+	}
+	return it.iter.Item().Key()[len(it.prefix):]
+}
+
+// Value returns nil if the value can't be read from the value log, the error is returned by Error
+func (it *BadgerDBIterator) Value() []byte {
+	if it.valid == false {
+		return nil
+	}
+	if it.value == nil {
+		v, err := it.iter.Item().ValueCopy(nil)
+		if err != nil {
+			it.err = err
+			return nil
+		}
+		it.value = v
+	}
+	return it.value
+}
+
+func (it *BadgerDBIterator) Release() {
+	if it.iter != nil {
+		it.iter.Close()
+		it.iter = nil
+	}
+	if it.txn != nil && it.ownsTx {
+		it.txn.Discard()
+	}
+	it.txn = nil
+	it.valid = false
+	it.value = nil
+}
+
+func (it *BadgerDBIterator) Error() error {
+	return it.err
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package badgerdb
+
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/dgraph-io/badger"
+)
+
+// BadgerDBSnapshot reads the database inside one read transaction. Badger keeps every version of
+// a key a transaction can still read, so long lived snapshots hold back compactions.
+type BadgerDBSnapshot struct {
+	txn *badger.Txn
+}
+
+var _ interfaces.ISnapshot = (*BadgerDBSnapshot)(nil)
+
+func (db *BadgerDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	return &BadgerDBSnapshot{txn: db.db.NewTransaction(false)}, nil
+}
+
+func (s *BadgerDBSnapshot) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	return get(s.txn, bucket, key, destination)
+}
+
+func (s *BadgerDBSnapshot) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return listAllKeys(s.txn, bucket)
+}
+
+func (s *BadgerDBSnapshot) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	return getAll(s.txn, bucket, sample)
+}
+
+func (s *BadgerDBSnapshot) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return doesKeyExist(s.txn, bucket, key)
+}
+
+// NewIterator returns an iterator inside the snapshot's transaction, it has to be released before
+// the snapshot is
+func (s *BadgerDBSnapshot) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return newIterator(s.txn, bucket, r), nil
+}
+
+func (s *BadgerDBSnapshot) Release() {
+	if s.txn != nil {
+		s.txn.Discard()
+		s.txn = nil
+	}
+}
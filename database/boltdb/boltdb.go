@@ -31,6 +31,8 @@ import (
 type BoltDB struct {
 	Sem sync.RWMutex
 	db  *bolt.DB // Pointer to the bolt db
+
+	snapshots map[*BoltDBSnapshot]bool // Open snapshots, which are saved the values writes replace
 }
 
 var _ interfaces.IDatabase = (*BoltDB)(nil)
@@ -76,6 +78,7 @@ func (db *BoltDB) Delete(bucket []byte, key []byte) error {
 		if err != nil {
 			return err
 		}
+		db.saveForSnapshots(tx, bucket, key)
 		b := tx.Bucket(bucket)
 		b.Delete(key)
 		return nil
@@ -135,6 +138,7 @@ func (db *BoltDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshalla
 		if err != nil {
 			return err
 		}
+		db.saveForSnapshots(tx, bucket, key)
 		b := tx.Bucket(bucket)
 		err = b.Put(key, hex)
 		return err
@@ -142,6 +146,11 @@ func (db *BoltDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshalla
 	return err
 }
 
+// AtomicBatches is true, a batch is written in one Bolt transaction
+func (db *BoltDB) AtomicBatches() bool {
+	return true
+}
+
 func (db *BoltDB) PutInBatch(records []interfaces.Record) error {
 	db.Sem.Lock()
 	defer db.Sem.Unlock()
@@ -157,6 +166,7 @@ func (db *BoltDB) PutInBatch(records []interfaces.Record) error {
 			if err != nil {
 				return err
 			}
+			db.saveForSnapshots(tx, v.Bucket, v.Key)
 			err = b.Put(v.Key, hex)
 			if err != nil {
 				return err
@@ -175,6 +185,7 @@ func (db *BoltDB) Clear(bucket []byte) error {
 	defer db.Sem.Unlock()
 
 	err := db.db.Update(func(tx *bolt.Tx) error {
+		db.saveBucketForSnapshots(tx, bucket)
 		err := tx.DeleteBucket(bucket)
 		if err != nil {
 			return fmt.Errorf("No bucket: %s", err)
@@ -0,0 +1,114 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package boltdb
+
+import (
+	"bytes"
+
+	"github.com/FactomProject/bolt"
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// BoltDBIterator walks the keys of one bucket inside a read transaction, which is held until the
+// iterator is released. Bolt can't grow its memory map while read transactions are open, so
+// iterators should not be kept around.
+type BoltDBIterator struct {
+	tx     *bolt.Tx
+	cursor *bolt.Cursor
+	r      *interfaces.IteratorRange
+
+	started bool
+	key     []byte
+	value   []byte
+}
+
+var _ interfaces.IIterator = (*BoltDBIterator)(nil)
+
+func (db *BoltDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIterator(tx, bucket, r), nil
+}
+
+func newIterator(tx *bolt.Tx, bucket []byte, r *interfaces.IteratorRange) *BoltDBIterator {
+	it := new(BoltDBIterator)
+	it.tx = tx
+	it.r = r
+	b := tx.Bucket(bucket)
+	if b != nil {
+		it.cursor = b.Cursor()
+	}
+	return it
+}
+
+// set keeps the key the cursor moved to, if it is in the range
+func (it *BoltDBIterator) set(k, v []byte) bool {
+	_, limit := it.r.Bounds()
+	if k == nil || (limit != nil && bytes.Compare(k, limit) >= 0) {
+		it.key = nil
+		it.value = nil
+		return false
+	}
+	it.key = k
+	it.value = v
+	return true
+}
+
+func (it *BoltDBIterator) Next() bool {
+	if it.cursor == nil {
+		return false
+	}
+	if it.started == false {
+		start, _ := it.r.Bounds()
+		return it.Seek(start)
+	}
+	if it.key == nil {
+		return false
+	}
+	return it.set(it.cursor.Next())
+}
+
+func (it *BoltDBIterator) Seek(key []byte) bool {
+	if it.cursor == nil {
+		return false
+	}
+	it.started = true
+	start, _ := it.r.Bounds()
+	if bytes.Compare(key, start) < 0 {
+		key = start
+	}
+	if len(key) == 0 {
+		return it.set(it.cursor.First())
+	}
+	return it.set(it.cursor.Seek(key))
+}
+
+func (it *BoltDBIterator) Key() []byte {
+	return it.key
+}
+
+func (it *BoltDBIterator) Value() []byte {
+	return it.value
+}
+
+func (it *BoltDBIterator) Release() {
+	if it.tx != nil {
+		it.tx.Rollback()
+	}
+	it.tx = nil
+	it.cursor = nil
+	it.key = nil
+	it.value = nil
+}
+
+func (it *BoltDBIterator) Error() error {
+	return nil
+}
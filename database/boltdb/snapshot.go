@@ -0,0 +1,312 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package boltdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/FactomProject/bolt"
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// BoltDBSnapshot reads the database as it was when the snapshot was taken. It does not hold a read
+// transaction, as Bolt can't grow its memory map while one is open, which would block writes until
+// the snapshot is released. Instead, while the snapshot is open the database saves into it the old
+// value of every key it writes, and the snapshot reads the saved values before the database.
+type BoltDBSnapshot struct {
+	db *BoltDB
+	// saved holds the values of the keys written since the snapshot was taken, by bucket and key.
+	// A nil value is a key that did not exist. It is guarded by the database's Sem.
+	saved map[string]map[string][]byte
+}
+
+var _ interfaces.ISnapshot = (*BoltDBSnapshot)(nil)
+
+func (db *BoltDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	s := &BoltDBSnapshot{db: db, saved: map[string]map[string][]byte{}}
+	if db.snapshots == nil {
+		db.snapshots = map[*BoltDBSnapshot]bool{}
+	}
+	db.snapshots[s] = true
+	return s, nil
+}
+
+// saveForSnapshots keeps the value of the key in the open snapshots that have not saved it yet. It
+// is called with Sem locked, inside the transaction that is about to write the key.
+func (db *BoltDB) saveForSnapshots(tx *bolt.Tx, bucket, key []byte) {
+	if len(db.snapshots) == 0 {
+		return
+	}
+	var v []byte
+	if b := tx.Bucket(bucket); b != nil {
+		if old := b.Get(key); old != nil {
+			v = copyValue(old)
+		}
+	}
+	for s := range db.snapshots {
+		s.save(bucket, key, v)
+	}
+}
+
+// saveBucketForSnapshots keeps every value of the bucket in the open snapshots, before the bucket
+// is cleared
+func (db *BoltDB) saveBucketForSnapshots(tx *bolt.Tx, bucket []byte) {
+	if len(db.snapshots) == 0 {
+		return
+	}
+	b := tx.Bucket(bucket)
+	if b == nil {
+		return
+	}
+	b.ForEach(func(k, v []byte) error {
+		for s := range db.snapshots {
+			s.save(bucket, k, copyValue(v))
+		}
+		return nil
+	})
+}
+
+func (s *BoltDBSnapshot) save(bucket, key, v []byte) {
+	b := s.saved[string(bucket)]
+	if b == nil {
+		b = map[string][]byte{}
+		s.saved[string(bucket)] = b
+	}
+	if _, ok := b[string(key)]; !ok {
+		b[string(key)] = v
+	}
+}
+
+// Values are only valid for the life of the transaction, so they are copied before they are
+// unmarshalled
+func copyValue(v []byte) []byte {
+	answer := make([]byte, len(v))
+	copy(answer, v)
+	return answer
+}
+
+// get returns the value the key had when the snapshot was taken, or nil
+func (s *BoltDBSnapshot) get(bucket, key []byte) []byte {
+	s.db.Sem.RLock()
+	defer s.db.Sem.RUnlock()
+
+	if v, ok := s.saved[string(bucket)][string(key)]; ok {
+		return v
+	}
+	var v []byte
+	s.db.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+		if found := b.Get(key); found != nil {
+			v = copyValue(found)
+		}
+		return nil
+	})
+	return v
+}
+
+func (s *BoltDBSnapshot) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	v := s.get(bucket, key)
+	if v == nil {
+		return nil, nil
+	}
+
+	_, err := destination.UnmarshalBinaryData(v)
+	if err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
+
+func (s *BoltDBSnapshot) ListAllKeys(bucket []byte) ([][]byte, error) {
+	keys := make([][]byte, 0, 32)
+	it, err := s.NewIterator(bucket, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		keys = append(keys, copyValue(it.Key()))
+	}
+	return keys, it.Error()
+}
+
+func (s *BoltDBSnapshot) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	answer := []interfaces.BinaryMarshallableAndCopyable{}
+	keys := [][]byte{}
+	it, err := s.NewIterator(bucket, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		tmp := sample.New()
+		err := tmp.UnmarshalBinary(copyValue(it.Value()))
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, copyValue(it.Key()))
+		answer = append(answer, tmp)
+	}
+	if err := it.Error(); err != nil {
+		return nil, nil, err
+	}
+	return answer, keys, nil
+}
+
+func (s *BoltDBSnapshot) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return s.get(bucket, key) != nil, nil
+}
+
+// NewIterator returns an iterator over the bucket as it was when the snapshot was taken. Like the
+// database's iterators, it holds a read transaction until it is released.
+func (s *BoltDBSnapshot) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	s.db.Sem.RLock()
+	defer s.db.Sem.RUnlock()
+
+	tx, err := s.db.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys saved after this point held the same values in the transaction, so a copy of the saved
+	// keys is all the iterator needs
+	it := new(snapshotIterator)
+	it.live = newIterator(tx, bucket, r)
+	for k, v := range s.saved[string(bucket)] {
+		if r.Contains([]byte(k)) {
+			it.saved = append(it.saved, savedValue{key: []byte(k), value: v})
+		}
+	}
+	sort.Sort(it.saved)
+	return it, nil
+}
+
+func (s *BoltDBSnapshot) Release() {
+	s.db.Sem.Lock()
+	defer s.db.Sem.Unlock()
+
+	delete(s.db.snapshots, s)
+	s.saved = map[string]map[string][]byte{}
+}
+
+type savedValue struct {
+	key   []byte
+	value []byte
+}
+
+// savedValues sorts the saved values by key
+type savedValues []savedValue
+
+func (v savedValues) Len() int {
+	return len(v)
+}
+func (v savedValues) Less(i, j int) bool {
+	return bytes.Compare(v[i].key, v[j].key) < 0
+}
+func (v savedValues) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+// snapshotIterator merges the keys of the database with the values a snapshot saved, which take
+// precedence
+type snapshotIterator struct {
+	live     *BoltDBIterator
+	liveOk   bool
+	saved    savedValues
+	i        int
+	started  bool
+	fromLive bool
+	fromSave bool
+	key      []byte
+	value    []byte
+}
+
+var _ interfaces.IIterator = (*snapshotIterator)(nil)
+
+// pick moves to the lowest key of the two sources, skipping keys that did not exist when the
+// snapshot was taken
+func (it *snapshotIterator) pick() bool {
+	for {
+		it.fromLive, it.fromSave = false, false
+		hasSaved := it.i < len(it.saved)
+		if it.liveOk == false && hasSaved == false {
+			it.key, it.value = nil, nil
+			return false
+		}
+		if hasSaved && (it.liveOk == false || bytes.Compare(it.saved[it.i].key, it.live.Key()) <= 0) {
+			it.fromSave = true
+			it.fromLive = it.liveOk && bytes.Equal(it.saved[it.i].key, it.live.Key())
+			if it.saved[it.i].value == nil {
+				it.advance()
+				continue
+			}
+			it.key, it.value = it.saved[it.i].key, it.saved[it.i].value
+			return true
+		}
+		it.fromLive = true
+		it.key, it.value = it.live.Key(), it.live.Value()
+		return true
+	}
+}
+
+// advance moves the sources past the current key
+func (it *snapshotIterator) advance() {
+	if it.fromSave {
+		it.i++
+	}
+	if it.fromLive {
+		it.liveOk = it.live.Next()
+	}
+}
+
+func (it *snapshotIterator) Next() bool {
+	if it.started == false {
+		start, _ := it.live.r.Bounds()
+		return it.Seek(start)
+	}
+	it.advance()
+	return it.pick()
+}
+
+func (it *snapshotIterator) Seek(key []byte) bool {
+	it.started = true
+	it.liveOk = it.live.Seek(key)
+	start, _ := it.live.r.Bounds()
+	if bytes.Compare(key, start) < 0 {
+		key = start
+	}
+	it.i = sort.Search(len(it.saved), func(i int) bool {
+		return bytes.Compare(it.saved[i].key, key) >= 0
+	})
+	return it.pick()
+}
+
+func (it *snapshotIterator) Key() []byte {
+	return it.key
+}
+
+func (it *snapshotIterator) Value() []byte {
+	return it.value
+}
+
+func (it *snapshotIterator) Release() {
+	it.live.Release()
+	it.saved = nil
+	it.key = nil
+	it.value = nil
+}
+
+func (it *snapshotIterator) Error() error {
+	return it.live.Error()
+}
@@ -0,0 +1,191 @@
+package databaseOverlay
+
+import (
+	"encoding/binary"
+
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The optional address index keeps a bucket per address (ADDRESS_TRANSACTIONS + address) whose keys
+// are the position of a transaction in the chain, so iterating the keys in order walks the history
+// of the address. The value is the id of the transaction.
+//
+//	key: dbheight (4 bytes) | block type (1 byte) | index in block (4 bytes)
+
+const (
+	AddressIndexFactoid     byte = 0
+	AddressIndexEntryCredit byte = 1
+)
+
+const addressIndexKeyLength = 9
+
+// SetAddressIndex turns maintaining the address index on or off for blocks saved from now on
+func (db *Overlay) SetAddressIndex(enabled bool) {
+	db.AddressIndex = enabled
+}
+
+func (db *Overlay) GetAddressIndex() bool {
+	return db.AddressIndex
+}
+
+func addressIndexBucket(address []byte) []byte {
+	return append(append([]byte{}, ADDRESS_TRANSACTIONS...), address...)
+}
+
+func addressIndexKey(height uint32, blockType byte, index uint32) []byte {
+	key := make([]byte, addressIndexKeyLength)
+	binary.BigEndian.PutUint32(key[0:4], height)
+	key[4] = blockType
+	binary.BigEndian.PutUint32(key[5:9], index)
+	return key
+}
+
+// fBlockAddressRecords returns the address index records for every input, output and
+// entry credit output of the transactions of the block
+func fBlockAddressRecords(block interfaces.IFBlock) []interfaces.Record {
+	batch := []interfaces.Record{}
+	height := block.GetDatabaseHeight()
+	for i, tx := range block.GetTransactions() {
+		key := addressIndexKey(height, AddressIndexFactoid, uint32(i))
+		txid := tx.GetSigHash()
+
+		addresses := []interfaces.ITransAddress{}
+		addresses = append(addresses, tx.GetInputs()...)
+		addresses = append(addresses, tx.GetOutputs()...)
+		addresses = append(addresses, tx.GetECOutputs()...)
+		for _, a := range addresses {
+			batch = append(batch, interfaces.Record{addressIndexBucket(a.GetAddress().Bytes()), key, txid})
+		}
+	}
+	return batch
+}
+
+// eCBlockAddressRecords returns the address index records for the chain and entry commits of the
+// block. Balance increases are not indexed, the factoid transaction that paid for them already is.
+func eCBlockAddressRecords(block interfaces.IEntryCreditBlock) []interfaces.Record {
+	batch := []interfaces.Record{}
+	height := block.GetDatabaseHeight()
+	for i, entry := range block.GetEntries() {
+		var pubKey *primitives.ByteSlice32
+		switch e := entry.(type) {
+		case *entryCreditBlock.CommitChain:
+			pubKey = e.ECPubKey
+		case *entryCreditBlock.CommitEntry:
+			pubKey = e.ECPubKey
+		default:
+			continue
+		}
+		if pubKey == nil {
+			continue
+		}
+		key := addressIndexKey(height, AddressIndexEntryCredit, uint32(i))
+		batch = append(batch, interfaces.Record{addressIndexBucket(pubKey[:]), key, entry.GetSigHash()})
+	}
+	return batch
+}
+
+// SaveAddressIndexFromBlock indexes a factoid or entry credit block that is being saved, if the
+// address index is turned on
+func (db *Overlay) SaveAddressIndexFromBlock(block interface{}, multiBatch bool) error {
+	if !db.AddressIndex {
+		return nil
+	}
+
+	var batch []interfaces.Record
+	switch b := block.(type) {
+	case interfaces.IFBlock:
+		batch = fBlockAddressRecords(b)
+	case interfaces.IEntryCreditBlock:
+		batch = eCBlockAddressRecords(b)
+	default:
+		return nil
+	}
+
+	if multiBatch {
+		db.PutInMultiBatch(batch)
+		return nil
+	}
+	return db.PutInBatch(batch)
+}
+
+// IndexFBlockAddresses adds the transactions of an already saved factoid block to the address index
+func (db *Overlay) IndexFBlockAddresses(block interfaces.IFBlock) error {
+	return db.PutInBatch(fBlockAddressRecords(block))
+}
+
+// IndexECBlockAddresses adds the commits of an already saved entry credit block to the address index
+func (db *Overlay) IndexECBlockAddresses(block interfaces.IEntryCreditBlock) error {
+	return db.PutInBatch(eCBlockAddressRecords(block))
+}
+
+// RebuildAddressIndex indexes every factoid and entry credit block in the database, so the
+// address index can be turned on for a database that was created without it.
+func (db *Overlay) RebuildAddressIndex() error {
+	for height := uint32(0); ; height++ {
+		fBlock, err := db.FetchFBlockByHeight(height)
+		if err != nil {
+			return err
+		}
+		ecBlock, err := db.FetchECBlockByHeight(height)
+		if err != nil {
+			return err
+		}
+		if fBlock == nil && ecBlock == nil {
+			return nil
+		}
+		if fBlock != nil {
+			if err := db.IndexFBlockAddresses(fBlock); err != nil {
+				return err
+			}
+		}
+		if ecBlock != nil {
+			if err := db.IndexECBlockAddresses(ecBlock); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// FetchAddressTransactions returns up to limit transactions touching the address, starting at the
+// given directory block height, in the order they were included in the chain. The returned cursor
+// is the start of the next page, or nil if there are no more transactions.
+func (db *Overlay) FetchAddressTransactions(address []byte, startHeight uint32, cursor []byte, limit int) ([]interfaces.AddressTransaction, []byte, error) {
+	start := addressIndexKey(startHeight, 0, 0)
+	if cursor != nil {
+		start = cursor
+	}
+	// Only the page is read, starting from its first key
+	iter, err := db.NewIterator(addressIndexBucket(address), &interfaces.IteratorRange{Start: start})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Release()
+
+	answer := []interfaces.AddressTransaction{}
+	for iter.Next() {
+		k := iter.Key()
+		if len(k) != addressIndexKeyLength {
+			continue
+		}
+		if limit > 0 && len(answer) >= limit {
+			return answer, append([]byte{}, k...), nil
+		}
+
+		txid := new(primitives.Hash)
+		if err := txid.UnmarshalBinary(iter.Value()); err != nil {
+			return nil, nil, err
+		}
+
+		tx := interfaces.AddressTransaction{}
+		tx.TxID = txid
+		tx.DBHeight = binary.BigEndian.Uint32(k[0:4])
+		tx.EntryCredit = k[4] == AddressIndexEntryCredit
+		answer = append(answer, tx)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	return answer, nil, nil
+}
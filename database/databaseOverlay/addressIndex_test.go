@@ -0,0 +1,113 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package databaseOverlay_test
+
+import (
+	"testing"
+
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestAddressIndex(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	f1 := testHelper.CreateTestFactoidBlock(nil)
+	err := dbo.ProcessFBlockBatch(f1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// Blocks saved before the index was turned on are not indexed
+	fa := testHelper.NewFactoidAddress(0).Bytes()
+	txs, _, err := dbo.FetchAddressTransactions(fa, 0, nil, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(txs) != 0 {
+		t.Errorf("Found %d transactions with the index turned off", len(txs))
+	}
+
+	dbo.SetAddressIndex(true)
+
+	f2 := testHelper.CreateTestFactoidBlock(f1)
+	err = dbo.ProcessFBlockBatch(f2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	eb, _ := testHelper.CreateTestEntryBlock(nil)
+	ec := testHelper.CreateTestEntryCreditBlock(nil)
+	ec.GetBody().AddEntry(testHelper.NewCommitEntry(eb))
+	err = dbo.ProcessECBlockBatch(ec, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err = dbo.RebuildAddressIndex()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// The coinbase and the entry credit purchase of each block
+	txs, _, err = dbo.FetchAddressTransactions(fa, 0, nil, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(txs) != 4 {
+		t.Fatalf("Expected 4 transactions, found %d", len(txs))
+	}
+	for i, tx := range txs {
+		if tx.EntryCredit {
+			t.Errorf("Transaction %d is not a factoid transaction", i)
+		}
+		if tx.DBHeight != uint32(i/2) {
+			t.Errorf("Transaction %d has height %d", i, tx.DBHeight)
+		}
+	}
+	if txs[1].TxID.IsSameAs(f1.GetTransactions()[1].GetSigHash()) == false {
+		t.Errorf("Wrong transaction id %v", txs[1].TxID)
+	}
+
+	page, cursor, err := dbo.FetchAddressTransactions(fa, 0, nil, 3)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(page) != 3 || cursor == nil {
+		t.Fatalf("Expected a page of 3 and a cursor, found %d and %x", len(page), cursor)
+	}
+	page, cursor, err = dbo.FetchAddressTransactions(fa, 0, cursor, 3)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(page) != 1 || cursor != nil {
+		t.Fatalf("Expected a last page of 1, found %d and %x", len(page), cursor)
+	}
+	if page[0].TxID.IsSameAs(txs[3].TxID) == false {
+		t.Errorf("Last page does not continue the first")
+	}
+
+	txs, _, err = dbo.FetchAddressTransactions(fa, 1, nil, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(txs) != 2 {
+		t.Errorf("Expected 2 transactions from height 1, found %d", len(txs))
+	}
+
+	// The entry credit purchases and the commit paid for by the address
+	txs, _, err = dbo.FetchAddressTransactions(testHelper.NewECAddress(0).Bytes(), 0, nil, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 transactions, found %d", len(txs))
+	}
+	if txs[0].EntryCredit || !txs[1].EntryCredit || txs[2].EntryCredit {
+		t.Errorf("Transactions are not in chain order")
+	}
+}
@@ -6,11 +6,15 @@ package databaseOverlay
 
 import (
 	//"fmt"
+	"encoding/binary"
+	"errors"
+	"sort"
+
 	"github.com/FactomProject/factomd/anchor"
 	"github.com/FactomProject/factomd/common/directoryBlock/dbInfo"
+	"github.com/FactomProject/factomd/common/entryBlock"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
-	"sort"
 )
 
 var AnchorBlockID string = "df3ade9eec4b08d5379cc64270c30ea7315d8a8a1a69efe2b98a60ecdd69e604"
@@ -48,6 +52,12 @@ func (dbo *Overlay) SaveAnchorInfoFromEntry(entry interfaces.IEBEntry) error {
 	if entry.DatabasePrimaryIndex().String() == "24674e6bc3094eb773297de955ee095a05830e431da13a37382dcdc89d73c7d7" {
 		return nil
 	}
+	if r := anchorRecordIndexRecord(entry); r != nil {
+		err := dbo.PutInBatch([]interfaces.Record{*r})
+		if err != nil {
+			return err
+		}
+	}
 	ar, ok, err := anchor.UnmarshalAndValidateAnchorEntryAnyVersion(entry, AnchorSigPublicKeys)
 	if err != nil {
 		return err
@@ -69,6 +79,9 @@ func (dbo *Overlay) SaveAnchorInfoFromEntryMultiBatch(entry interfaces.IEBEntry)
 	if entry.DatabasePrimaryIndex().String() == "24674e6bc3094eb773297de955ee095a05830e431da13a37382dcdc89d73c7d7" {
 		return nil
 	}
+	if r := anchorRecordIndexRecord(entry); r != nil {
+		dbo.PutInMultiBatch([]interfaces.Record{*r})
+	}
 	ar, ok, err := anchor.UnmarshalAndValidateAnchorEntryAnyVersion(entry, AnchorSigPublicKeys)
 	if err != nil {
 		return err
@@ -162,6 +175,153 @@ func AnchorRecordToDirBlockInfo(ar *anchor.AnchorRecord) (*dbInfo.DirBlockInfo,
 	return dbi, nil
 }
 
+func anchorRecordIndexKey(dbHeight uint32, entryHash interfaces.IHash) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, dbHeight)
+	return append(key, entryHash.Bytes()...)
+}
+
+// anchorRecordIndexRecord returns the ANCHOR_RECORDS record of an anchor chain entry, or nil if the
+// entry holds no anchor record. Records are indexed whether their signature is valid or not.
+func anchorRecordIndexRecord(entry interfaces.IEBEntry) *interfaces.Record {
+	ar, err := anchor.UnmarshalAnchorRecord(entry.GetContent())
+	if err != nil || ar == nil {
+		return nil
+	}
+	hash := entry.DatabasePrimaryIndex()
+	return &interfaces.Record{ANCHOR_RECORDS, anchorRecordIndexKey(ar.DBHeight, hash), hash}
+}
+
+// AnchorRecordIndexBatchSize is the most anchor chain entries RebuildAnchorRecordIndex reads and
+// indexes at once
+var AnchorRecordIndexBatchSize = 1000
+
+var errAnchorRecordBatchFull = errors.New("Anchor record batch is full")
+
+// RebuildAnchorRecordIndex indexes every entry of the anchor chain, for databases saved before the
+// index existed. The entries are read in batches of AnchorRecordIndexBatchSize, the iterator is
+// released before each batch is written.
+func (dbo *Overlay) RebuildAnchorRecordIndex() error {
+	chainID, err := primitives.NewShaHashFromStr(AnchorBlockID)
+	if err != nil {
+		return err
+	}
+	var start []byte
+	for {
+		batch := []interfaces.Record{}
+		var last []byte
+		read := 0
+		err = dbo.ForEachInBucket(chainID.Bytes(), &interfaces.IteratorRange{Start: start}, entryBlock.NewEntry(), func(k []byte, value interfaces.BinaryMarshallableAndCopyable) error {
+			if read == AnchorRecordIndexBatchSize {
+				return errAnchorRecordBatchFull
+			}
+			read++
+			last = k
+			if r := anchorRecordIndexRecord(value.(interfaces.IEBEntry)); r != nil {
+				batch = append(batch, *r)
+			}
+			return nil
+		})
+		if err != nil && err != errAnchorRecordBatchFull {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := dbo.PutInBatch(batch); err != nil {
+				return err
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		// The next batch starts right after the last key read
+		start = append(last, 0)
+	}
+}
+
+// AnchorRecordIndexKey is set in the key value store once the anchor record index is built
+var AnchorRecordIndexKey = []byte("AnchorRecordIndex")
+
+// InitAnchorRecordIndex builds the anchor record index the first time a database is opened by a
+// version that keeps it. Blocks saved afterwards are indexed as they are saved. It can run in the
+// background, the anchor entries of older blocks are missing from the index until it returns.
+func (dbo *Overlay) InitAnchorRecordIndex() error {
+	built, err := dbo.FetchKeyValueStore(AnchorRecordIndexKey, new(primitives.ByteSlice))
+	if err != nil {
+		return err
+	}
+	if built != nil {
+		return nil
+	}
+	err = dbo.RebuildAnchorRecordIndex()
+	if err != nil {
+		return err
+	}
+	return dbo.SaveKeyValueStore(&primitives.ByteSlice{Bytes: []byte{1}}, AnchorRecordIndexKey)
+}
+
+// FetchAnchorEntriesByDBHeight returns the anchor chain entries holding an anchor record for the
+// directory block at the given height, and whether they are signed by one of the anchor keys
+func (dbo *Overlay) FetchAnchorEntriesByDBHeight(dbHeight uint32) ([]interfaces.AnchorEntry, error) {
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, dbHeight)
+
+	answer := []interfaces.AnchorEntry{}
+	err := dbo.ForEachInBucket(ANCHOR_RECORDS, &interfaces.IteratorRange{Prefix: prefix}, new(primitives.Hash), func(k []byte, value interfaces.BinaryMarshallableAndCopyable) error {
+		if len(k) != 4+32 {
+			return nil
+		}
+		hash, err := primitives.NewShaHash(k[4:])
+		if err != nil {
+			return err
+		}
+		entry, err := dbo.FetchEntry(hash)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return nil
+		}
+		ar, valid, err := anchor.UnmarshalAndValidateAnchorEntryAnyVersion(entry, AnchorSigPublicKeys)
+		answer = append(answer, interfaces.AnchorEntry{Entry: entry, Valid: err == nil && valid && ar != nil})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// SaveEthereumAnchorRecord saves the anchor record of a directory block anchored into Ethereum.
+// The directory block infos only hold Bitcoin anchors, so Ethereum records are kept on their own.
+func (dbo *Overlay) SaveEthereumAnchorRecord(dbHeight uint32, record interfaces.IAnchorRecord) error {
+	data, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, dbHeight)
+	return dbo.Put(ETHEREUM_ANCHOR_RECORDS, key, &primitives.ByteSlice{Bytes: data})
+}
+
+// FetchEthereumAnchorRecord returns the Ethereum anchor record of the directory block at the given
+// height, or nil if there is none
+func (dbo *Overlay) FetchEthereumAnchorRecord(dbHeight uint32) (interfaces.IAnchorRecord, error) {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, dbHeight)
+	data, err := dbo.Get(ETHEREUM_ANCHOR_RECORDS, key, new(primitives.ByteSlice))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	ar, err := anchor.UnmarshalAnchorRecord(data.(*primitives.ByteSlice).Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return ar, nil
+}
+
 // AnchorRecord array sorting implementation - accending
 type ByAnchorDBHeightAccending []*anchor.AnchorRecord
 
@@ -6,6 +6,7 @@ package databaseOverlay_test
 
 import (
 	"github.com/FactomProject/factomd/anchor"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
 	"github.com/FactomProject/factomd/testHelper"
 	"testing"
 )
@@ -119,3 +120,105 @@ func CreateAnchors() []*anchor.AnchorRecord {
 
 	return answer
 }
+
+func TestFetchAnchorEntriesByDBHeight(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	blocks := testHelper.CreateFullTestBlockSet()
+
+	for _, block := range blocks[:len(blocks)-1] {
+		entries, err := dbo.FetchAnchorEntriesByDBHeight(uint32(block.Height))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("Expected 1 anchor entry at height %v, got %v", block.Height, len(entries))
+			continue
+		}
+		if entries[0].Valid == false {
+			t.Errorf("Anchor entry at height %v is not valid", block.Height)
+		}
+		ar, err := anchor.UnmarshalAnchorRecord(entries[0].Entry.GetContent())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if ar.KeyMR != block.DBlock.GetKeyMR().String() {
+			t.Errorf("Anchor record at height %v is for the wrong directory block", block.Height)
+		}
+	}
+
+	// The index is rebuilt from the anchor chain the first time the database is opened, reading
+	// the entries in batches
+	err := dbo.Clear(databaseOverlay.ANCHOR_RECORDS)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	batchSize := databaseOverlay.AnchorRecordIndexBatchSize
+	databaseOverlay.AnchorRecordIndexBatchSize = 1
+	err = dbo.InitAnchorRecordIndex()
+	databaseOverlay.AnchorRecordIndexBatchSize = batchSize
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, block := range blocks[:len(blocks)-1] {
+		entries, err := dbo.FetchAnchorEntriesByDBHeight(uint32(block.Height))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("Expected 1 anchor entry at height %v after rebuilding the index, got %v", block.Height, len(entries))
+		}
+	}
+
+	// But only the first time
+	err = dbo.Clear(databaseOverlay.ANCHOR_RECORDS)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = dbo.InitAnchorRecordIndex()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entries, err := dbo.FetchAnchorEntriesByDBHeight(uint32(blocks[0].Height))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the index to be built once, got %v entries", len(entries))
+	}
+}
+
+func TestSaveEthereumAnchorRecord(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+
+	ar, err := dbo.FetchEthereumAnchorRecord(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ar != nil {
+		t.Errorf("Fetched an Ethereum anchor record that was never saved")
+	}
+
+	record := new(anchor.AnchorRecord)
+	record.AnchorRecordVer = 1
+	record.DBHeight = 1
+	record.KeyMR = "3b504616495fc9cf7be9b5b776692a9abbfb95491fa62abf62dcdf4d53ff5979"
+	record.Ethereum = new(anchor.EthereumStruct)
+	record.Ethereum.TXID = "0x50ea0effc383542811a58704a6d6842ed6d76439a2d942d941896ad097c06a78"
+	record.Ethereum.BlockHeight = 293003
+	err = dbo.SaveEthereumAnchorRecord(record.DBHeight, record)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ar, err = dbo.FetchEthereumAnchorRecord(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ar == nil {
+		t.Fatalf("Ethereum anchor record was not saved")
+	}
+	r := ar.(*anchor.AnchorRecord)
+	if r.KeyMR != record.KeyMR || r.Ethereum == nil || r.Ethereum.TXID != record.Ethereum.TXID {
+		t.Errorf("Wrong Ethereum anchor record - %v", r)
+	}
+}
@@ -0,0 +1,342 @@
+package databaseOverlay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/util"
+)
+
+// Historical balances are reconstructed by replaying the factoid and entry credit blocks. To keep
+// that fast, the balances of every address are saved every BalanceCheckpointInterval blocks, so a
+// query never has to replay more than one interval.
+const BalanceCheckpointInterval = 1000
+
+// BalanceCheckpoint holds the balances of every address after the block at DBHeight was applied
+type BalanceCheckpoint struct {
+	DBHeight        uint32
+	FactoidBalances map[[32]byte]int64
+	ECBalances      map[[32]byte]int64
+}
+
+var _ interfaces.BinaryMarshallable = (*BalanceCheckpoint)(nil)
+
+func NewBalanceCheckpoint() *BalanceCheckpoint {
+	c := new(BalanceCheckpoint)
+	c.Init()
+	return c
+}
+
+func (c *BalanceCheckpoint) Init() {
+	if c.FactoidBalances == nil {
+		c.FactoidBalances = map[[32]byte]int64{}
+	}
+	if c.ECBalances == nil {
+		c.ECBalances = map[[32]byte]int64{}
+	}
+}
+
+func marshalBalances(buf *primitives.Buffer, balances map[[32]byte]int64) error {
+	keys := make([][]byte, 0, len(balances))
+	for k := range balances {
+		key := k
+		keys = append(keys, key[:])
+	}
+	// Sorted so the same balances always marshal to the same bytes
+	sort.Sort(util.ByByteArray(keys))
+
+	err := buf.PushVarInt(uint64(len(keys)))
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err = buf.Push(k)
+		if err != nil {
+			return err
+		}
+		var fixed [32]byte
+		copy(fixed[:], k)
+		err = buf.PushInt64(balances[fixed])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalBalances(buf *primitives.Buffer, balances map[[32]byte]int64) error {
+	l, err := buf.PopVarInt()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < l; i++ {
+		var k [32]byte
+		err = buf.Pop(k[:])
+		if err != nil {
+			return err
+		}
+		v, err := buf.PopInt64()
+		if err != nil {
+			return err
+		}
+		balances[k] = v
+	}
+	return nil
+}
+
+func (c *BalanceCheckpoint) MarshalBinary() ([]byte, error) {
+	c.Init()
+	buf := primitives.NewBuffer(nil)
+
+	err := buf.PushUInt32(c.DBHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = marshalBalances(buf, c.FactoidBalances)
+	if err != nil {
+		return nil, err
+	}
+	err = marshalBalances(buf, c.ECBalances)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (c *BalanceCheckpoint) UnmarshalBinaryData(data []byte) ([]byte, error) {
+	c.FactoidBalances = nil
+	c.ECBalances = nil
+	c.Init()
+	buf := primitives.NewBuffer(data)
+
+	h, err := buf.PopUInt32()
+	if err != nil {
+		return nil, err
+	}
+	c.DBHeight = h
+	err = unmarshalBalances(buf, c.FactoidBalances)
+	if err != nil {
+		return nil, err
+	}
+	err = unmarshalBalances(buf, c.ECBalances)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (c *BalanceCheckpoint) UnmarshalBinary(data []byte) error {
+	_, err := c.UnmarshalBinaryData(data)
+	return err
+}
+
+// applyFBlock moves the factoids of every transaction in the block, and credits the entry credits
+// bought, the same way the factoid state does
+func (c *BalanceCheckpoint) applyFBlock(block interfaces.IFBlock) {
+	rate := int64(block.GetExchRate())
+	for _, tx := range block.GetTransactions() {
+		for _, input := range tx.GetInputs() {
+			c.FactoidBalances[input.GetAddress().Fixed()] -= int64(input.GetAmount())
+		}
+		for _, output := range tx.GetOutputs() {
+			c.FactoidBalances[output.GetAddress().Fixed()] += int64(output.GetAmount())
+		}
+		if rate == 0 {
+			continue
+		}
+		for _, ecOut := range tx.GetECOutputs() {
+			c.ECBalances[ecOut.GetAddress().Fixed()] += int64(ecOut.GetAmount()) / rate
+		}
+	}
+}
+
+// applyECBlock takes the entry credits paid for chain and entry commits
+func (c *BalanceCheckpoint) applyECBlock(block interfaces.IEntryCreditBlock) {
+	for _, entry := range block.GetEntries() {
+		switch e := entry.(type) {
+		case *entryCreditBlock.CommitChain:
+			c.ECBalances[e.ECPubKey.Fixed()] -= int64(e.Credits)
+		case *entryCreditBlock.CommitEntry:
+			c.ECBalances[e.ECPubKey.Fixed()] -= int64(e.Credits)
+		}
+	}
+}
+
+func balanceCheckpointKey(height uint32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, height)
+	return key
+}
+
+func (db *Overlay) SaveBalanceCheckpoint(c *BalanceCheckpoint) error {
+	return db.Put(BALANCE_CHECKPOINT, balanceCheckpointKey(c.DBHeight), c)
+}
+
+func (db *Overlay) FetchBalanceCheckpoint(height uint32) (*BalanceCheckpoint, error) {
+	c, err := db.Get(BALANCE_CHECKPOINT, balanceCheckpointKey(height), NewBalanceCheckpoint())
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, nil
+	}
+	return c.(*BalanceCheckpoint), nil
+}
+
+// MaxBalanceReplay is the most blocks a balance query replays itself. Longer replays are left to
+// BuildBalanceCheckpoints, which runs in the background.
+const MaxBalanceReplay = 2 * BalanceCheckpointInterval
+
+// ErrBalancesNotBuilt is returned for a balance query that would replay too many blocks, until
+// the checkpoints below its height are built
+var ErrBalancesNotBuilt = errors.New("Balance history is not built yet")
+
+// highestBalanceCheckpoint returns the highest saved checkpoint, or nil if there is none
+func (db *Overlay) highestBalanceCheckpoint() (*BalanceCheckpoint, error) {
+	keys, err := db.ListAllKeys(BALANCE_CHECKPOINT)
+	if err != nil {
+		return nil, err
+	}
+	// The backends do not all list their keys in order
+	found := false
+	highest := uint32(0)
+	for _, k := range keys {
+		if len(k) != 4 {
+			continue
+		}
+		h := binary.BigEndian.Uint32(k)
+		if !found || h > highest {
+			highest = h
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return db.FetchBalanceCheckpoint(highest)
+}
+
+// replayBalances applies the blocks from the height after the balances up to the given height,
+// saving the checkpoints it passes on the way. With stopAtEnd, it stops without an error at the
+// first height whose blocks are not in the database.
+func (db *Overlay) replayBalances(balances *BalanceCheckpoint, start, height uint32, stopAtEnd bool) error {
+	for h := start; h <= height; h++ {
+		fBlock, err := db.FetchFBlockByHeight(h)
+		if err != nil {
+			return err
+		}
+		ecBlock, err := db.FetchECBlockByHeight(h)
+		if err != nil {
+			return err
+		}
+		if fBlock == nil || ecBlock == nil {
+			if stopAtEnd {
+				return nil
+			}
+			return fmt.Errorf("Blocks at height %d are not in the database", h)
+		}
+
+		balances.applyFBlock(fBlock)
+		balances.applyECBlock(ecBlock)
+		balances.DBHeight = h
+
+		if h%BalanceCheckpointInterval == 0 {
+			err = db.SaveBalanceCheckpoint(balances)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BuildBalanceCheckpoints saves the balance checkpoints of every block in the database past the
+// highest saved checkpoint. It is meant to run in the background, and is started by the first
+// query that finds the checkpoints too far behind. It returns at once if a build is already running.
+func (db *Overlay) BuildBalanceCheckpoints() error {
+	db.balanceBuildMutex.Lock()
+	if db.balanceBuilding {
+		db.balanceBuildMutex.Unlock()
+		return nil
+	}
+	db.balanceBuilding = true
+	db.balanceBuildMutex.Unlock()
+
+	defer func() {
+		db.balanceBuildMutex.Lock()
+		db.balanceBuilding = false
+		db.balanceBuildMutex.Unlock()
+	}()
+
+	balances, err := db.highestBalanceCheckpoint()
+	if err != nil {
+		return err
+	}
+	start := uint32(0)
+	if balances == nil {
+		balances = NewBalanceCheckpoint()
+	} else {
+		start = balances.DBHeight + 1
+	}
+	return db.replayBalances(balances, start, math.MaxUint32, true)
+}
+
+// FetchBalancesAtHeight returns the balances of every address after the block at the given
+// height was applied. It starts from the closest checkpoint below the height, and returns an error
+// ErrBalancesNotBuilt if that would replay more than MaxBalanceReplay blocks, starting a checkpoint
+// build instead.
+func (db *Overlay) FetchBalancesAtHeight(height uint32) (*BalanceCheckpoint, error) {
+	var balances *BalanceCheckpoint
+	for h := int64(height / BalanceCheckpointInterval * BalanceCheckpointInterval); h >= 0 && int64(height)-h < MaxBalanceReplay; h -= BalanceCheckpointInterval {
+		c, err := db.FetchBalanceCheckpoint(uint32(h))
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			balances = c
+			break
+		}
+	}
+
+	start := uint32(0)
+	if balances != nil {
+		start = balances.DBHeight + 1
+	} else if height < MaxBalanceReplay {
+		balances = NewBalanceCheckpoint()
+	} else {
+		go db.BuildBalanceCheckpoints()
+		return nil, ErrBalancesNotBuilt
+	}
+
+	err := db.replayBalances(balances, start, height, false)
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// FetchFactoidBalanceAtHeight returns the factoid balance of the address as of the block at the given height
+func (db *Overlay) FetchFactoidBalanceAtHeight(address [32]byte, height uint32) (int64, error) {
+	balances, err := db.FetchBalancesAtHeight(height)
+	if err != nil {
+		return 0, err
+	}
+	return balances.FactoidBalances[address], nil
+}
+
+// FetchECBalanceAtHeight returns the entry credit balance of the address as of the block at the given height
+func (db *Overlay) FetchECBalanceAtHeight(address [32]byte, height uint32) (int64, error) {
+	balances, err := db.FetchBalancesAtHeight(height)
+	if err != nil {
+		return 0, err
+	}
+	return balances.ECBalances[address], nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package databaseOverlay_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestBalanceCheckpointMarshalUnmarshal(t *testing.T) {
+	c := NewBalanceCheckpoint()
+	c.DBHeight = 1234
+	c.FactoidBalances[testHelper.NewFactoidAddress(0).Fixed()] = 100
+	c.FactoidBalances[testHelper.NewFactoidAddress(1).Fixed()] = -5
+	c.ECBalances[testHelper.NewECAddress(0).Fixed()] = 42
+
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c2 := NewBalanceCheckpoint()
+	rest, err := c2.UnmarshalBinaryData(b)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(rest) > 0 {
+		t.Errorf("Returned too much data - %x", rest)
+	}
+
+	b2, err := c2.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if primitives.AreBytesEqual(b, b2) == false {
+		t.Errorf("Checkpoints are not the same")
+	}
+	if c2.DBHeight != 1234 || c2.FactoidBalances[testHelper.NewFactoidAddress(1).Fixed()] != -5 || c2.ECBalances[testHelper.NewECAddress(0).Fixed()] != 42 {
+		t.Errorf("Wrong balances after unmarshalling")
+	}
+}
+
+func TestFetchBalancesAtHeight(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	fa := testHelper.NewFactoidAddress(0).Fixed()
+	ec := testHelper.NewECAddress(0).Fixed()
+
+	var fBlock interfaces.IFBlock
+	var ecBlock interfaces.IEntryCreditBlock
+	var faBalances, ecBalances []int64
+	fBal, ecBal := int64(0), int64(0)
+	for i := 0; i < 3; i++ {
+		fBlock = testHelper.CreateTestFactoidBlock(fBlock)
+		ecBlock = testHelper.CreateTestEntryCreditBlock(ecBlock)
+		if i > 0 {
+			eb, _ := testHelper.CreateTestEntryBlock(nil)
+			commit := testHelper.NewCommitEntry(eb)
+			ecBlock.GetBody().AddEntry(commit)
+			ecBal -= int64(commit.Credits)
+		}
+
+		for _, tx := range fBlock.GetTransactions() {
+			for _, in := range tx.GetInputs() {
+				fBal -= int64(in.GetAmount())
+			}
+			for _, out := range tx.GetOutputs() {
+				fBal += int64(out.GetAmount())
+			}
+			for _, out := range tx.GetECOutputs() {
+				ecBal += int64(out.GetAmount() / fBlock.GetExchRate())
+			}
+		}
+		faBalances = append(faBalances, fBal)
+		ecBalances = append(ecBalances, ecBal)
+
+		err := dbo.ProcessFBlockBatch(fBlock)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		err = dbo.ProcessECBlockBatch(ecBlock, false)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	for i := len(faBalances) - 1; i >= 0; i-- {
+		bal, err := dbo.FetchFactoidBalanceAtHeight(fa, uint32(i))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if bal != faBalances[i] {
+			t.Errorf("Factoid balance at height %d is %d, expected %d", i, bal, faBalances[i])
+		}
+		bal, err = dbo.FetchECBalanceAtHeight(ec, uint32(i))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if bal != ecBalances[i] {
+			t.Errorf("EC balance at height %d is %d, expected %d", i, bal, ecBalances[i])
+		}
+	}
+
+	// Replaying from genesis saved the first checkpoint
+	c, err := dbo.FetchBalanceCheckpoint(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if c == nil {
+		t.Fatalf("Checkpoint was not saved")
+	}
+	if c.FactoidBalances[fa] != faBalances[0] {
+		t.Errorf("Checkpoint has balance %d, expected %d", c.FactoidBalances[fa], faBalances[0])
+	}
+
+	_, err = dbo.FetchBalancesAtHeight(3)
+	if err == nil {
+		t.Errorf("Fetched balances above the highest block")
+	}
+}
+
+func TestBuildBalanceCheckpoints(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	var fBlock interfaces.IFBlock
+	var ecBlock interfaces.IEntryCreditBlock
+	for i := 0; i < 3; i++ {
+		fBlock = testHelper.CreateTestFactoidBlock(fBlock)
+		ecBlock = testHelper.CreateTestEntryCreditBlock(ecBlock)
+		err := dbo.ProcessFBlockBatch(fBlock)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		err = dbo.ProcessECBlockBatch(ecBlock, false)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	err := dbo.BuildBalanceCheckpoints()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, err := dbo.FetchBalanceCheckpoint(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if c == nil {
+		t.Fatalf("Checkpoint was not saved")
+	}
+
+	// Building again starts from the saved checkpoint
+	err = dbo.BuildBalanceCheckpoints()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestFetchBalancesNotBuilt(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	_, err := dbo.FetchBalancesAtHeight(MaxBalanceReplay)
+	if err != ErrBalancesNotBuilt {
+		t.Errorf("Got error %v, expected %v", err, ErrBalancesNotBuilt)
+	}
+}
@@ -0,0 +1,287 @@
+package databaseOverlay
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The block cache keeps decoded directory blocks, entry blocks and entries, and the indexes that
+// lead to them, so the blocks asked for again and again are not read and unmarshalled on every
+// fetch. The decoded values in the cache are never handed out, every hit is copied into the
+// caller's destination, so callers are free to modify what they fetch. The copies share the
+// hashes of the cached value, which are never modified in place.
+
+type blockCacheKey struct {
+	bucket string
+	key    string
+}
+
+type blockCacheItem struct {
+	key   blockCacheKey
+	value interfaces.BinaryMarshallable
+}
+
+// blockCacheRead tracks the reads of a key from the database that are in progress
+type blockCacheRead struct {
+	count   int
+	version uint64
+}
+
+// BlockCache is a least recently used cache of decoded values, by bucket and key
+type BlockCache struct {
+	mutex sync.Mutex
+	size  int
+	order *list.List
+	items map[blockCacheKey]*list.Element
+	reads map[blockCacheKey]*blockCacheRead
+}
+
+// NewBlockCache returns a cache of at most size values
+func NewBlockCache(size int) *BlockCache {
+	c := new(BlockCache)
+	c.size = size
+	c.order = list.New()
+	c.items = map[blockCacheKey]*list.Element{}
+	c.reads = map[blockCacheKey]*blockCacheRead{}
+	return c
+}
+
+// Get returns the cached value, and whether there was one. The value belongs to the cache and
+// must not be modified.
+func (c *BlockCache) Get(bucket, key []byte) (interfaces.BinaryMarshallable, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.items[blockCacheKey{string(bucket), string(key)}]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*blockCacheItem).value, true
+}
+
+// BeginRead is called before the value of a key is read from the database after a miss, and
+// returns the version of the key. Every BeginRead has to be followed by an EndRead.
+func (c *BlockCache) BeginRead(bucket, key []byte) uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	k := blockCacheKey{string(bucket), string(key)}
+	r, ok := c.reads[k]
+	if !ok {
+		r = new(blockCacheRead)
+		c.reads[k] = r
+	}
+	r.count++
+	return r.version
+}
+
+// EndRead adds the value read from the database, unless the key was removed since BeginRead
+// returned the version, so a value overwritten in the meantime does not make it into the cache.
+// A nil value only ends the read.
+func (c *BlockCache) EndRead(version uint64, bucket, key []byte, value interfaces.BinaryMarshallable) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	k := blockCacheKey{string(bucket), string(key)}
+	r, ok := c.reads[k]
+	if !ok {
+		return
+	}
+	r.count--
+	if r.count == 0 {
+		delete(c.reads, k)
+	}
+	if value == nil || r.version != version {
+		return
+	}
+	c.add(k, value)
+}
+
+// Add caches the value, dropping the least recently used ones over the size
+func (c *BlockCache) Add(bucket, key []byte, value interfaces.BinaryMarshallable) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.add(blockCacheKey{string(bucket), string(key)}, value)
+}
+
+func (c *BlockCache) add(k blockCacheKey, value interfaces.BinaryMarshallable) {
+	if e, ok := c.items[k]; ok {
+		e.Value.(*blockCacheItem).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[k] = c.order.PushFront(&blockCacheItem{key: k, value: value})
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*blockCacheItem).key)
+	}
+}
+
+// Remove drops the value of the key
+func (c *BlockCache) Remove(bucket, key []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	k := blockCacheKey{string(bucket), string(key)}
+	if r, ok := c.reads[k]; ok {
+		r.version++
+	}
+	if e, ok := c.items[k]; ok {
+		c.order.Remove(e)
+		delete(c.items, k)
+	}
+}
+
+// Purge drops every value
+func (c *BlockCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, r := range c.reads {
+		r.version++
+	}
+	c.order.Init()
+	c.items = map[blockCacheKey]*list.Element{}
+}
+
+// Len returns the number of cached values
+func (c *BlockCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// SetBlockCacheSize caches up to size decoded blocks and entries, 0 turns the cache off
+func (db *Overlay) SetBlockCacheSize(size int) {
+	if size <= 0 {
+		db.BlockCache = nil
+		return
+	}
+	db.BlockCache = NewBlockCache(size)
+}
+
+// copyCached copies the decoded value into the destination, and returns false if the value is
+// not of a type the cache copies
+func copyCached(value, destination interfaces.BinaryMarshallable) bool {
+	switch v := value.(type) {
+	case *primitives.Hash:
+		d, ok := destination.(*primitives.Hash)
+		if !ok {
+			return false
+		}
+		*d = *v
+	case *directoryBlock.DirectoryBlock:
+		d, ok := destination.(*directoryBlock.DirectoryBlock)
+		if !ok {
+			return false
+		}
+		*d = *v
+		if h, ok := v.Header.(*directoryBlock.DBlockHeader); ok {
+			header := *h
+			d.Header = &header
+		}
+		d.DBEntries = make([]interfaces.IDBEntry, len(v.DBEntries))
+		for i, e := range v.DBEntries {
+			d.DBEntries[i] = e
+			if dbEntry, ok := e.(*directoryBlock.DBEntry); ok {
+				entry := *dbEntry
+				d.DBEntries[i] = &entry
+			}
+		}
+	case *entryBlock.EBlock:
+		d, ok := destination.(*entryBlock.EBlock)
+		if !ok {
+			return false
+		}
+		*d = *v
+		if h, ok := v.Header.(*entryBlock.EBlockHeader); ok {
+			header := *h
+			d.Header = &header
+		}
+		if v.Body != nil {
+			d.Body = new(entryBlock.EBlockBody)
+			d.Body.EBEntries = append([]interfaces.IHash{}, v.Body.EBEntries...)
+		}
+	case *entryBlock.Entry:
+		d, ok := destination.(*entryBlock.Entry)
+		if !ok {
+			return false
+		}
+		*d = *v
+		d.ExtIDs = make([]primitives.ByteSlice, len(v.ExtIDs))
+		for i, extID := range v.ExtIDs {
+			d.ExtIDs[i].Bytes = append([]byte{}, extID.Bytes...)
+		}
+		d.Content.Bytes = append([]byte{}, v.Content.Bytes...)
+	default:
+		return false
+	}
+	return true
+}
+
+// newCached returns an empty value of the same type, for the cache to keep its own copy in
+func newCached(value interfaces.BinaryMarshallable) interfaces.BinaryMarshallable {
+	switch value.(type) {
+	case *primitives.Hash:
+		return new(primitives.Hash)
+	case *directoryBlock.DirectoryBlock:
+		return new(directoryBlock.DirectoryBlock)
+	case *entryBlock.EBlock:
+		return new(entryBlock.EBlock)
+	case *entryBlock.Entry:
+		return new(entryBlock.Entry)
+	}
+	return nil
+}
+
+// sharedCacheBuckets are the buckets whose values never change for a key, blocks being keyed by
+// their KeyMR, so snapshot views can share the block cache of their database for them
+var sharedCacheBuckets = map[string]bool{
+	string(DIRECTORYBLOCK): true,
+	string(ENTRYBLOCK):     true,
+}
+
+// getCached reads the value through the block cache, if there is one
+func (db *Overlay) getCached(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	cache := db.BlockCache
+	if cache == nil || (db.sharedBlockCache && !sharedCacheBuckets[string(bucket)]) {
+		return db.Get(bucket, key, destination)
+	}
+	if value, ok := cache.Get(bucket, key); ok && copyCached(value, destination) {
+		OverlayDBBlockCacheHits.WithLabelValues(BucketLabel(bucket)).Inc()
+		return destination, nil
+	}
+	OverlayDBBlockCacheMisses.WithLabelValues(BucketLabel(bucket)).Inc()
+
+	version := cache.BeginRead(bucket, key)
+	value, err := db.Get(bucket, key, destination)
+	if err != nil || value == nil {
+		cache.EndRead(version, bucket, key, nil)
+		return value, err
+	}
+	// The cache keeps a copy of its own, the caller may modify the value it was given
+	cached := newCached(value)
+	if cached == nil || copyCached(value, cached) == false {
+		cached = nil
+	}
+	cache.EndRead(version, bucket, key, cached)
+	return value, nil
+}
+
+// invalidate drops the cached values of the records written
+func (db *Overlay) invalidate(records []interfaces.Record) {
+	cache := db.BlockCache
+	if cache == nil {
+		return
+	}
+	for _, r := range records {
+		cache.Remove(r.Bucket, r.Key)
+	}
+}
@@ -0,0 +1,208 @@
+package databaseOverlay_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestBlockCache(t *testing.T) {
+	c := NewBlockCache(2)
+	bucket := []byte("bucket")
+	one := primitives.Sha([]byte("one"))
+	two := primitives.Sha([]byte("two"))
+	three := primitives.Sha([]byte("three"))
+
+	c.Add(bucket, []byte{1}, one)
+	c.Add(bucket, []byte{2}, two)
+	if _, ok := c.Get(bucket, []byte{1}); ok == false {
+		t.Errorf("Value 1 is not cached")
+	}
+	// 2 is now the least recently used
+	c.Add(bucket, []byte{3}, three)
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 cached values, got %v", c.Len())
+	}
+	if _, ok := c.Get(bucket, []byte{2}); ok {
+		t.Errorf("Value 2 was not evicted")
+	}
+	if v, ok := c.Get(bucket, []byte{3}); ok == false || v.(interfaces.IHash).IsSameAs(three) == false {
+		t.Errorf("Value 3 is not cached")
+	}
+	if _, ok := c.Get([]byte("other"), []byte{3}); ok {
+		t.Errorf("Value found in the wrong bucket")
+	}
+
+	c.Remove(bucket, []byte{3})
+	if _, ok := c.Get(bucket, []byte{3}); ok {
+		t.Errorf("Value 3 was not removed")
+	}
+
+	// A value read before its key was removed is not added
+	version := c.BeginRead(bucket, []byte{2})
+	c.Remove(bucket, []byte{2})
+	c.EndRead(version, bucket, []byte{2}, two)
+	if _, ok := c.Get(bucket, []byte{2}); ok {
+		t.Errorf("A stale value was added")
+	}
+
+	// But removing other keys does not keep it out
+	version = c.BeginRead(bucket, []byte{2})
+	c.Remove(bucket, []byte{3})
+	c.EndRead(version, bucket, []byte{2}, two)
+	if _, ok := c.Get(bucket, []byte{2}); ok == false {
+		t.Errorf("A value was not added after another key was removed")
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Expected an empty cache, got %v values", c.Len())
+	}
+}
+
+func TestBlockCacheFetches(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	defer dbo.Close()
+	dbo.SetBlockCacheSize(100)
+
+	dBlock, err := dbo.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, ok := dbo.BlockCache.Get(DIRECTORYBLOCK, dBlock.GetKeyMR().Bytes()); ok == false {
+		t.Errorf("The directory block was not cached")
+	}
+	again, err := dbo.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if again == nil || again.GetKeyMR().IsSameAs(dBlock.GetKeyMR()) == false {
+		t.Errorf("The cached directory block is not the same")
+	}
+	if again == dBlock {
+		t.Errorf("The cached directory block is shared by the callers")
+	}
+
+	// A caller modifying what it fetched does not change the cache
+	again.GetHeader().SetDBHeight(1000)
+	byKeyMR, err := dbo.FetchDBlock(dBlock.GetKeyMR())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if byKeyMR == nil || byKeyMR.GetDatabaseHeight() != 1 {
+		t.Errorf("A change to a fetched directory block made it into the cache")
+	}
+
+	var entryHash interfaces.IHash
+	for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+		eBlock, err := dbo.FetchEBlock(dbEntry.GetKeyMR())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		for _, h := range eBlock.GetEntryHashes() {
+			if h.IsMinuteMarker() == false {
+				entryHash = h
+			}
+		}
+	}
+	if entryHash == nil {
+		t.Fatalf("No entry in the directory block")
+	}
+	entry, err := dbo.FetchEntry(entryHash)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	again2, err := dbo.FetchEntry(entryHash)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if entry == nil || again2 == nil || again2.GetHash().IsSameAs(entry.GetHash()) == false {
+		t.Errorf("The cached entry is not the same")
+	}
+	if _, ok := dbo.BlockCache.Get(entry.GetChainID().Bytes(), entryHash.Bytes()); ok == false {
+		t.Errorf("The entry was not cached")
+	}
+
+	// Deleted values are dropped from the cache
+	err = dbo.SaveEntryPruneHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = dbo.PruneEntries(2, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entry, err = dbo.FetchEntry(entryHash)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if entry != nil {
+		t.Errorf("A pruned entry was fetched from the cache")
+	}
+
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, 1)
+	err = dbo.Delete(DIRECTORYBLOCK_NUMBER, key)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	again, err = dbo.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if again != nil {
+		t.Errorf("A removed height was fetched from the cache")
+	}
+
+	// Moving a chain head drops everything
+	err = dbo.SetChainHeads([]interfaces.IHash{dBlock.GetKeyMR()}, []interfaces.IHash{dBlock.GetChainID()})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dbo.BlockCache.Len() != 0 {
+		t.Errorf("The cache was not purged, %v values left", dbo.BlockCache.Len())
+	}
+
+	dbo.SetBlockCacheSize(0)
+	if dbo.BlockCache != nil {
+		t.Errorf("The cache was not turned off")
+	}
+}
+
+func TestBlockCacheSnapshotView(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	defer dbo.Close()
+	dbo.SetBlockCacheSize(100)
+
+	view, err := dbo.NewSnapshotView()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer view.Close()
+
+	dBlock, err := view.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// The view shares the blocks, but not the indexes that can change
+	if _, ok := dbo.BlockCache.Get(DIRECTORYBLOCK, dBlock.GetKeyMR().Bytes()); ok == false {
+		t.Errorf("The directory block read through the view was not cached")
+	}
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, 1)
+	if _, ok := dbo.BlockCache.Get(DIRECTORYBLOCK_NUMBER, key); ok {
+		t.Errorf("The height index read through the view was cached")
+	}
+
+	again, err := dbo.FetchDBlock(dBlock.GetKeyMR())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if again == nil || again.GetKeyMR().IsSameAs(dBlock.GetKeyMR()) == false {
+		t.Errorf("The directory block cached by the view is not the same")
+	}
+}
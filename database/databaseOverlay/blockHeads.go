@@ -0,0 +1,169 @@
+package databaseOverlay
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// ErrNoCompleteBlockSet is returned by CheckBlockHeads if no directory block was saved with all of
+// its blocks, then there is nothing to check the heads against
+var ErrNoCompleteBlockSet = errors.New("No complete block set in the database")
+
+// CheckBlockHeads makes the chain heads agree with the highest complete block set, the directory
+// block saved with its admin, entry credit and factoid blocks. Heads of blocks above it are rolled
+// back, and the height index of the directory blocks above it is removed, so they are synced
+// again. The heads of the entry chains of the depth directory blocks below it are set to their
+// latest entry block. It returns the number of heads that were repaired.
+func (db *Overlay) CheckBlockHeads(depth uint32) (int, error) {
+	head, err := db.FetchDBlockHead()
+	if err != nil {
+		return 0, err
+	}
+	if head == nil {
+		return 0, nil
+	}
+
+	// Directory blocks can be saved above the head if the heads were not
+	top := head.GetDatabaseHeight()
+	for {
+		dBlock, err := db.FetchDBlockByHeight(top + 1)
+		if err != nil {
+			return 0, err
+		}
+		if dBlock == nil {
+			break
+		}
+		top++
+	}
+
+	height := top
+	var bs *BlockSet
+	for {
+		bs, err = db.FetchBlockSetByHeight(height)
+		if err != nil {
+			return 0, err
+		}
+		if bs != nil && bs.ABlock != nil && bs.ECBlock != nil && bs.FBlock != nil {
+			break
+		}
+		if height == 0 {
+			return 0, ErrNoCompleteBlockSet
+		}
+		height--
+	}
+
+	chainIDs := []interfaces.IHash{bs.DBlock.GetChainID(), bs.ABlock.GetChainID(), bs.ECBlock.GetChainID(), bs.FBlock.GetChainID()}
+	heads := []interfaces.IHash{bs.DBlock.DatabasePrimaryIndex(), bs.ABlock.DatabasePrimaryIndex(), bs.ECBlock.DatabasePrimaryIndex(), bs.FBlock.DatabasePrimaryIndex()}
+	seen := map[[32]byte]bool{}
+
+	// The latest entry block of each chain, walking down from the complete block set. Entry blocks
+	// are synced after their directory block, a chain whose latest entry block is missing is left
+	// as it is.
+	for h := int64(height); h >= 0 && h > int64(height)-int64(depth); h-- {
+		dBlock, err := db.FetchDBlockByHeight(uint32(h))
+		if err != nil {
+			return 0, err
+		}
+		if dBlock == nil {
+			continue
+		}
+		for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+			if seen[dbEntry.GetChainID().Fixed()] {
+				continue
+			}
+			seen[dbEntry.GetChainID().Fixed()] = true
+			exists, err := db.DoesKeyExist(ENTRYBLOCK, dbEntry.GetKeyMR().Bytes())
+			if err != nil {
+				return 0, err
+			}
+			if exists {
+				chainIDs = append(chainIDs, dbEntry.GetChainID())
+				heads = append(heads, dbEntry.GetKeyMR())
+			}
+		}
+	}
+
+	// The chains written to above the complete block set go back to their entry block below it,
+	// a nil head removes the chain
+	for h := height + 1; h <= top; h++ {
+		dBlock, err := db.FetchDBlockByHeight(h)
+		if err != nil {
+			return 0, err
+		}
+		if dBlock == nil {
+			continue
+		}
+		for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+			if seen[dbEntry.GetChainID().Fixed()] {
+				continue
+			}
+			seen[dbEntry.GetChainID().Fixed()] = true
+			keyMR, found, err := db.eBlockAtHeight(dbEntry.GetKeyMR(), height)
+			if err != nil {
+				return 0, err
+			}
+			if found {
+				chainIDs = append(chainIDs, dbEntry.GetChainID())
+				heads = append(heads, keyMR)
+			}
+		}
+	}
+
+	repaired := 0
+	for i, chainID := range chainIDs {
+		current, err := db.FetchHeadIndexByChainID(chainID)
+		if err != nil {
+			return repaired, err
+		}
+		if heads[i] == nil {
+			if current != nil {
+				err = db.Delete(CHAIN_HEAD, chainID.Bytes())
+				if err != nil {
+					return repaired, err
+				}
+				repaired++
+			}
+			continue
+		}
+		if current == nil || current.IsSameAs(heads[i]) == false {
+			err = db.SetChainHeads([]interfaces.IHash{heads[i]}, []interfaces.IHash{chainID})
+			if err != nil {
+				return repaired, err
+			}
+			repaired++
+		}
+	}
+
+	for h := height + 1; h <= top; h++ {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, h)
+		err = db.Delete(DIRECTORYBLOCK_NUMBER, key)
+		if err != nil {
+			return repaired, err
+		}
+	}
+
+	return repaired, nil
+}
+
+// eBlockAtHeight follows the chain back from the entry block to the first one at or below the
+// height. It returns a nil hash if the chain starts above the height, and found is false if an
+// entry block on the way is missing.
+func (db *Overlay) eBlockAtHeight(keyMR interfaces.IHash, height uint32) (interfaces.IHash, bool, error) {
+	for keyMR != nil && keyMR.IsZero() == false {
+		eBlock, err := db.FetchEBlock(keyMR)
+		if err != nil {
+			return nil, false, err
+		}
+		if eBlock == nil {
+			return nil, false, nil
+		}
+		if eBlock.GetHeader().GetDBHeight() <= height {
+			return keyMR, true, nil
+		}
+		keyMR = eBlock.GetHeader().GetPrevKeyMR()
+	}
+	return nil, true, nil
+}
@@ -0,0 +1,110 @@
+package databaseOverlay_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestCheckBlockHeads(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	defer dbo.Close()
+
+	repaired, err := dbo.CheckBlockHeads(100)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if repaired != 0 {
+		t.Errorf("Repaired %v heads of a sound database", repaired)
+	}
+
+	top := uint32(testHelper.BlockCount - 1)
+	below, err := dbo.FetchBlockSetByHeight(top - 1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// The last save is missing its admin block
+	aBlock, err := dbo.FetchABlockByHeight(top)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = dbo.Delete(ADMINBLOCK, aBlock.DatabasePrimaryIndex().Bytes())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// And a chain head is out of date
+	old, err := dbo.FetchBlockSetByHeight(2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = dbo.SetChainHeads([]interfaces.IHash{old.FBlock.DatabasePrimaryIndex()}, []interfaces.IHash{old.FBlock.GetChainID()})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	repaired, err = dbo.CheckBlockHeads(100)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if repaired == 0 {
+		t.Errorf("Nothing was repaired")
+	}
+
+	head, err := dbo.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if head.GetDatabaseHeight() != top-1 {
+		t.Errorf("Expected the head at %v, got %v", top-1, head.GetDatabaseHeight())
+	}
+	dBlock, err := dbo.FetchDBlockByHeight(top)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dBlock != nil {
+		t.Errorf("The incomplete directory block is still indexed by height")
+	}
+	fHead, err := dbo.FetchHeadIndexByChainID(below.FBlock.GetChainID())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if fHead.IsSameAs(below.FBlock.DatabasePrimaryIndex()) == false {
+		t.Errorf("The factoid chain head was not repaired")
+	}
+	for _, eBlock := range below.EBlocks {
+		eHead, err := dbo.FetchHeadIndexByChainID(eBlock.GetChainID())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if eHead.IsSameAs(eBlock.DatabasePrimaryIndex()) == false {
+			t.Errorf("The head of chain %v was not rolled back", eBlock.GetChainID())
+		}
+	}
+
+	repaired, err = dbo.CheckBlockHeads(100)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if repaired != 0 {
+		t.Errorf("Repaired %v heads twice", repaired)
+	}
+}
+
+func TestCheckBlockHeadsWithoutCompleteBlockSet(t *testing.T) {
+	dbo := testHelper.CreateEmptyTestDatabaseOverlay()
+	defer dbo.Close()
+
+	blocks := testHelper.CreateFullTestBlockSet()
+	err := dbo.ProcessDBlockBatch(blocks[0].DBlock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	_, err = dbo.CheckBlockHeads(100)
+	if err != ErrNoCompleteBlockSet {
+		t.Errorf("Expected ErrNoCompleteBlockSet, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@
 package databaseOverlay
 
 import (
+	"encoding/binary"
 	"sort"
 
 	"github.com/FactomProject/factomd/common/directoryBlock"
@@ -88,7 +89,7 @@ func (db *Overlay) FetchDBlock(hash interfaces.IHash) (interfaces.IDirectoryBloc
 
 // FetchDBlock gets an entry by hash from the database.
 func (db *Overlay) FetchDBlockByPrimary(keyMR interfaces.IHash) (interfaces.IDirectoryBlock, error) {
-	block, err := db.FetchBlock(DIRECTORYBLOCK, keyMR, new(directoryBlock.DirectoryBlock))
+	block, err := db.getCached(DIRECTORYBLOCK, keyMR.Bytes(), new(directoryBlock.DirectoryBlock))
 	if err != nil {
 		return nil, err
 	}
@@ -112,14 +113,17 @@ func (db *Overlay) FetchDBlockBySecondary(dBMR interfaces.IHash) (interfaces.IDi
 
 // FetchDBlockByHeight gets an directory block by height from the database.
 func (db *Overlay) FetchDBlockByHeight(dBlockHeight uint32) (interfaces.IDirectoryBlock, error) {
-	block, err := db.FetchBlockByHeight(DIRECTORYBLOCK_NUMBER, DIRECTORYBLOCK, dBlockHeight, new(directoryBlock.DirectoryBlock))
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, dBlockHeight)
+
+	keyMR, err := db.getCached(DIRECTORYBLOCK_NUMBER, key, new(primitives.Hash))
 	if err != nil {
 		return nil, err
 	}
-	if block == nil {
+	if keyMR == nil {
 		return nil, nil
 	}
-	return block.(interfaces.IDirectoryBlock), nil
+	return db.FetchDBlockByPrimary(keyMR.(interfaces.IHash))
 }
 
 // FetchDBKeyMRByHeight gets a dBlock KeyMR from the database.
@@ -1,6 +1,8 @@
 package databaseOverlay
 
 import (
+	"encoding/binary"
+
 	"github.com/FactomProject/factomd/common/entryBlock"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
@@ -76,7 +78,7 @@ func (db *Overlay) FetchEBlockBySecondary(hash interfaces.IHash) (interfaces.IEn
 
 // FetchEBlockByKeyMR gets an entry by hash from the database.
 func (db *Overlay) FetchEBlockByPrimary(hash interfaces.IHash) (interfaces.IEntryBlock, error) {
-	block, err := db.FetchBlock(ENTRYBLOCK, hash, entryBlock.NewEBlock())
+	block, err := db.getCached(ENTRYBLOCK, hash.Bytes(), entryBlock.NewEBlock())
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +114,82 @@ func (db *Overlay) FetchAllEBlocksByChain(chainID interfaces.IHash) ([]interface
 	return list, nil
 }
 
+// FetchEBlockHeightsByChain gets the directory block heights at which the chain has an entry block,
+// in ascending order. Only the keys of the chain's number bucket are read, not the blocks themselves.
+func (db *Overlay) FetchEBlockHeightsByChain(chainID interfaces.IHash) ([]uint32, error) {
+	bucket := append(append([]byte{}, ENTRYBLOCK_CHAIN_NUMBER...), chainID.Bytes()...)
+	iter, err := db.NewIterator(bucket, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Release()
+
+	// Heights are stored big endian, so the iterator returns them in numeric order
+	heights := []uint32{}
+	for iter.Next() {
+		if len(iter.Key()) != 4 {
+			continue
+		}
+		heights = append(heights, binary.BigEndian.Uint32(iter.Key()))
+	}
+	return heights, iter.Error()
+}
+
+// FetchEBlocksByChainInHeightRange gets up to limit entry blocks of a chain whose directory block
+// height is between startHeight and endHeight inclusive, in ascending order. A limit of 0 or less
+// returns every block in the range. The chain's number bucket is read from startHeight onwards
+// only as far as the range or the limit reaches.
+func (db *Overlay) FetchEBlocksByChainInHeightRange(chainID interfaces.IHash, startHeight, endHeight uint32, limit int) ([]interfaces.IEntryBlock, error) {
+	indexes, err := db.fetchEBlockIndexesInHeightRange(chainID, startHeight, endHeight, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []interfaces.IEntryBlock{}
+	for _, index := range indexes {
+		block, err := db.FetchEBlock(index)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			continue
+		}
+		list = append(list, block)
+	}
+	return list, nil
+}
+
+// fetchEBlockIndexesInHeightRange gets the KeyMRs of up to limit entry blocks of a chain in the
+// height range. The iterator is released before any block is fetched.
+func (db *Overlay) fetchEBlockIndexesInHeightRange(chainID interfaces.IHash, startHeight, endHeight uint32, limit int) ([]interfaces.IHash, error) {
+	bucket := append(append([]byte{}, ENTRYBLOCK_CHAIN_NUMBER...), chainID.Bytes()...)
+	start := make([]byte, 4)
+	binary.BigEndian.PutUint32(start, startHeight)
+
+	iter, err := db.NewIterator(bucket, &interfaces.IteratorRange{Start: start})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Release()
+
+	indexes := []interfaces.IHash{}
+	for iter.Next() {
+		if len(iter.Key()) != 4 {
+			continue
+		}
+		if binary.BigEndian.Uint32(iter.Key()) > endHeight || (limit > 0 && len(indexes) >= limit) {
+			break
+		}
+		index := new(primitives.Hash)
+		err = index.UnmarshalBinary(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, iter.Error()
+}
+
 func (db *Overlay) SaveEBlockHead(block interfaces.DatabaseBlockWithEntries, checkForDuplicateEntries bool) error {
 	return db.ProcessEBlockBatch(block, checkForDuplicateEntries)
 }
@@ -211,3 +211,60 @@ func TestFetchAllEBlockChainIDs(t *testing.T) {
 		t.Errorf("Got wrong number of chains - %v", len(chains))
 	}
 }
+
+func TestFetchEBlocksByChainInHeightRange(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	var prev *EBlock
+	for i := 0; i < 10; i++ {
+		prev, _ = testHelper.CreateTestEntryBlock(prev)
+		err := dbo.SaveEBlockHead(prev, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain := prev.GetChainID()
+
+	heights, err := dbo.FetchEBlockHeightsByChain(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 10 {
+		t.Fatalf("Expected 10 heights, got %v", len(heights))
+	}
+	for i, h := range heights {
+		if h != uint32(i) {
+			t.Errorf("Expected height %v, got %v", i, h)
+		}
+	}
+
+	blocks, err := dbo.FetchEBlocksByChainInHeightRange(chain, 3, 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("Expected 5 blocks, got %v", len(blocks))
+	}
+	for i, b := range blocks {
+		if b.GetDatabaseHeight() != uint32(i+3) {
+			t.Errorf("Expected height %v, got %v", i+3, b.GetDatabaseHeight())
+		}
+	}
+
+	blocks, err = dbo.FetchEBlocksByChainInHeightRange(chain, 3, 7, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[1].GetDatabaseHeight() != 4 {
+		t.Errorf("Limit was not respected")
+	}
+
+	blocks, err = dbo.FetchEBlocksByChainInHeightRange(primitives.NewZeroHash(), 0, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 0 {
+		t.Errorf("Found blocks for an unknown chain")
+	}
+}
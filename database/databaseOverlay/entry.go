@@ -52,7 +52,7 @@ func (db *Overlay) InsertEntryMultiBatch(entry interfaces.IEBEntry) error {
 
 // FetchEntry gets an entry by hash from the database.
 func (db *Overlay) FetchEntry(hash interfaces.IHash) (interfaces.IEBEntry, error) {
-	chainID, err := db.FetchPrimaryIndexBySecondaryIndex(ENTRY, hash)
+	chainID, err := db.getCached(ENTRY, hash.Bytes(), new(primitives.Hash))
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +60,7 @@ func (db *Overlay) FetchEntry(hash interfaces.IHash) (interfaces.IEBEntry, error
 		return nil, nil
 	}
 
-	entry, err := db.FetchBlock(chainID.Bytes(), hash, entryBlock.NewEntry())
+	entry, err := db.getCached(chainID.(interfaces.IHash).Bytes(), hash.Bytes(), entryBlock.NewEntry())
 	if err != nil {
 		return nil, err
 	}
@@ -84,19 +84,13 @@ func (db *Overlay) FetchAllEntryIDsByChainID(chainID interfaces.IHash) ([]interf
 }
 
 func (db *Overlay) FetchAllEntryIDs() ([]interfaces.IHash, error) {
-	ids, err := db.ListAllKeys(ENTRY)
-	if err != nil {
-		return nil, err
-	}
-	entries := []interfaces.IHash{}
-	for _, id := range ids {
-		h, err := primitives.NewShaHash(id)
-		if err != nil {
-			return nil, err
-		}
-		entries = append(entries, h)
-	}
-	return entries, nil
+	return db.FetchAllBlockKeysFromBucket(ENTRY)
+}
+
+// ForEachEntryID calls f with the hash of every entry in the database, without loading them
+// all into memory
+func (db *Overlay) ForEachEntryID(f func(entryID interfaces.IHash) error) error {
+	return db.ForEachBlockKeyInBucket(ENTRY, f)
 }
 
 func toEntryList(source []interfaces.BinaryMarshallableAndCopyable) []interfaces.IEBEntry {
@@ -13,7 +13,11 @@ func (db *Overlay) ProcessFBlockBatch(block interfaces.DatabaseBlockWithEntries)
 	if err != nil {
 		return err
 	}
-	return db.SaveIncludedInMultiFromBlock(block, false)
+	err = db.SaveIncludedInMultiFromBlock(block, false)
+	if err != nil {
+		return err
+	}
+	return db.SaveAddressIndexFromBlock(block, false)
 }
 
 func (db *Overlay) ProcessFBlockBatchWithoutHead(block interfaces.DatabaseBlockWithEntries) error {
@@ -21,7 +25,11 @@ func (db *Overlay) ProcessFBlockBatchWithoutHead(block interfaces.DatabaseBlockW
 	if err != nil {
 		return err
 	}
-	return db.SaveIncludedInMultiFromBlock(block, false)
+	err = db.SaveIncludedInMultiFromBlock(block, false)
+	if err != nil {
+		return err
+	}
+	return db.SaveAddressIndexFromBlock(block, false)
 }
 
 func (db *Overlay) ProcessFBlockMultiBatch(block interfaces.DatabaseBlockWithEntries) error {
@@ -29,7 +37,11 @@ func (db *Overlay) ProcessFBlockMultiBatch(block interfaces.DatabaseBlockWithEnt
 	if err != nil {
 		return err
 	}
-	return db.SaveIncludedInMultiFromBlockMultiBatch(block, true)
+	err = db.SaveIncludedInMultiFromBlockMultiBatch(block, true)
+	if err != nil {
+		return err
+	}
+	return db.SaveAddressIndexFromBlock(block, true)
 }
 
 func (db *Overlay) FetchFBlock(hash interfaces.IHash) (interfaces.IFBlock, error) {
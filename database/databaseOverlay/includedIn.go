@@ -13,7 +13,7 @@ func (db *Overlay) SaveIncludedIn(entry, block interfaces.IHash) error {
 
 	batch = append(batch, interfaces.Record{INCLUDED_IN, entry.Bytes(), block})
 
-	err := db.DB.PutInBatch(batch)
+	err := db.PutInBatch(batch)
 	if err != nil {
 		return err
 	}
@@ -83,7 +83,7 @@ func (db *Overlay) SaveIncludedInMulti(entries []interfaces.IHash, block interfa
 		batch = append(batch, interfaces.Record{INCLUDED_IN, entry.Bytes(), block})
 	}
 
-	err := db.DB.PutInBatch(batch)
+	err := db.PutInBatch(batch)
 	if err != nil {
 		return err
 	}
@@ -92,7 +92,7 @@ func (db *Overlay) SaveIncludedInMulti(entries []interfaces.IHash, block interfa
 }
 
 func (db *Overlay) FetchIncludedIn(hash interfaces.IHash) (interfaces.IHash, error) {
-	block, err := db.DB.Get(INCLUDED_IN, hash.Bytes(), new(primitives.Hash))
+	block, err := db.Get(INCLUDED_IN, hash.Bytes(), new(primitives.Hash))
 	if err != nil {
 		return nil, err
 	}
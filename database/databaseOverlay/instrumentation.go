@@ -119,6 +119,65 @@ var (
 		Name: "factomd_database_overlay_gets_paidfor",
 		Help: "Counts gets from the database",
 	})
+
+	// Latency and sizes, by bucket
+	OverlayDBGetSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_get_seconds",
+		Help:    "Time taken by gets from the database",
+		Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"bucket"})
+
+	OverlayDBPutSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_put_seconds",
+		Help:    "Time taken by puts to the database",
+		Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"bucket"})
+
+	OverlayDBGetBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_get_bytes",
+		Help:    "Size of the values read from the database",
+		Buckets: prometheus.ExponentialBuckets(16, 4, 8),
+	}, []string{"bucket"})
+
+	OverlayDBPutBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_put_bytes",
+		Help:    "Size of the values written to the database, alone or in batches",
+		Buckets: prometheus.ExponentialBuckets(16, 4, 8),
+	}, []string{"bucket"})
+
+	OverlayDBBatchSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_batch_seconds",
+		Help:    "Time taken by batches written to the database",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	})
+
+	OverlayDBBatchRecords = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_batch_records",
+		Help:    "Number of records in batches written to the database",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	})
+
+	OverlayDBBatchBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "factomd_database_overlay_batch_bytes",
+		Help:    "Size of batches written to the database",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+	})
+
+	OverlayDBSlowOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "factomd_database_overlay_slow_operations",
+		Help: "Counts database operations slower than the slow operation threshold",
+	}, []string{"operation"})
+
+	// Block cache
+	OverlayDBBlockCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "factomd_database_overlay_block_cache_hits",
+		Help: "Counts fetches answered by the block cache",
+	}, []string{"bucket"})
+
+	OverlayDBBlockCacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "factomd_database_overlay_block_cache_misses",
+		Help: "Counts fetches the block cache did not have, read from the database",
+	}, []string{"bucket"})
 )
 
 var registered = false
@@ -154,6 +213,16 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(OverlayDBGetsDirBlockInfoSecondary)
 	prometheus.MustRegister(OverlayDBGetsInvludeIn)
 	prometheus.MustRegister(OverlayDBGetsPaidFor)
+	prometheus.MustRegister(OverlayDBGetSeconds)
+	prometheus.MustRegister(OverlayDBPutSeconds)
+	prometheus.MustRegister(OverlayDBGetBytes)
+	prometheus.MustRegister(OverlayDBPutBytes)
+	prometheus.MustRegister(OverlayDBBatchSeconds)
+	prometheus.MustRegister(OverlayDBBatchRecords)
+	prometheus.MustRegister(OverlayDBBatchBytes)
+	prometheus.MustRegister(OverlayDBSlowOperations)
+	prometheus.MustRegister(OverlayDBBlockCacheHits)
+	prometheus.MustRegister(OverlayDBBlockCacheMisses)
 }
 
 func GetBucket(bucket []byte) {
@@ -1,9 +1,17 @@
 package databaseOverlay_test
 
 import (
+	"bytes"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/FactomProject/factomd/common/primitives"
 	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
 )
 
 func TestInstrumentation(t *testing.T) {
@@ -34,3 +42,79 @@ func TestInstrumentation(t *testing.T) {
 	GetBucket(INCLUDED_IN)
 	GetBucket(PAID_FOR)
 }
+
+func TestBucketLabel(t *testing.T) {
+	chainID := primitives.Sha([]byte("chain")).Bytes()
+	labels := map[string]string{
+		string(DIRECTORYBLOCK): "DirectoryBlock",
+		string(ENTRY):          "Entry",
+		string(append(append([]byte{}, ENTRYBLOCK_CHAIN_NUMBER...), chainID...)): "EntryBlockChainNumber",
+		string(chainID): "EntryChain",
+		"Unknown":       "Other",
+	}
+	for bucket, label := range labels {
+		if BucketLabel([]byte(bucket)) != label {
+			t.Errorf("Expected %v for bucket %x, got %v", label, bucket, BucketLabel([]byte(bucket)))
+		}
+	}
+}
+
+func TestSlowOperations(t *testing.T) {
+	slowCount := func(operation string) float64 {
+		m := new(dto.Metric)
+		err := OverlayDBSlowOperations.WithLabelValues(operation).Write(m)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	dbo := NewOverlay(new(mapdb.MapDB))
+	h := primitives.Sha([]byte("value"))
+
+	// Nothing is slow without a threshold
+	puts := slowCount("Put")
+	err := dbo.Put(KEY_VALUE_STORE, []byte("key"), h)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if slowCount("Put") != puts || buf.Len() > 0 {
+		t.Errorf("A slow operation was counted without a threshold")
+	}
+
+	SlowOperationThreshold = time.Nanosecond
+	defer func() {
+		SlowOperationThreshold = 0
+	}()
+
+	puts, gets := slowCount("Put"), slowCount("Get")
+	err = dbo.Put(KEY_VALUE_STORE, []byte("key"), h)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	v, err := dbo.Get(KEY_VALUE_STORE, []byte("key"), new(primitives.Hash))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v.(*primitives.Hash).IsSameAs(h) == false {
+		t.Errorf("Got %v, expected %v", v, h)
+	}
+
+	if slowCount("Put") != puts+1 {
+		t.Errorf("Slow Put was not counted")
+	}
+	if slowCount("Get") != gets+1 {
+		t.Errorf("Slow Get was not counted")
+	}
+	logged := buf.String()
+	if strings.Contains(logged, "Slow database operation") == false {
+		t.Errorf("Slow operation was not logged - %s", logged)
+	}
+	if strings.Contains(logged, "operation=Put") == false || strings.Contains(logged, "bucket=KeyValueStore") == false {
+		t.Errorf("Slow operation log is missing the operation or the bucket - %s", logged)
+	}
+}
@@ -0,0 +1,157 @@
+package databaseOverlay
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// MultiBatchJournalKey keeps the multi batch being written, until all of it is in the database
+var MultiBatchJournalKey = []byte("Pending")
+
+// MultiBatchJournal is the intent log of a multi batch. It is written in one Put before the batch,
+// and removed once the batch is written, so a batch interrupted by a crash is written again when
+// the database is opened. The checksum makes a journal torn by the crash itself unreadable, then
+// none of the batch was written and it is dropped.
+type MultiBatchJournal struct {
+	// Records hold the data as marshalled, in primitives.ByteSlice
+	Records []interfaces.Record
+}
+
+var _ interfaces.BinaryMarshallable = (*MultiBatchJournal)(nil)
+
+// NewMultiBatchJournal marshals the data of every record
+func NewMultiBatchJournal(records []interfaces.Record) (*MultiBatchJournal, error) {
+	j := new(MultiBatchJournal)
+	j.Records = make([]interfaces.Record, len(records))
+	for i, r := range records {
+		data, err := r.Data.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		j.Records[i] = interfaces.Record{Bucket: r.Bucket, Key: r.Key, Data: &primitives.ByteSlice{Bytes: data}}
+	}
+	return j, nil
+}
+
+func (j *MultiBatchJournal) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+
+	err := buf.PushVarInt(uint64(len(j.Records)))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range j.Records {
+		data, err := r.Data.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range [][]byte{r.Bucket, r.Key, data} {
+			err = buf.PushBytes(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	body := buf.DeepCopyBytes()
+	sum := sha256.Sum256(body)
+	return append(sum[:], body...), nil
+}
+
+func (j *MultiBatchJournal) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling MultiBatchJournal: %v", r)
+		}
+	}()
+
+	if len(data) < sha256.Size {
+		return nil, fmt.Errorf("MultiBatchJournal is too short")
+	}
+	sum := sha256.Sum256(data[sha256.Size:])
+	if primitives.AreBytesEqual(sum[:], data[:sha256.Size]) == false {
+		return nil, fmt.Errorf("MultiBatchJournal checksum does not match")
+	}
+	newData = data[sha256.Size:]
+
+	// The buffer copies what is left on every pop, which is too slow for a large batch
+	var count uint64
+	count, newData = primitives.DecodeVarInt(newData)
+	if count > uint64(len(newData)) {
+		return nil, fmt.Errorf("MultiBatchJournal has too many records: %v", count)
+	}
+	pop := func() []byte {
+		l, rest := primitives.DecodeVarInt(newData)
+		if l > uint64(len(rest)) {
+			panic("End of buffer")
+		}
+		b := append([]byte{}, rest[:l]...)
+		newData = rest[l:]
+		return b
+	}
+	j.Records = make([]interfaces.Record, int(count))
+	for i := range j.Records {
+		j.Records[i].Bucket = pop()
+		j.Records[i].Key = pop()
+		j.Records[i].Data = &primitives.ByteSlice{Bytes: pop()}
+	}
+	return
+}
+
+func (j *MultiBatchJournal) UnmarshalBinary(data []byte) (err error) {
+	_, err = j.UnmarshalBinaryData(data)
+	return
+}
+
+// writeMultiBatch writes the journal, then the batch, then removes the journal. Only databases
+// whose batches are not atomic need the journal, the batch is written as is on the others, and
+// so is a batch of a single record, every database writes one record at once.
+func (db *Overlay) writeMultiBatch(records []interfaces.Record) error {
+	if len(records) < 2 || interfaces.AtomicBatches(db.DB) {
+		return db.PutInBatch(records)
+	}
+
+	journal, err := NewMultiBatchJournal(records)
+	if err != nil {
+		return err
+	}
+	err = db.DB.Put(MULTIBATCH_JOURNAL, MultiBatchJournalKey, journal)
+	if err != nil {
+		return err
+	}
+	err = db.PutInBatch(journal.Records)
+	if err != nil {
+		return err
+	}
+	return db.DB.Delete(MULTIBATCH_JOURNAL, MultiBatchJournalKey)
+}
+
+// RecoverMultiBatch finishes the multi batch a crash interrupted. It returns true if a batch was
+// written again, and false if there was none, or if the crash happened while the journal itself
+// was written, in which case none of the batch is in the database.
+func (db *Overlay) RecoverMultiBatch() (bool, error) {
+	db.BatchSemaphore.Lock()
+	defer db.BatchSemaphore.Unlock()
+
+	bs, err := db.DB.Get(MULTIBATCH_JOURNAL, MultiBatchJournalKey, new(primitives.ByteSlice))
+	if err != nil {
+		return false, err
+	}
+	if bs == nil {
+		return false, nil
+	}
+
+	rolledForward := false
+	journal := new(MultiBatchJournal)
+	if journal.UnmarshalBinary(bs.(*primitives.ByteSlice).Bytes) == nil {
+		err = db.PutInBatch(journal.Records)
+		if err != nil {
+			return false, err
+		}
+		rolledForward = true
+	}
+	return rolledForward, db.DB.Delete(MULTIBATCH_JOURNAL, MultiBatchJournalKey)
+}
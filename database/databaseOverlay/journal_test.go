@@ -0,0 +1,177 @@
+package databaseOverlay_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+)
+
+func testJournalRecords() []interfaces.Record {
+	return []interfaces.Record{
+		{Bucket: []byte("one"), Key: []byte("a"), Data: &primitives.ByteSlice{Bytes: []byte{1, 2, 3}}},
+		{Bucket: []byte("one"), Key: []byte("b"), Data: &primitives.ByteSlice{Bytes: []byte{}}},
+		{Bucket: []byte("two"), Key: []byte("a"), Data: primitives.Sha([]byte("a"))},
+	}
+}
+
+func TestMarshalUnmarshalMultiBatchJournal(t *testing.T) {
+	j, err := NewMultiBatchJournal(testJournalRecords())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	data, err := j.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	j2 := new(MultiBatchJournal)
+	err = j2.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(j2.Records) != len(j.Records) {
+		t.Fatalf("Expected %v records, got %v", len(j.Records), len(j2.Records))
+	}
+	for i := range j.Records {
+		a, _ := j.Records[i].Data.MarshalBinary()
+		b, _ := j2.Records[i].Data.MarshalBinary()
+		if primitives.AreBytesEqual(j.Records[i].Bucket, j2.Records[i].Bucket) == false ||
+			primitives.AreBytesEqual(j.Records[i].Key, j2.Records[i].Key) == false ||
+			primitives.AreBytesEqual(a, b) == false {
+			t.Errorf("Record %v is not the same", i)
+		}
+	}
+
+	// A torn journal is not read
+	for _, l := range []int{0, 10, len(data) - 1} {
+		err = new(MultiBatchJournal).UnmarshalBinary(data[:l])
+		if err == nil {
+			t.Errorf("Expected an error unmarshalling %v of %v bytes", l, len(data))
+		}
+	}
+}
+
+func TestRecoverMultiBatch(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	rolledForward, err := dbo.RecoverMultiBatch()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if rolledForward {
+		t.Errorf("Nothing to recover in a new database")
+	}
+
+	// A crash after the journal was written
+	j, err := NewMultiBatchJournal(testJournalRecords())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = dbo.Put(MULTIBATCH_JOURNAL, MultiBatchJournalKey, j)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rolledForward, err = dbo.RecoverMultiBatch()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if rolledForward == false {
+		t.Errorf("The batch was not written")
+	}
+	for _, r := range testJournalRecords() {
+		// MapDB does not count an empty value as a key, so the values are read back instead
+		v, err := dbo.Get(r.Bucket, r.Key, new(primitives.ByteSlice))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		data, _ := r.Data.MarshalBinary()
+		if v == nil || primitives.AreBytesEqual(v.(*primitives.ByteSlice).Bytes, data) == false {
+			t.Errorf("Record %s/%s was not written", r.Bucket, r.Key)
+		}
+	}
+	exists, err := dbo.DoesKeyExist(MULTIBATCH_JOURNAL, MultiBatchJournalKey)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists {
+		t.Errorf("The journal was not removed")
+	}
+
+	// A crash while the journal was written
+	dbo = NewOverlay(new(mapdb.MapDB))
+	data, _ := j.MarshalBinary()
+	err = dbo.Put(MULTIBATCH_JOURNAL, MultiBatchJournalKey, &primitives.ByteSlice{Bytes: data[:len(data)/2]})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rolledForward, err = dbo.RecoverMultiBatch()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if rolledForward {
+		t.Errorf("A torn journal was written")
+	}
+	exists, err = dbo.DoesKeyExist([]byte("one"), []byte("a"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists {
+		t.Errorf("A record of a torn journal was written")
+	}
+
+	// A finished batch leaves no journal
+	dbo.StartMultiBatch()
+	dbo.PutInMultiBatch(testJournalRecords())
+	err = dbo.ExecuteMultiBatch()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exists, err = dbo.DoesKeyExist(MULTIBATCH_JOURNAL, MultiBatchJournalKey)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists {
+		t.Errorf("ExecuteMultiBatch left the journal")
+	}
+}
+
+// failingDB fails every batch, and says whether its batches are atomic
+type failingDB struct {
+	*mapdb.MapDB
+	atomic bool
+}
+
+func (db *failingDB) PutInBatch(records []interfaces.Record) error {
+	return errors.New("Batch failed")
+}
+
+func (db *failingDB) AtomicBatches() bool {
+	return db.atomic
+}
+
+func TestMultiBatchJournalOnlyForNonAtomicBatches(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		m := new(mapdb.MapDB)
+		m.Init(nil)
+		dbo := NewOverlay(&failingDB{MapDB: m, atomic: atomic})
+
+		dbo.StartMultiBatch()
+		dbo.PutInMultiBatch(testJournalRecords())
+		err := dbo.ExecuteMultiBatch()
+		if err == nil {
+			t.Errorf("Expected the batch to fail")
+		}
+		exists, err := dbo.DoesKeyExist(MULTIBATCH_JOURNAL, MultiBatchJournalKey)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if exists == atomic {
+			t.Errorf("Journal written is %v for a database with atomic batches %v", exists, atomic)
+		}
+	}
+}
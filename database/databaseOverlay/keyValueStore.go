@@ -13,7 +13,7 @@ func (db *Overlay) SaveKeyValueStore(kvs interfaces.BinaryMarshallable, key []by
 
 	batch = append(batch, interfaces.Record{KEY_VALUE_STORE, key, kvs})
 
-	err := db.DB.PutInBatch(batch)
+	err := db.PutInBatch(batch)
 	if err != nil {
 		return err
 	}
@@ -22,7 +22,7 @@ func (db *Overlay) SaveKeyValueStore(kvs interfaces.BinaryMarshallable, key []by
 }
 
 func (db *Overlay) FetchKeyValueStore(key []byte, dst interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
-	block, err := db.DB.Get(KEY_VALUE_STORE, key, dst)
+	block, err := db.Get(KEY_VALUE_STORE, key, dst)
 	if err != nil {
 		return nil, err
 	}
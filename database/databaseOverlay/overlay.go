@@ -12,6 +12,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
@@ -68,6 +69,21 @@ var (
 	PAID_FOR = []byte("PaidFor")
 
 	KEY_VALUE_STORE = []byte("KeyValueStore")
+
+	//Optional index of factoid and entry credit transactions by address
+	ADDRESS_TRANSACTIONS = []byte("AddressTransactions")
+
+	//Balances of every address at regular heights, to speed up historical balance queries
+	BALANCE_CHECKPOINT = []byte("BalanceCheckpoint")
+
+	//Anchor chain entries by the height of the directory block they anchor
+	ANCHOR_RECORDS = []byte("AnchorRecords")
+
+	//Anchor records of directory blocks anchored into Ethereum by this node
+	ETHEREUM_ANCHOR_RECORDS = []byte("EthereumAnchorRecords")
+
+	//Multi batch being written, to finish it after a crash
+	MULTIBATCH_JOURNAL = []byte("MultiBatchJournal")
 )
 
 var ConstantNamesMap map[string]string
@@ -107,6 +123,11 @@ func init() {
 
 	ConstantNamesMap[string(PAID_FOR)] = "PaidFor"
 	ConstantNamesMap[string(KEY_VALUE_STORE)] = "KeyValueStore"
+	ConstantNamesMap[string(ADDRESS_TRANSACTIONS)] = "AddressTransactions"
+	ConstantNamesMap[string(BALANCE_CHECKPOINT)] = "BalanceCheckpoint"
+	ConstantNamesMap[string(ANCHOR_RECORDS)] = "AnchorRecords"
+	ConstantNamesMap[string(ETHEREUM_ANCHOR_RECORDS)] = "EthereumAnchorRecords"
+	ConstantNamesMap[string(MULTIBATCH_JOURNAL)] = "MultiBatchJournal"
 
 	RegisterPrometheus()
 }
@@ -117,9 +138,21 @@ type Overlay struct {
 	ExportData     bool
 	ExportDataPath string
 
+	AddressIndex bool
+
 	BatchSemaphore sync.Mutex
 	MultiBatch     []interfaces.Record
 	BlockExtractor blockExtractor.BlockExtractor
+
+	// BlockCache keeps decoded blocks, nil if they are not cached
+	BlockCache *BlockCache
+	// sharedBlockCache is set for a snapshot view using the block cache of its database, which
+	// it only uses for the buckets whose values never change
+	sharedBlockCache bool
+
+	// balanceBuilding is set while BuildBalanceCheckpoints runs
+	balanceBuildMutex sync.Mutex
+	balanceBuilding   bool
 }
 
 var _ interfaces.IDatabase = (*Overlay)(nil)
@@ -149,15 +182,59 @@ func (db *Overlay) ExecuteMultiBatch() error {
 		db.MultiBatch = nil
 		db.BatchSemaphore.Unlock()
 	}()
-	return db.PutInBatch(db.MultiBatch)
+	return db.writeMultiBatch(db.MultiBatch)
+}
+
+// AbortMultiBatch drops the multi batch started, nothing of it is written
+func (db *Overlay) AbortMultiBatch() {
+	db.MultiBatch = nil
+	db.BatchSemaphore.Unlock()
 }
 
 func (db *Overlay) PutInBatch(records []interfaces.Record) error {
-	return db.DB.PutInBatch(records)
+	start := time.Now()
+	sized := make([]interfaces.Record, len(records))
+	for i, r := range records {
+		sized[i] = r
+		if r.Data != nil {
+			sized[i].Data = &sizedMarshaler{BinaryMarshallable: r.Data}
+		}
+	}
+	err := db.DB.PutInBatch(sized)
+	db.invalidate(records)
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	total := 0
+	for _, r := range sized {
+		if m, ok := r.Data.(*sizedMarshaler); ok {
+			OverlayDBPutBytes.WithLabelValues(BucketLabel(r.Bucket)).Observe(float64(m.size))
+			total += m.size
+		}
+	}
+	OverlayDBBatchSeconds.Observe(elapsed.Seconds())
+	OverlayDBBatchRecords.Observe(float64(len(records)))
+	OverlayDBBatchBytes.Observe(float64(total))
+	logIfSlow("PutInBatch", fmt.Sprintf("%v records", len(records)), elapsed)
+	return nil
 }
 
 func (db *Overlay) Put(bucket, key []byte, data interfaces.BinaryMarshallable) error {
-	return db.DB.Put(bucket, key, data)
+	defer db.invalidate([]interfaces.Record{{Bucket: bucket, Key: key}})
+	if data == nil {
+		return db.DB.Put(bucket, key, data)
+	}
+	start := time.Now()
+	sized := &sizedMarshaler{BinaryMarshallable: data}
+	err := db.DB.Put(bucket, key, sized)
+	if err != nil {
+		return err
+	}
+	OverlayDBPutBytes.WithLabelValues(BucketLabel(bucket)).Observe(float64(sized.size))
+	observe("Put", bucket, start, OverlayDBPutSeconds)
+	return nil
 }
 
 func (db *Overlay) ListAllKeys(bucket []byte) ([][]byte, error) {
@@ -168,13 +245,40 @@ func (db *Overlay) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAnd
 	return db.DB.GetAll(bucket, sample)
 }
 
+func (db *Overlay) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return db.DB.NewIterator(bucket, r)
+}
+
+func (db *Overlay) GetSnapshot() (interfaces.ISnapshot, error) {
+	return db.DB.GetSnapshot()
+}
+
 func (db *Overlay) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
 	GetBucket(bucket)
-	return db.DB.Get(bucket, key, destination)
+	start := time.Now()
+	sized := &sizedMarshaler{BinaryMarshallable: destination}
+	answer, err := db.DB.Get(bucket, key, sized)
+	observe("Get", bucket, start, OverlayDBGetSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if answer == nil {
+		return nil, nil
+	}
+	OverlayDBGetBytes.WithLabelValues(BucketLabel(bucket)).Observe(float64(sized.size))
+	if answer == sized {
+		// The database hands back what it was given
+		return destination, nil
+	}
+	return answer, nil
 }
 
 func (db *Overlay) Clear(bucket []byte) error {
-	return db.DB.Clear(bucket)
+	err := db.DB.Clear(bucket)
+	if db.BlockCache != nil {
+		db.BlockCache.Purge()
+	}
+	return err
 }
 
 func (db *Overlay) Close() (err error) {
@@ -189,7 +293,9 @@ func (db *Overlay) Trim() {
 }
 
 func (db *Overlay) Delete(bucket, key []byte) error {
-	return db.DB.Delete(bucket, key)
+	err := db.DB.Delete(bucket, key)
+	db.invalidate([]interfaces.Record{{Bucket: bucket, Key: key}})
+	return err
 }
 
 func NewOverlay(db interfaces.IDatabase) *Overlay {
@@ -256,8 +362,59 @@ func (db *Overlay) FetchBlock(bucket []byte, key interfaces.IHash, dst interface
 	return block.(interfaces.DatabaseBatchable), nil
 }
 
+// ForEachInBucket calls f with every value of the bucket in key order, without loading the
+// bucket into memory. Iteration stops at the first error f returns.
+func (db *Overlay) ForEachInBucket(bucket []byte, r *interfaces.IteratorRange, sample interfaces.BinaryMarshallableAndCopyable, f func(key []byte, value interfaces.BinaryMarshallableAndCopyable) error) error {
+	iter, err := db.NewIterator(bucket, r)
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	for iter.Next() {
+		value := sample.New()
+		err = value.UnmarshalBinary(iter.Value())
+		if err != nil {
+			return err
+		}
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		err = f(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// ForEachBlockKeyInBucket calls f with every key of the bucket in order, without loading the
+// bucket into memory. Iteration stops at the first error f returns.
+func (db *Overlay) ForEachBlockKeyInBucket(bucket []byte, f func(key interfaces.IHash) error) error {
+	iter, err := db.NewIterator(bucket, nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	for iter.Next() {
+		h, err := primitives.NewShaHash(iter.Key())
+		if err != nil {
+			return err
+		}
+		err = f(h)
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
 func (db *Overlay) FetchAllBlocksFromBucket(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, error) {
-	answer, _, err := db.GetAll(bucket, sample)
+	answer := []interfaces.BinaryMarshallableAndCopyable{}
+	err := db.ForEachInBucket(bucket, nil, sample, func(key []byte, value interfaces.BinaryMarshallableAndCopyable) error {
+		answer = append(answer, value)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -265,17 +422,14 @@ func (db *Overlay) FetchAllBlocksFromBucket(bucket []byte, sample interfaces.Bin
 }
 
 func (db *Overlay) FetchAllBlockKeysFromBucket(bucket []byte) ([]interfaces.IHash, error) {
-	entries, err := db.ListAllKeys(bucket)
+	answer := []interfaces.IHash{}
+	err := db.ForEachBlockKeyInBucket(bucket, func(key interfaces.IHash) error {
+		answer = append(answer, key)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	answer := make([]interfaces.IHash, len(entries))
-	for i := range entries {
-		answer[i], err = primitives.NewShaHash(entries[i])
-		if err != nil {
-			return nil, err
-		}
-	}
 	return answer, nil
 }
 
@@ -613,6 +767,9 @@ func (db *Overlay) FetchBlockSetByHeightWithEntries(dbheight uint32) (*BlockSet,
 	}
 
 	for _, eBlock := range bs.EBlocks {
+		if eBlock == nil {
+			continue
+		}
 		entries := eBlock.GetEntryHashes()
 		for _, e := range entries {
 			entry, err := db.FetchEntry(e)
@@ -636,5 +793,10 @@ func (db *Overlay) SetChainHeads(primaryIndexes, chainIDs []interfaces.IHash) er
 		batch = append(batch, interfaces.Record{CHAIN_HEAD, chainIDs[i].Bytes(), primaryIndexes[i]})
 	}
 
-	return db.PutInBatch(batch)
+	err := db.PutInBatch(batch)
+	// Moving a head back means the blocks above it are saved again, maybe different ones
+	if db.BlockCache != nil {
+		db.BlockCache.Purge()
+	}
+	return err
 }
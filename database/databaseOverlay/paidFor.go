@@ -14,7 +14,7 @@ func (db *Overlay) SavePaidFor(entry, ecEntry interfaces.IHash) error {
 
 	batch = append(batch, interfaces.Record{PAID_FOR, entry.Bytes(), ecEntry})
 
-	err := db.DB.PutInBatch(batch)
+	err := db.PutInBatch(batch)
 	if err != nil {
 		return err
 	}
@@ -95,7 +95,7 @@ func (db *Overlay) SavePaidForMultiFromBlock(block interfaces.IEntryCreditBlock,
 		return nil
 	}
 
-	err := db.DB.PutInBatch(batch)
+	err := db.PutInBatch(batch)
 	if err != nil {
 		return err
 	}
@@ -104,7 +104,7 @@ func (db *Overlay) SavePaidForMultiFromBlock(block interfaces.IEntryCreditBlock,
 }
 
 func (db *Overlay) FetchPaidFor(hash interfaces.IHash) (interfaces.IHash, error) {
-	block, err := db.DB.Get(PAID_FOR, hash.Bytes(), new(primitives.Hash))
+	block, err := db.Get(PAID_FOR, hash.Bytes(), new(primitives.Hash))
 	if err != nil {
 		return nil, err
 	}
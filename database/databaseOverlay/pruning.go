@@ -0,0 +1,157 @@
+package databaseOverlay
+
+import (
+	"math"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// EntryPruneHeightKey keeps the height the entries of lower directory blocks were pruned below
+var EntryPruneHeightKey = []byte("EntryPruneHeight")
+
+func (db *Overlay) SaveEntryPruneHeight(height uint32) error {
+	buf := primitives.NewBuffer(nil)
+	buf.PushUInt32(height)
+	bs := new(primitives.ByteSlice)
+	bs.Bytes = buf.DeepCopyBytes()
+
+	return db.SaveKeyValueStore(bs, EntryPruneHeightKey)
+}
+
+// FetchEntryPruneHeight returns 0 if no entries were pruned
+func (db *Overlay) FetchEntryPruneHeight() (uint32, error) {
+	bs := new(primitives.ByteSlice)
+	_, err := db.FetchKeyValueStore(EntryPruneHeightKey, bs)
+	if err != nil {
+		return 0, err
+	}
+	if len(bs.Bytes) == 0 {
+		return 0, nil
+	}
+	buf := primitives.NewBuffer(bs.Bytes)
+	height, err := buf.PopUInt32()
+	if err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+// PruneEntries deletes the content of the entries of the directory blocks below pruneHeight, from
+// the height the last call stopped at, except for the chains keep returns true for, and the
+// entries an entry block of the same chain at or above pruneHeight includes again. An entry
+// included again below pruneHeight goes with the entry block that includes it. The entry blocks
+// stay, and so does the ENTRY index, so a pruned entry can be told apart from one that was never
+// saved. It returns the number of entries deleted.
+func (db *Overlay) PruneEntries(pruneHeight uint32, keep func(chainID interfaces.IHash) bool) (int, error) {
+	height, err := db.FetchEntryPruneHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	p := new(entryPruner)
+	p.db = db
+	p.pruneHeight = pruneHeight
+	p.keep = keep
+	p.kept = map[[32]byte]map[[32]byte]bool{}
+
+	count := 0
+	for ; height < pruneHeight; height++ {
+		dBlock, err := db.FetchDBlockByHeight(height)
+		if err != nil {
+			return count, err
+		}
+		if dBlock == nil {
+			break
+		}
+		n, err := p.prune(dBlock)
+		count += n
+		if err != nil {
+			return count, err
+		}
+		err = db.SaveEntryPruneHeight(height + 1)
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// entryPruner prunes the directory blocks below pruneHeight. The entries of the entry blocks a
+// chain keeps are read once per chain, in one pass from pruneHeight on.
+type entryPruner struct {
+	db          *Overlay
+	pruneHeight uint32
+	keep        func(chainID interfaces.IHash) bool
+	// kept holds the entry hashes of the entry blocks at or above pruneHeight, by chain ID
+	kept map[[32]byte]map[[32]byte]bool
+}
+
+func (p *entryPruner) prune(dBlock interfaces.IDirectoryBlock) (int, error) {
+	count := 0
+	for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+		if p.keep != nil && p.keep(dbEntry.GetChainID()) {
+			continue
+		}
+		eBlock, err := p.db.FetchEBlock(dbEntry.GetKeyMR())
+		if err != nil {
+			return count, err
+		}
+		if eBlock == nil {
+			continue
+		}
+		kept, err := p.keptEntries(eBlock.GetChainID())
+		if err != nil {
+			return count, err
+		}
+		for _, hash := range eBlock.GetEntryHashes() {
+			if hash.IsMinuteMarker() || kept[hash.Fixed()] {
+				continue
+			}
+			err = p.db.Delete(eBlock.GetChainID().Bytes(), hash.Bytes())
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// keptEntries returns the hashes of the entries in the entry blocks of the chain at or above
+// pruneHeight
+func (p *entryPruner) keptEntries(chainID interfaces.IHash) (map[[32]byte]bool, error) {
+	if hashes, ok := p.kept[chainID.Fixed()]; ok {
+		return hashes, nil
+	}
+	hashes := map[[32]byte]bool{}
+	eBlocks, err := p.db.FetchEBlocksByChainInHeightRange(chainID, p.pruneHeight, math.MaxUint32, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, eBlock := range eBlocks {
+		for _, hash := range eBlock.GetEntryHashes() {
+			if hash.IsMinuteMarker() == false {
+				hashes[hash.Fixed()] = true
+			}
+		}
+	}
+	p.kept[chainID.Fixed()] = hashes
+	return hashes, nil
+}
+
+// IsEntryPruned returns true if the entry was saved, but its content was pruned since
+func (db *Overlay) IsEntryPruned(hash interfaces.IHash) (bool, error) {
+	chainID, err := db.FetchPrimaryIndexBySecondaryIndex(ENTRY, hash)
+	if err != nil {
+		return false, err
+	}
+	if chainID == nil {
+		return false, nil
+	}
+	exists, err := db.DB.DoesKeyExist(chainID.Bytes(), hash.Bytes())
+	if err != nil {
+		return false, err
+	}
+	return exists == false, nil
+}
@@ -0,0 +1,188 @@
+package databaseOverlay_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/mapdb"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestSaveLoadEntryPruneHeight(t *testing.T) {
+	dbo := NewOverlay(new(mapdb.MapDB))
+	defer dbo.Close()
+
+	height, err := dbo.FetchEntryPruneHeight()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if height != 0 {
+		t.Errorf("Expected 0 before anything was pruned, got %v", height)
+	}
+
+	err = dbo.SaveEntryPruneHeight(1234)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	height, err = dbo.FetchEntryPruneHeight()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if height != 1234 {
+		t.Errorf("Expected 1234, got %v", height)
+	}
+}
+
+func TestPruneEntries(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	defer dbo.Close()
+
+	dBlock, err := dbo.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	entryHashes := func(keep bool) []interfaces.IHash {
+		hashes := []interfaces.IHash{}
+		for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+			if (dbEntry.GetChainID().String() == AnchorBlockID) != keep {
+				continue
+			}
+			eBlock, err := dbo.FetchEBlock(dbEntry.GetKeyMR())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			for _, h := range eBlock.GetEntryHashes() {
+				if h.IsMinuteMarker() == false {
+					hashes = append(hashes, h)
+				}
+			}
+		}
+		return hashes
+	}
+	pruned := entryHashes(false)
+	kept := entryHashes(true)
+	if len(pruned) == 0 || len(kept) == 0 {
+		t.Fatalf("Expected entries in both the anchor chain and other chains")
+	}
+
+	// Keep the anchor chain, and prune the directory block alone
+	err = dbo.SaveEntryPruneHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	count, err := dbo.PruneEntries(2, func(chainID interfaces.IHash) bool {
+		return chainID.String() == AnchorBlockID
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != len(pruned) {
+		t.Errorf("Expected %v entries to be pruned, got %v", len(pruned), count)
+	}
+
+	for _, h := range pruned {
+		entry, err := dbo.FetchEntry(h)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if entry != nil {
+			t.Errorf("Entry %v was not pruned", h)
+		}
+		isPruned, err := dbo.IsEntryPruned(h)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if isPruned == false {
+			t.Errorf("Entry %v is not reported as pruned", h)
+		}
+	}
+	for _, h := range kept {
+		entry, err := dbo.FetchEntry(h)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if entry == nil {
+			t.Errorf("Entry %v of a kept chain was pruned", h)
+		}
+	}
+
+	// The entry blocks are kept
+	for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+		eBlock, err := dbo.FetchEBlock(dbEntry.GetKeyMR())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if eBlock == nil {
+			t.Errorf("Entry block %v was pruned", dbEntry.GetKeyMR())
+		}
+	}
+
+	// An entry that was never saved is not pruned
+	isPruned, err := dbo.IsEntryPruned(dBlock.GetKeyMR())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if isPruned {
+		t.Errorf("Unknown entry reported as pruned")
+	}
+}
+
+func TestPruneEntriesIncludedAgain(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	defer dbo.Close()
+
+	dBlock, err := dbo.FetchDBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var old interfaces.IEntryBlock
+	for _, dbEntry := range dBlock.GetEBlockDBEntries() {
+		if dbEntry.GetChainID().String() != AnchorBlockID {
+			old, err = dbo.FetchEBlock(dbEntry.GetKeyMR())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			break
+		}
+	}
+	if old == nil {
+		t.Fatalf("Found no entry block to prune")
+	}
+	hash := old.GetEntryHashes()[0]
+	entry, err := dbo.FetchEntry(hash)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// A newer entry block of the same chain includes the entry again
+	newer := entryBlock.NewEBlock()
+	newer.Header.SetChainID(old.GetChainID())
+	newer.Header.SetDBHeight(1000)
+	err = newer.AddEBEntry(entry)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = dbo.ProcessEBlockBatch(newer, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err = dbo.SaveEntryPruneHeight(1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = dbo.PruneEntries(2, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entry, err = dbo.FetchEntry(hash)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if entry == nil {
+		t.Errorf("Entry included by a newer entry block was pruned")
+	}
+}
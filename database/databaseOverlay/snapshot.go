@@ -0,0 +1,89 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package databaseOverlay
+
+import (
+	"errors"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// ErrReadOnlySnapshot is returned by every write to a snapshot view
+var ErrReadOnlySnapshot = errors.New("Can't write to a database snapshot")
+
+// NewSnapshotView returns an overlay that reads from a snapshot of the database. Every read made
+// through the view sees the database as it was when the view was made, even while a block set is
+// being saved, so API calls that read several blocks see one consistent height. The view is read
+// only, and Close releases the snapshot.
+func (db *Overlay) NewSnapshotView() (*Overlay, error) {
+	snap, err := db.DB.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	view := NewOverlay(&snapshotDB{snap: snap})
+	view.AddressIndex = db.AddressIndex
+	view.BlockCache = db.BlockCache
+	view.sharedBlockCache = true
+	return view, nil
+}
+
+// snapshotDB lets an overlay read from a snapshot
+type snapshotDB struct {
+	snap interfaces.ISnapshot
+}
+
+var _ interfaces.IDatabase = (*snapshotDB)(nil)
+
+func (db *snapshotDB) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	return db.snap.Get(bucket, key, destination)
+}
+
+func (db *snapshotDB) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return db.snap.ListAllKeys(bucket)
+}
+
+func (db *snapshotDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	return db.snap.GetAll(bucket, sample)
+}
+
+func (db *snapshotDB) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return db.snap.DoesKeyExist(bucket, key)
+}
+
+func (db *snapshotDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return db.snap.NewIterator(bucket, r)
+}
+
+func (db *snapshotDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	return nil, errors.New("Can't take a snapshot of a database snapshot")
+}
+
+func (db *snapshotDB) ListAllBuckets() ([][]byte, error) {
+	return nil, errors.New("Can't list the buckets of a database snapshot")
+}
+
+func (db *snapshotDB) Close() error {
+	db.snap.Release()
+	return nil
+}
+
+func (db *snapshotDB) Trim() {
+}
+
+func (db *snapshotDB) Put(bucket, key []byte, data interfaces.BinaryMarshallable) error {
+	return ErrReadOnlySnapshot
+}
+
+func (db *snapshotDB) PutInBatch(records []interfaces.Record) error {
+	return ErrReadOnlySnapshot
+}
+
+func (db *snapshotDB) Delete(bucket, key []byte) error {
+	return ErrReadOnlySnapshot
+}
+
+func (db *snapshotDB) Clear(bucket []byte) error {
+	return ErrReadOnlySnapshot
+}
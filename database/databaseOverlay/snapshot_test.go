@@ -0,0 +1,84 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package databaseOverlay_test
+
+import (
+	"testing"
+
+	. "github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestSnapshotView(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+
+	head, err := dbo.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	view, err := dbo.NewSnapshotView()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer view.Close()
+
+	// A block saved after the view was made is not seen by it
+	blocks := testHelper.CreateFullTestBlockSet()
+	dblock := testHelper.CreateTestBlockSet(blocks[len(blocks)-1]).DBlock
+	err = dbo.ProcessDBlockBatch(dblock)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	newHead, err := dbo.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if newHead.GetDatabaseHeight() != head.GetDatabaseHeight()+1 {
+		t.Errorf("The database did not save the new head")
+	}
+
+	viewHead, err := view.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if viewHead.GetKeyMR().IsSameAs(head.GetKeyMR()) == false {
+		t.Errorf("The view sees a head saved after it was made - %v", viewHead.GetDatabaseHeight())
+	}
+	b, err := view.FetchDBlockByHeight(dblock.GetDatabaseHeight())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if b != nil {
+		t.Errorf("The view sees a block saved after it was made")
+	}
+	b, err = view.FetchDBlockByHeight(head.GetDatabaseHeight())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if b == nil {
+		t.Errorf("The view does not see a block saved before it was made")
+	}
+
+	err = view.ProcessDBlockBatch(dblock)
+	if err != ErrReadOnlySnapshot {
+		t.Errorf("Expected ErrReadOnlySnapshot, got %v", err)
+	}
+
+	// A new view sees the new block
+	view2, err := dbo.NewSnapshotView()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer view2.Close()
+	viewHead, err = view2.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if viewHead.GetKeyMR().IsSameAs(dblock.GetKeyMR()) == false {
+		t.Errorf("The new view does not see the new head")
+	}
+}
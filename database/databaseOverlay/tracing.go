@@ -0,0 +1,100 @@
+package databaseOverlay
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// SlowOperationThreshold logs the database operations that take at least this long, with the
+// overlay function they were called through. 0 logs none.
+var SlowOperationThreshold time.Duration
+
+var overlayLogger = log.WithFields(log.Fields{"package": "databaseOverlay"})
+
+// BucketLabel names the bucket for the metrics. Every entry chain has its own buckets, they are
+// counted together so the number of labels stays small.
+func BucketLabel(bucket []byte) string {
+	if name, ok := ConstantNamesMap[string(bucket)]; ok {
+		return name
+	}
+	if bytes.HasPrefix(bucket, ENTRYBLOCK_CHAIN_NUMBER) {
+		return "EntryBlockChainNumber"
+	}
+	if len(bucket) == 32 {
+		return "EntryChain"
+	}
+	return "Other"
+}
+
+// sizedMarshaler keeps the size of the value written or read through it
+type sizedMarshaler struct {
+	interfaces.BinaryMarshallable
+	size int
+}
+
+func (m *sizedMarshaler) MarshalBinary() ([]byte, error) {
+	data, err := m.BinaryMarshallable.MarshalBinary()
+	m.size = len(data)
+	return data, err
+}
+
+func (m *sizedMarshaler) UnmarshalBinary(data []byte) error {
+	m.size = len(data)
+	return m.BinaryMarshallable.UnmarshalBinary(data)
+}
+
+func (m *sizedMarshaler) UnmarshalBinaryData(data []byte) ([]byte, error) {
+	newData, err := m.BinaryMarshallable.UnmarshalBinaryData(data)
+	m.size = len(data) - len(newData)
+	return newData, err
+}
+
+// observe records the time taken by an operation on a bucket, and logs it if it is slow
+func observe(operation string, bucket []byte, start time.Time, histogram *prometheus.HistogramVec) {
+	elapsed := time.Since(start)
+	histogram.WithLabelValues(BucketLabel(bucket)).Observe(elapsed.Seconds())
+	logIfSlow(operation, BucketLabel(bucket), elapsed)
+}
+
+func logIfSlow(operation string, bucket string, elapsed time.Duration) {
+	if SlowOperationThreshold <= 0 || elapsed < SlowOperationThreshold {
+		return
+	}
+	OverlayDBSlowOperations.WithLabelValues(operation).Inc()
+	overlayLogger.WithFields(log.Fields{
+		"operation": operation,
+		"bucket":    bucket,
+		"duration":  elapsed.String(),
+		"caller":    overlayCaller(),
+	}).Warn("Slow database operation")
+}
+
+// overlayCaller returns the outermost overlay function on the stack, and the function that called it
+func overlayCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	overlayFunction := ""
+	for {
+		frame, more := frames.Next()
+		if strings.Contains(frame.Function, "/databaseOverlay.") {
+			overlayFunction = shortFunctionName(frame.Function)
+		} else if overlayFunction != "" {
+			return overlayFunction + " called by " + shortFunctionName(frame.Function)
+		}
+		if more == false {
+			return overlayFunction
+		}
+	}
+}
+
+func shortFunctionName(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
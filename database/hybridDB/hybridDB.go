@@ -92,6 +92,12 @@ func (db *HybridDB) Put(bucket, key []byte, data interfaces.BinaryMarshallable)
 	return nil
 }
 
+// AtomicBatches is true if the batches of the persistent storage are, the temporary storage does
+// not outlive a crash
+func (db *HybridDB) AtomicBatches() bool {
+	return interfaces.AtomicBatches(db.persistentStorage)
+}
+
 func (db *HybridDB) PutInBatch(records []interfaces.Record) error {
 	db.Sem.Lock()
 	defer db.Sem.Unlock()
@@ -116,8 +122,10 @@ func (db *HybridDB) Get(bucket, key []byte, destination interfaces.BinaryMarshal
 		return nil, err
 	}
 	if answer != nil {
+		HybridDBCacheHits.Inc()
 		return answer, nil
 	}
+	HybridDBCacheMisses.Inc()
 
 	answer, err = db.persistentStorage.Get(bucket, key, destination)
 	if err != nil {
@@ -158,6 +166,22 @@ func (db *HybridDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAn
 	return db.persistentStorage.GetAll(bucket, sample)
 }
 
+// NewIterator walks the persistent storage, which holds everything the temporary storage does
+func (db *HybridDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	return db.persistentStorage.NewIterator(bucket, r)
+}
+
+// GetSnapshot takes the snapshot from the persistent storage, the temporary storage only caches it
+func (db *HybridDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	return db.persistentStorage.GetSnapshot()
+}
+
 func (db *HybridDB) Clear(bucket []byte) error {
 	db.Sem.Lock()
 	defer db.Sem.Unlock()
@@ -0,0 +1,31 @@
+package hybridDB
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	HybridDBCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_database_hybrid_cache_hits",
+		Help: "Counts gets answered by the temporary storage",
+	})
+	HybridDBCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_database_hybrid_cache_misses",
+		Help: "Counts gets read from the persistent storage",
+	})
+)
+
+var registered = false
+
+// RegisterPrometheus registers the variables to be exposed. This can only be run once, hence the
+// boolean flag to prevent panics if launched more than once. This is called in NetStart
+func RegisterPrometheus() {
+	if registered {
+		return
+	}
+	registered = true
+
+	// HybridDB
+	prometheus.MustRegister(HybridDBCacheHits)
+	prometheus.MustRegister(HybridDBCacheMisses)
+}
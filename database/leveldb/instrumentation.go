@@ -1,6 +1,9 @@
 package leveldb
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -17,6 +20,28 @@ var (
 		Name: "factomd_database_leveldb_cacheblock",
 		Help: "Memory used by Level DB for caching",
 	})
+
+	// Compaction stats, by level
+	LevelDBLevelTables = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "factomd_database_leveldb_level_tables",
+		Help: "Number of tables of a Level DB level",
+	}, []string{"level"})
+	LevelDBLevelSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "factomd_database_leveldb_level_bytes",
+		Help: "Size in bytes of a Level DB level",
+	}, []string{"level"})
+	LevelDBCompactionSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "factomd_database_leveldb_compaction_seconds",
+		Help: "Time spent compacting a Level DB level since the database was opened",
+	}, []string{"level"})
+	LevelDBCompactionRead = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "factomd_database_leveldb_compaction_read_bytes",
+		Help: "Bytes read compacting a Level DB level since the database was opened",
+	}, []string{"level"})
+	LevelDBCompactionWrite = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "factomd_database_leveldb_compaction_write_bytes",
+		Help: "Bytes written compacting a Level DB level since the database was opened",
+	}, []string{"level"})
 )
 
 var registered = false
@@ -33,4 +58,69 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(LevelDBGets)
 	prometheus.MustRegister(LevelDBPuts)
 	prometheus.MustRegister(LevelDBCacheblock)
+	prometheus.MustRegister(LevelDBLevelTables)
+	prometheus.MustRegister(LevelDBLevelSize)
+	prometheus.MustRegister(LevelDBCompactionSeconds)
+	prometheus.MustRegister(LevelDBCompactionRead)
+	prometheus.MustRegister(LevelDBCompactionWrite)
+}
+
+// CompactionStats is a row of the "leveldb.stats" property
+type CompactionStats struct {
+	Level      int
+	Tables     int
+	Size       float64 // bytes
+	Seconds    float64
+	ReadBytes  float64
+	WriteBytes float64
+}
+
+// ParseCompactionStats reads the table of the "leveldb.stats" property, which gives the sizes in MB
+func ParseCompactionStats(stats string) []CompactionStats {
+	answer := []CompactionStats{}
+	for _, line := range strings.Split(stats, "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 6 {
+			continue
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			// The header
+			continue
+		}
+		tables, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+		values := make([]float64, 4)
+		for i := range values {
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(fields[i+2]), 64)
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		answer = append(answer, CompactionStats{
+			Level:      level,
+			Tables:     tables,
+			Size:       values[0] * 1048576,
+			Seconds:    values[1],
+			ReadBytes:  values[2] * 1048576,
+			WriteBytes: values[3] * 1048576,
+		})
+	}
+	return answer
+}
+
+func setCompactionStats(stats []CompactionStats) {
+	for _, s := range stats {
+		level := strconv.Itoa(s.Level)
+		LevelDBLevelTables.WithLabelValues(level).Set(float64(s.Tables))
+		LevelDBLevelSize.WithLabelValues(level).Set(s.Size)
+		LevelDBCompactionSeconds.WithLabelValues(level).Set(s.Seconds)
+		LevelDBCompactionRead.WithLabelValues(level).Set(s.ReadBytes)
+		LevelDBCompactionWrite.WithLabelValues(level).Set(s.WriteBytes)
+	}
 }
@@ -0,0 +1,30 @@
+package leveldb_test
+
+import (
+	"testing"
+
+	. "github.com/FactomProject/factomd/database/leveldb"
+)
+
+func TestParseCompactionStats(t *testing.T) {
+	stats := "Compactions\n" +
+		" Level |   Tables   |    Size(MB)   |    Time(sec)  |    Read(MB)   |   Write(MB)\n" +
+		"-------+------------+---------------+---------------+---------------+---------------\n" +
+		"   0   |          2 |       1.00000 |       0.50000 |       0.00000 |       1.00000\n" +
+		"   1   |          5 |       4.50000 |       2.25000 |       3.00000 |       4.50000\n"
+
+	parsed := ParseCompactionStats(stats)
+	if len(parsed) != 2 {
+		t.Fatalf("Expected 2 levels, got %v", len(parsed))
+	}
+	if parsed[0].Level != 0 || parsed[0].Tables != 2 || parsed[0].Size != 1048576 || parsed[0].Seconds != 0.5 {
+		t.Errorf("Level 0 is wrong: %+v", parsed[0])
+	}
+	if parsed[1].Level != 1 || parsed[1].Tables != 5 || parsed[1].ReadBytes != 3*1048576 || parsed[1].WriteBytes != 4.5*1048576 {
+		t.Errorf("Level 1 is wrong: %+v", parsed[1])
+	}
+
+	if len(ParseCompactionStats("")) != 0 {
+		t.Errorf("Expected no levels in empty stats")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package leveldb
+
+import (
+	"bytes"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/goleveldb/leveldb/iterator"
+	"github.com/FactomProject/goleveldb/leveldb/opt"
+	"github.com/FactomProject/goleveldb/leveldb/util"
+)
+
+// LevelDBIterator walks the keys of one bucket. LevelDB iterators read from an implicit
+// snapshot, so writes made while iterating are not seen.
+type LevelDBIterator struct {
+	iter   iterator.Iterator
+	prefix []byte // the bucket and separator every key starts with
+	start  []byte
+}
+
+var _ interfaces.IIterator = (*LevelDBIterator)(nil)
+
+func (db *LevelDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	db.dbLock.RLock()
+	defer db.dbLock.RUnlock()
+
+	return newIterator(db.lDB, db.ro, bucket, r), nil
+}
+
+// newIterator builds every bound from its own copy of the bucket, so a bucket slice with spare
+// capacity can't have one bound overwrite another.
+func newIterator(reader reader, ro *opt.ReadOptions, bucket []byte, r *interfaces.IteratorRange) *LevelDBIterator {
+	ldbKey := ExtendBucket(bucket)
+	start, limit := r.Bounds()
+
+	slice := new(util.Range)
+	slice.Start = CombineBucketAndKey(bucket, start)
+	if limit == nil {
+		slice.Limit = interfaces.PrefixLimit(ldbKey)
+	} else {
+		slice.Limit = CombineBucketAndKey(bucket, limit)
+	}
+
+	it := new(LevelDBIterator)
+	it.iter = reader.NewIterator(slice, ro)
+	it.prefix = ldbKey
+	it.start = slice.Start
+	return it
+}
+
+func (it *LevelDBIterator) Next() bool {
+	return it.iter.Next()
+}
+
+func (it *LevelDBIterator) Seek(key []byte) bool {
+	ldbKey := append(append([]byte{}, it.prefix...), key...)
+	if bytes.Compare(ldbKey, it.start) < 0 {
+		ldbKey = it.start
+	}
+	return it.iter.Seek(ldbKey)
+}
+
+func (it *LevelDBIterator) Key() []byte {
+	key := it.iter.Key()
+	if key == nil {
+		return nil
+	}
+	return key[len(it.prefix):]
+}
+
+func (it *LevelDBIterator) Value() []byte {
+	return it.iter.Value()
+}
+
+func (it *LevelDBIterator) Release() {
+	it.iter.Release()
+}
+
+func (it *LevelDBIterator) Error() error {
+	return it.iter.Error()
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/goleveldb/leveldb"
+	"github.com/FactomProject/goleveldb/leveldb/iterator"
 	"github.com/FactomProject/goleveldb/leveldb/opt"
 	"github.com/FactomProject/goleveldb/leveldb/util"
 	"strconv"
@@ -53,13 +54,35 @@ func (db *LevelDB) ListAllBuckets() ([][]byte, error) {
 		return answer, nil*/
 }
 
-// Can't trim a real database
+// ListBucketsWithPrefix returns the buckets that have keys and whose names are the prefix followed
+// by nameLength more bytes. LevelDB can't tell where the name of a bucket ends, the caller knows
+// how long the names are.
+func (db *LevelDB) ListBucketsWithPrefix(prefix []byte, nameLength int) ([][]byte, error) {
+	db.dbLock.RLock()
+	defer db.dbLock.RUnlock()
+
+	return listBucketsWithPrefix(db.lDB, db.ro, prefix, nameLength)
+}
+
+// CountAllKeys returns the number of keys in the database, in every bucket
+func (db *LevelDB) CountAllKeys() (uint64, error) {
+	db.dbLock.RLock()
+	defer db.dbLock.RUnlock()
+
+	return countAllKeys(db.lDB, db.ro)
+}
+
+// Can't trim a real database, the metrics are updated instead
 func (db *LevelDB) Trim() {
 	cache, _ := db.lDB.GetProperty("leveldb.cachedblock")
 	v, err := strconv.Atoi(cache)
 	if err == nil {
 		LevelDBCacheblock.Set(float64(v))
 	}
+	stats, err := db.lDB.GetProperty("leveldb.stats")
+	if err == nil {
+		setCompactionStats(ParseCompactionStats(stats))
+	}
 }
 
 func (db *LevelDB) Delete(bucket []byte, key []byte) error {
@@ -78,8 +101,10 @@ func (db *LevelDB) Close() error {
 	return db.lDB.Close()
 }
 
+// ExtendBucket returns a new slice holding the bucket and the separator. The bucket is copied
+// first, so spare capacity in the caller's slice is never written to.
 func ExtendBucket(bucket []byte) []byte {
-	return append(bucket, ';')
+	return append(append(make([]byte, 0, len(bucket)+1), bucket...), ';')
 }
 
 func CombineBucketAndKey(bucket []byte, key []byte) []byte {
@@ -94,21 +119,7 @@ func (db *LevelDB) Get(bucket []byte, key []byte, destination interfaces.BinaryM
 
 	LevelDBGets.Inc()
 
-	ldbKey := CombineBucketAndKey(bucket, key)
-	data, err := db.lDB.Get(ldbKey, db.ro)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	_, err = destination.UnmarshalBinaryData(data)
-	if err != nil {
-		return nil, err
-	}
-
-	return destination, nil
+	return get(db.lDB, db.ro, bucket, key, destination)
 }
 
 func (db *LevelDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshallable) error {
@@ -137,6 +148,11 @@ func (db *LevelDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshall
 	return nil
 }
 
+// AtomicBatches is true, a LevelDB batch is written all at once
+func (db *LevelDB) AtomicBatches() bool {
+	return true
+}
+
 func (db *LevelDB) PutInBatch(records []interfaces.Record) error {
 	db.dbLock.Lock()
 	defer db.dbLock.Unlock()
@@ -195,13 +211,49 @@ func (db *LevelDB) ListAllKeys(bucket []byte) (keys [][]byte, err error) {
 	db.dbLock.RLock()
 	defer db.dbLock.RUnlock()
 
+	return listAllKeys(db.lDB, db.ro, bucket)
+}
+
+func (db *LevelDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	db.dbLock.RLock()
+	defer db.dbLock.RUnlock()
+
+	return getAll(db.lDB, db.ro, bucket, sample)
+}
+
+// reader is what the database and its snapshots have in common
+type reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	Has(key []byte, ro *opt.ReadOptions) (bool, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+func get(r reader, ro *opt.ReadOptions, bucket []byte, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	ldbKey := CombineBucketAndKey(bucket, key)
+	data, err := r.Get(ldbKey, ro)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	_, err = destination.UnmarshalBinaryData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return destination, nil
+}
+
+func listAllKeys(r reader, ro *opt.ReadOptions, bucket []byte) (keys [][]byte, err error) {
 	ldbKey := ExtendBucket(bucket)
 
 	var fromKey []byte = ldbKey[:]
 	var toKey []byte = ldbKey[:]
 	toKey = addOneToByteArray(toKey)
 
-	iter := db.lDB.NewIterator(&util.Range{Start: fromKey, Limit: toKey}, db.ro)
+	iter := r.NewIterator(&util.Range{Start: fromKey, Limit: toKey}, ro)
 
 	var answer [][]byte
 
@@ -220,17 +272,54 @@ func (db *LevelDB) ListAllKeys(bucket []byte) (keys [][]byte, err error) {
 	return answer, nil
 }
 
-func (db *LevelDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
-	db.dbLock.RLock()
-	defer db.dbLock.RUnlock()
+func listBucketsWithPrefix(r reader, ro *opt.ReadOptions, prefix []byte, nameLength int) ([][]byte, error) {
+	iter := r.NewIterator(&util.Range{Start: prefix, Limit: interfaces.PrefixLimit(prefix)}, ro)
+	defer iter.Release()
+
+	separator := len(prefix) + nameLength
+	answer := [][]byte{}
+	for ok := iter.Next(); ok; {
+		key := iter.Key()
+		if len(key) <= separator || key[separator] != ';' {
+			ok = iter.Next()
+			continue
+		}
+		bucket := make([]byte, separator)
+		copy(bucket, key)
+		answer = append(answer, bucket)
+		// Skip the other keys of the bucket
+		ok = iter.Seek(interfaces.PrefixLimit(ExtendBucket(append([]byte{}, bucket...))))
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+func countAllKeys(r reader, ro *opt.ReadOptions) (uint64, error) {
+	iter := r.NewIterator(nil, ro)
+	defer iter.Release()
+
+	var count uint64
+	for iter.Next() {
+		count++
+	}
+	err := iter.Error()
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
 
+func getAll(r reader, ro *opt.ReadOptions, bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
 	ldbKey := ExtendBucket(bucket)
 
 	var fromKey []byte = ldbKey[:]
 	var toKey []byte = ldbKey[:]
 	toKey = addOneToByteArray(toKey)
 
-	iter := db.lDB.NewIterator(&util.Range{Start: fromKey, Limit: toKey}, db.ro)
+	iter := r.NewIterator(&util.Range{Start: fromKey, Limit: toKey}, ro)
 
 	answer := []interfaces.BinaryMarshallableAndCopyable{}
 	keys := [][]byte{}
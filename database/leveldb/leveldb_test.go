@@ -233,3 +233,87 @@ func TestGetAll(t *testing.T) {
 		}
 	}
 }
+
+func TestListBucketsWithPrefix(t *testing.T) {
+	m, err := NewLevelDB(dbFilename, true)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	defer CleanupTest(t, m)
+	db := m.(*LevelDB)
+
+	prefix := []byte("prefix")
+	// A name with the separator in it is still read to its full length
+	names := [][]byte{[]byte("ab;d"), []byte("abcd"), []byte("wxyz")}
+	test := &TestData{Str: "test"}
+	for _, name := range names {
+		bucket := append(append([]byte{}, prefix...), name...)
+		for _, key := range []string{"1", "2;", "3"} {
+			err = db.Put(bucket, []byte(key), test)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+	}
+	err = db.Put(prefix, []byte("key"), test)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	buckets, err := db.ListBucketsWithPrefix(prefix, 4)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(buckets) != len(names) {
+		t.Fatalf("Expected %v buckets, got %v", len(names), len(buckets))
+	}
+	for i, name := range names {
+		if primitives.AreBytesEqual(buckets[i], append(append([]byte{}, prefix...), name...)) == false {
+			t.Errorf("Expected bucket %s, got %s", name, buckets[i])
+		}
+	}
+
+	count, err := db.CountAllKeys()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != uint64(3*len(names)+1) {
+		t.Errorf("Expected %v keys, got %v", 3*len(names)+1, count)
+	}
+}
+
+func TestIteratorBucketWithSpareCapacity(t *testing.T) {
+	m, err := NewLevelDB(dbFilename, true)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	defer CleanupTest(t, m)
+
+	test := &TestData{Str: "test"}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		err = m.Put([]byte("bucket"), []byte(key), test)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// Appending to a bucket with spare capacity must not let the limit overwrite the start
+	bucket := make([]byte, 0, 64)
+	bucket = append(bucket, "bucket"...)
+	iter, err := m.NewIterator(bucket, &interfaces.IteratorRange{Start: []byte("b"), Limit: []byte("d")})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer iter.Release()
+
+	keys := []string{}
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	if fmt.Sprintf("%v", keys) != "[b c]" {
+		t.Errorf("Expected keys [b c], got %v", keys)
+	}
+	if string(bucket) != "bucket" || string(bucket[:cap(bucket)][:len(bucket)+1]) != "bucket\x00" {
+		t.Errorf("Bucket slice was written to")
+	}
+}
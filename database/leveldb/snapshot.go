@@ -0,0 +1,64 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package leveldb
+
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/goleveldb/leveldb"
+	"github.com/FactomProject/goleveldb/leveldb/opt"
+)
+
+// LevelDBSnapshot is a LevelDB snapshot, it reads the database at the sequence number it was taken at
+type LevelDBSnapshot struct {
+	snap *leveldb.Snapshot
+	ro   *opt.ReadOptions
+}
+
+var _ interfaces.ISnapshot = (*LevelDBSnapshot)(nil)
+
+func (db *LevelDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	db.dbLock.RLock()
+	defer db.dbLock.RUnlock()
+
+	snap, err := db.lDB.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBSnapshot{snap: snap, ro: db.ro}, nil
+}
+
+func (s *LevelDBSnapshot) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	LevelDBGets.Inc()
+
+	return get(s.snap, s.ro, bucket, key, destination)
+}
+
+func (s *LevelDBSnapshot) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return listAllKeys(s.snap, s.ro, bucket)
+}
+
+func (s *LevelDBSnapshot) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	return getAll(s.snap, s.ro, bucket, sample)
+}
+
+func (s *LevelDBSnapshot) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return s.snap.Has(CombineBucketAndKey(bucket, key), s.ro)
+}
+
+func (s *LevelDBSnapshot) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return newIterator(s.snap, s.ro, bucket, r), nil
+}
+
+func (s *LevelDBSnapshot) ListBucketsWithPrefix(prefix []byte, nameLength int) ([][]byte, error) {
+	return listBucketsWithPrefix(s.snap, s.ro, prefix, nameLength)
+}
+
+func (s *LevelDBSnapshot) CountAllKeys() (uint64, error) {
+	return countAllKeys(s.snap, s.ro)
+}
+
+func (s *LevelDBSnapshot) Release() {
+	s.snap.Release()
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package mapdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/util"
+)
+
+// MapDBIterator walks the keys a bucket held when the iterator was created. The values are not
+// copied, Put replaces them instead of changing them in place.
+type MapDBIterator struct {
+	keys   [][]byte
+	values map[string][]byte
+	pos    int
+}
+
+var _ interfaces.IIterator = (*MapDBIterator)(nil)
+
+func (db *MapDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	db.createCache(bucket)
+
+	db.Sem.RLock()
+	defer db.Sem.RUnlock()
+
+	it := new(MapDBIterator)
+	it.pos = -1
+	it.values = map[string][]byte{}
+	for k, v := range db.Cache[string(bucket)] {
+		key := []byte(k)
+		if r.Contains(key) == false {
+			continue
+		}
+		it.keys = append(it.keys, key)
+		it.values[k] = v
+	}
+	sort.Sort(util.ByByteArray(it.keys))
+
+	return it, nil
+}
+
+func (it *MapDBIterator) Next() bool {
+	if it.pos < len(it.keys) {
+		it.pos++
+	}
+	return it.pos < len(it.keys)
+}
+
+func (it *MapDBIterator) Seek(key []byte) bool {
+	it.pos = sort.Search(len(it.keys), func(i int) bool {
+		return bytes.Compare(it.keys[i], key) >= 0
+	})
+	return it.pos < len(it.keys)
+}
+
+func (it *MapDBIterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+func (it *MapDBIterator) Value() []byte {
+	k := it.Key()
+	if k == nil {
+		return nil
+	}
+	return it.values[string(k)]
+}
+
+func (it *MapDBIterator) Release() {
+	it.keys = nil
+	it.values = nil
+	it.pos = 0
+}
+
+func (it *MapDBIterator) Error() error {
+	return nil
+}
@@ -15,6 +15,9 @@ import (
 type MapDB struct {
 	Sem   sync.RWMutex
 	Cache map[string]map[string][]byte // Our Cache
+
+	// Buckets a snapshot still reads, they are copied before they are written to
+	shared map[string]bool
 }
 
 var _ interfaces.IDatabase = (*MapDB)(nil)
@@ -66,6 +69,7 @@ func (db *MapDB) Init(bucketList [][]byte) {
 	defer db.Sem.Unlock()
 
 	db.Cache = map[string]map[string][]byte{}
+	db.shared = nil
 	for _, v := range bucketList {
 		db.Cache[string(v)] = map[string][]byte{}
 	}
@@ -93,6 +97,7 @@ func (db *MapDB) rawPut(bucket, key []byte, data interfaces.BinaryMarshallable)
 	if ok == false {
 		db.Cache[string(bucket)] = map[string][]byte{}
 	}
+	db.ownBucket(bucket)
 	var hex []byte
 	var err error
 	if data != nil {
@@ -105,6 +110,26 @@ func (db *MapDB) rawPut(bucket, key []byte, data interfaces.BinaryMarshallable)
 	return nil
 }
 
+// ownBucket copies a bucket that is shared with a snapshot, so it can be written to without
+// changing what the snapshot reads. The lock has to be held.
+func (db *MapDB) ownBucket(bucket []byte) {
+	if db.shared[string(bucket)] == false {
+		return
+	}
+	old := db.Cache[string(bucket)]
+	b := make(map[string][]byte, len(old))
+	for k, v := range old {
+		b[k] = v
+	}
+	db.Cache[string(bucket)] = b
+	delete(db.shared, string(bucket))
+}
+
+// AtomicBatches is true, nothing of a MapDB is left after a crash
+func (db *MapDB) AtomicBatches() bool {
+	return true
+}
+
 func (db *MapDB) PutInBatch(records []interfaces.Record) error {
 	db.Sem.Lock()
 	defer db.Sem.Unlock()
@@ -156,6 +181,7 @@ func (db *MapDB) Delete(bucket, key []byte) error {
 	if ok == false {
 		db.Cache[string(bucket)] = map[string][]byte{}
 	}
+	db.ownBucket(bucket)
 	delete(db.Cache[string(bucket)], string(key))
 	return nil
 }
@@ -223,6 +249,7 @@ func (db *MapDB) Clear(bucket []byte) error {
 		db.Cache = map[string]map[string][]byte{}
 	}
 	delete(db.Cache, string(bucket))
+	delete(db.shared, string(bucket))
 	return nil
 }
 
@@ -0,0 +1,64 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package mapdb
+
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// MapDBSnapshot reads the buckets as they were when the snapshot was taken. The buckets are shared
+// with the database until it writes to them, so taking a snapshot only copies the list of buckets.
+type MapDBSnapshot struct {
+	db *MapDB
+}
+
+var _ interfaces.ISnapshot = (*MapDBSnapshot)(nil)
+
+func (db *MapDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	db.Sem.Lock()
+	defer db.Sem.Unlock()
+
+	if db.Cache == nil {
+		db.Cache = map[string]map[string][]byte{}
+	}
+	if db.shared == nil {
+		db.shared = map[string]bool{}
+	}
+
+	snap := new(MapDB)
+	snap.Cache = make(map[string]map[string][]byte, len(db.Cache))
+	for k, v := range db.Cache {
+		snap.Cache[k] = v
+		db.shared[k] = true
+	}
+
+	return &MapDBSnapshot{db: snap}, nil
+}
+
+func (s *MapDBSnapshot) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	return s.db.Get(bucket, key, destination)
+}
+
+func (s *MapDBSnapshot) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return s.db.ListAllKeys(bucket)
+}
+
+func (s *MapDBSnapshot) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	return s.db.GetAll(bucket, sample)
+}
+
+func (s *MapDBSnapshot) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return s.db.DoesKeyExist(bucket, key)
+}
+
+func (s *MapDBSnapshot) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return s.db.NewIterator(bucket, r)
+}
+
+// Release drops the snapshot's buckets, the database keeps copying buckets it shared with the
+// snapshot the first time it writes to them
+func (s *MapDBSnapshot) Release() {
+	s.db.Init(nil)
+}
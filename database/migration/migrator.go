@@ -0,0 +1,583 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FactomProject/factomd/anchor"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// Migrator copies a database into a database of another type while the node keeps running on
+// it. The writes of the node go through a MirrorDB, which writes them to both databases, while
+// the migrator copies the rest a batch at a time. Once everything is copied, the blocks of both
+// databases are compared height by height, and the target is marked complete so it can replace
+// the source on the next start.
+//
+// A migration that is stopped starts again from the beginning, keys the target already has are
+// read but not written again.
+type Migrator struct {
+	Mirror *MirrorDB
+
+	// BatchSize is the number of keys read from the source and written to the target at once
+	BatchSize int
+
+	mutex  sync.Mutex
+	status MigrationStatus
+}
+
+func NewMigrator(mirror *MirrorDB, sourceType, targetType string) *Migrator {
+	m := new(Migrator)
+	m.Mirror = mirror
+	m.BatchSize = 1000
+	m.status.SourceType = sourceType
+	m.status.TargetType = targetType
+	return m
+}
+
+// GetStatus returns a copy of the status of the migration
+func (m *Migrator) GetStatus() MigrationStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.status
+}
+
+func (m *Migrator) setStatus(f func(status *MigrationStatus)) error {
+	m.mutex.Lock()
+	f(&m.status)
+	status := m.status
+	m.mutex.Unlock()
+
+	return SaveStatus(m.Mirror.Target, &status)
+}
+
+// Run copies and verifies the database, it returns once the target is complete
+func (m *Migrator) Run() error {
+	err := m.setStatus(func(status *MigrationStatus) {
+		status.Phase = MigrationCopying
+		status.CopiedKeys = 0
+		status.VerifiedHeight = 0
+		status.Started = time.Now().Unix()
+		status.Finished = 0
+		status.LastError = ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = m.run()
+	if err != nil {
+		m.setStatus(func(status *MigrationStatus) {
+			status.Phase = MigrationFailed
+			status.LastError = err.Error()
+		})
+		return err
+	}
+
+	return m.setStatus(func(status *MigrationStatus) {
+		status.Phase = MigrationComplete
+		status.Finished = time.Now().Unix()
+	})
+}
+
+func (m *Migrator) run() error {
+	err := m.Copy()
+	if err != nil {
+		return err
+	}
+	err = m.setStatus(func(status *MigrationStatus) {
+		status.Phase = MigrationVerifying
+	})
+	if err != nil {
+		return err
+	}
+	return m.Verify()
+}
+
+// Copy brings every bucket of the target up to date with the source. Buckets only the target has
+// are copied too, which empties them.
+func (m *Migrator) Copy() error {
+	m.Mirror.StartTracking()
+	defer m.Mirror.StopTracking()
+
+	buckets, err := m.buckets()
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range buckets {
+		err = m.copyBucket(bucket)
+		if err != nil {
+			return fmt.Errorf("Error copying bucket %x: %v", bucket, err)
+		}
+	}
+	return nil
+}
+
+// buckets returns the buckets of the source and the buckets only the target has
+func (m *Migrator) buckets() ([][]byte, error) {
+	buckets, err := Buckets(m.Mirror.Source)
+	if err != nil {
+		return nil, err
+	}
+	targetBuckets, err := Buckets(m.Mirror.Target)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, bucket := range buckets {
+		known[string(bucket)] = true
+	}
+	for _, bucket := range targetBuckets {
+		if known[string(bucket)] == false {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets, nil
+}
+
+// copyBucket walks the bucket a batch of keys at a time, no iterator is kept open while the target
+// is written to. Every batch covers the keys from start up to the first key of the next batch, the
+// keys of the target in that range are read so only what changed is written.
+func (m *Migrator) copyBucket(bucket []byte) error {
+	var start []byte
+	for {
+		records, next, err := readBatch(m.Mirror.Source, bucket, start, m.BatchSize)
+		if err != nil {
+			return err
+		}
+		existing, err := readRange(m.Mirror.Target, bucket, start, next)
+		if err != nil {
+			return err
+		}
+
+		changed := make([]interfaces.Record, 0, len(records))
+		for _, r := range records {
+			v, ok := existing[string(r.Key)]
+			if ok {
+				delete(existing, string(r.Key))
+			}
+			if ok == false || bytes.Equal(v, r.Data.(*primitives.ByteSlice).Bytes) == false {
+				changed = append(changed, r)
+			}
+		}
+		stale := make([][]byte, 0, len(existing))
+		for k := range existing {
+			stale = append(stale, []byte(k))
+		}
+
+		count, err := m.Mirror.applyCopy(bucket, changed, stale)
+		if err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		m.status.CopiedKeys += uint64(count)
+		m.mutex.Unlock()
+
+		if next == nil {
+			return nil
+		}
+		start = next
+	}
+}
+
+// readBatch returns up to size records of the bucket from start on, and the key after the last
+// record, or nil if it is the end of the bucket
+func readBatch(db interfaces.IDatabase, bucket, start []byte, size int) ([]interfaces.Record, []byte, error) {
+	it, err := db.NewIterator(bucket, &interfaces.IteratorRange{Start: start})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Release()
+
+	records := []interfaces.Record{}
+	for it.Next() {
+		key := copyBytes(it.Key())
+		if len(records) == size {
+			return records, key, nil
+		}
+		records = append(records, interfaces.Record{Bucket: bucket, Key: key, Data: &primitives.ByteSlice{Bytes: copyBytes(it.Value())}})
+	}
+	return records, nil, it.Error()
+}
+
+// readRange returns the values of the keys >= start and < limit, a nil limit is the end of the bucket
+func readRange(db interfaces.IDatabase, bucket, start, limit []byte) (map[string][]byte, error) {
+	it, err := db.NewIterator(bucket, &interfaces.IteratorRange{Start: start, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	values := map[string][]byte{}
+	for it.Next() {
+		values[string(it.Key())] = copyBytes(it.Value())
+	}
+	return values, it.Error()
+}
+
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// Buckets returns every bucket of the database. LevelDB can't list its buckets, the list is then
+// made of the buckets of the overlay and the anchorer, the buckets of every entry chain and the
+// buckets of the address index. Those buckets must hold every key of the database, or keys would be
+// left behind, and a database whose buckets can't all be listed is not migrated.
+func Buckets(db interfaces.IDatabase) ([][]byte, error) {
+	buckets, err := db.ListAllBuckets()
+	if err != nil {
+		buckets, err = scanBuckets(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	answer := make([][]byte, 0, len(buckets))
+	for _, bucket := range buckets {
+		if bytes.Equal(bucket, DB_MIGRATION) == false {
+			answer = append(answer, bucket)
+		}
+	}
+	return answer, nil
+}
+
+// bucketScanner is a database that can't list its buckets, but finds the buckets whose names have
+// a known length and counts its keys, as LevelDB does
+type bucketScanner interface {
+	ListBucketsWithPrefix(prefix []byte, nameLength int) ([][]byte, error)
+	CountAllKeys() (uint64, error)
+}
+
+// scanBuckets lists the buckets factomd writes to in a snapshot of the database, and checks their
+// keys add up to every key of the snapshot
+func scanBuckets(db interfaces.IDatabase) ([][]byte, error) {
+	snapshot, err := db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+	scanner, ok := snapshot.(bucketScanner)
+	if ok == false {
+		return nil, fmt.Errorf("Can't list the buckets of a database of type %T", db)
+	}
+
+	buckets := [][]byte{anchor.ANCHOR_JOBS, anchor.ANCHOR_OPEN_JOBS, DB_MIGRATION}
+	for k := range databaseOverlay.ConstantNamesMap {
+		buckets = append(buckets, []byte(k))
+	}
+	chainIDs, err := snapshot.ListAllKeys(databaseOverlay.CHAIN_HEAD)
+	if err != nil {
+		return nil, err
+	}
+	for _, chainID := range chainIDs {
+		buckets = append(buckets, chainID)
+		buckets = append(buckets, append(append([]byte{}, databaseOverlay.ENTRYBLOCK_CHAIN_NUMBER...), chainID...))
+	}
+	addresses, err := scanner.ListBucketsWithPrefix(databaseOverlay.ADDRESS_TRANSACTIONS, 32)
+	if err != nil {
+		return nil, err
+	}
+	buckets = append(buckets, addresses...)
+
+	var listed uint64
+	for _, bucket := range buckets {
+		count, err := countKeys(snapshot, bucket)
+		if err != nil {
+			return nil, err
+		}
+		listed += count
+	}
+	total, err := scanner.CountAllKeys()
+	if err != nil {
+		return nil, err
+	}
+	if listed != total {
+		return nil, fmt.Errorf("The buckets known to factomd hold %v of the %v keys of the database, the database can't be migrated without losing keys", listed, total)
+	}
+	return buckets, nil
+}
+
+// keyCounter is what databases and their snapshots have in common to count keys with
+type keyCounter interface {
+	NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error)
+}
+
+func countKeys(db keyCounter, bucket []byte) (uint64, error) {
+	it, err := db.NewIterator(bucket, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Release()
+
+	var count uint64
+	for it.Next() {
+		count++
+	}
+	return count, it.Error()
+}
+
+// Verify compares the blocks of both databases from the genesis block to the current head, and
+// the number of keys of every bucket
+func (m *Migrator) Verify() error {
+	err := m.verifyBlocks()
+	if err != nil {
+		return err
+	}
+	return m.verifyKeyCounts()
+}
+
+func (m *Migrator) verifyBlocks() error {
+	source := databaseOverlay.NewOverlay(m.Mirror.Source)
+	target := databaseOverlay.NewOverlay(m.Mirror.Target)
+
+	head, err := source.FetchDBlockHead()
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return nil
+	}
+
+	for height := uint32(0); height <= head.GetDatabaseHeight(); height++ {
+		err = m.Mirror.Failed()
+		if err != nil {
+			return err
+		}
+		err = VerifyHeight(source, target, height)
+		if err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		m.status.VerifiedHeight = height
+		m.mutex.Unlock()
+	}
+	return nil
+}
+
+// verifyKeyCounts checks every bucket has as many keys in the target as in the source, which
+// covers the buckets no block is read from, such as the address index
+func (m *Migrator) verifyKeyCounts() error {
+	buckets, err := m.buckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		err = m.Mirror.Failed()
+		if err != nil {
+			return err
+		}
+		count, targetCount, err := m.Mirror.countKeys(bucket)
+		if err != nil {
+			return err
+		}
+		if count != targetCount {
+			return fmt.Errorf("Bucket %x has %v keys in the target instead of %v", bucket, targetCount, count)
+		}
+	}
+	return nil
+}
+
+// VerifyHeight checks the target has the directory block of the height, with the KeyMR of the
+// source, and every block it lists, with the same content as in the source
+func VerifyHeight(source, target interfaces.DBOverlay, height uint32) error {
+	keyMR, err := source.FetchDBKeyMRByHeight(height)
+	if err != nil {
+		return err
+	}
+	if keyMR == nil {
+		return fmt.Errorf("Directory block %v is missing from the source", height)
+	}
+	dBlock, err := target.FetchDBlockByHeight(height)
+	if err != nil {
+		return err
+	}
+	if dBlock == nil {
+		return fmt.Errorf("Directory block %v is missing from the target", height)
+	}
+	// The KeyMR is computed from the block read back from the target
+	if dBlock.GetKeyMR().IsSameAs(keyMR) == false {
+		return fmt.Errorf("Directory block %v has KeyMR %v in the target instead of %v", height, dBlock.GetKeyMR(), keyMR)
+	}
+
+	for _, e := range dBlock.GetDBEntries() {
+		chainID := e.GetChainID().Bytes()
+		switch {
+		case bytes.Equal(chainID, constants.ADMIN_CHAINID):
+			a, err := source.FetchABlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			b, err := target.FetchABlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			err = compareBlocks("Admin", height, e.GetKeyMR(), a, b)
+			if err != nil {
+				return err
+			}
+		case bytes.Equal(chainID, constants.EC_CHAINID):
+			a, err := source.FetchECBlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			b, err := target.FetchECBlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			err = compareBlocks("Entry credit", height, e.GetKeyMR(), a, b)
+			if err != nil {
+				return err
+			}
+		case bytes.Equal(chainID, constants.FACTOID_CHAINID):
+			a, err := source.FetchFBlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			b, err := target.FetchFBlock(e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+			err = compareBlocks("Factoid", height, e.GetKeyMR(), a, b)
+			if err != nil {
+				return err
+			}
+		default:
+			err = verifyEBlock(source, target, height, e.GetKeyMR())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyEBlock checks the KeyMR of the entry block read back from the target, and that the target
+// has every entry of it the source has
+func verifyEBlock(source, target interfaces.DBOverlay, height uint32, keyMR interfaces.IHash) error {
+	a, err := source.FetchEBlock(keyMR)
+	if err != nil {
+		return err
+	}
+	b, err := target.FetchEBlock(keyMR)
+	if err != nil {
+		return err
+	}
+	err = compareBlocks("Entry", height, keyMR, a, b)
+	if err != nil || b == nil {
+		return err
+	}
+	bKeyMR, err := b.KeyMR()
+	if err != nil {
+		return err
+	}
+	if bKeyMR.IsSameAs(keyMR) == false {
+		return fmt.Errorf("Entry block %v of directory block %v has KeyMR %v in the target", keyMR, height, bKeyMR)
+	}
+
+	for _, hash := range b.GetEntryHashes() {
+		if hash.IsMinuteMarker() {
+			continue
+		}
+		inSource, err := source.DoesKeyExist(databaseOverlay.ENTRY, hash.Bytes())
+		if err != nil {
+			return err
+		}
+		if inSource == false {
+			continue
+		}
+		inTarget, err := target.DoesKeyExist(databaseOverlay.ENTRY, hash.Bytes())
+		if err != nil {
+			return err
+		}
+		if inTarget == false {
+			return fmt.Errorf("Entry %v of directory block %v is missing from the target", hash, height)
+		}
+	}
+	return nil
+}
+
+// compareBlocks checks the target has the block if the source has it, with the same content
+func compareBlocks(kind string, height uint32, keyMR interfaces.IHash, a, b interfaces.BinaryMarshallable) error {
+	if a == nil {
+		return nil
+	}
+	if b == nil {
+		return fmt.Errorf("%v block %v of directory block %v is missing from the target", kind, keyMR, height)
+	}
+	aData, err := a.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	bData, err := b.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(aData, bData) == false {
+		return fmt.Errorf("%v block %v of directory block %v differs in the target", kind, keyMR, height)
+	}
+	return nil
+}
+
+// CanSwitch returns true if the target can replace the source: a migration into it completed and
+// it has the head of the source, or the node was switched to it already
+func CanSwitch(source, target interfaces.IDatabase) (bool, error) {
+	status, err := LoadStatus(target)
+	if err != nil {
+		return false, err
+	}
+	if status == nil {
+		return false, nil
+	}
+	if status.Phase == MigrationSwitched {
+		return true, nil
+	}
+	if status.Phase != MigrationComplete {
+		return false, nil
+	}
+
+	// The node kept writing to both databases after the migration completed, a write the target
+	// missed shows in its head
+	sourceOverlay := databaseOverlay.NewOverlay(source)
+	targetOverlay := databaseOverlay.NewOverlay(target)
+	head, err := sourceOverlay.FetchDBlockHead()
+	if err != nil {
+		return false, err
+	}
+	targetHead, err := targetOverlay.FetchDBlockHead()
+	if err != nil {
+		return false, err
+	}
+	if head == nil || targetHead == nil || head.GetKeyMR().IsSameAs(targetHead.GetKeyMR()) == false {
+		return false, nil
+	}
+	if VerifyHeight(sourceOverlay, targetOverlay, head.GetDatabaseHeight()) != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// MarkSwitched records that the node runs on the target. The source stops being written to, so
+// from then on the target replaces it on every start without comparing them.
+func MarkSwitched(target interfaces.IDatabase) error {
+	status, err := LoadStatus(target)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		status = new(MigrationStatus)
+	}
+	status.Phase = MigrationSwitched
+	return SaveStatus(target, status)
+}
@@ -0,0 +1,231 @@
+package migration_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/leveldb"
+	"github.com/FactomProject/factomd/database/mapdb"
+	. "github.com/FactomProject/factomd/database/migration"
+	. "github.com/FactomProject/factomd/testHelper"
+)
+
+func TestMarshalUnmarshalMigrationStatus(t *testing.T) {
+	status := new(MigrationStatus)
+	status.SourceType = "LDB"
+	status.TargetType = "Bolt"
+	status.Phase = MigrationVerifying
+	status.CopiedKeys = 123456
+	status.VerifiedHeight = 42
+	status.Started = 1500000000
+	status.LastError = "error"
+
+	data, err := status.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	status2 := new(MigrationStatus)
+	rest, err := status2.UnmarshalBinaryData(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(rest) > 0 {
+		t.Errorf("Returned too much data")
+	}
+	if *status != *status2 {
+		t.Errorf("Statuses are not equal - %v vs %v", status, status2)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	source := CreateAndPopulateTestDatabaseOverlay()
+	target := new(mapdb.MapDB)
+	target.Init(nil)
+
+	// A key the source does not have is removed from the target
+	stale := []byte("stale")
+	err := target.Put(databaseOverlay.KEY_VALUE_STORE, stale, &primitives.ByteSlice{Bytes: stale})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	mirror := NewMirrorDB(source.DB, target)
+	migrator := NewMigrator(mirror, "Map", "Map")
+	migrator.BatchSize = 3
+
+	ok, err := CanSwitch(mirror.Source, target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok {
+		t.Errorf("Can switch before the migration ran")
+	}
+
+	err = migrator.Run()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	status, err := LoadStatus(target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if status == nil || status.Phase != MigrationComplete {
+		t.Fatalf("Expected a complete migration, got %v", status)
+	}
+	if status.VerifiedHeight != uint32(BlockCount-1) {
+		t.Errorf("Expected height %v to be verified, got %v", BlockCount-1, status.VerifiedHeight)
+	}
+
+	buckets, err := Buckets(mirror.Source)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, bucket := range buckets {
+		keys, err := mirror.Source.ListAllKeys(bucket)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		targetKeys, err := target.ListAllKeys(bucket)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(keys) != len(targetKeys) {
+			t.Errorf("Bucket %x has %v keys in the target instead of %v", bucket, len(targetKeys), len(keys))
+		}
+	}
+	exists, err := target.DoesKeyExist(databaseOverlay.KEY_VALUE_STORE, stale)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists {
+		t.Errorf("Stale key was not removed from the target")
+	}
+
+	ok, err = CanSwitch(mirror.Source, target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok == false {
+		t.Errorf("Can't switch after the migration completed")
+	}
+
+	// Writes through the mirror reach both databases
+	dbo := databaseOverlay.NewOverlay(mirror)
+	key := []byte("key")
+	err = dbo.Put(databaseOverlay.KEY_VALUE_STORE, key, &primitives.ByteSlice{Bytes: key})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exists, err = target.DoesKeyExist(databaseOverlay.KEY_VALUE_STORE, key)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists == false {
+		t.Errorf("Write through the mirror did not reach the target")
+	}
+
+	// A missing entry fails the verification
+	entries, err := target.ListAllKeys(databaseOverlay.ENTRY)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatalf("No entries in the target")
+	}
+	err = target.Delete(databaseOverlay.ENTRY, entries[0])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = migrator.Verify()
+	if err == nil {
+		t.Errorf("Verification passed with a missing entry")
+	}
+
+	// A head the target does not have keeps the node on the source
+	head, err := source.FetchDBlockHead()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = target.Delete(databaseOverlay.DIRECTORYBLOCK, head.DatabasePrimaryIndex().Bytes())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ok, err = CanSwitch(mirror.Source, target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok {
+		t.Errorf("Can switch to a target without the head of the source")
+	}
+
+	// Once switched, the target is used without comparing it to the source
+	err = MarkSwitched(target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ok, err = CanSwitch(mirror.Source, target)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ok == false {
+		t.Errorf("Can't switch to a target the node was switched to")
+	}
+}
+
+func TestMigratorLevelDB(t *testing.T) {
+	dbFilename := "migrationTest.db"
+	ldb, err := leveldb.NewLevelDB(dbFilename, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dbFilename)
+	defer ldb.Close()
+
+	source := databaseOverlay.NewOverlay(ldb)
+	PopulateTestDatabaseOverlay(source)
+	// LevelDB can't list its buckets, the address index is found by the length of the addresses
+	address := primitives.RandomHash().Bytes()
+	bucket := append(append([]byte{}, databaseOverlay.ADDRESS_TRANSACTIONS...), address...)
+	err = ldb.Put(bucket, []byte("key"), primitives.RandomHash())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	target := new(mapdb.MapDB)
+	target.Init(nil)
+	mirror := NewMirrorDB(ldb, target)
+	migrator := NewMigrator(mirror, "LDB", "Map")
+	err = migrator.Run()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exists, err := target.DoesKeyExist(bucket, []byte("key"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists == false {
+		t.Errorf("The address index was not migrated")
+	}
+
+	// A key the target is missing fails the verification
+	err = target.Delete(bucket, []byte("key"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = migrator.Verify()
+	if err == nil {
+		t.Errorf("Verification passed with a missing address index key")
+	}
+
+	// A key in a bucket factomd doesn't know keeps the database from being migrated
+	err = ldb.Put([]byte("unknown"), []byte("key"), primitives.RandomHash())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = Buckets(ldb)
+	if err == nil {
+		t.Errorf("Listed the buckets of a database with a bucket factomd doesn't know")
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// MirrorDB reads from the source database and writes to both databases, so the target keeps up
+// with the node while the migrator copies what the source held before.
+//
+// While tracking, the mirror remembers every key written or deleted, and the migrator leaves those
+// keys alone: the target already has what the node wrote, which is newer than what the migrator
+// read from the source.
+type MirrorDB struct {
+	Source interfaces.IDatabase
+	Target interfaces.IDatabase
+
+	mutex    sync.Mutex
+	tracking bool
+	dirty    map[[2]string]bool
+	cleared  map[string]bool
+	// failed is the first error writing to the target, the target is not written to after it
+	failed error
+}
+
+var _ interfaces.IDatabase = (*MirrorDB)(nil)
+
+func NewMirrorDB(source, target interfaces.IDatabase) *MirrorDB {
+	db := new(MirrorDB)
+	db.Source = source
+	db.Target = target
+	return db
+}
+
+// StartTracking forgets the keys written so far and remembers every key written from now on
+func (db *MirrorDB) StartTracking() {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.tracking = true
+	db.dirty = map[[2]string]bool{}
+	db.cleared = map[string]bool{}
+}
+
+func (db *MirrorDB) StopTracking() {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.tracking = false
+	db.dirty = nil
+	db.cleared = nil
+}
+
+// Failed returns the error that stopped the target from being written to, or nil
+func (db *MirrorDB) Failed() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.failed
+}
+
+func (db *MirrorDB) mark(bucket, key []byte) {
+	if db.tracking {
+		db.dirty[[2]string{string(bucket), string(key)}] = true
+	}
+}
+
+func (db *MirrorDB) isDirty(bucket, key []byte) bool {
+	return db.dirty[[2]string{string(bucket), string(key)}]
+}
+
+// mirror keeps the first error writing to the target
+func (db *MirrorDB) mirror(err error) {
+	if err != nil && db.failed == nil {
+		db.failed = err
+		fmt.Printf("Error writing to the database being migrated to, it is no longer kept up to date: %v\n", err)
+	}
+}
+
+// applyCopy writes what the migrator read from the source to the target, leaving out every key
+// the node wrote since tracking started. It returns how many keys were written or deleted.
+func (db *MirrorDB) applyCopy(bucket []byte, records []interfaces.Record, stale [][]byte) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.failed != nil {
+		return 0, db.failed
+	}
+
+	batch := make([]interfaces.Record, 0, len(records))
+	// A cleared bucket only has what was written after it was cleared, which is in the target
+	// already, anything read before the clear is out of date
+	if db.cleared[string(bucket)] == false {
+		for _, r := range records {
+			if db.isDirty(r.Bucket, r.Key) == false {
+				batch = append(batch, r)
+			}
+		}
+	}
+	if len(batch) > 0 {
+		err := db.Target.PutInBatch(batch)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count := len(batch)
+	for _, key := range stale {
+		if db.isDirty(bucket, key) {
+			continue
+		}
+		err := db.Target.Delete(bucket, key)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// countKeys returns the number of keys of the bucket in the source and in the target. They are
+// counted again with writes held off if the node wrote to the bucket while they were counted.
+func (db *MirrorDB) countKeys(bucket []byte) (uint64, uint64, error) {
+	count, targetCount, err := countBothKeys(db.Source, db.Target, bucket)
+	if err != nil || count == targetCount {
+		return count, targetCount, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return countBothKeys(db.Source, db.Target, bucket)
+}
+
+func countBothKeys(source, target interfaces.IDatabase, bucket []byte) (uint64, uint64, error) {
+	count, err := countKeys(source, bucket)
+	if err != nil {
+		return 0, 0, err
+	}
+	targetCount, err := countKeys(target, bucket)
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, targetCount, nil
+}
+
+/***************************************
+ *       Methods
+ ***************************************/
+
+func (db *MirrorDB) Put(bucket, key []byte, data interfaces.BinaryMarshallable) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err := db.Source.Put(bucket, key, data)
+	if err != nil {
+		return err
+	}
+	db.mark(bucket, key)
+	if db.failed == nil {
+		db.mirror(db.Target.Put(bucket, key, data))
+	}
+	return nil
+}
+
+func (db *MirrorDB) PutInBatch(records []interfaces.Record) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err := db.Source.PutInBatch(records)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		db.mark(r.Bucket, r.Key)
+	}
+	if db.failed == nil {
+		db.mirror(db.Target.PutInBatch(records))
+	}
+	return nil
+}
+
+func (db *MirrorDB) Delete(bucket, key []byte) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err := db.Source.Delete(bucket, key)
+	if err != nil {
+		return err
+	}
+	db.mark(bucket, key)
+	if db.failed == nil {
+		db.mirror(db.Target.Delete(bucket, key))
+	}
+	return nil
+}
+
+func (db *MirrorDB) Clear(bucket []byte) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err := db.Source.Clear(bucket)
+	if err != nil {
+		return err
+	}
+	if db.tracking {
+		db.cleared[string(bucket)] = true
+	}
+	if db.failed == nil {
+		db.mirror(db.Target.Clear(bucket))
+	}
+	return nil
+}
+
+func (db *MirrorDB) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	return db.Source.Get(bucket, key, destination)
+}
+
+func (db *MirrorDB) ListAllBuckets() ([][]byte, error) {
+	return db.Source.ListAllBuckets()
+}
+
+func (db *MirrorDB) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return db.Source.ListAllKeys(bucket)
+}
+
+func (db *MirrorDB) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	return db.Source.GetAll(bucket, sample)
+}
+
+func (db *MirrorDB) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return db.Source.DoesKeyExist(bucket, key)
+}
+
+func (db *MirrorDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	return db.Source.NewIterator(bucket, r)
+}
+
+func (db *MirrorDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	return db.Source.GetSnapshot()
+}
+
+func (db *MirrorDB) Trim() {
+	db.Source.Trim()
+	db.Target.Trim()
+}
+
+func (db *MirrorDB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err := db.Target.Close()
+	err2 := db.Source.Close()
+	if err != nil {
+		return err
+	}
+	return err2
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// DB_MIGRATION is the bucket of the target database a migration keeps its status in. It is never
+// copied, so a database that was the target of one migration can be the source of the next.
+var DB_MIGRATION = []byte("DBMigration")
+
+var statusKey = []byte("Status")
+
+const (
+	// MigrationCopying is copying every bucket of the source
+	MigrationCopying = iota
+	// MigrationVerifying is comparing the blocks of both databases height by height
+	MigrationVerifying
+	// MigrationComplete has a target that can replace the source on the next start
+	MigrationComplete
+	// MigrationSwitched has a target the node runs on, the source is no longer written to
+	MigrationSwitched
+	// MigrationFailed stopped with LastError, the next start begins again
+	MigrationFailed
+)
+
+var migrationPhaseNames = []string{"copying", "verifying", "complete", "switched", "failed"}
+
+// MigrationPhaseName returns the name of a migration phase
+func MigrationPhaseName(phase int) string {
+	if phase < 0 || phase >= len(migrationPhaseNames) {
+		return "unknown"
+	}
+	return migrationPhaseNames[phase]
+}
+
+// MigrationStatus tracks copying one database into another
+type MigrationStatus struct {
+	SourceType string
+	TargetType string
+
+	Phase          int
+	CopiedKeys     uint64
+	VerifiedHeight uint32
+	Started        int64 // Unix time in seconds
+	Finished       int64 // Unix time in seconds
+	LastError      string
+}
+
+var _ interfaces.BinaryMarshallable = (*MigrationStatus)(nil)
+
+// LoadStatus returns the status of the migration into the database, or nil if there was none
+func LoadStatus(db interfaces.IDatabase) (*MigrationStatus, error) {
+	status, err := db.Get(DB_MIGRATION, statusKey, new(MigrationStatus))
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, nil
+	}
+	return status.(*MigrationStatus), nil
+}
+
+// SaveStatus writes the status of the migration into the database
+func SaveStatus(db interfaces.IDatabase, status *MigrationStatus) error {
+	return db.Put(DB_MIGRATION, statusKey, status)
+}
+
+func (e *MigrationStatus) PhaseName() string {
+	return MigrationPhaseName(e.Phase)
+}
+
+func (e *MigrationStatus) String() string {
+	str := fmt.Sprintf("%v to %v: %v, %v keys copied", e.SourceType, e.TargetType, e.PhaseName(), e.CopiedKeys)
+	if e.Phase >= MigrationVerifying {
+		str = fmt.Sprintf("%v, verified to height %v", str, e.VerifiedHeight)
+	}
+	if e.LastError != "" {
+		str = fmt.Sprintf("%v, error: %v", str, e.LastError)
+	}
+	return str
+}
+
+func (e *MigrationStatus) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+
+	err := buf.PushString(e.SourceType)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushString(e.TargetType)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushVarInt(uint64(e.Phase))
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushVarInt(e.CopiedKeys)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushUInt32(e.VerifiedHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushInt64(e.Started)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushInt64(e.Finished)
+	if err != nil {
+		return nil, err
+	}
+	err = buf.PushString(e.LastError)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (e *MigrationStatus) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling MigrationStatus: %v", r)
+		}
+	}()
+	buf := primitives.NewBuffer(data)
+
+	e.SourceType, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+	e.TargetType, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+	phase, err := buf.PopVarInt()
+	if err != nil {
+		return nil, err
+	}
+	e.Phase = int(phase)
+	e.CopiedKeys, err = buf.PopVarInt()
+	if err != nil {
+		return nil, err
+	}
+	e.VerifiedHeight, err = buf.PopUInt32()
+	if err != nil {
+		return nil, err
+	}
+	e.Started, err = buf.PopInt64()
+	if err != nil {
+		return nil, err
+	}
+	e.Finished, err = buf.PopInt64()
+	if err != nil {
+		return nil, err
+	}
+	e.LastError, err = buf.PopString()
+	if err != nil {
+		return nil, err
+	}
+
+	newData = buf.DeepCopyBytes()
+	return
+}
+
+func (e *MigrationStatus) UnmarshalBinary(data []byte) error {
+	_, err := e.UnmarshalBinaryData(data)
+	return err
+}
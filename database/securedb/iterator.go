@@ -0,0 +1,93 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package securedb
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// EncryptedDBIterator walks the underlying database and decrypts the values. Keys are not
+// encrypted, so the range is passed down as is.
+type EncryptedDBIterator struct {
+	iter          interfaces.IIterator
+	encryptionkey []byte
+
+	value []byte
+	err   error
+}
+
+var _ interfaces.IIterator = (*EncryptedDBIterator)(nil)
+
+func (db *EncryptedDB) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	iter, err := db.db.NewIterator(bucket, r)
+	if err != nil {
+		return nil, err
+	}
+	it := new(EncryptedDBIterator)
+	it.iter = iter
+	it.encryptionkey = db.encryptionkey
+	return it, nil
+}
+
+// decryptValue reverses EncryptedMarshaler.MarshalBinary
+func decryptValue(cipherData []byte, key []byte) ([]byte, error) {
+	if len(cipherData) < 4 {
+		return nil, fmt.Errorf("Encrypted value is too short")
+	}
+	l, err := bytesToUint32(cipherData[:4])
+	if err != nil {
+		return nil, err
+	}
+	if int(l) > len(cipherData)-4 {
+		return nil, fmt.Errorf("Encrypted value is too short")
+	}
+	return Decrypt(cipherData[4:l+4], key)
+}
+
+func (it *EncryptedDBIterator) Next() bool {
+	it.value = nil
+	return it.iter.Next()
+}
+
+func (it *EncryptedDBIterator) Seek(key []byte) bool {
+	it.value = nil
+	return it.iter.Seek(key)
+}
+
+func (it *EncryptedDBIterator) Key() []byte {
+	return it.iter.Key()
+}
+
+// Value returns nil if the value can't be decrypted, the error is returned by Error
+func (it *EncryptedDBIterator) Value() []byte {
+	if it.value != nil {
+		return it.value
+	}
+	cipherData := it.iter.Value()
+	if cipherData == nil {
+		return nil
+	}
+	value, err := decryptValue(cipherData, it.encryptionkey)
+	if err != nil {
+		it.err = err
+		return nil
+	}
+	it.value = value
+	return value
+}
+
+func (it *EncryptedDBIterator) Release() {
+	it.iter.Release()
+	it.value = nil
+}
+
+func (it *EncryptedDBIterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.iter.Error()
+}
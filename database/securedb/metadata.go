@@ -10,17 +10,45 @@ import (
 
 var _ = fmt.Sprint
 
+// SecureDBMetaDataVersion is the version new metadata is written with. Version 0 is the original
+// format, the salt and the challenge only.
+const SecureDBMetaDataVersion = 1
+
+// versionMarker starts every metadata from version 1 on. Version 0 starts with the length of the
+// salt, which is never this large.
+const versionMarker = 0xFFFFFFFF
+
 type SecureDBMetaData struct {
+	Version uint32
+
 	Salt      primitives.ByteSlice
 	Challenge primitives.ByteSlice
+
+	// NextSalt and NextChallenge are set while the database is re-encrypted with a new key
+	NextSalt      primitives.ByteSlice
+	NextChallenge primitives.ByteSlice
+
+	// BucketsComplete is true if the database kept the list of its buckets since it was made, so
+	// every value can be found even when the underlying database can't list its buckets
+	BucketsComplete bool
 }
 
 func NewSecureDBMetaData() *SecureDBMetaData {
 	s := new(SecureDBMetaData)
+	s.Version = SecureDBMetaDataVersion
 	return s
 }
 
+// IsRotating returns true if a key rotation was started and did not finish
+func (m SecureDBMetaData) IsRotating() bool {
+	return len(m.NextSalt.Bytes) > 0
+}
+
 func (m *SecureDBMetaData) IsSameAs(b *SecureDBMetaData) bool {
+	if m.Version != b.Version {
+		return false
+	}
+
 	if !m.Salt.IsSameAs(&b.Salt) {
 		return false
 	}
@@ -29,6 +57,18 @@ func (m *SecureDBMetaData) IsSameAs(b *SecureDBMetaData) bool {
 		return false
 	}
 
+	if !m.NextSalt.IsSameAs(&b.NextSalt) {
+		return false
+	}
+
+	if !m.NextChallenge.IsSameAs(&b.NextChallenge) {
+		return false
+	}
+
+	if m.BucketsComplete != b.BucketsComplete {
+		return false
+	}
+
 	return true
 }
 
@@ -46,41 +86,104 @@ func (m *SecureDBMetaData) UnmarshalBinaryData(data []byte) (newData []byte, err
 
 	newData = data
 
-	slen, err := bytesToUint32(newData[:4])
+	m.Version = 0
+	m.NextSalt.Bytes = nil
+	m.NextChallenge.Bytes = nil
+	m.BucketsComplete = false
+
+	if len(newData) < 4 {
+		return nil, fmt.Errorf("Metadata of %v bytes is truncated", len(data))
+	}
+	marker, err := bytesToUint32(newData[:4])
+	if err != nil {
+		return nil, err
+	}
+	if marker == versionMarker {
+		if len(newData) < 8 {
+			return nil, fmt.Errorf("Metadata of %v bytes is truncated before its version", len(data))
+		}
+		m.Version, err = bytesToUint32(newData[4:8])
+		if err != nil {
+			return nil, err
+		}
+		if m.Version > SecureDBMetaDataVersion {
+			return nil, fmt.Errorf("Metadata version %v is newer than this version of factomd supports", m.Version)
+		}
+		newData = newData[8:]
+	}
+
+	m.Salt.Bytes, newData, err = popLengthPrefixed(newData)
 	if err != nil {
 		return nil, err
 	}
-	saltData := newData[4 : slen+4]
-	m.Salt.Bytes = saltData
-	newData = newData[slen+4:]
+	m.Challenge.Bytes, newData, err = popLengthPrefixed(newData)
+	if err != nil {
+		return nil, err
+	}
+	if m.Version == 0 {
+		return
+	}
 
-	clen, err := bytesToUint32(newData[:4])
+	m.NextSalt.Bytes, newData, err = popLengthPrefixed(newData)
+	if err != nil {
+		return nil, err
+	}
+	m.NextChallenge.Bytes, newData, err = popLengthPrefixed(newData)
 	if err != nil {
 		return nil, err
 	}
-	challengeData := newData[4 : clen+4]
-	m.Challenge.Bytes = challengeData
-	newData = newData[clen+4:]
+	if len(newData) < 1 {
+		return nil, fmt.Errorf("Metadata of %v bytes is truncated before its bucket state", len(data))
+	}
+	m.BucketsComplete = newData[0] == 1
+	newData = newData[1:]
 
 	return
 }
 
+// popLengthPrefixed splits a slice with a 4 byte length in front of it off the data
+func popLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("Metadata is truncated before the length of a field")
+	}
+	l, err := bytesToUint32(data[:4])
+	if err != nil {
+		return nil, nil, err
+	}
+	if uint64(l)+4 > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("Metadata field of %v bytes is truncated to %v", l, len(data)-4)
+	}
+	return data[4 : l+4], data[l+4:], nil
+}
+
 func (m *SecureDBMetaData) MarshalBinary() ([]byte, error) {
 	buf := primitives.NewBuffer(nil)
 
-	buf.Write(intToBytes(len(m.Salt.Bytes)))
-	data, err := m.Salt.MarshalBinary()
-	if err != nil {
-		return nil, err
+	if m.Version > 0 {
+		buf.Write(uint32ToBytes(versionMarker))
+		buf.Write(uint32ToBytes(m.Version))
 	}
-	buf.Write(data)
 
-	buf.Write(intToBytes(len(m.Challenge.Bytes)))
-	data, err = m.Challenge.MarshalBinary()
-	if err != nil {
-		return nil, err
+	slices := []*primitives.ByteSlice{&m.Salt, &m.Challenge}
+	if m.Version > 0 {
+		slices = append(slices, &m.NextSalt, &m.NextChallenge)
+	}
+	for _, s := range slices {
+		buf.Write(intToBytes(len(s.Bytes)))
+		data, err := s.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+
+	if m.Version > 0 {
+		if m.BucketsComplete {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
 	}
-	buf.Write(data)
 
 	return buf.DeepCopyBytes(), nil
 }
@@ -92,8 +195,12 @@ func bytesToUint32(data []byte) (ret uint32, err error) {
 }
 
 func intToBytes(val int) []byte {
+	return uint32ToBytes(uint32(val))
+}
+
+func uint32ToBytes(val uint32) []byte {
 	b := make([]byte, 4)
-	binary.BigEndian.PutUint32(b, uint32(val))
+	binary.BigEndian.PutUint32(b, val)
 
 	return b
 }
@@ -38,3 +38,56 @@ func TestSecureDBMetaData(t *testing.T) {
 		}
 	}
 }
+
+func TestSecureDBMetaDataVersion1(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		m := NewSecureDBMetaData()
+		m.Salt.Bytes = random.RandByteSlice()
+		m.Challenge.Bytes = random.RandByteSlice()
+		if i%2 == 0 {
+			m.NextSalt.Bytes = random.RandByteSlice()
+			m.NextChallenge.Bytes = random.RandByteSlice()
+		}
+		m.BucketsComplete = i%3 == 0
+
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Error(err)
+		}
+
+		m2 := new(SecureDBMetaData)
+		nd, err := m2.UnmarshalBinaryData(data)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(nd) != 0 {
+			t.Errorf("Should have 0 bytes left, found %d", len(nd))
+		}
+
+		if !m.IsSameAs(m2) {
+			t.Errorf("Not same %v | %v", m, m2)
+		}
+	}
+}
+
+func TestSecureDBMetaDataTruncated(t *testing.T) {
+	for _, m := range []*SecureDBMetaData{new(SecureDBMetaData), NewSecureDBMetaData()} {
+		m.Salt.Bytes = random.RandByteSlice()
+		m.Challenge.Bytes = random.RandByteSlice()
+		m.NextSalt.Bytes = random.RandByteSlice()
+		m.NextChallenge.Bytes = random.RandByteSlice()
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every truncation is an error, as after a crash in the middle of writing the metadata
+		for i := 0; i < len(data); i++ {
+			m2 := new(SecureDBMetaData)
+			_, err = m2.UnmarshalBinaryData(data[:i])
+			if err == nil {
+				t.Errorf("Unmarshalled metadata truncated to %v of %v bytes", i, len(data))
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package securedb
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// rotationBatchSize is the number of values re-encrypted at once
+const rotationBatchSize = 1000
+
+// RotateKey re-encrypts every value of the database in place, from the key made from secret to
+// the key made from newSecret, and returns the database opened with the new key.
+//
+// The new salt and challenge are written to the metadata before any value, so an interrupted
+// rotation is resumed by calling RotateKey again with the same secrets. Values already
+// re-encrypted are recognised by decrypting with the new key. Once the rotation is done, calling
+// RotateKey again opens the database with the new key.
+func RotateKey(db interfaces.IDatabase, secret, newSecret string) (*EncryptedDB, error) {
+	e := new(EncryptedDB)
+	e.db = db
+	e.buckets = map[string]bool{}
+
+	exists, err := e.loadMetaData()
+	if err != nil {
+		return nil, err
+	}
+	if exists == false || len(e.metadata.Challenge.Bytes) == 0 {
+		// Nothing to re-encrypt
+		return OpenEncryptedDB(db, newSecret)
+	}
+
+	if e.metadata.IsRotating() == false {
+		newKey, err := GetKey(newSecret, e.metadata.Salt.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if checkChallenge(e.metadata.Challenge.Bytes, newKey) == nil {
+			// Rotated already
+			return OpenEncryptedDB(db, newSecret)
+		}
+	}
+
+	oldKey, err := GetKey(secret, e.metadata.Salt.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	err = checkChallenge(e.metadata.Challenge.Bytes, oldKey)
+	if err != nil {
+		return nil, err
+	}
+	e.encryptionkey = oldKey
+	err = e.upgradeMetaData()
+	if err != nil {
+		return nil, err
+	}
+	if e.metadata.BucketsComplete == false {
+		return nil, fmt.Errorf("Can't rotate the key, the database was made by an older version and does not know all of its buckets")
+	}
+
+	var newKey []byte
+	if e.metadata.IsRotating() {
+		newKey, err = GetKey(newSecret, e.metadata.NextSalt.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		err = checkChallenge(e.metadata.NextChallenge.Bytes, newKey)
+		if err != nil {
+			return nil, fmt.Errorf("The new password is not the one the interrupted key rotation was started with")
+		}
+	} else {
+		e.metadata.NextSalt.Bytes = newSalt()
+		newKey, err = GetKey(newSecret, e.metadata.NextSalt.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		e.metadata.NextChallenge.Bytes, err = Encrypt(challenge, newKey)
+		if err != nil {
+			return nil, err
+		}
+		err = e.saveMetaData()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buckets, err := e.ListAllBuckets()
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets {
+		err = e.reencryptBucket(bucket, oldKey, newKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	e.metadata.Salt = e.metadata.NextSalt
+	e.metadata.Challenge = e.metadata.NextChallenge
+	e.metadata.NextSalt = primitives.ByteSlice{}
+	e.metadata.NextChallenge = primitives.ByteSlice{}
+	err = e.saveMetaData()
+	if err != nil {
+		return nil, err
+	}
+	e.encryptionkey = newKey
+	return e, nil
+}
+
+// reencryptBucket walks the bucket a batch at a time, no iterator is kept open while writing
+func (db *EncryptedDB) reencryptBucket(bucket []byte, oldKey, newKey []byte) error {
+	var start []byte
+	for {
+		it, err := db.db.NewIterator(bucket, &interfaces.IteratorRange{Start: start})
+		if err != nil {
+			return err
+		}
+		batch := []interfaces.Record{}
+		start = nil
+		for it.Next() {
+			if len(batch) == rotationBatchSize {
+				start = append([]byte{}, it.Key()...)
+				break
+			}
+			cipherData := it.Value()
+			if _, err := decryptValue(cipherData, newKey); err == nil {
+				continue
+			}
+			plainData, err := decryptValue(cipherData, oldKey)
+			if err != nil {
+				it.Release()
+				return fmt.Errorf("Can't decrypt key %x of bucket %x: %v", it.Key(), bucket, err)
+			}
+			cipherData, err = Encrypt(plainData, newKey)
+			if err != nil {
+				it.Release()
+				return err
+			}
+			value := append(intToBytes(len(cipherData)), cipherData...)
+			key := append([]byte{}, it.Key()...)
+			batch = append(batch, interfaces.Record{Bucket: bucket, Key: key, Data: &primitives.ByteSlice{Bytes: value}})
+		}
+		err = it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+
+		if len(batch) > 0 {
+			err = db.db.PutInBatch(batch)
+			if err != nil {
+				return err
+			}
+		}
+		if start == nil {
+			return nil
+		}
+	}
+}
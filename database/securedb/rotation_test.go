@@ -0,0 +1,66 @@
+package securedb_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/mapdb"
+	. "github.com/FactomProject/factomd/database/securedb"
+)
+
+func TestRotateKey(t *testing.T) {
+	m := new(mapdb.MapDB)
+	m.Init(nil)
+
+	s, err := OpenEncryptedDB(m, "oldPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buckets := [][]byte{[]byte("one"), []byte("two")}
+	for _, bucket := range buckets {
+		for i := 0; i < 10; i++ {
+			err = s.Put(bucket, []byte{byte(i)}, &primitives.ByteSlice{Bytes: []byte{byte(i), byte(i)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	s, err = RotateKey(m, "oldPassword", "newPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetMetaData().IsRotating() {
+		t.Error("The rotation did not finish")
+	}
+
+	_, err = OpenEncryptedDB(m, "oldPassword")
+	if err == nil {
+		t.Error("The old password should not open the database")
+	}
+	s, err = OpenEncryptedDB(m, "newPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bucket := range buckets {
+		for i := 0; i < 10; i++ {
+			v, err := s.Get(bucket, []byte{byte(i)}, new(primitives.ByteSlice))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v == nil || primitives.AreBytesEqual(v.(*primitives.ByteSlice).Bytes, []byte{byte(i), byte(i)}) == false {
+				t.Errorf("Wrong value for key %v of bucket %s: %v", i, bucket, v)
+			}
+		}
+	}
+
+	// Rotating again with the same passwords opens the database
+	_, err = RotateKey(m, "oldPassword", "newPassword")
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = RotateKey(m, "wrongPassword", "otherPassword")
+	if err == nil {
+		t.Error("Should error on the wrong password")
+	}
+}
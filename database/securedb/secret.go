@@ -0,0 +1,45 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package securedb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ReadSecret returns the database secret from the key file, or from the environment variable if
+// no key file is given. The key file must not be readable by other users. Whitespace around the
+// secret is dropped, so the file may end with a newline.
+func ReadSecret(keyFile, envVar string) (string, error) {
+	if keyFile != "" {
+		info, err := os.Stat(keyFile)
+		if err != nil {
+			return "", err
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+			return "", fmt.Errorf("The key file %v can be read by other users, its permissions must be 0600 or stricter", keyFile)
+		}
+		data, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return "", err
+		}
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return "", fmt.Errorf("The key file %v is empty", keyFile)
+		}
+		return secret, nil
+	}
+
+	if envVar != "" {
+		secret := strings.TrimSpace(os.Getenv(envVar))
+		if secret != "" {
+			return secret, nil
+		}
+	}
+	return "", fmt.Errorf("No database key given, set a key file or the %v environment variable", envVar)
+}
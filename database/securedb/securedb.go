@@ -7,7 +7,9 @@ package securedb
 import (
 	"crypto/rand"
 	"crypto/subtle"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
@@ -20,9 +22,16 @@ var (
 	// Bucket for all db metadata
 	EncyptedMetaData = []byte("EncyptedDBMetaData")
 
+	// Bucket listing every bucket written to, as LevelDB can't list them
+	EncryptedBuckets = []byte("EncryptedDBBuckets")
+
 	challenge = []byte("Challenge")
 )
 
+// ErrKeyRotationPending is returned when opening a database whose key rotation was interrupted,
+// it has to be resumed with RotateKey
+var ErrKeyRotationPending = errors.New("A key rotation of this database was interrupted, it must be finished before the database is used")
+
 // EncryptedDB is a database with symmetric encryption to encrypt all writes, and decrypt all reads
 type EncryptedDB struct {
 	// Stores all encrypted data
@@ -33,6 +42,10 @@ type EncryptedDB struct {
 
 	// encryptionkey is a hash of the password and salt
 	encryptionkey []byte
+
+	// buckets are the buckets known to be in EncryptedBuckets
+	bucketsMutex sync.Mutex
+	buckets      map[string]bool
 }
 
 // NewEncryptedDB takes the filename, dbtype, and password.
@@ -52,19 +65,50 @@ func NewEncryptedDB(filename, dbtype, password string) (*EncryptedDB, error) {
 	return e, nil
 }
 
-// InitSecureDB will init the Salt and metadata
-func (db *EncryptedDB) initSecureDB(password string) error {
+// OpenEncryptedDB encrypts everything written to an open database, with a key made from the secret
+func OpenEncryptedDB(db interfaces.IDatabase, secret string) (*EncryptedDB, error) {
+	e := new(EncryptedDB)
+	e.db = db
+
+	err := e.initSecureDB(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// loadMetaData returns false if the database has no metadata yet
+func (db *EncryptedDB) loadMetaData() (bool, error) {
 	m := new(SecureDBMetaData)
 	v, err := db.db.Get(EncyptedMetaData, EncyptedMetaData, m)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if v == nil {
 		// need to init new metadata
 		db.initNewMetaData()
-	} else {
-		db.metadata = m
+		return false, nil
+	}
+	db.metadata = m
+	return true, nil
+}
+
+func (db *EncryptedDB) saveMetaData() error {
+	return db.db.Put(EncyptedMetaData, EncyptedMetaData, db.metadata)
+}
+
+// InitSecureDB will init the Salt and metadata
+func (db *EncryptedDB) initSecureDB(password string) error {
+	db.buckets = map[string]bool{}
+
+	_, err := db.loadMetaData()
+	if err != nil {
+		return err
+	}
+	if db.metadata.IsRotating() {
+		return ErrKeyRotationPending
 	}
 
 	key, err := GetKey(password, db.metadata.Salt.Bytes)
@@ -84,43 +128,121 @@ func (db *EncryptedDB) initSecureDB(password string) error {
 		var c primitives.ByteSlice
 		c.Bytes = cipherText
 		db.metadata.Challenge = c
-		err = db.db.Put(EncyptedMetaData, EncyptedMetaData, db.metadata)
+		err = db.saveMetaData()
 		if err != nil {
 			return err
 		}
 
 	} else {
 		// Do challenge
-		plainText, err := Decrypt(db.metadata.Challenge.Bytes, db.encryptionkey)
+		err = checkChallenge(db.metadata.Challenge.Bytes, db.encryptionkey)
 		if err != nil {
 			return err
 		}
+	}
 
-		if subtle.ConstantTimeCompare(plainText, challenge) == 0 {
-			return fmt.Errorf("Wrong password given for this database")
-		}
+	return db.upgradeMetaData()
+}
+
+func checkChallenge(cipherText []byte, key []byte) error {
+	plainText, err := Decrypt(cipherText, key)
+	if err != nil {
+		return fmt.Errorf("Wrong password given for this database")
 	}
 
+	if subtle.ConstantTimeCompare(plainText, challenge) == 0 {
+		return fmt.Errorf("Wrong password given for this database")
+	}
 	return nil
 }
 
+// upgradeMetaData brings the metadata of a database made by an older version up to date. Version
+// 0 did not keep the list of buckets, it is made from the underlying database if it can list them.
+func (db *EncryptedDB) upgradeMetaData() error {
+	if db.metadata.Version >= SecureDBMetaDataVersion {
+		return nil
+	}
+
+	buckets, err := db.db.ListAllBuckets()
+	if err == nil {
+		for _, bucket := range buckets {
+			if isInternalBucket(bucket) {
+				continue
+			}
+			err = db.registerBucket(bucket)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	db.metadata.BucketsComplete = err == nil
+	db.metadata.Version = SecureDBMetaDataVersion
+	return db.saveMetaData()
+}
+
 func (db *EncryptedDB) initNewMetaData() {
 	db.metadata = NewSecureDBMetaData()
+	db.metadata.Salt.Bytes = newSalt()
+	// A new database lists its buckets from the start
+	db.metadata.BucketsComplete = true
+}
+
+func newSalt() []byte {
 	salt := make([]byte, 30)
 	_, err := rand.Read(salt)
 	if err != nil {
 		panic(err)
 	}
+	return salt
+}
 
-	db.metadata.Salt.Bytes = salt
+// GetMetaData returns a copy of the metadata of the database
+func (db *EncryptedDB) GetMetaData() SecureDBMetaData {
+	return *db.metadata
+}
+
+func isInternalBucket(bucket []byte) bool {
+	return primitives.AreBytesEqual(bucket, EncyptedMetaData) || primitives.AreBytesEqual(bucket, EncryptedBuckets)
+}
+
+// registerBucket adds the bucket to EncryptedBuckets, once per bucket
+func (db *EncryptedDB) registerBucket(bucket []byte) error {
+	db.bucketsMutex.Lock()
+	defer db.bucketsMutex.Unlock()
+
+	if db.buckets[string(bucket)] {
+		return nil
+	}
+	err := db.db.Put(EncryptedBuckets, bucket, new(primitives.ByteSlice))
+	if err != nil {
+		return err
+	}
+	db.buckets[string(bucket)] = true
+	return nil
 }
 
 /***************************************
  *       Methods
  ***************************************/
 
+// ListAllBuckets works on every underlying database, as long as the database kept the list of its
+// buckets since it was made
 func (db *EncryptedDB) ListAllBuckets() ([][]byte, error) {
-	return db.db.ListAllBuckets()
+	if db.metadata.BucketsComplete {
+		return db.db.ListAllKeys(EncryptedBuckets)
+	}
+
+	buckets, err := db.db.ListAllBuckets()
+	if err != nil {
+		return nil, err
+	}
+	answer := make([][]byte, 0, len(buckets))
+	for _, bucket := range buckets {
+		if isInternalBucket(bucket) == false {
+			answer = append(answer, bucket)
+		}
+	}
+	return answer, nil
 }
 
 // We don't care if delete works or not.  If the key isn't there, that's ok
@@ -151,13 +273,26 @@ func (db *EncryptedDB) Get(bucket []byte, key []byte, destination interfaces.Bin
 }
 
 func (db *EncryptedDB) Put(bucket []byte, key []byte, data interfaces.BinaryMarshallable) error {
+	err := db.registerBucket(bucket)
+	if err != nil {
+		return err
+	}
 	e := NewEncryptedMarshaler(db.encryptionkey, data)
 	return db.db.Put(bucket, key, e)
 }
 
+// AtomicBatches is true if the batches of the underlying database are
+func (db *EncryptedDB) AtomicBatches() bool {
+	return interfaces.AtomicBatches(db.db)
+}
+
 func (db *EncryptedDB) PutInBatch(records []interfaces.Record) error {
 	cipherRecords := make([]interfaces.Record, len(records))
 	for i, r := range records {
+		err := db.registerBucket(r.Bucket)
+		if err != nil {
+			return err
+		}
 		cipherRecords[i].Bucket = r.Bucket
 		cipherRecords[i].Key = r.Key
 
@@ -0,0 +1,78 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package securedb
+
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// EncryptedSnapshot decrypts what it reads from a snapshot of the underlying database
+type EncryptedSnapshot struct {
+	snap          interfaces.ISnapshot
+	encryptionkey []byte
+}
+
+var _ interfaces.ISnapshot = (*EncryptedSnapshot)(nil)
+
+func (db *EncryptedDB) GetSnapshot() (interfaces.ISnapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedSnapshot{snap: snap, encryptionkey: db.encryptionkey}, nil
+}
+
+func (s *EncryptedSnapshot) Get(bucket, key []byte, destination interfaces.BinaryMarshallable) (interfaces.BinaryMarshallable, error) {
+	e := NewEncryptedMarshaler(s.encryptionkey, destination)
+	tmp, err := s.snap.Get(bucket, key, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if tmp == nil {
+		return nil, nil
+	}
+
+	return e.Original, nil
+}
+
+func (s *EncryptedSnapshot) ListAllKeys(bucket []byte) ([][]byte, error) {
+	return s.snap.ListAllKeys(bucket)
+}
+
+func (s *EncryptedSnapshot) GetAll(bucket []byte, sample interfaces.BinaryMarshallableAndCopyable) ([]interfaces.BinaryMarshallableAndCopyable, [][]byte, error) {
+	e := NewEncryptedMarshaler(s.encryptionkey, sample.(interfaces.BinaryMarshallable))
+
+	cipheredAll, keys, err := s.snap.GetAll(bucket, e)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	originalSamples := make([]interfaces.BinaryMarshallableAndCopyable, len(cipheredAll))
+	for i, c := range cipheredAll {
+		originalSamples[i] = c.(*EncryptedMarshaler).Original.(interfaces.BinaryMarshallableAndCopyable)
+	}
+
+	return originalSamples, keys, nil
+}
+
+func (s *EncryptedSnapshot) DoesKeyExist(bucket, key []byte) (bool, error) {
+	return s.snap.DoesKeyExist(bucket, key)
+}
+
+func (s *EncryptedSnapshot) NewIterator(bucket []byte, r *interfaces.IteratorRange) (interfaces.IIterator, error) {
+	iter, err := s.snap.NewIterator(bucket, r)
+	if err != nil {
+		return nil, err
+	}
+	it := new(EncryptedDBIterator)
+	it.iter = iter
+	it.encryptionkey = s.encryptionkey
+	return it, nil
+}
+
+func (s *EncryptedSnapshot) Release() {
+	s.snap.Release()
+}
@@ -7,11 +7,14 @@ package database_test
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/common/primitives/random"
+	"github.com/FactomProject/factomd/database/badgerdb"
 	"github.com/FactomProject/factomd/database/boltdb"
 	"github.com/FactomProject/factomd/database/databaseOverlay"
 	"github.com/FactomProject/factomd/database/leveldb"
@@ -48,7 +51,7 @@ var dbFilename = "testdb"
 
 func TestAllDatabases(t *testing.T) {
 	// Secure Bolt
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m, err := securedb.NewEncryptedDB(dbFilename, "Bolt", random.RandomString())
 		if err != nil {
 			t.Error(err)
@@ -58,7 +61,7 @@ func TestAllDatabases(t *testing.T) {
 	}
 
 	// Secure LDB
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m, err := securedb.NewEncryptedDB(dbFilename, "LDB", random.RandomString())
 		if err != nil {
 			t.Error(err)
@@ -68,7 +71,7 @@ func TestAllDatabases(t *testing.T) {
 	}
 
 	// Secure Map
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m, err := securedb.NewEncryptedDB(dbFilename, "Map", random.RandomString())
 		if err != nil {
 			t.Error(err)
@@ -78,14 +81,14 @@ func TestAllDatabases(t *testing.T) {
 	}
 
 	// Bolt
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m := boltdb.NewBoltDB(nil, dbFilename)
 		testDB(t, m, i)
 		CleanupTest(t, m)
 	}
 
 	// Level
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m, err := leveldb.NewLevelDB(dbFilename, true)
 		if err != nil {
 			t.Error(err)
@@ -94,8 +97,18 @@ func TestAllDatabases(t *testing.T) {
 		CleanupTest(t, m)
 	}
 
+	// Badger
+	for i := 0; i < 6; i++ {
+		m, err := badgerdb.NewBadgerDB(dbFilename, true)
+		if err != nil {
+			t.Error(err)
+		}
+		testDB(t, m, i)
+		CleanupTest(t, m)
+	}
+
 	// Map
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		m := new(mapdb.MapDB)
 		testDB(t, m, i)
 		CleanupTest(t, m)
@@ -112,6 +125,10 @@ func testDB(t *testing.T, m interfaces.IDatabase, i int) {
 		testDoesKeyExist(t, m)
 	case 3:
 		testGetAll(t, m)
+	case 4:
+		testIterator(t, m)
+	case 5:
+		testSnapshot(t, m)
 	}
 }
 
@@ -286,3 +303,200 @@ func testGetAll(t *testing.T, m interfaces.IDatabase) {
 		}
 	}
 }
+
+func iteratorKeys(t *testing.T, m interfaces.IDatabase, bucket []byte, r *interfaces.IteratorRange) []string {
+	it, err := m.NewIterator(bucket, r)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer it.Release()
+
+	keys := []string{}
+	for it.Next() {
+		td := new(TestData)
+		err = td.UnmarshalBinary(it.Value())
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+		if td.Str != "Data "+string(it.Key()) {
+			t.Errorf("Wrong value for key %s - %v", it.Key(), td.Str)
+		}
+		keys = append(keys, string(it.Key()))
+	}
+	if it.Error() != nil {
+		t.Errorf("%v", it.Error())
+	}
+	return keys
+}
+
+func testIterator(t *testing.T, m interfaces.IDatabase) {
+	defer CleanupTest(t, m)
+
+	bucket := []byte("bucket")
+	batch := []interfaces.Record{}
+	for _, b := range [][]byte{bucket, []byte("bucket2"), []byte("a")} {
+		for _, k := range []string{"a1", "a2", "b1", "b2", "b3", "c1"} {
+			td := new(TestData)
+			td.Str = "Data " + k
+			batch = append(batch, interfaces.Record{Bucket: b, Key: []byte(k), Data: td})
+		}
+	}
+	err := m.PutInBatch(batch)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	tests := []struct {
+		Range    *interfaces.IteratorRange
+		Expected string
+	}{
+		{nil, "a1 a2 b1 b2 b3 c1"},
+		{&interfaces.IteratorRange{Prefix: []byte("b")}, "b1 b2 b3"},
+		{&interfaces.IteratorRange{Start: []byte("a2"), Limit: []byte("b3")}, "a2 b1 b2"},
+		{&interfaces.IteratorRange{Start: []byte("b2"), Prefix: []byte("b")}, "b2 b3"},
+		{&interfaces.IteratorRange{Limit: []byte("b2"), Prefix: []byte("b")}, "b1"},
+		{&interfaces.IteratorRange{Prefix: []byte("d")}, ""},
+	}
+	for i, test := range tests {
+		keys := fmt.Sprintf("%v", iteratorKeys(t, m, bucket, test.Range))
+		if keys != "["+test.Expected+"]" {
+			t.Errorf("Test %v - expected [%v], got %v", i, test.Expected, keys)
+		}
+	}
+
+	it, err := m.NewIterator(bucket, &interfaces.IteratorRange{Prefix: []byte("b")})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer it.Release()
+	if it.Seek([]byte("b15")) == false || string(it.Key()) != "b2" {
+		t.Errorf("Seek did not stop at b2 - %s", it.Key())
+	}
+	if it.Next() == false || string(it.Key()) != "b3" {
+		t.Errorf("Next after Seek did not return b3 - %s", it.Key())
+	}
+	if it.Next() == true {
+		t.Errorf("Iterator went past its prefix - %s", it.Key())
+	}
+	if it.Seek([]byte("a")) == false || string(it.Key()) != "b1" {
+		t.Errorf("Seek before the range did not stop at b1 - %s", it.Key())
+	}
+	if it.Seek([]byte("c")) == true {
+		t.Errorf("Seek after the range found %s", it.Key())
+	}
+}
+
+func testSnapshot(t *testing.T, m interfaces.IDatabase) {
+	defer CleanupTest(t, m)
+
+	bucket := []byte("bucket")
+	put := func(key, value string) {
+		td := new(TestData)
+		td.Str = value
+		err := m.Put(bucket, []byte(key), td)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	put("a", "old a")
+	put("b", "old b")
+
+	snap, err := m.GetSnapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer snap.Release()
+
+	// An open snapshot must not block writes. They are made in another goroutine, so a database
+	// that does fails the test instead of hanging it.
+	done := make(chan error, 1)
+	go func() {
+		for _, v := range []string{"a", "c"} {
+			err := m.Put(bucket, []byte(v), &TestData{Str: "new " + v})
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		// Enough data that Bolt has to grow its memory map
+		err := m.Put([]byte("filler"), []byte("filler"), &TestData{Str: strings.Repeat("0", 10000000)})
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- m.Delete(bucket, []byte("b"))
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Writes are blocked by an open snapshot")
+	}
+
+	resp, err := snap.Get(bucket, []byte("a"), new(TestData))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if resp == nil || resp.(*TestData).Str != "old a" {
+		t.Errorf("Snapshot sees a write made after it was taken - %v", resp)
+	}
+	resp, err = m.Get(bucket, []byte("a"), new(TestData))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if resp == nil || resp.(*TestData).Str != "new a" {
+		t.Errorf("Database does not see its own write - %v", resp)
+	}
+
+	exists, err := snap.DoesKeyExist(bucket, []byte("b"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists == false {
+		t.Errorf("Snapshot does not see a key deleted after it was taken")
+	}
+	exists, err = snap.DoesKeyExist(bucket, []byte("c"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exists == true {
+		t.Errorf("Snapshot sees a key added after it was taken")
+	}
+
+	keys, err := snap.ListAllKeys(bucket)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if fmt.Sprintf("%s", keys) != "[a b]" {
+		t.Errorf("Wrong keys in the snapshot - %s", keys)
+	}
+
+	all, _, err := snap.GetAll(bucket, new(TestData))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(all) != 2 || all[0].(*TestData).Str != "old a" || all[1].(*TestData).Str != "old b" {
+		t.Errorf("Wrong values in the snapshot - %v", all)
+	}
+
+	it, err := snap.NewIterator(bucket, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	keys = [][]byte{}
+	for it.Next() {
+		keys = append(keys, append([]byte{}, it.Key()...))
+	}
+	if fmt.Sprintf("%s", keys) != "[a b]" {
+		t.Errorf("Wrong keys iterated in the snapshot - %s", keys)
+	}
+	if it.Seek([]byte("b")) == false || string(it.Key()) != "b" {
+		t.Errorf("Seek did not find a key deleted after the snapshot was taken")
+	}
+	if it.Next() == true {
+		t.Errorf("Iterated past the last key of the snapshot to %s", it.Key())
+	}
+	it.Release()
+}
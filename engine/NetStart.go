@@ -19,6 +19,8 @@ import (
 	"github.com/FactomProject/factomd/common/messages"
 	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/controlPanel"
+	"github.com/FactomProject/factomd/database/badgerdb"
+	"github.com/FactomProject/factomd/database/hybridDB"
 	"github.com/FactomProject/factomd/database/leveldb"
 	"github.com/FactomProject/factomd/p2p"
 	"github.com/FactomProject/factomd/state"
@@ -334,6 +336,13 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 			SeedURL:                  seedURL,
 			SpecialPeers:             specialPeers,
 			ConnectionMetricsChannel: connectionMetricsChannel,
+			NodeKeyFile:              s.NodeKeyFile,
+			AllowPlaintextPeers:      s.AllowPlaintextPeers,
+			AppTypeLanes:             P2PAppTypeLanes(),
+			SendBytesPerSecond:       s.PeerSendBytesPerSecond,
+			SendMessagesPerSecond:    s.PeerSendMessagesPerSecond,
+			ReceiveBytesPerSecond:    s.PeerReceiveBytesPerSecond,
+			ReceiveMessagesPerSecond: s.PeerReceiveMessagesPerSecond,
 		}
 		p2pNetwork = new(p2p.Controller).Init(ci)
 		fnodes[0].State.NetworkControler = p2pNetwork
@@ -341,6 +350,8 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 		p2pProxy = new(P2PProxy).Init(fnodes[0].State.FactomNodeName, "P2P Network").(*P2PProxy)
 		p2pProxy.FromNetwork = p2pNetwork.FromNetwork
 		p2pProxy.ToNetwork = p2pNetwork.ToNetwork
+		p2pProxy.FromNetworkHigh = p2pNetwork.FromNetworkHigh
+		p2pProxy.ToNetworkHigh = p2pNetwork.ToNetworkHigh
 
 		fnodes[0].Peers = append(fnodes[0].Peers, p2pProxy)
 		p2pProxy.SetDebugMode(p.Netdebug)
@@ -494,6 +505,8 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 	state.RegisterPrometheus()
 	p2p.RegisterPrometheus()
 	leveldb.RegisterPrometheus()
+	badgerdb.RegisterPrometheus()
+	hybridDB.RegisterPrometheus()
 	RegisterPrometheus()
 
 	go controlPanel.ServeControlPanel(fnodes[0].State.ControlPanelChannel, fnodes[0].State, connectionMetricsChannel, p2pNetwork, Build)
@@ -535,6 +548,7 @@ func startServers(load bool) {
 			go state.LoadDatabase(fnode.State)
 		}
 		go fnode.State.GoSyncEntries()
+		go fnode.State.GoPruneEntries()
 		go Timer(fnode.State)
 		go fnode.State.ValidatorLoop()
 	}
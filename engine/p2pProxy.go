@@ -10,8 +10,7 @@ import (
 	"os"
 	"time"
 
-	// "github.com/FactomProject/factomd/common/constants"
-
+	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
 	"github.com/FactomProject/factomd/common/primitives"
@@ -34,8 +33,10 @@ type P2PProxy struct {
 	BroadcastOut chan interface{} // FactomMessage ToNetwork from factomd
 	BroadcastIn  chan interface{} // FactomMessage FromNetwork for Factomd
 
-	ToNetwork   chan interface{} // p2p.Parcel From p2pProxy to the p2p Controller
-	FromNetwork chan interface{} // p2p.Parcel Parcels from the network for the application
+	ToNetwork       chan interface{} // p2p.Parcel From p2pProxy to the p2p Controller
+	FromNetwork     chan interface{} // p2p.Parcel Parcels from the network for the application
+	ToNetworkHigh   chan interface{} // p2p.Parcel Parcels of the high p2p lanes, if set, to the p2p Controller
+	FromNetworkHigh chan interface{} // p2p.Parcel Parcels of the high p2p lanes, if set, for the application
 
 	logFile   os.File
 	logWriter bufio.Writer
@@ -53,6 +54,33 @@ type FactomMessage struct {
 	AppType  string
 }
 
+// P2PAppTypeLanes returns the p2p send lanes of the message types, by the AppType the proxy
+// gives them.  The messages consensus waits on go ahead of the rest, and the large responses
+// used to catch up go last, so a peer that is syncing can't crowd out acks and EOMs.
+func P2PAppTypeLanes() map[string]uint8 {
+	lanes := map[string]uint8{}
+	for _, t := range []byte{
+		constants.EOM_MSG,
+		constants.ACK_MSG,
+		constants.DIRECTORY_BLOCK_SIGNATURE_MSG,
+		constants.FED_SERVER_FAULT_MSG,
+		constants.FULL_SERVER_FAULT_MSG,
+		constants.HEARTBEAT_MSG,
+		constants.MISSING_MSG,
+		constants.MISSING_MSG_RESPONSE,
+	} {
+		lanes[fmt.Sprintf("%d", t)] = p2p.LaneHigh
+	}
+	for _, t := range []byte{
+		constants.DBSTATE_MSG,
+		constants.DATA_RESPONSE,
+		constants.ENTRY_BLOCK_RESPONSE,
+	} {
+		lanes[fmt.Sprintf("%d", t)] = p2p.LaneBulk
+	}
+	return lanes
+}
+
 func (e *FactomMessage) JSONByte() ([]byte, error) {
 	return primitives.EncodeJSON(e)
 }
@@ -303,7 +331,11 @@ func (f *P2PProxy) ManageOutChannel() {
 				parcel.Header.AppHash = fmessage.AppHash
 				parcel.Header.AppType = fmessage.AppType
 				parcel.Trace("P2PProxy.ManageOutChannel()", "b")
-				p2p.BlockFreeChannelSend(f.ToNetwork, parcel)
+				if nil != f.ToNetworkHigh && p2p.LaneHigh >= p2p.ParcelLane(&parcel) {
+					p2p.BlockFreeChannelSend(f.ToNetworkHigh, parcel)
+				} else {
+					p2p.BlockFreeChannelSend(f.ToNetwork, parcel)
+				}
 			}
 		default:
 			fmt.Printf("Garbage on f.BrodcastOut. %+v", data)
@@ -311,9 +343,19 @@ func (f *P2PProxy) ManageOutChannel() {
 	}
 }
 
-// manageInChannel takes messages from the network and stuffs it in the f.BroadcastIn channel
+// manageInChannel takes messages from the network and stuffs it in the f.BroadcastIn channel,
+// the ones of the high p2p lanes first
 func (f *P2PProxy) ManageInChannel() {
-	for data := range f.FromNetwork {
+	for {
+		var data interface{}
+		select {
+		case data = <-f.FromNetworkHigh:
+		default:
+			select {
+			case data = <-f.FromNetworkHigh:
+			case data = <-f.FromNetwork:
+			}
+		}
 		switch data.(type) {
 		case p2p.Parcel:
 			parcel := data.(p2p.Parcel)
@@ -370,6 +412,8 @@ func (f *P2PProxy) InstantaneousStatusReport(fnodes []*FactomNode) {
 	}
 	fmt.Printf("      ToNetwork Queue:       %d\n", len(f.ToNetwork))
 	fmt.Printf("      FromNetwork Queue:     %d\n", len(f.FromNetwork))
+	fmt.Printf("      ToNetworkHigh Queue:   %d\n", len(f.ToNetworkHigh))
+	fmt.Printf("      FromNetworkHigh Queue: %d\n", len(f.FromNetworkHigh))
 	fmt.Printf("      BroadcastOut Queue:    %d\n", len(f.BroadcastOut))
 	fmt.Printf("      BroadcastIn Queue:     %d\n", len(f.BroadcastIn))
 	fmt.Printf("      Weight:                %d\n", f.NumPeers)
@@ -100,6 +100,8 @@ func InitNetwork() {
 	p2pProxy = new(engine.P2PProxy).Init("testnode", "P2P Network").(*engine.P2PProxy)
 	p2pProxy.FromNetwork = p2pNetwork.FromNetwork
 	p2pProxy.ToNetwork = p2pNetwork.ToNetwork
+	p2pProxy.FromNetworkHigh = p2pNetwork.FromNetworkHigh
+	p2pProxy.ToNetworkHigh = p2pNetwork.ToNetworkHigh
 	p2pProxy.SetDebugMode(netdebug)
 
 	if netdebug > 0 {
@@ -5,6 +5,7 @@
 package p2p
 
 import (
+	"bufio"
 	"encoding/gob"
 	"fmt"
 	"hash/crc32"
@@ -26,15 +27,26 @@ var conLogger = packageLogger.WithFields(log.Fields{"subpack": "connection"})
 // (defined below).
 type Connection struct {
 	conn           net.Conn
-	Errors         chan error              // handle errors from connections.
-	Commands       chan *ConnectionCommand // handle connection commands
-	SendChannel    chan interface{}        // Send means "towards the network" Channel sends Parcels and ConnectionCommands
-	ReceiveChannel chan interface{}        // Recieve means "from the network" Channel recieves Parcels and ConnectionCommands
-	ReceiveParcel  chan *Parcel            // Parcels to be handled.
+	Errors         chan error                      // handle errors from connections.
+	Commands       chan *ConnectionCommand         // handle connection commands
+	SendChannel    chan interface{}                // Send means "towards the network" Channel sends ConnectionCommands, parcels go in the lanes
+	ReceiveChannel chan interface{}                // Recieve means "from the network" Channel recieves ConnectionCommands, parcels go in the lanes
+	ReceiveParcel  chan *Parcel                    // Parcels to be handled.
+	sendLanes      [NumberOfLanes]chan interface{} // Parcels towards the network, by priority. See lanes.go
+	receiveLanes   [NumberOfLanes]chan interface{} // Parcels from the network for the controller, by priority
+	sendLimit      *RateLimiter                    // Paces the parcels we send in all lanes, nil if unlimited
+	sendHeld       *ConnectionParcel               // The next parcel to send, when it waits for the limit
+	sendHeldUntil  time.Time                       // When the held parcel can go
+	receiveLimit   *RateLimiter                    // Paces the parcels we read, nil if unlimited
 	// and as "address" for sending messages to specific nodes.
-	encoder         *gob.Encoder      // Wire format is gobs in this version, may switch to binary
-	decoder         *gob.Decoder      // Wire format is gobs in this version, may switch to binary
+	encoder         *gob.Encoder      // Wire format is gobs until we switch to binary, see parcel_binary.go
+	decoder         *gob.Decoder      // Wire format is gobs until the peer switches to binary
+	reader          *bufio.Reader     // The decoders read from this, so no bytes are lost when switching
+	sendBinary      bool              // We send parcels in the binary format. Only processSends changes this.
+	receiveBinary   bool              // The peer sends parcels in the binary format. Only processReceives changes this.
+	binaryRequested bool              // We told the peer we switch to the binary format
 	peer            Peer              // the datastructure representing the peer we are talking to. defined in peer.go
+	remoteIP        net.IP            // The IP address we are connected to, a hostname is resolved once when dialled
 	attempts        int               // reconnection attempts
 	TimeLastpacket  time.Time         // Time we last successfully recieved a packet or command.
 	timeLastAttempt time.Time         // time of last attempt to connect via dial
@@ -105,8 +117,13 @@ type ConnectionMetrics struct {
 	// Red: Below -50
 	// Yellow: -50 - 100
 	// Green: > 100
-	ConnectionState string // Basic state of the connection
-	ConnectionNotes string // Connectivity notes for the connection
+	ConnectionState string            // Basic state of the connection
+	ConnectionNotes string            // Connectivity notes for the connection
+	PeerPort        string            // Port the peer listens on
+	IsOutgoing      bool              // We dialed the peer
+	IsPersistent    bool              // The connection redials until shut down, as for special peers
+	IsSpecial       bool              // The peer is a special peer
+	PeerPenalties   map[string]uint32 // Penalties of the peer by reason, see reputation.go
 }
 
 // ConnectionCommand is used to instruct the Connection to carry out some functionality.
@@ -138,6 +155,8 @@ const (
 	ConnectionAdjustPeerQuality
 	ConnectionUpdateMetrics
 	ConnectionGoOffline // Notifies the connection it should go offinline (eg from another goroutine)
+	ConnectionBanned    // Notifies the controller that we banned our peer, so the bans are saved
+	ConnectionCheckBans // Notifies the connection to check its peer against the bans
 )
 
 //////////////////////////////
@@ -149,10 +168,14 @@ const (
 // InitWithConn is called from our accept loop when a peer dials into us and we already have a network conn
 func (c *Connection) InitWithConn(conn net.Conn, peer Peer) *Connection {
 	c.conn = conn
+	c.remoteIP = connectionIP(conn)
 	c.isOutGoing = false // InitWithConn is called by controller's accept() loop
 	c.commonInit(peer)
 	c.isPersistent = false
-	c.goOnline()
+	if nil == LocalNodeKey {
+		c.goOnline()
+	}
+	// Otherwise the runloop takes the connection online, as the handshake would hold up the controller
 	return c
 }
 
@@ -200,6 +223,12 @@ func (c *Connection) commonInit(peer Peer) {
 	c.SendChannel = make(chan interface{}, StandardChannelSize)
 	c.ReceiveChannel = make(chan interface{}, StandardChannelSize)
 	c.ReceiveParcel = make(chan *Parcel, StandardChannelSize)
+	for i := range c.sendLanes {
+		c.sendLanes[i] = make(chan interface{}, StandardChannelSize)
+		c.receiveLanes[i] = make(chan interface{}, StandardChannelSize)
+	}
+	c.sendLimit = NewRateLimiter(PeerSendBytesPerSecond, PeerSendMessagesPerSecond)
+	c.receiveLimit = NewRateLimiter(PeerReceiveBytesPerSecond, PeerReceiveMessagesPerSecond)
 	c.metrics = ConnectionMetrics{MomentConnected: time.Now()}
 	c.timeLastMetrics = time.Now()
 	c.timeLastAttempt = time.Now()
@@ -246,7 +275,11 @@ func (c *Connection) runLoop() {
 		switch c.state {
 		case ConnectionInitialized:
 			p2pConnectionRunLoopInitalized.Inc()
-			if MinumumQualityScore > c.peer.QualityScore && !c.isPersistent {
+			if !c.isOutGoing { // the peer dialed us, we only get one go at the connection
+				if !c.goOnline() {
+					c.goShutdown()
+				}
+			} else if MinumumQualityScore > c.peer.QualityScore && !c.isPersistent {
 				c.updatePeer() // every PeerSaveInterval * 0.90 we send an update peer to the controller.
 				c.goShutdown()
 			} else {
@@ -297,8 +330,11 @@ func (c *Connection) dialLoop() {
 
 	for {
 		c.timeLastAttempt = time.Now()
-		if c.dial() {
-			c.goOnline()
+		if c.dial() && c.goOnline() {
+			return
+		}
+		c.handleCommand() // eg a shutdown of a persistent connection
+		if ConnectionShuttingDown == c.state {
 			return
 		}
 		switch {
@@ -318,24 +354,71 @@ func (c *Connection) dialLoop() {
 	}
 }
 
-// dial() handles connection logic and shifts states based on results.
+// dial() handles connection logic and shifts states based on results.  The IP address of a
+// hostname is only known once dialled, so it is checked against the bans then.
 func (c *Connection) dial() bool {
+	if ban, banned := IsBanned(c.peer.Address); banned {
+		c.setNotes("Connection(%s) not dialed, the address is banned: %s", c.peer.AddressPort(), ban.Reason)
+		return false
+	}
 	address := c.peer.AddressPort()
 	// conn, err := net.Dial("tcp", c.peer.Address)
 	conn, err := net.DialTimeout("tcp", address, time.Second*10)
-	if nil == err {
-		c.conn = conn
-		return true
+	if nil != err {
+		return false
+	}
+	ip := connectionIP(conn)
+	if ban, banned := IsBanned(ip.String()); nil != ip && banned {
+		c.setNotes("Connection(%s) dropped, %s is banned: %s", c.peer.AddressPort(), ip, ban.Reason)
+		conn.Close()
+		return false
+	}
+	c.conn = conn
+	c.remoteIP = ip
+	return true
+}
+
+// connectionIP returns the IP address of the other end of the connection, nil if it has none
+func connectionIP(conn net.Conn) net.IP {
+	if address, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return address.IP
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if nil != err {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// isBanned returns the ban covering the IP address we are connected to, or the address of the
+// peer before we are connected
+func (c *Connection) isBanned() (Ban, bool) {
+	if nil == c.remoteIP {
+		return IsBanned(c.peer.Address)
 	}
-	return false
+	return IsBanned(c.remoteIP.String())
 }
 
-// Called when we are online and connected to the peer.
-func (c *Connection) goOnline() {
+// Called when we are connected to the peer. Returns false, having closed the connection, if the
+// handshake fails.
+func (c *Connection) goOnline() bool {
 	p2pConnectionOnlineCall.Inc()
+	if nil != LocalNodeKey {
+		err := c.handshake()
+		if nil != err {
+			c.setNotes("Connection(%s) handshake failed: %v", c.peer.AddressPort(), err)
+			c.conn.Close()
+			c.conn = nil
+			return false
+		}
+	}
 	now := time.Now()
 	c.encoder = gob.NewEncoder(c.conn)
-	c.decoder = gob.NewDecoder(c.conn)
+	c.reader = bufio.NewReader(c.conn)
+	c.decoder = gob.NewDecoder(c.reader)
+	c.sendBinary = false
+	c.receiveBinary = false
+	c.binaryRequested = false
 	c.attempts = 0
 	c.timeLastPing = now
 	c.timeLastAttempt = now
@@ -351,12 +434,73 @@ func (c *Connection) goOnline() {
 	// Now ask the other side for the peers they know about.
 	parcel := NewParcel(CurrentNetwork, []byte("Peer Request"))
 	parcel.Header.Type = TypePeerRequest
-	BlockFreeChannelSend(c.SendChannel, ConnectionParcel{Parcel: *parcel})
+	c.queueParcel(*parcel)
+	return true
+}
+
+// handshake encrypts the connection and checks the node key of the peer against the pinned one.
+// Peers holding the node key of a pinned special peer are special whatever their address, and
+// when running exclusive with pinned keys, only they may dial us.  With AllowPlaintextPeers,
+// peers without a node key are talked to in plaintext, see AllowPlaintextPeers.
+func (c *Connection) handshake() error {
+	if c.isOutGoing && isPlaintextPeer(c.peer.AddressPort()) && nil == c.plaintextRefused() {
+		c.peer.PublicKey = ""
+		return nil
+	}
+	if !c.isOutGoing {
+		conn, hello, err := StartsHandshake(c.conn)
+		if nil != err {
+			return err
+		}
+		c.conn = conn
+		if !hello {
+			if err = c.plaintextRefused(); nil != err {
+				return err
+			}
+			c.peer.PublicKey = ""
+			return nil
+		}
+	}
+	conn, peerKey, err := Handshake(c.conn, LocalNodeKey, c.isOutGoing)
+	if errNoHandshake == err && c.isOutGoing && nil == c.plaintextRefused() {
+		// The peer has no node key, so the next dial is in plaintext
+		markPlaintextPeer(c.peer.AddressPort())
+	}
+	if nil != err {
+		return err
+	}
+	switch {
+	case isSpecialPeerKey(c.peer.PublicKey) && c.peer.PublicKey != peerKey:
+		return fmt.Errorf("Peer has node key %s, expected %s", peerKey, c.peer.PublicKey)
+	case !c.isOutGoing && OnlySpecialPeers && hasSpecialPeerKeys() && !isSpecialPeerKey(peerKey):
+		return fmt.Errorf("Peer node key %s is not pinned", peerKey)
+	}
+	c.conn = conn
+	c.peer.PublicKey = peerKey
+	c.peer.Verified = true
+	if isSpecialPeerKey(peerKey) {
+		c.peer.Type = SpecialPeer
+	}
+	return nil
+}
+
+// plaintextRefused tells why the peer may not be talked to without the handshake, or nil if it may
+func (c *Connection) plaintextRefused() error {
+	switch {
+	case !AllowPlaintextPeers:
+		return errNoHandshake
+	case isSpecialPeerKey(c.peer.PublicKey):
+		return fmt.Errorf("Peer is pinned by node key %s and must complete the handshake", c.peer.PublicKey)
+	case !c.isOutGoing && OnlySpecialPeers && hasSpecialPeerKeys():
+		return fmt.Errorf("Peer without a node key is not pinned")
+	}
+	return nil
 }
 
 func (c *Connection) goOffline() {
 	p2pConnectionOfflineCall.Inc()
 	c.state = ConnectionOffline
+	c.peer.Verified = false
 	c.attempts = 0
 	c.peer.demerit()
 }
@@ -369,6 +513,7 @@ func (c *Connection) goShutdown() {
 	}
 	c.decoder = nil
 	c.encoder = nil
+	c.reader = nil
 	c.state = ConnectionShuttingDown
 }
 
@@ -388,12 +533,15 @@ func (c *Connection) processSends() {
 	for ConnectionClosed != c.state && c.state != ConnectionShuttingDown {
 		// note(c.peer.PeerIdent(), "Connection.processSends() called. Items in send channel: %d State: %s", len(c.SendChannel), c.ConnectionState())
 	conloop:
-		for ConnectionOnline == c.state && len(c.SendChannel) > 0 {
-			// This was blocking. By checking the length of the channel before entering, this does not block.
+		for ConnectionOnline == c.state {
+			// This was blocking. nextToSend doesn't block on empty channels.
 			// The problem was this routine was blocked on a closed connection. Idealling we do want to block
 			// on a 0 length channel, and this is still possible if use a select and close the channel when we
 			// close the connection.
-			message := <-c.SendChannel
+			message := c.nextToSend(time.Now())
+			if nil == message {
+				break
+			}
 			switch message.(type) {
 			case ConnectionParcel:
 				if nil == c.decoder || nil == c.conn {
@@ -407,10 +555,118 @@ func (c *Connection) processSends() {
 			default:
 			}
 		}
+		// Pass on the commands while offline too, so a connection redialing can be shut down
+		for ConnectionOnline != c.state && 0 < len(c.SendChannel) {
+			if command, ok := (<-c.SendChannel).(ConnectionCommand); ok {
+				c.Commands <- &command
+			}
+		}
 		time.Sleep(100 * time.Millisecond)
 	}
 }
 
+// nextToSend returns the next command or parcel to go out, nil if there is none.  Commands go
+// first, then the parcels of the first lane holding any.  The lanes share the rate limit of the
+// connection: a parcel over it is held until it can go, and the lanes only decide which parcel
+// is next.
+func (c *Connection) nextToSend(now time.Time) interface{} {
+	select {
+	case message := <-c.SendChannel:
+		return message
+	default:
+	}
+	if nil == c.sendHeld {
+	lanes:
+		for _, lane := range c.sendLanes {
+			select {
+			case message := <-lane:
+				parcel := message.(ConnectionParcel)
+				c.sendHeld = &parcel
+				c.sendHeldUntil = now
+				if SpecialPeer != c.peer.Type {
+					c.sendHeldUntil = now.Add(c.sendLimit.Delay(len(parcel.Parcel.Payload), now))
+				}
+				break lanes
+			default:
+			}
+		}
+		if nil == c.sendHeld {
+			return nil
+		}
+	}
+	if now.Before(c.sendHeldUntil) {
+		return nil
+	}
+	parcel := *c.sendHeld
+	c.sendHeld = nil
+	return parcel
+}
+
+// nextReceived returns the next command or parcel for the controller, nil if there is none.
+// Commands go first, then the parcels of the first lane holding any.
+func (c *Connection) nextReceived() interface{} {
+	select {
+	case message := <-c.ReceiveChannel:
+		return message
+	default:
+	}
+	for _, lane := range c.receiveLanes {
+		select {
+		case message := <-lane:
+			return message
+		default:
+		}
+	}
+	return nil
+}
+
+// queueParcel puts the parcel in its lane to be sent.  A full lane drops its oldest parcels,
+// leaving the other lanes alone.
+func (c *Connection) queueParcel(parcel Parcel) {
+	lane := ParcelLane(&parcel)
+	removed := BlockFreeChannelSend(c.sendLanes[lane], ConnectionParcel{Parcel: parcel})
+	if 0 < removed {
+		p2pConnectionDroppedParcels.WithLabelValues("send", LaneNames[lane]).Add(float64(removed))
+	}
+}
+
+// receiveParcel passes a parcel from the peer on to the controller in its lane, so a full lane of
+// bulk parcels doesn't push out the others.  The parcels dropped when the controller falls behind
+// are our own backlog, not the peer's doing, so they are only counted.
+func (c *Connection) receiveParcel(parcel Parcel) {
+	lane := ParcelLane(&parcel)
+	removed := BlockFreeChannelSend(c.receiveLanes[lane], ConnectionParcel{Parcel: parcel})
+	if 0 < removed {
+		p2pConnectionDroppedParcels.WithLabelValues("receive", LaneNames[lane]).Add(float64(removed))
+	}
+}
+
+// penalize takes the score from the peer's quality for a protocol penalty.  Peers on another
+// network, and peers whose quality falls below MinumumQualityScore, have their address banned.  Special
+// and persistent peers are never banned for what they do.
+func (c *Connection) penalize(reason uint8, score int32) {
+	c.peer.penalize(reason, score)
+	p2pPeerPenalties.WithLabelValues(PenaltyNames[reason]).Inc()
+	note(c.peer.PeerIdent(), "penalize() %s: Score: %d Quality: %d", PenaltyNames[reason], score, c.peer.QualityScore)
+	if SpecialPeer == c.peer.Type || c.isPersistent {
+		return
+	}
+	if PenaltyWrongNetwork == reason || MinumumQualityScore > c.peer.QualityScore {
+		if _, banned := c.isBanned(); banned {
+			return
+		}
+		_, err := BanAddress(banSubnet(c.remoteIP), BanDuration, PenaltyNames[reason])
+		if nil != err {
+			// Without a ban the peer can dial straight back, but at least it is cut off now
+			logerror(c.peer.PeerIdent(), "penalize() could not ban the peer for %s, disconnecting it: %v", PenaltyNames[reason], err)
+			c.setNotes("Connection(%s) shutting down, the peer could not be banned: %v", c.peer.AddressPort(), err)
+			c.goShutdown()
+			return
+		}
+		BlockFreeChannelSend(c.ReceiveChannel, ConnectionCommand{Command: ConnectionBanned})
+	}
+}
+
 func (c *Connection) handleCommand() {
 	select {
 	case command := <-c.Commands:
@@ -428,7 +684,14 @@ func (c *Connection) handleCommand() {
 		case ConnectionAdjustPeerQuality:
 			delta := command.Delta
 			note(c.peer.PeerIdent(), "handleCommand() ConnectionAdjustPeerQuality: Current Score: %d Delta: %d", c.peer.QualityScore, delta)
-			c.peer.QualityScore = c.peer.QualityScore + delta
+			// Application demerits are recorded, but only ever disconnect the peer: bans are
+			// left to the protocol penalties
+			if 0 > delta {
+				c.peer.penalize(PenaltyApplication, -delta)
+				p2pPeerPenalties.WithLabelValues(PenaltyNames[PenaltyApplication]).Inc()
+			} else {
+				c.peer.QualityScore = c.peer.QualityScore + delta
+			}
 			if MinumumQualityScore > c.peer.QualityScore {
 				debug(c.peer.PeerIdent(), "handleCommand() disconnecting peer: %s for quality score: %d", c.peer.PeerIdent(), c.peer.QualityScore)
 				c.updatePeer()
@@ -438,6 +701,11 @@ func (c *Connection) handleCommand() {
 		case ConnectionGoOffline:
 			debug(c.peer.PeerIdent(), "handleCommand() disconnecting peer: %s goOffline command recieved", c.peer.PeerIdent())
 			c.goOffline()
+		case ConnectionCheckBans:
+			if ban, banned := c.isBanned(); banned {
+				c.setNotes("Connection(%s) shutting down, the address is banned: %s", c.peer.AddressPort(), ban.Reason)
+				c.goShutdown()
+			}
 		default:
 			logfatal(c.peer.PeerIdent(), "handleCommand() unknown command?: %+v ", command)
 		}
@@ -456,8 +724,17 @@ func (c *Connection) sendParcel(parcel Parcel) {
 	//	deadline = time.Now().Add(time.Duration(ms)*time.Millisecond)
 	//}
 	//c.conn.SetWriteDeadline(deadline)
-	encode := c.encoder
-	err := encode.Encode(parcel)
+	var err error
+	switch {
+	case c.sendBinary:
+		err = WriteParcel(c.conn, &parcel)
+	default:
+		encode := c.encoder
+		err = encode.Encode(parcel)
+		if nil == err && TypeBinaryWireFormat == parcel.Header.Type {
+			c.sendBinary = true // The peer decodes what follows as binary
+		}
+	}
 	switch {
 	case nil == err:
 		c.metrics.BytesSent += parcel.Header.Length
@@ -488,7 +765,15 @@ func (c *Connection) processReceives() {
 			var message Parcel
 
 			// c.conn.SetReadDeadline(time.Now().Add(NetworkDeadline))
-			err := c.decoder.Decode(&message)
+			var err error
+			if c.receiveBinary {
+				err = ReadParcel(c.reader, &message)
+			} else {
+				err = c.decoder.Decode(&message)
+				if nil == err && TypeBinaryWireFormat == message.Header.Type {
+					c.receiveBinary = true // The peer sends binary from here on
+				}
+			}
 			switch {
 			case nil == err:
 				c.metrics.BytesReceived += message.Header.Length
@@ -496,6 +781,12 @@ func (c *Connection) processReceives() {
 				message.Header.PeerAddress = c.peer.Address
 				c.ReceiveParcel <- &message
 				c.TimeLastpacket = time.Now()
+				if SpecialPeer != c.peer.Type {
+					// Not reading holds the peer back.  That is the whole remedy: a peer
+					// syncing in bulk runs into the limit for a long time without doing
+					// anything wrong, so it is not penalized for it.
+					time.Sleep(c.receiveLimit.Delay(len(message.Payload), time.Now()))
+				}
 			default:
 				c.Errors <- err
 			}
@@ -505,7 +796,7 @@ func (c *Connection) processReceives() {
 	}
 }
 
-//handleNetErrors Reacts to errors we get from encoder or decoder
+// handleNetErrors Reacts to errors we get from encoder or decoder
 func (c *Connection) handleNetErrors(toss bool) {
 	done := false
 	for {
@@ -557,13 +848,13 @@ func (c *Connection) handleParcel(parcel Parcel) {
 		parcel.Trace("Connection.handleParcel()-InvalidPeerDemerit", "I")
 		debug(c.peer.PeerIdent(), "Connection.handleParcel() got invalid message")
 		parcel.Print()
-		c.peer.demerit()
 		return
 	case ParcelValid:
 		parcel.Trace("Connection.handleParcel()-ParcelValid", "I")
 		c.peer.LastContact = time.Now() // We only update for valid messages (incluidng pings and heartbeats)
 		c.attempts = 0                  // reset since we are clearly in touch now.
 		c.peer.merit()                  // Increase peer quality score.
+		c.requestBinaryWireFormat(parcel)
 		debug(c.peer.PeerIdent(), "Connection.handleParcel() got ParcelValid %s", parcel.MessageType())
 		if Notes <= CurrentLoggingLevel {
 			parcel.PrintMessageType()
@@ -596,6 +887,7 @@ func (c *Connection) parcelValidity(parcel Parcel) uint8 {
 	case parcel.Header.Network != CurrentNetwork:
 		parcel.Trace("Connection.isValidParcel()-network", "H")
 		c.setNotes(fmt.Sprintf("Connection.isValidParcel(), failed due to wrong network. Remote: %0x Us: %0x", parcel.Header.Network, CurrentNetwork))
+		c.penalize(PenaltyWrongNetwork, PenaltyScores[PenaltyWrongNetwork])
 		return InvalidDisconnectPeer
 	case parcel.Header.Version < ProtocolVersionMinimum:
 		parcel.Trace("Connection.isValidParcel()-version", "H")
@@ -604,43 +896,59 @@ func (c *Connection) parcelValidity(parcel Parcel) uint8 {
 	case parcel.Header.Length != uint32(len(parcel.Payload)):
 		parcel.Trace("Connection.isValidParcel()-length", "H")
 		significant(c.peer.PeerIdent(), "Connection.isValidParcel(), failed due to wrong length: %+v", parcel.Header)
+		c.penalize(PenaltyInvalidMessage, PenaltyScores[PenaltyInvalidMessage])
 		return InvalidPeerDemerit
 	case parcel.Header.Crc32 != crc:
 		parcel.Trace("Connection.isValidParcel()-checksum", "H")
 		significant(c.peer.PeerIdent(), "Connection.isValidParcel(), failed due to bad checksum: %+v", parcel.Header)
+		c.penalize(PenaltyBadChecksum, PenaltyScores[PenaltyBadChecksum])
 		return InvalidPeerDemerit
 	default:
 		parcel.Trace("Connection.isValidParcel()-ParcelValid", "H")
 		return ParcelValid
 	}
 }
+
+// requestBinaryWireFormat switches what we send to the binary format, once the peer shows it
+// knows the format.  The peer decodes our parcels as binary after the TypeBinaryWireFormat one.
+func (c *Connection) requestBinaryWireFormat(parcel Parcel) {
+	if UseBinaryWireFormat && !c.binaryRequested && ProtocolVersionBinary <= parcel.Header.Version {
+		c.binaryRequested = true
+		request := NewParcel(CurrentNetwork, []byte("Binary Wire Format"))
+		request.Header.Type = TypeBinaryWireFormat
+		c.queueParcel(*request)
+	}
+}
+
 func (c *Connection) handleParcelTypes(parcel Parcel) {
 	switch parcel.Header.Type {
+	case TypeBinaryWireFormat: // processReceives has switched to reading binary already
+		return
 	case TypeAlert:
 		significant(c.peer.PeerIdent(), "!!!!!!!!!!!!!!!!!! Alert: Alert feature not implemented.")
 	case TypePing:
 		// Send Pong
 		pong := NewParcel(CurrentNetwork, []byte("Pong"))
 		pong.Header.Type = TypePong
-		BlockFreeChannelSend(c.SendChannel, ConnectionParcel{Parcel: *pong})
+		c.queueParcel(*pong)
 	case TypePong: // all we need is the timestamp which is set already
 		return
 	case TypePeerRequest:
-		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+		c.receiveParcel(parcel) // Controller handles these.
 	case TypePeerResponse:
-		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+		c.receiveParcel(parcel) // Controller handles these.
 	case TypeMessage:
 		c.peer.QualityScore = c.peer.QualityScore + 1
 		// Store our connection ID so the controller can direct response to us.
 		parcel.Header.TargetPeer = c.peer.Hash
 		parcel.Header.NodeID = NodeID
-		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+		c.receiveParcel(parcel) // Controller handles these.
 	case TypeMessagePart:
 		c.peer.QualityScore = c.peer.QualityScore + 1
 		// Store our connection ID so the controller can direct response to us.
 		parcel.Header.TargetPeer = c.peer.Hash
 		parcel.Header.NodeID = NodeID
-		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+		c.receiveParcel(parcel) // Controller handles these.
 	default:
 		significant(c.peer.PeerIdent(), "!!!!!!!!!!!!!!!!!! Got message of unknown type?")
 	}
@@ -658,7 +966,7 @@ func (c *Connection) pingPeer() {
 			parcel.Header.Type = TypePing
 			c.timeLastPing = time.Now()
 			c.attempts++
-			BlockFreeChannelSend(c.SendChannel, ConnectionParcel{Parcel: *parcel})
+			c.queueParcel(*parcel)
 		}
 	}
 }
@@ -675,6 +983,11 @@ func (c *Connection) updateStats() {
 		c.metrics.PeerQuality = c.peer.QualityScore
 		c.metrics.ConnectionState = connectionStateStrings[c.state]
 		c.metrics.ConnectionNotes = c.notes
+		c.metrics.PeerPort = c.peer.Port
+		c.metrics.IsOutgoing = c.isOutGoing
+		c.metrics.IsPersistent = c.isPersistent
+		c.metrics.IsSpecial = SpecialPeer == c.peer.Type
+		c.metrics.PeerPenalties = c.peer.Penalties
 		verbose(c.peer.PeerIdent(), "updatePeer() SENDING ConnectionUpdateMetrics - Bytes Sent: %d Bytes Received: %d", c.metrics.BytesSent, c.metrics.BytesReceived)
 		BlockFreeChannelSend(c.ReceiveChannel, ConnectionCommand{Command: ConnectionUpdateMetrics, Metrics: c.metrics})
 	}
@@ -15,6 +15,7 @@ import (
 	"math/rand"
 	"net"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -39,13 +40,19 @@ type Controller struct {
 	// After launching the network, the management is done via these channels.
 	commandChannel chan interface{} // Application use controller public API to send commands on this channel to controllers goroutines.
 
-	ToNetwork   chan interface{} // Parcels from the application for us to route
-	FromNetwork chan interface{} // Parcels from the network for the application
+	ToNetwork       chan interface{} // Parcels from the application for us to route
+	FromNetwork     chan interface{} // Parcels from the network for the application
+	ToNetworkHigh   chan interface{} // Parcels of the control and high lanes from the application, routed first
+	FromNetworkHigh chan interface{} // Parcels of the control and high lanes for the application, see lanes.go
 
 	connectionMetricsChannel chan interface{} // Channel on which we put the connection metrics map, periodically.
 
 	connectionMetrics           map[string]ConnectionMetrics // map of the metrics indexed by peer hash
 	lastConnectionMetricsUpdate time.Time                    // update once a second.
+	publishedMetrics            map[string]ConnectionMetrics // copy of the last metrics update for GetConnectionMetrics
+	publishedMetricsMutex       sync.RWMutex
+	publishedConnections        map[string]bool // copy of the hashes of the connections for IsConnected
+	publishedConnectionsMutex   sync.RWMutex
 
 	discovery Discovery // Our discovery structure
 
@@ -55,9 +62,10 @@ type Controller struct {
 	lastDiscoveryRequest       time.Time
 	NodeID                     uint64
 	lastStatusReport           time.Time
-	lastPeerRequest            time.Time       // Last time we asked peers about the peers they know about.
-	specialPeersString         string          // configuration set special peers
-	partsAssembler             *PartsAssembler // a data structure that assembles full messages from received message parts
+	lastPeerRequest            time.Time         // Last time we asked peers about the peers they know about.
+	specialPeersString         string            // configuration set special peers
+	specialPeers               map[string]string // addresses of the special peers we dial, from the configuration or the API, to their pinned node keys
+	partsAssembler             *PartsAssembler   // a data structure that assembles full messages from received message parts
 }
 
 type ControllerInit struct {
@@ -70,6 +78,13 @@ type ControllerInit struct {
 	ConnectionMetricsChannel chan interface{} // Channel on which we put the connection metrics map, periodically.
 	LogPath                  string           // Path for logs
 	LogLevel                 string           // Logging level
+	NodeKeyFile              string           // Path to the node key, connections are encrypted and authenticated when set
+	AllowPlaintextPeers      bool             // Talk to peers without a node key in plaintext, while node keys are rolled out
+	AppTypeLanes             map[string]uint8 // Priority lanes of the application messages by AppType, see lanes.go
+	SendBytesPerSecond       int              // Limit on the bytes we send each peer, 0 is unlimited
+	SendMessagesPerSecond    int              // Limit on the parcels we send each peer, 0 is unlimited
+	ReceiveBytesPerSecond    int              // Limit on the bytes we read from each peer, 0 is unlimited
+	ReceiveMessagesPerSecond int              // Limit on the parcels we read from each peer, 0 is unlimited
 }
 
 // CommandDialPeer is used to instruct the Controller to dial a peer address
@@ -144,6 +159,18 @@ func (e *CommandDisconnect) String() string {
 	return str
 }
 
+// CommandBansChanged is used to instruct the Controller to drop the connections of newly banned
+// addresses and save the bans
+type CommandBansChanged struct {
+	_ uint8
+}
+
+// CommandRemoveSpecialPeer is used to instruct the Controller to stop treating a peer as special
+// and disconnect from it
+type CommandRemoveSpecialPeer struct {
+	Address string
+}
+
 // CommandChangeLogging is used to instruct the Controller to takve various actions.
 type CommandChangeLogging struct {
 	Level uint8
@@ -180,9 +207,14 @@ func (c *Controller) Init(ci ControllerInit) *Controller {
 	c.commandChannel = make(chan interface{}, StandardChannelSize) // Commands from App
 	c.FromNetwork = make(chan interface{}, StandardChannelSize)    // Channel to the app for network data
 	c.ToNetwork = make(chan interface{}, StandardChannelSize)      // Parcels from the app for the network
+	c.FromNetworkHigh = make(chan interface{}, StandardChannelSize)
+	c.ToNetworkHigh = make(chan interface{}, StandardChannelSize)
 	c.connections = make(map[string]*Connection)
 	c.connectionsByAddress = make(map[string]*Connection)
 	c.connectionMetrics = make(map[string]ConnectionMetrics)
+	c.publishedMetrics = make(map[string]ConnectionMetrics)
+	c.publishedConnections = make(map[string]bool)
+	c.specialPeers = make(map[string]string)
 	c.connectionMetricsChannel = ci.ConnectionMetricsChannel
 	c.listenPort = ci.Port
 	NetworkListenPort = ci.Port
@@ -191,6 +223,23 @@ func (c *Controller) Init(ci ControllerInit) *Controller {
 	CurrentNetwork = ci.Network
 	OnlySpecialPeers = ci.Exclusive
 	c.specialPeersString = ci.SpecialPeers
+	if nil != ci.AppTypeLanes {
+		AppTypeLanes = ci.AppTypeLanes
+	}
+	PeerSendBytesPerSecond = ci.SendBytesPerSecond
+	PeerSendMessagesPerSecond = ci.SendMessagesPerSecond
+	PeerReceiveBytesPerSecond = ci.ReceiveBytesPerSecond
+	PeerReceiveMessagesPerSecond = ci.ReceiveMessagesPerSecond
+	if "" != ci.NodeKeyFile {
+		key, err := LoadOrCreateNodeKey(ci.NodeKeyFile)
+		if nil != err {
+			// Carrying on without the key would leave the connections unencrypted
+			panic(fmt.Sprintf("Controller.Init() could not load the node key: %v", err))
+		}
+		LocalNodeKey = key
+		AllowPlaintextPeers = ci.AllowPlaintextPeers
+		significant("ctrlr", "Controller.Init() node key: %s", key.PublicKeyString())
+	}
 	c.lastDiscoveryRequest = time.Now() // Discovery does its own on startup.
 	c.lastConnectionMetricsUpdate = time.Now()
 	c.partsAssembler = new(PartsAssembler).Init()
@@ -213,24 +262,63 @@ func (c *Controller) StartNetwork() {
 	go c.runloop()
 }
 
-// DialSpecialPeersString lets us pass in a string of special peers to dial
+// DialSpecialPeersString lets us pass in a string of special peers to dial.  A peer given as
+// nodekey@127.0.0.1:8999 is pinned by its node key: the connection is dropped if the peer
+// can't prove it holds the key, and a peer that does is special from any address.
 func (c *Controller) DialSpecialPeersString(peersString string) {
 	parseFunc := func(c rune) bool {
 		return !unicode.IsLetter(c) && !unicode.IsNumber(c) && !unicode.IsPunct(c)
 	}
 	peerAddresses := strings.FieldsFunc(peersString, parseFunc)
 	for _, peerAddress := range peerAddresses {
-		ipPort := strings.Split(peerAddress, ":")
-		if len(ipPort) == 2 {
-			peer := new(Peer).Init(ipPort[0], ipPort[1], 0, SpecialPeer, 0)
-			peer.Source["Local-Configuration"] = time.Now()
-			c.DialPeer(*peer, true) // these are persistent connections
-		} else {
-			logfatal("Controller", "Error: %s is not a valid peer, use format: 127.0.0.1:8999", peerAddress)
+		err := c.dialSpecialPeer(peerAddress, "Local-Configuration")
+		if nil != err {
+			logfatal("Controller", "Error: %v", err)
 		}
 	}
 }
 
+// AddSpecialPeer adds a special peer while running, given as 127.0.0.1:8999 or
+// nodekey@127.0.0.1:8999, and dials it
+func (c *Controller) AddSpecialPeer(peerAddress string) error {
+	return c.dialSpecialPeer(peerAddress, "API")
+}
+
+// RemoveSpecialPeer makes the special peer at the address, given as it was added or as just
+// 127.0.0.1, a regular peer again, and disconnects from it
+func (c *Controller) RemoveSpecialPeer(address string) {
+	address = address[strings.LastIndex(address, "@")+1:] // without the node key
+	BlockFreeChannelSend(c.commandChannel, CommandRemoveSpecialPeer{Address: strings.Split(address, ":")[0]})
+}
+
+// dialSpecialPeer pins the node key of the special peer, if given, and dials it for good
+func (c *Controller) dialSpecialPeer(peerAddress string, source string) error {
+	publicKey := ""
+	if keyAddress := strings.Split(peerAddress, "@"); len(keyAddress) == 2 {
+		if nil == LocalNodeKey {
+			return fmt.Errorf("%s is pinned by its node key, which needs a node key file", peerAddress)
+		}
+		key, err := ParseNodeKey(keyAddress[0])
+		if nil != err {
+			return err
+		}
+		publicKey = key
+		peerAddress = keyAddress[1]
+	}
+	ipPort := strings.Split(peerAddress, ":")
+	if len(ipPort) != 2 {
+		return fmt.Errorf("%s is not a valid peer, use format: 127.0.0.1:8999 or nodekey@127.0.0.1:8999", peerAddress)
+	}
+	if "" != publicKey {
+		pinSpecialPeerKey(publicKey)
+	}
+	peer := new(Peer).Init(ipPort[0], ipPort[1], 0, SpecialPeer, 0)
+	peer.PublicKey = publicKey
+	peer.Source[source] = time.Now()
+	c.DialPeer(*peer, true) // these are persistent connections
+	return nil
+}
+
 func (c *Controller) StartLogging(level uint8) {
 	BlockFreeChannelSend(c.commandChannel, CommandChangeLogging{Level: level})
 }
@@ -268,10 +356,53 @@ func (c *Controller) Disconnect(peerHash string) {
 	BlockFreeChannelSend(c.commandChannel, CommandDisconnect{PeerHash: peerHash})
 }
 
+// BanAddress bans an address or subnet, eg 1.2.3.4 or 1.2.3.0/24, for the duration, or for
+// good if the duration is 0, and disconnects the peers it covers
+func (c *Controller) BanAddress(address string, duration time.Duration, reason string) (Ban, error) {
+	ban, err := BanAddress(address, duration, reason)
+	if nil == err {
+		BlockFreeChannelSend(c.commandChannel, CommandBansChanged{})
+	}
+	return ban, err
+}
+
+// Unban lifts the ban of an address or subnet, returning false if it was not banned
+func (c *Controller) Unban(address string) (bool, error) {
+	present, err := Unban(address)
+	if present {
+		BlockFreeChannelSend(c.commandChannel, CommandBansChanged{})
+	}
+	return present, err
+}
+
+// Bans returns the bans in force
+func (c *Controller) Bans() []Ban {
+	return Bans()
+}
+
 func (c *Controller) GetNumberConnections() int {
 	return len(c.connections)
 }
 
+// GetConnectionMetrics returns the metrics of the connections, by peer hash, as of the last
+// update.  They are updated every second.
+func (c *Controller) GetConnectionMetrics() map[string]ConnectionMetrics {
+	c.publishedMetricsMutex.RLock()
+	defer c.publishedMetricsMutex.RUnlock()
+	metrics := make(map[string]ConnectionMetrics, len(c.publishedMetrics))
+	for hash, value := range c.publishedMetrics {
+		metrics[hash] = value
+	}
+	return metrics
+}
+
+// IsConnected returns whether we have a connection to the peer, online or not
+func (c *Controller) IsConnected(peerHash string) bool {
+	c.publishedConnectionsMutex.RLock()
+	defer c.publishedConnectionsMutex.RUnlock()
+	return c.publishedConnections[peerHash]
+}
+
 //////////////////////////////////////////////////////////////////////
 //
 // Private API (unexported)
@@ -304,7 +435,11 @@ func (c *Controller) acceptLoop(listener net.Listener) {
 		conn, err := listener.Accept()
 		switch err {
 		case nil:
+			_, banned := IsBanned(connectionIP(conn).String())
 			switch {
+			case banned:
+				note("ctrlr", "Controller.acceptLoop() refused banned peer: %+v", conn.RemoteAddr())
+				conn.Close()
 			case c.numberIncommingConnections < MaxNumberIncommingConnections:
 				c.AddPeer(conn) // Sends command to add the peer to the peers list
 				note("ctrlr", "Controller.acceptLoop() new peer: %+v", conn)
@@ -338,6 +473,8 @@ func (c *Controller) runloop() {
 		significant("ctrlr", "     Command Queue: %d", len(c.commandChannel))
 		significant("ctrlr", "         ToNetwork: %d", len(c.ToNetwork))
 		significant("ctrlr", "       FromNetwork: %d", len(c.FromNetwork))
+		significant("ctrlr", "     ToNetworkHigh: %d", len(c.ToNetworkHigh))
+		significant("ctrlr", "   FromNetworkHigh: %d", len(c.FromNetworkHigh))
 		significant("ctrlr", "        Total RECV: %d", TotalMessagesRecieved)
 		significant("ctrlr", "  Application RECV: %d", ApplicationMessagesRecieved)
 		significant("ctrlr", "        Total XMIT: %d", TotalMessagesSent)
@@ -399,9 +536,8 @@ func (c *Controller) runloop() {
 func (c *Controller) route() {
 	// Recieve messages from the peers & forward to application.
 	for peerHash, connection := range c.connections {
-		// Empty the recieve channel, stuff the application channel.
-		for 0 < len(connection.ReceiveChannel) { // effectively "While there are messages"
-			message := <-connection.ReceiveChannel
+		// Empty the recieve channel and lanes, stuff the application channel.
+		for message := connection.nextReceived(); nil != message; message = connection.nextReceived() {
 			switch message.(type) {
 			case ConnectionCommand:
 				c.handleConnectionCommand(message.(ConnectionCommand), *connection)
@@ -413,10 +549,15 @@ func (c *Controller) route() {
 		}
 	}
 	// For each message, see if it is directed, if so, send to the
-	// specific peer, otherwise, broadcast.
+	// specific peer, otherwise, broadcast.  The high lane parcels go first.
 	// significant("ctrlr", "Controller.route() size of ToNetwork channel: %d", len(c.ToNetwork))
-	for 0 < len(c.ToNetwork) { // effectively "While there are messages"
-		message := <-c.ToNetwork
+	for 0 < len(c.ToNetworkHigh) || 0 < len(c.ToNetwork) { // effectively "While there are messages"
+		var message interface{}
+		select {
+		case message = <-c.ToNetworkHigh:
+		default:
+			message = <-c.ToNetwork
+		}
 		parcel := message.(Parcel)
 		TotalMessagesSent++
 		switch parcel.Header.TargetPeer {
@@ -448,7 +589,7 @@ func (c *Controller) route() {
 				loopcnt := 0
 				for _, connection := range c.connections {
 					if loopcnt == spot {
-						BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+						connection.queueParcel(parcel)
 						spot++
 						if spot >= clen {
 							spot = 0
@@ -494,7 +635,7 @@ func (c *Controller) route() {
 func (c *Controller) doDirectedSend(parcel Parcel) {
 	connection, present := c.connections[parcel.Header.TargetPeer]
 	if present { // We're still connected to the target
-		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+		connection.queueParcel(parcel)
 	}
 }
 
@@ -507,19 +648,19 @@ func (c *Controller) handleParcelReceive(message interface{}, peerHash string, c
 	switch parcel.Header.Type {
 	case TypeMessage: // Application message, send it on.
 		ApplicationMessagesRecieved++
-		BlockFreeChannelSend(c.FromNetwork, parcel)
+		c.passToApplication(parcel)
 	case TypeMessagePart: // A part of the application message, handle by assembler and if we have the full message, send it on.
 		assembled := c.partsAssembler.handlePart(parcel)
 		if assembled != nil {
 			ApplicationMessagesRecieved++
-			BlockFreeChannelSend(c.FromNetwork, *assembled)
+			c.passToApplication(*assembled)
 		}
-	case TypePeerRequest: // send a response to the connection over its connection.SendChannel
+	case TypePeerRequest: // send a response to the connection over its send lanes
 		// Get selection of peers from discovery
 		response := NewParcel(CurrentNetwork, c.discovery.SharePeers())
 		response.Header.Type = TypePeerResponse
 		// Send them out to the network - on the connection that requested it!
-		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: *response})
+		connection.queueParcel(*response)
 	case TypePeerResponse:
 		// Add these peers to our known peers
 		c.discovery.LearnPeers(parcel)
@@ -529,17 +670,56 @@ func (c *Controller) handleParcelReceive(message interface{}, peerHash string, c
 
 }
 
+// passToApplication sends the parcel on to the application, the parcels of the control and high
+// lanes in FromNetworkHigh so they don't wait behind the rest.  When the application falls
+// behind, the oldest parcels are dropped, as BlockFreeChannelSend does, and each is counted
+// against the peer that sent it.
+func (c *Controller) passToApplication(parcel Parcel) {
+	channel := c.FromNetwork
+	if LaneHigh >= ParcelLane(&parcel) {
+		channel = c.FromNetworkHigh
+	}
+	highWaterMark := int(float64(cap(channel)) * 0.95)
+	for highWaterMark <= len(channel) {
+		select {
+		case dropped := <-channel:
+			countApplicationDrop(dropped.(Parcel))
+		default:
+		}
+	}
+	select {
+	case channel <- parcel:
+	default:
+		countApplicationDrop(parcel)
+	}
+}
+
+// countApplicationDrop counts a parcel the application never got against the peer that sent it
+func countApplicationDrop(parcel Parcel) {
+	p2pConnectionDroppedParcels.WithLabelValues("application", LaneNames[ParcelLane(&parcel)]).Inc()
+}
+
 func (c *Controller) handleConnectionCommand(command ConnectionCommand, connection Connection) {
 	switch command.Command {
 	case ConnectionUpdateMetrics:
 		c.connectionMetrics[connection.peer.Hash] = command.Metrics
 	case ConnectionIsClosed:
-		delete(c.connectionsByAddress, connection.peer.Address)
+		// A connection replacing this one may hold the address by now
+		if byAddress, present := c.connectionsByAddress[connection.peer.Address]; present && byAddress.peer.Hash == connection.peer.Hash {
+			delete(c.connectionsByAddress, connection.peer.Address)
+		}
 		delete(c.connections, connection.peer.Hash)
+		c.publishConnections()
 		delete(c.connectionMetrics, connection.peer.Hash)
 		go connection.goShutdown()
 	case ConnectionUpdatingPeer:
-		c.discovery.updatePeer(command.Peer)
+		peer := command.Peer
+		if _, special := c.specialPeers[peer.Address]; SpecialPeer == peer.Type && !special && !isSpecialPeerKey(peer.PublicKey) {
+			peer.Type = RegularPeer // It was removed from the special peers while connected
+		}
+		c.discovery.updatePeer(peer)
+	case ConnectionBanned:
+		c.handleCommand(CommandBansChanged{})
 	default:
 		logfatal("ctrlr", "handleParcelReceive() unknown command.command?: %+v ", command.Command)
 	}
@@ -549,10 +729,24 @@ func (c *Controller) handleCommand(command interface{}) {
 	switch commandType := command.(type) {
 	case CommandDialPeer: // parameter is the peer address
 		parameters := command.(CommandDialPeer)
+		if SpecialPeer == parameters.peer.Type {
+			c.specialPeers[parameters.peer.Address] = parameters.peer.PublicKey
+		}
+		// We talk to each address over one connection.  A special peer replaces a regular
+		// connection, so it is dialled for good; any other dial of a connected address is dropped.
+		if existing, present := c.connectionsByAddress[parameters.peer.Address]; present {
+			if SpecialPeer != parameters.peer.Type || existing.isPersistent {
+				note("ctrlr", "Controller.handleCommand() already connected to %s, not dialling it again", parameters.peer.Address)
+				break
+			}
+			BlockFreeChannelSend(existing.SendChannel, ConnectionCommand{Command: ConnectionShutdownNow})
+			delete(c.connectionsByAddress, parameters.peer.Address)
+		}
 		conn := new(Connection).Init(parameters.peer, parameters.persistent)
 		conn.Start()
 
 		c.connections[conn.peer.Hash] = conn
+		c.publishConnections()
 		c.connectionsByAddress[conn.peer.Address] = conn
 	case CommandAddPeer: // parameter is a Connection. This message is sent by the accept loop which is in a different goroutine
 
@@ -566,6 +760,7 @@ func (c *Controller) handleCommand(command interface{}) {
 		connection.Start()
 
 		c.connections[connection.peer.Hash] = connection
+		c.publishConnections()
 		c.connectionsByAddress[connection.peer.Address] = connection
 	case CommandShutdown:
 		c.shutdown()
@@ -579,7 +774,37 @@ func (c *Controller) handleCommand(command interface{}) {
 	case CommandBan:
 		parameters := command.(CommandBan)
 		peerHash := parameters.PeerHash
+		connection, present := c.connections[peerHash]
+		if present {
+			// A peer dialled by hostname is banned by its IP address when its quality drops below
+			// the minimum, as the connection knows it
+			if _, err := BanAddress(connection.peer.Address, BanDuration, "banned by the application"); nil != err {
+				note("ctrlr", "CommandBan could not ban %s: %v", connection.peer.Address, err)
+			}
+			c.discovery.SavePeers()
+		}
 		c.applicationPeerUpdate(BannedQualityScore, peerHash)
+	case CommandBansChanged:
+		for _, connection := range c.connections {
+			BlockFreeChannelSend(connection.SendChannel, ConnectionCommand{Command: ConnectionCheckBans})
+		}
+		c.discovery.SavePeers()
+	case CommandRemoveSpecialPeer:
+		parameters := command.(CommandRemoveSpecialPeer)
+		if key := c.specialPeers[parameters.Address]; "" != key {
+			unpinSpecialPeerKey(key) // Whether or not we are connected to it
+		}
+		delete(c.specialPeers, parameters.Address)
+		for _, connection := range c.connections {
+			if connection.peer.Address == parameters.Address && SpecialPeer == connection.peer.Type {
+				unpinSpecialPeerKey(connection.peer.PublicKey)
+				BlockFreeChannelSend(connection.SendChannel, ConnectionCommand{Command: ConnectionShutdownNow})
+			}
+		}
+		if peer := c.discovery.getPeer(parameters.Address); SpecialPeer == peer.Type {
+			peer.Type = RegularPeer
+			c.discovery.updatePeer(peer)
+		}
 	case CommandDisconnect:
 		parameters := command.(CommandDisconnect)
 		peerHash := parameters.PeerHash
@@ -592,6 +817,18 @@ func (c *Controller) handleCommand(command interface{}) {
 	}
 }
 
+// publishConnections copies the hashes of the connections for IsConnected, as the connections
+// are only touched by the runloop
+func (c *Controller) publishConnections() {
+	published := make(map[string]bool, len(c.connections))
+	for hash := range c.connections {
+		published[hash] = true
+	}
+	c.publishedConnectionsMutex.Lock()
+	c.publishedConnections = published
+	c.publishedConnectionsMutex.Unlock()
+}
+
 func (c *Controller) applicationPeerUpdate(qualityDelta int32, peerHash string) {
 	connection, present := c.connections[peerHash]
 	if present {
@@ -634,7 +871,7 @@ func (c *Controller) managePeers() {
 			parcel := *parcelp
 			parcel.Header.Type = TypePeerRequest
 			for _, connection := range c.connections {
-				BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+				connection.queueParcel(parcel)
 			}
 		}
 	}
@@ -708,10 +945,22 @@ func (c *Controller) updateMetrics() {
 					PeerQuality:      metrics.PeerQuality,
 					ConnectionState:  metrics.ConnectionState,
 					ConnectionNotes:  metrics.ConnectionNotes,
+					PeerPort:         metrics.PeerPort,
+					IsOutgoing:       metrics.IsOutgoing,
+					IsPersistent:     metrics.IsPersistent,
+					IsSpecial:        metrics.IsSpecial,
+					PeerPenalties:    metrics.PeerPenalties,
 				}
 			}
 		}
 		dot("@@9\n")
+		published := make(map[string]ConnectionMetrics, len(newMetrics))
+		for key, value := range newMetrics {
+			published[key] = value
+		}
+		c.publishedMetricsMutex.Lock()
+		c.publishedMetrics = published
+		c.publishedMetricsMutex.Unlock()
 		BlockFreeChannelSend(c.connectionMetricsChannel, newMetrics)
 		dot("@@10\n")
 	}
@@ -762,6 +1011,8 @@ func (c *Controller) networkStatusReport() {
 		silence("ctrlr", "          commandChannel: %d", len(c.commandChannel))
 		silence("ctrlr", "               ToNetwork: %d", len(c.ToNetwork))
 		silence("ctrlr", "             FromNetwork: %d", len(c.FromNetwork))
+		silence("ctrlr", "           ToNetworkHigh: %d", len(c.ToNetworkHigh))
+		silence("ctrlr", "         FromNetworkHigh: %d", len(c.FromNetworkHigh))
 		silence("ctrlr", "connectionMetricsChannel: %d", len(c.connectionMetricsChannel))
 		silence("ctrlr", "===================================")
 		silence("ctrlr", "###################################\n\n\n")
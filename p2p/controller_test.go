@@ -0,0 +1,56 @@
+package p2p_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/FactomProject/factomd/p2p"
+)
+
+func newTestController(t *testing.T) (*Controller, func()) {
+	dir, err := ioutil.TempDir("", "controller")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ci := ControllerInit{
+		Port:                     "0",
+		PeersFile:                filepath.Join(dir, "peers.json"),
+		Network:                  TestNet,
+		ConnectionMetricsChannel: make(chan interface{}, StandardChannelSize),
+	}
+	return new(Controller).Init(ci), func() { os.RemoveAll(dir) }
+}
+
+func TestAddSpecialPeer(t *testing.T) {
+	controller, cleanup := newTestController(t)
+	defer cleanup()
+
+	err := controller.AddSpecialPeer("127.0.0.1:8108")
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	for _, address := range []string{"127.0.0.1", "127.0.0.1:8108:1", "abcd@127.0.0.1:8108"} {
+		err = controller.AddSpecialPeer(address)
+		if err == nil {
+			t.Errorf("Added the special peer %s", address)
+		}
+	}
+	controller.RemoveSpecialPeer("127.0.0.1:8108")
+}
+
+func TestGetConnectionMetrics(t *testing.T) {
+	controller, cleanup := newTestController(t)
+	defer cleanup()
+
+	metrics := controller.GetConnectionMetrics()
+	if metrics == nil || len(metrics) != 0 {
+		t.Errorf("Metrics of a controller without connections: %+v", metrics)
+	}
+	// The copy returned is the caller's own
+	metrics["peer"] = ConnectionMetrics{}
+	if len(controller.GetConnectionMetrics()) != 0 {
+		t.Errorf("Changing the metrics returned changed the controller's")
+	}
+}
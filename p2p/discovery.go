@@ -8,6 +8,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
@@ -29,6 +30,13 @@ type Discovery struct {
 
 var UpdateKnownPeers sync.Mutex
 
+// peersFile is what the peers file holds.  Files saved before bans were kept hold only the
+// map of peers, by address and port.
+type peersFile struct {
+	Peers map[string]Peer
+	Bans  []Ban
+}
+
 // Discovery provides the code for sharing and managing peers,
 // namely keeping track of all the peers we know about (not just the ones
 // we are connected to.)  The discovery "service" is owned by the
@@ -43,6 +51,7 @@ func (d *Discovery) Init(peersFile string, seed string) *Discovery {
 	d.peersFilePath = peersFile
 	d.seedURL = seed
 	//d.LoadPeers()
+	d.LoadBans()
 	d.DiscoverPeersFromSeed()
 	return d
 }
@@ -74,25 +83,50 @@ func (d *Discovery) isPeerPresent(peer Peer) bool {
 	return present
 }
 
+// readPeersFile reads the peers and bans from the peers file, in either layout
+func (d *Discovery) readPeersFile() (peersFile, error) {
+	saved := peersFile{}
+	data, err := ioutil.ReadFile(d.peersFilePath)
+	if nil != err {
+		return saved, err
+	}
+	err = json.Unmarshal(data, &saved)
+	if nil == err && nil == saved.Peers && nil == saved.Bans {
+		err = json.Unmarshal(data, &saved.Peers)
+	}
+	return saved, err
+}
+
 // LoadPeers loads the known peers from disk OVERWRITING PREVIOUS VALUES
 func (d *Discovery) LoadPeers() {
-	file, err := os.Open(d.peersFilePath)
+	saved, err := d.readPeersFile()
 	if nil != err {
 		logerror("discovery", "Discover.LoadPeers() File read error on file: %s, Error: %+v", d.peersFilePath, err)
 		return
 	}
-	dec := json.NewDecoder(bufio.NewReader(file))
 	UpdateKnownPeers.Lock()
-	dec.Decode(&d.knownPeers)
 	// since this is run at startup, reset quality scores.
-	for _, peer := range d.knownPeers {
+	for _, peer := range saved.Peers {
 		peer.QualityScore = 0
 		peer.Location = peer.LocationFromAddress()
 		d.knownPeers[peer.Address] = peer
 	}
 	UpdateKnownPeers.Unlock()
 	note("discovery", "LoadPeers() found %d peers in peers.josn", len(d.knownPeers))
-	file.Close()
+	restoreBans(saved.Bans)
+}
+
+// LoadBans restores the bans saved in the peers file, leaving the known peers alone
+func (d *Discovery) LoadBans() {
+	saved, err := d.readPeersFile()
+	if nil != err {
+		if !os.IsNotExist(err) {
+			logerror("discovery", "Discover.LoadBans() File read error on file: %s, Error: %+v", d.peersFilePath, err)
+		}
+		return
+	}
+	restoreBans(saved.Bans)
+	note("discovery", "LoadBans() found %d bans in peers.json", len(saved.Bans))
 }
 
 // SavePeers just saves our known peers out to disk. Called periodically.
@@ -126,9 +160,10 @@ func (d *Discovery) SavePeers() {
 		}
 	}
 	UpdateKnownPeers.Unlock()
-	encoder.Encode(qualityPeers)
+	saved := peersFile{Peers: qualityPeers, Bans: Bans()}
+	encoder.Encode(saved)
 	writer.Flush()
-	note("discovery", "SavePeers() saved %d peers in peers.json. \n They were: %+v", len(qualityPeers), qualityPeers)
+	note("discovery", "SavePeers() saved %d peers and %d bans in peers.json. \n They were: %+v", len(qualityPeers), len(saved.Bans), saved)
 }
 
 // LearnPeers recieves a set of peers from other hosts
@@ -145,6 +180,8 @@ func (d *Discovery) LearnPeers(parcel Parcel) {
 	filteredArray := d.filterPeersFromOtherNetworks(peerArray)
 	for _, value := range filteredArray {
 		value.QualityScore = 0
+		value.PublicKey = "" // only a handshake with the peer tells us its key
+		value.Verified = false
 		switch d.isPeerPresent(value) {
 		case true:
 			alreadyKnownPeer := d.getPeer(value.Address)
@@ -205,6 +242,9 @@ func (d *Discovery) GetOutgoingPeers() []Peer {
 	selectedPeers := map[string]Peer{}
 	UpdateKnownPeers.Lock()
 	for _, peer := range d.knownPeers {
+		if _, banned := IsBanned(peer.Address); banned {
+			continue
+		}
 		switch {
 		case OnlySpecialPeers && SpecialPeer == peer.Type:
 			firstPassPeers = append(firstPassPeers, peer)
@@ -266,6 +306,9 @@ func (d *Discovery) getPeerSelection() []byte {
 	specialPeersByLocation := map[uint32]Peer{}
 	UpdateKnownPeers.Lock()
 	for _, peer := range d.knownPeers {
+		if _, banned := IsBanned(peer.Address); banned { // Don't pass on the peers we refuse
+			continue
+		}
 		if peer.QualityScore > MinumumSharingQualityScore { // Only share peers that have earned positive reputation
 			firstPassPeers = append(firstPassPeers, peer)
 		}
@@ -0,0 +1,357 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	"golang.org/x/crypto/curve25519"
+)
+
+// The handshake authenticates both ends of a connection with their ed25519 node keys and
+// agrees on the keys that encrypt the connection from then on.  Both sides send a hello with
+// an ephemeral curve25519 key and their node key, then a signature over both hellos.  The
+// signatures tie the ephemeral keys to the node keys, so a man in the middle can neither read
+// the traffic nor pose as either side.  After the handshake the gobs are sent in frames sealed
+// with AES-GCM, a 4 byte length followed by the sealed data.
+
+const (
+	handshakeVersion  byte = 1
+	handshakeHelloLen      = 8 + 1 + 4 + 32 + 32 // magic, version, network, ephemeral key, node key
+	maxFramePayload        = 64 * 1024
+)
+
+var handshakeMagic = []byte("FCTP2PHS")
+
+// HandshakeTimeout is how long the handshake of a new connection may take
+var HandshakeTimeout = time.Second * 10
+
+// LocalNodeKey is the key this node proves its identity with.  Connections are only
+// encrypted when it is set, and then every peer must complete the handshake unless
+// AllowPlaintextPeers is set.
+var LocalNodeKey *primitives.PrivateKey
+
+// AllowPlaintextPeers lets a node with a node key keep talking to peers without one while the
+// node keys of a network are rolled out.  Whether a connection is encrypted is settled per
+// peer: a peer dialing in is encrypted if it starts the handshake, and a peer we dial is dialed
+// in plaintext for PlaintextPeerRetry once it answered our hello without one.  Pinned special
+// peers, and every peer when running exclusive with pinned keys, must still complete it.
+var AllowPlaintextPeers bool
+
+// PlaintextPeerRetry is how long a peer we dial is taken to have no node key, after which the
+// handshake is tried again in case the peer has one by now
+var PlaintextPeerRetry = time.Hour
+
+// errNoHandshake is returned when the peer sends something other than a handshake hello
+var errNoHandshake = fmt.Errorf("Peer did not start the handshake")
+
+// plaintextPeers holds when the peers we dial answered without a handshake, by address and port
+var plaintextPeers = map[string]time.Time{}
+var plaintextPeersMutex sync.Mutex
+
+func markPlaintextPeer(address string) {
+	plaintextPeersMutex.Lock()
+	plaintextPeers[address] = time.Now()
+	plaintextPeersMutex.Unlock()
+}
+
+func isPlaintextPeer(address string) bool {
+	plaintextPeersMutex.Lock()
+	defer plaintextPeersMutex.Unlock()
+	marked, ok := plaintextPeers[address]
+	if ok && PlaintextPeerRetry < time.Since(marked) {
+		delete(plaintextPeers, address)
+		return false
+	}
+	return ok
+}
+
+// specialPeerKeys holds the node keys of special peers pinned in the configuration
+var specialPeerKeys = map[string]bool{}
+var specialPeerKeysMutex sync.RWMutex
+
+func pinSpecialPeerKey(key string) {
+	specialPeerKeysMutex.Lock()
+	specialPeerKeys[key] = true
+	specialPeerKeysMutex.Unlock()
+}
+
+func unpinSpecialPeerKey(key string) {
+	specialPeerKeysMutex.Lock()
+	delete(specialPeerKeys, key)
+	specialPeerKeysMutex.Unlock()
+}
+
+func isSpecialPeerKey(key string) bool {
+	specialPeerKeysMutex.RLock()
+	defer specialPeerKeysMutex.RUnlock()
+	return specialPeerKeys[key]
+}
+
+func hasSpecialPeerKeys() bool {
+	specialPeerKeysMutex.RLock()
+	defer specialPeerKeysMutex.RUnlock()
+	return 0 < len(specialPeerKeys)
+}
+
+// LoadOrCreateNodeKey reads the node key from the file, or creates a new key and saves it
+// there if the file does not exist yet.  The file holds the hex encoded private key.
+func LoadOrCreateNodeKey(path string) (*primitives.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		key, err := primitives.NewPrivateKeyFromHex(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid node key in %s: %v", path, err)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := new(primitives.PrivateKey)
+	err = key.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(path, []byte(key.PrivateKeyString()+"\n"), 0600)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParseNodeKey checks a hex encoded node public key, as given for a pinned special peer
+func ParseNodeKey(key string) (string, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("%s is not a hex encoded node key", key)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Handshake runs the handshake over conn with the local node key.  The dialing side is the
+// initiator.  It returns the encrypted connection and the hex encoded node key of the peer.
+func Handshake(conn net.Conn, key *primitives.PrivateKey, initiator bool) (net.Conn, string, error) {
+	conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	ephemeral := new([32]byte)
+	_, err := io.ReadFull(rand.Reader, ephemeral[:])
+	if err != nil {
+		return nil, "", err
+	}
+	ephemeralPub := new([32]byte)
+	curve25519.ScalarBaseMult(ephemeralPub, ephemeral)
+
+	hello := make([]byte, 0, handshakeHelloLen)
+	hello = append(hello, handshakeMagic...)
+	hello = append(hello, handshakeVersion)
+	hello = append(hello, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(hello[9:], uint32(CurrentNetwork))
+	hello = append(hello, ephemeralPub[:]...)
+	hello = append(hello, key.Pub[:]...)
+
+	_, err = conn.Write(hello)
+	if err != nil {
+		return nil, "", err
+	}
+	peerHello := make([]byte, handshakeHelloLen)
+	_, err = io.ReadFull(conn, peerHello)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case !bytes.Equal(peerHello[:8], handshakeMagic):
+		return nil, "", errNoHandshake
+	case peerHello[8] != handshakeVersion:
+		return nil, "", fmt.Errorf("Peer uses handshake version %d", peerHello[8])
+	case NetworkID(binary.BigEndian.Uint32(peerHello[9:])) != CurrentNetwork:
+		return nil, "", fmt.Errorf("Peer is on network %x", binary.BigEndian.Uint32(peerHello[9:]))
+	case bytes.Equal(peerHello[45:], key.Pub[:]):
+		return nil, "", fmt.Errorf("Peer uses our own node key")
+	}
+
+	// The transcript is the same on both sides: the hello of the initiator comes first
+	var transcript []byte
+	if initiator {
+		transcript = append(append(transcript, hello...), peerHello...)
+	} else {
+		transcript = append(append(transcript, peerHello...), hello...)
+	}
+	sig := key.Sign(handshakeSignedData(transcript, initiator)).Bytes()
+	_, err = conn.Write(sig)
+	if err != nil {
+		return nil, "", err
+	}
+	peerSig := make([]byte, len(sig))
+	_, err = io.ReadFull(conn, peerSig)
+	if err != nil {
+		return nil, "", err
+	}
+	if !primitives.VerifySlice(peerHello[45:], handshakeSignedData(transcript, !initiator), peerSig) {
+		return nil, "", fmt.Errorf("Peer signature does not match its node key")
+	}
+
+	peerEphemeral := new([32]byte)
+	copy(peerEphemeral[:], peerHello[13:45])
+	secret := new([32]byte)
+	curve25519.ScalarMult(secret, ephemeral, peerEphemeral)
+
+	send, err := newFrameCipher(secret, transcript, initiator)
+	if err != nil {
+		return nil, "", err
+	}
+	receive, err := newFrameCipher(secret, transcript, !initiator)
+	if err != nil {
+		return nil, "", err
+	}
+	sc := &secureConn{Conn: conn, send: send, receive: receive}
+	return sc, hex.EncodeToString(peerHello[45:]), nil
+}
+
+// StartsHandshake reads, without consuming them, the first bytes a peer dialing in sends, and
+// tells whether they are a handshake hello.  The returned connection reads those bytes again.
+func StartsHandshake(conn net.Conn) (net.Conn, bool, error) {
+	conn.SetReadDeadline(time.Now().Add(HandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	magic, err := reader.Peek(len(handshakeMagic))
+	if err != nil {
+		return nil, false, err
+	}
+	return &peekedConn{Conn: conn, reader: reader}, bytes.Equal(magic, handshakeMagic), nil
+}
+
+// peekedConn is a net.Conn whose first bytes have been peeked at through its reader
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (p *peekedConn) Read(data []byte) (int, error) {
+	return p.reader.Read(data)
+}
+
+// handshakeSignedData is what the initiator or the responder signs
+func handshakeSignedData(transcript []byte, initiator bool) []byte {
+	role := "responder"
+	if initiator {
+		role = "initiator"
+	}
+	hash := sha256.Sum256(transcript)
+	return append([]byte("factomd p2p handshake "+role), hash[:]...)
+}
+
+// frameCipher seals the frames sent in one direction, with a counter as nonce
+type frameCipher struct {
+	aead    cipher.AEAD
+	counter uint64
+	nonce   []byte
+}
+
+func newFrameCipher(secret *[32]byte, transcript []byte, initiator bool) (*frameCipher, error) {
+	direction := "responder to initiator"
+	if initiator {
+		direction = "initiator to responder"
+	}
+	hash := sha256.Sum256(transcript)
+	data := append([]byte("factomd p2p "+direction), secret[:]...)
+	key := sha256.Sum256(append(data, hash[:]...))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &frameCipher{aead: aead, nonce: make([]byte, aead.NonceSize())}, nil
+}
+
+func (f *frameCipher) nextNonce() []byte {
+	binary.BigEndian.PutUint64(f.nonce[len(f.nonce)-8:], f.counter)
+	f.counter++
+	return f.nonce
+}
+
+// secureConn is a net.Conn that encrypts what is written to it and decrypts what is read
+type secureConn struct {
+	net.Conn
+	send        *frameCipher
+	receive     *frameCipher
+	writeMutex  sync.Mutex
+	readMutex   sync.Mutex
+	unread      []byte // decrypted data not read yet
+	frameLength [4]byte
+}
+
+func (s *secureConn) Write(data []byte) (int, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	written := 0
+	for written < len(data) {
+		end := written + maxFramePayload
+		if end > len(data) {
+			end = len(data)
+		}
+		frame := make([]byte, 4, 4+end-written+s.send.aead.Overhead())
+		frame = s.send.aead.Seal(frame, s.send.nextNonce(), data[written:end], nil)
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+		_, err := s.Conn.Write(frame)
+		if err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+func (s *secureConn) Read(data []byte) (int, error) {
+	s.readMutex.Lock()
+	defer s.readMutex.Unlock()
+
+	if len(s.unread) == 0 {
+		_, err := io.ReadFull(s.Conn, s.frameLength[:])
+		if err != nil {
+			return 0, err
+		}
+		length := binary.BigEndian.Uint32(s.frameLength[:])
+		if length > uint32(maxFramePayload+s.receive.aead.Overhead()) {
+			return 0, fmt.Errorf("Frame of %d bytes is too large", length)
+		}
+		frame := make([]byte, length)
+		_, err = io.ReadFull(s.Conn, frame)
+		if err != nil {
+			return 0, err
+		}
+		s.unread, err = s.receive.aead.Open(frame[:0], s.receive.nextNonce(), frame, nil)
+		if err != nil {
+			return 0, fmt.Errorf("Frame failed authentication")
+		}
+	}
+	n := copy(data, s.unread)
+	s.unread = s.unread[n:]
+	return n, nil
+}
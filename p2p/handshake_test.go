@@ -0,0 +1,202 @@
+package p2p_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/p2p"
+)
+
+// tcpPipe returns both ends of a loopback connection. Unlike net.Pipe, writes don't wait for
+// the other end to read, as on the network.
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer listener.Close()
+	a, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	b, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return a, b
+}
+
+func newNodeKey(t *testing.T) *primitives.PrivateKey {
+	key := new(primitives.PrivateKey)
+	err := key.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return key
+}
+
+type handshakeResult struct {
+	conn net.Conn
+	key  string
+	err  error
+}
+
+func runHandshake(a, b net.Conn, keyA, keyB *primitives.PrivateKey) (handshakeResult, handshakeResult) {
+	done := make(chan handshakeResult)
+	go func() {
+		conn, key, err := Handshake(b, keyB, false)
+		done <- handshakeResult{conn, key, err}
+	}()
+	conn, key, err := Handshake(a, keyA, true)
+	return handshakeResult{conn, key, err}, <-done
+}
+
+func TestHandshake(t *testing.T) {
+	keyA := newNodeKey(t)
+	keyB := newNodeKey(t)
+	a, b := tcpPipe(t)
+	defer a.Close()
+	defer b.Close()
+
+	resA, resB := runHandshake(a, b, keyA, keyB)
+	if resA.err != nil || resB.err != nil {
+		t.Fatalf("Handshake failed: %v %v", resA.err, resB.err)
+	}
+	if resA.key != keyB.PublicKeyString() || resB.key != keyA.PublicKeyString() {
+		t.Errorf("Handshake returned the wrong node keys")
+	}
+
+	// More than one frame each way
+	data := make([]byte, 200*1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	go func() {
+		resA.conn.Write(data)
+	}()
+	received := make([]byte, len(data))
+	_, err := io.ReadFull(resB.conn, received)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !bytes.Equal(data, received) {
+		t.Errorf("Data changed crossing the connection")
+	}
+}
+
+func TestHandshakeTampered(t *testing.T) {
+	keyA := newNodeKey(t)
+	keyB := newNodeKey(t)
+	a, b := tcpPipe(t)
+	defer a.Close()
+	defer b.Close()
+
+	resA, resB := runHandshake(a, b, keyA, keyB)
+	if resA.err != nil || resB.err != nil {
+		t.Fatalf("Handshake failed: %v %v", resA.err, resB.err)
+	}
+
+	// A frame written to the raw connection without the keys fails
+	go func() {
+		a.Write([]byte{0, 0, 0, 20})
+		a.Write(make([]byte, 20))
+	}()
+	_, err := resB.conn.Read(make([]byte, 10))
+	if err == nil {
+		t.Errorf("Read a frame that was not sealed with the connection keys")
+	}
+}
+
+func TestHandshakeNotAPeer(t *testing.T) {
+	a, b := tcpPipe(t)
+	defer a.Close()
+	defer b.Close()
+
+	go func() {
+		b.Write(make([]byte, 77))
+		b.Read(make([]byte, 77))
+	}()
+	_, _, err := Handshake(a, newNodeKey(t), true)
+	if err == nil {
+		t.Errorf("Handshake succeeded with a peer not running it")
+	}
+}
+
+func TestStartsHandshake(t *testing.T) {
+	keyA := newNodeKey(t)
+	keyB := newNodeKey(t)
+	a, b := tcpPipe(t)
+	defer a.Close()
+	defer b.Close()
+
+	// A peer with a node key dialing in starts the handshake
+	done := make(chan handshakeResult)
+	go func() {
+		conn, key, err := Handshake(a, keyA, true)
+		done <- handshakeResult{conn, key, err}
+	}()
+	conn, hello, err := StartsHandshake(b)
+	if err != nil || !hello {
+		t.Fatalf("Handshake hello not recognised: %v", err)
+	}
+	_, key, err := Handshake(conn, keyB, false)
+	resA := <-done
+	if err != nil || resA.err != nil {
+		t.Fatalf("Handshake failed after the hello was peeked at: %v %v", resA.err, err)
+	}
+	if key != keyA.PublicKeyString() {
+		t.Errorf("Handshake returned the wrong node key")
+	}
+
+	// A peer without one sends plaintext, which is still read in full
+	c, d := tcpPipe(t)
+	defer c.Close()
+	defer d.Close()
+	plaintext := []byte("a plaintext gob parcel")
+	go c.Write(plaintext)
+	conn, hello, err = StartsHandshake(d)
+	if err != nil || hello {
+		t.Fatalf("Plaintext taken for a handshake hello: %v", err)
+	}
+	read := make([]byte, len(plaintext))
+	_, err = io.ReadFull(conn, read)
+	if err != nil || !bytes.Equal(read, plaintext) {
+		t.Errorf("Peeked plaintext not read again: %v %s", err, read)
+	}
+}
+
+func TestLoadOrCreateNodeKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nodekey")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "node.key")
+
+	key, err := LoadOrCreateNodeKey(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	loaded, err := LoadOrCreateNodeKey(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if key.PublicKeyString() != loaded.PublicKeyString() {
+		t.Errorf("The node key changed when loaded again")
+	}
+
+	_, err = ParseNodeKey(key.PublicKeyString())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	_, err = ParseNodeKey("abcd")
+	if err == nil {
+		t.Errorf("Parsed a short node key")
+	}
+}
@@ -91,6 +91,15 @@ var (
 		Name: "factomd_p2p_goOffline_total",
 		Help: "Number of times we call goOffline()",
 	})
+
+	p2pConnectionDroppedParcels = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "factomd_p2p_connection_dropped_parcels_total",
+		Help: "Number of parcels dropped from full queues, by direction and lane",
+	}, []string{"direction", "lane"})
+	p2pPeerPenalties = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "factomd_p2p_peer_penalties_total",
+		Help: "Number of penalties given to peers, by reason",
+	}, []string{"reason"})
 )
 
 var registered = false
@@ -126,5 +135,7 @@ func RegisterPrometheus() {
 
 	// Connections
 	prometheus.MustRegister(p2pConnectionCommonInit)
+	prometheus.MustRegister(p2pConnectionDroppedParcels)
+	prometheus.MustRegister(p2pPeerPenalties)
 
 }
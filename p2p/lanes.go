@@ -0,0 +1,100 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"math"
+	"time"
+)
+
+// Each connection queues the parcels it sends in priority lanes.  A lane is sent only when the
+// lanes before it are empty, and a full lane drops its own oldest parcels, so bulk parcels never
+// hold up or push out the ones consensus depends on.
+const (
+	LaneControl   uint8 = iota // Network parcels: pings, peer requests and the like
+	LaneHigh                   // Messages consensus waits on, eg acks, EOMs and directory block signatures
+	LaneNormal                 // Other application messages
+	LaneBulk                   // Large responses, eg directory block states
+	NumberOfLanes              // Not a lane, the number of lanes
+)
+
+// LaneNames maps the lanes to names for metrics and printing
+var LaneNames = map[uint8]string{
+	LaneControl: "control",
+	LaneHigh:    "high",
+	LaneNormal:  "normal",
+	LaneBulk:    "bulk",
+}
+
+// AppTypeLanes assigns the application messages to lanes by the AppType of their parcels.
+// Messages of other types go in LaneNormal.  Set by the controller from ControllerInit.
+var AppTypeLanes = map[string]uint8{}
+
+// ParcelLane returns the lane the parcel is sent in
+func ParcelLane(parcel *Parcel) uint8 {
+	switch parcel.Header.Type {
+	case TypeMessage, TypeMessagePart:
+		lane, present := AppTypeLanes[parcel.Header.AppType]
+		if present && lane < NumberOfLanes {
+			return lane
+		}
+		return LaneNormal
+	default:
+		return LaneControl
+	}
+}
+
+// RateLimiter paces the parcels going one way over a connection to a number of bytes and a number
+// of messages per second.  Up to a second's worth of each can go at once.  A nil RateLimiter,
+// as NewRateLimiter returns when there is no limit, never waits.
+type RateLimiter struct {
+	bytesPerSecond    float64
+	messagesPerSecond float64
+	bytes             float64 // Allowance left, negative when a parcel had to wait for it
+	messages          float64
+	last              time.Time
+}
+
+// NewRateLimiter returns a limiter to the rates, 0 leaving a rate unlimited
+func NewRateLimiter(bytesPerSecond int, messagesPerSecond int) *RateLimiter {
+	if 0 >= bytesPerSecond && 0 >= messagesPerSecond {
+		return nil
+	}
+	r := new(RateLimiter)
+	r.bytesPerSecond = math.Max(0, float64(bytesPerSecond))
+	r.messagesPerSecond = math.Max(0, float64(messagesPerSecond))
+	r.bytes = r.bytesPerSecond
+	r.messages = r.messagesPerSecond
+	r.last = time.Now()
+	return r
+}
+
+// Delay takes a parcel with a payload of size bytes from the allowance and returns how long to
+// wait, from now, before it goes
+func (r *RateLimiter) Delay(size int, now time.Time) time.Duration {
+	if nil == r {
+		return 0
+	}
+	elapsed := now.Sub(r.last).Seconds()
+	if 0 > elapsed {
+		elapsed = 0
+	}
+	r.last = now
+
+	wait := 0.0
+	if 0 < r.bytesPerSecond {
+		r.bytes = math.Min(r.bytes+elapsed*r.bytesPerSecond, r.bytesPerSecond) - float64(size)
+		if 0 > r.bytes {
+			wait = -r.bytes / r.bytesPerSecond
+		}
+	}
+	if 0 < r.messagesPerSecond {
+		r.messages = math.Min(r.messages+elapsed*r.messagesPerSecond, r.messagesPerSecond) - 1
+		if 0 > r.messages {
+			wait = math.Max(wait, -r.messages/r.messagesPerSecond)
+		}
+	}
+	return time.Duration(wait * float64(time.Second))
+}
@@ -0,0 +1,60 @@
+package p2p_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/FactomProject/factomd/p2p"
+)
+
+func TestParcelLane(t *testing.T) {
+	AppTypeLanes = map[string]uint8{"1": LaneHigh, "20": LaneBulk}
+	defer func() { AppTypeLanes = map[string]uint8{} }()
+
+	parcel := NewParcel(TestNet, []byte("payload"))
+	for appType, lane := range map[string]uint8{"1": LaneHigh, "20": LaneBulk, "5": LaneNormal} {
+		parcel.Header.AppType = appType
+		parcel.Header.Type = TypeMessage
+		if ParcelLane(parcel) != lane {
+			t.Errorf("Message of type %s went in lane %d, not %d", appType, ParcelLane(parcel), lane)
+		}
+		parcel.Header.Type = TypeMessagePart
+		if ParcelLane(parcel) != lane {
+			t.Errorf("Message part of type %s went in lane %d, not %d", appType, ParcelLane(parcel), lane)
+		}
+	}
+	parcel.Header.Type = TypePing
+	if ParcelLane(parcel) != LaneControl {
+		t.Errorf("Ping went in lane %d", ParcelLane(parcel))
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *RateLimiter
+	if unlimited.Delay(1000000, time.Now()) != 0 || NewRateLimiter(0, 0) != nil {
+		t.Errorf("An unlimited rate limiter waited")
+	}
+
+	now := time.Now()
+	bytes := NewRateLimiter(1000, 0)
+	// A second's worth goes at once, then each parcel waits for its bytes
+	if bytes.Delay(1000, now) != 0 {
+		t.Errorf("The first second's worth of bytes waited")
+	}
+	if delay := bytes.Delay(500, now); delay != 500*time.Millisecond {
+		t.Errorf("Waited %s for 500 bytes over the limit", delay)
+	}
+	if delay := bytes.Delay(500, now.Add(2*time.Second)); delay != 0 {
+		t.Errorf("Waited %s after the allowance built up again", delay)
+	}
+
+	messages := NewRateLimiter(0, 10)
+	for i := 0; i < 10; i++ {
+		if messages.Delay(1000000, now) != 0 {
+			t.Errorf("Message %d of the first second waited", i)
+		}
+	}
+	if delay := messages.Delay(1, now); delay != 100*time.Millisecond {
+		t.Errorf("Waited %s for a message over the limit", delay)
+	}
+}
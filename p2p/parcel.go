@@ -42,26 +42,28 @@ type ParcelCommandType uint16
 
 // Parcel commands -- all new commands should be added to the *end* of the list!
 const ( // iota is reset to 0
-	TypeHeartbeat    ParcelCommandType = iota // "Note, I'm still alive"
-	TypePing                                  // "Are you there?"
-	TypePong                                  // "yes, I'm here"
-	TypePeerRequest                           // "Please share some peers"
-	TypePeerResponse                          // "Here's some peers I know about."
-	TypeAlert                                 // network wide alerts (used in bitcoin to indicate criticalities)
-	TypeMessage                               // Application level message
-	TypeMessagePart                           // Application level message that was split into multiple parts
+	TypeHeartbeat        ParcelCommandType = iota // "Note, I'm still alive"
+	TypePing                                      // "Are you there?"
+	TypePong                                      // "yes, I'm here"
+	TypePeerRequest                               // "Please share some peers"
+	TypePeerResponse                              // "Here's some peers I know about."
+	TypeAlert                                     // network wide alerts (used in bitcoin to indicate criticalities)
+	TypeMessage                                   // Application level message
+	TypeMessagePart                               // Application level message that was split into multiple parts
+	TypeBinaryWireFormat                          // "The parcels after this one are in the binary format"
 )
 
 // CommandStrings is a Map of command ids to strings for easy printing of network comands
 var CommandStrings = map[ParcelCommandType]string{
-	TypeHeartbeat:    "Heartbeat",     // "Note, I'm still alive"
-	TypePing:         "Ping",          // "Are you there?"
-	TypePong:         "Pong",          // "yes, I'm here"
-	TypePeerRequest:  "Peer-Request",  // "Please share some peers"
-	TypePeerResponse: "Peer-Response", // "Here's some peers I know about."
-	TypeAlert:        "Alert",         // network wide alerts (used in bitcoin to indicate criticalities)
-	TypeMessage:      "Message",       // Application level message
-	TypeMessagePart:  "MessagePart",   // Application level message that was split into multiple parts
+	TypeHeartbeat:        "Heartbeat",        // "Note, I'm still alive"
+	TypePing:             "Ping",             // "Are you there?"
+	TypePong:             "Pong",             // "yes, I'm here"
+	TypePeerRequest:      "Peer-Request",     // "Please share some peers"
+	TypePeerResponse:     "Peer-Response",    // "Here's some peers I know about."
+	TypeAlert:            "Alert",            // network wide alerts (used in bitcoin to indicate criticalities)
+	TypeMessage:          "Message",          // Application level message
+	TypeMessagePart:      "MessagePart",      // Application level message that was split into multiple parts
+	TypeBinaryWireFormat: "BinaryWireFormat", // "The parcels after this one are in the binary format"
 }
 
 // MaxPayloadSize is the maximum bytes a message can be at the networking level.
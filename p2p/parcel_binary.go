@@ -0,0 +1,158 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The binary wire format of a parcel.  Connections start out sending gobs, which every version
+// understands.  Once a peer shows with the version in its parcel headers that it knows the binary
+// format, we send it a TypeBinaryWireFormat parcel, still as a gob, and every parcel after it in
+// the binary format.  Each direction of a connection switches on its own.
+//
+// A parcel is sent as a frame, all integers big endian:
+//
+//	4 bytes  length of the rest of the frame
+//	2 bytes  format version, BinaryFormatVersion
+//	4 bytes  Network
+//	2 bytes  Version
+//	2 bytes  Type
+//	4 bytes  Length
+//	4 bytes  Crc32
+//	2 bytes  PartNo
+//	2 bytes  PartsTotal
+//	8 bytes  NodeID
+//	TargetPeer, PeerAddress, PeerPort, AppHash and AppType, each as a 2 byte length and the bytes
+//	the payload, up to the end of the frame
+
+// BinaryFormatVersion is the version of the binary format written in each frame
+const BinaryFormatVersion uint16 = 1
+
+const (
+	binaryFixedHeaderSize = 2 + 4 + 2 + 2 + 4 + 4 + 2 + 2 + 8
+	binaryStringCount     = 5
+	maxBinaryHeaderSize   = binaryFixedHeaderSize + binaryStringCount*(2+0xffff)
+	maxBinaryFrameSize    = maxBinaryHeaderSize + MaxPayloadSize
+)
+
+// writeFrame returns the parcel as a frame of the binary wire format.  It is not MarshalBinary,
+// which would make gob encode parcels with it and break the gobs older peers decode.
+func (p *Parcel) writeFrame() ([]byte, error) {
+	fields := []string{p.Header.TargetPeer, p.Header.PeerAddress, p.Header.PeerPort, p.Header.AppHash, p.Header.AppType}
+	size := 4 + binaryFixedHeaderSize + len(p.Payload)
+	for _, s := range fields {
+		if len(s) > 0xffff {
+			return nil, fmt.Errorf("Parcel header field of %d bytes is too long", len(s))
+		}
+		size += 2 + len(s)
+	}
+	if size-4 > maxBinaryFrameSize {
+		return nil, fmt.Errorf("Parcel of %d bytes is too large", size)
+	}
+
+	data := make([]byte, size)
+	binary.BigEndian.PutUint32(data[0:], uint32(size-4))
+	binary.BigEndian.PutUint16(data[4:], BinaryFormatVersion)
+	binary.BigEndian.PutUint32(data[6:], uint32(p.Header.Network))
+	binary.BigEndian.PutUint16(data[10:], p.Header.Version)
+	binary.BigEndian.PutUint16(data[12:], uint16(p.Header.Type))
+	binary.BigEndian.PutUint32(data[14:], p.Header.Length)
+	binary.BigEndian.PutUint32(data[18:], p.Header.Crc32)
+	binary.BigEndian.PutUint16(data[22:], p.Header.PartNo)
+	binary.BigEndian.PutUint16(data[24:], p.Header.PartsTotal)
+	binary.BigEndian.PutUint64(data[26:], p.Header.NodeID)
+	i := 4 + binaryFixedHeaderSize
+	for _, s := range fields {
+		binary.BigEndian.PutUint16(data[i:], uint16(len(s)))
+		i += 2 + copy(data[i+2:], s)
+	}
+	copy(data[i:], p.Payload)
+	return data, nil
+}
+
+// readFrame reads the parcel from a frame of the binary wire format
+func (p *Parcel) readFrame(data []byte) error {
+	if len(data) < 4 || int(binary.BigEndian.Uint32(data)) != len(data)-4 {
+		return fmt.Errorf("Parcel frame length does not match its %d bytes", len(data))
+	}
+	return p.unmarshalFrame(data[4:])
+}
+
+// unmarshalFrame reads the parcel from a frame without its length
+func (p *Parcel) unmarshalFrame(frame []byte) error {
+	if len(frame) < binaryFixedHeaderSize {
+		return fmt.Errorf("Parcel frame of %d bytes is too short", len(frame))
+	}
+	version := binary.BigEndian.Uint16(frame[0:])
+	if version != BinaryFormatVersion {
+		return fmt.Errorf("Parcel frame has unknown format version %d", version)
+	}
+
+	header := ParcelHeader{}
+	header.Network = NetworkID(binary.BigEndian.Uint32(frame[2:]))
+	header.Version = binary.BigEndian.Uint16(frame[6:])
+	header.Type = ParcelCommandType(binary.BigEndian.Uint16(frame[8:]))
+	header.Length = binary.BigEndian.Uint32(frame[10:])
+	header.Crc32 = binary.BigEndian.Uint32(frame[14:])
+	header.PartNo = binary.BigEndian.Uint16(frame[18:])
+	header.PartsTotal = binary.BigEndian.Uint16(frame[20:])
+	header.NodeID = binary.BigEndian.Uint64(frame[22:])
+
+	fields := []*string{&header.TargetPeer, &header.PeerAddress, &header.PeerPort, &header.AppHash, &header.AppType}
+	i := binaryFixedHeaderSize
+	for _, s := range fields {
+		if len(frame) < i+2 {
+			return fmt.Errorf("Parcel frame ends in its header")
+		}
+		length := int(binary.BigEndian.Uint16(frame[i:]))
+		i += 2
+		if len(frame) < i+length {
+			return fmt.Errorf("Parcel frame ends in its header")
+		}
+		*s = string(frame[i : i+length])
+		i += length
+	}
+
+	p.Header = header
+	p.Payload = frame[i:]
+	return nil
+}
+
+// WriteParcel writes the parcel to w in the binary wire format
+func WriteParcel(w io.Writer, parcel *Parcel) error {
+	data, err := parcel.writeFrame()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadParcel reads a parcel in the binary wire format from r
+func ReadParcel(r io.Reader, parcel *Parcel) error {
+	var length [4]byte
+	_, err := io.ReadFull(r, length[:])
+	if err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxBinaryFrameSize {
+		return fmt.Errorf("Parcel frame of %d bytes is too large", size)
+	}
+	// The buffer grows as the frame arrives, rather than trusting the length up front
+	var frame bytes.Buffer
+	_, err = io.CopyN(&frame, r, int64(size))
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	return parcel.unmarshalFrame(frame.Bytes())
+}
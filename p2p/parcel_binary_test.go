@@ -0,0 +1,138 @@
+package p2p_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	. "github.com/FactomProject/factomd/p2p"
+)
+
+func testParcel() *Parcel {
+	parcel := NewParcel(TestNet, []byte("Some payload"))
+	parcel.Header.TargetPeer = "127.0.0.1:8108 1234"
+	parcel.Header.PeerAddress = "127.0.0.1"
+	parcel.Header.NodeID = 0x1234567890
+	parcel.Header.PartNo = 1
+	parcel.Header.PartsTotal = 2
+	return parcel
+}
+
+func TestWriteReadParcel(t *testing.T) {
+	parcel := testParcel()
+	var buffer bytes.Buffer
+	err := WriteParcel(&buffer, parcel)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	data := buffer.Bytes()
+	parcel2 := new(Parcel)
+	err = ReadParcel(bytes.NewReader(data), parcel2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !reflect.DeepEqual(parcel, parcel2) {
+		t.Errorf("Parcels differ:\n%+v\n%+v", parcel, parcel2)
+	}
+
+	// Every shorter frame fails
+	for i := 0; i < len(data); i++ {
+		err = ReadParcel(bytes.NewReader(data[:i]), new(Parcel))
+		if err == nil {
+			t.Errorf("Read a frame cut to %d bytes", i)
+		}
+	}
+}
+
+// gobParcel is the Parcel of the versions without the binary format, which decode its gobs
+type gobParcel struct {
+	Header  ParcelHeader
+	Payload []byte
+}
+
+// TestParcelGob checks a parcel, sent by value as the connection does, still encodes as the gob
+// of a plain struct
+func TestParcelGob(t *testing.T) {
+	parcel := testParcel()
+	var stream bytes.Buffer
+	err := gob.NewEncoder(&stream).Encode(*parcel)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var old gobParcel
+	err = gob.NewDecoder(&stream).Decode(&old)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !reflect.DeepEqual(parcel.Header, old.Header) || !bytes.Equal(parcel.Payload, old.Payload) {
+		t.Errorf("Parcels differ:\n%+v\n%+v", parcel, old)
+	}
+}
+
+func TestReadParcelGarbage(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		data := make([]byte, r.Intn(200))
+		r.Read(data)
+		if i%2 == 0 && len(data) > 6 {
+			// Mostly valid frame starts, to get past the checks of the frame
+			data[0], data[1], data[2], data[3] = 0, 0, 0, byte(len(data)-4)
+			data[4], data[5] = 0, 1
+		}
+		ReadParcel(bytes.NewReader(data), new(Parcel))
+	}
+}
+
+// TestSwitchToBinary checks a stream of gobs followed by binary frames, as sent by a connection
+// switching to the binary format, reads back whole
+func TestSwitchToBinary(t *testing.T) {
+	var stream bytes.Buffer
+	encoder := gob.NewEncoder(&stream)
+	first := testParcel()
+	request := NewParcel(TestNet, []byte("Binary Wire Format"))
+	request.Header.Type = TypeBinaryWireFormat
+	err := encoder.Encode(first)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = encoder.Encode(request)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	parcels := []*Parcel{}
+	for i := 0; i < 3; i++ {
+		parcel := NewParcel(TestNet, bytes.Repeat([]byte{byte(i)}, 5000*i))
+		parcels = append(parcels, parcel)
+		err = WriteParcel(&stream, parcel)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	reader := bufio.NewReader(&stream)
+	decoder := gob.NewDecoder(reader)
+	var parcel Parcel
+	err = decoder.Decode(&parcel)
+	if err != nil || parcel.Header.NodeID != first.Header.NodeID {
+		t.Fatalf("Decoding the first gob: %v", err)
+	}
+	err = decoder.Decode(&parcel)
+	if err != nil || parcel.Header.Type != TypeBinaryWireFormat {
+		t.Fatalf("Decoding the switch to binary: %v", err)
+	}
+	for _, expected := range parcels {
+		err = ReadParcel(reader, &parcel)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !bytes.Equal(parcel.Payload, expected.Payload) || parcel.Header.Crc32 != expected.Header.Crc32 {
+			t.Errorf("Binary parcel differs")
+		}
+	}
+	if ReadParcel(reader, &parcel) == nil {
+		t.Errorf("Read a parcel past the end of the stream")
+	}
+}
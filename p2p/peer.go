@@ -6,6 +6,7 @@ package p2p
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -26,6 +27,9 @@ type Peer struct {
 	Connections  int                  // Number of successful connections.
 	LastContact  time.Time            // Keep track of how long ago we talked to the peer.
 	Source       map[string]time.Time // source where we heard from the peer.
+	PublicKey    string               // hex encoded node key, pinned in the configuration or proven in the handshake
+	Verified     bool                 `json:"-"` // the peer proved it holds PublicKey on the current connection
+	Penalties    map[string]uint32    // number of times the peer was penalized, by reason. See reputation.go
 }
 
 const ( // iota is reset to 0
@@ -109,6 +113,22 @@ func (p *Peer) demerit() {
 	}
 }
 
+// penalize takes the score from the peer's reputation and records the reason.  The penalties
+// are copied rather than changed in place, as copies of the peer on other goroutines share them.
+func (p *Peer) penalize(reason uint8, score int32) {
+	penalties := map[string]uint32{}
+	for name, count := range p.Penalties {
+		penalties[name] = count
+	}
+	penalties[PenaltyNames[reason]]++
+	p.Penalties = penalties
+	if math.MinInt32+score < p.QualityScore {
+		p.QualityScore -= score
+	} else {
+		p.QualityScore = math.MinInt32
+	}
+}
+
 // sort.Sort interface implementation
 type PeerQualitySort []Peer
 
@@ -67,6 +67,14 @@ var (
 	PeerSaveInterval                     = time.Second * 30
 	PeerRequestInterval                  = time.Second * 180
 	PeerDiscoveryInterval                = time.Hour * 4
+	UseBinaryWireFormat                  = true               // Switch connections to the binary parcel format when the peer knows it
+	BanDuration                          = time.Hour * 24 * 7 // How long a peer is banned for, see reputation.go
+	// Limits on what goes to and comes from each peer, 0 is unlimited. The send limits apply to each
+	// lane. Special peers are never limited.
+	PeerSendBytesPerSecond       = 0
+	PeerSendMessagesPerSecond    = 0
+	PeerReceiveBytesPerSecond    = 0
+	PeerReceiveMessagesPerSecond = 0
 
 	// Testing metrics
 	TotalMessagesRecieved       uint64
@@ -80,9 +88,11 @@ var (
 
 const (
 	// ProtocolVersion is the latest version this package supports
-	ProtocolVersion uint16 = 8
+	ProtocolVersion uint16 = 9
 	// ProtocolVersionMinimum is the earliest version this package supports
 	ProtocolVersionMinimum uint16 = 8
+	// ProtocolVersionBinary is the earliest version that knows the binary parcel format
+	ProtocolVersionBinary uint16 = 9
 )
 
 // NetworkIdentifier represents the P2P network we are participating in (eg: test, nmain, etc.)
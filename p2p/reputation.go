@@ -0,0 +1,193 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Peers lose quality for what they do wrong.  Each penalty is recorded against the peer by its
+// reason, and a peer whose quality falls below MinumumQualityScore, or that is on another
+// network, has its address, or the /64 of an IPv6 address, banned for BanDuration.  Operators
+// can ban and unban addresses and whole subnets through the controller.  Bans are saved with the
+// peers in the peers file, so they outlast a restart.
+
+// The reasons a peer is penalized
+const (
+	PenaltyBadChecksum    uint8 = iota // A parcel did not match its checksum
+	PenaltyInvalidMessage              // A parcel or application message we could not read
+	PenaltyWrongNetwork                // A peer on another network, banned at once
+	PenaltyApplication                 // The application lowered the quality of the peer, which never bans it
+)
+
+// PenaltyNames maps the penalties to the names they are recorded and counted under
+var PenaltyNames = map[uint8]string{
+	PenaltyBadChecksum:    "bad-checksum",
+	PenaltyInvalidMessage: "invalid-message",
+	PenaltyWrongNetwork:   "wrong-network",
+	PenaltyApplication:    "application",
+}
+
+// PenaltyScores is how much quality each penalty costs a peer.  The application gives its own.
+var PenaltyScores = map[uint8]int32{
+	PenaltyBadChecksum:    20,
+	PenaltyInvalidMessage: 10,
+	PenaltyWrongNetwork:   0, // banned outright
+}
+
+// Ban keeps us from dialing the peers of an address or subnet, and them from dialing us
+type Ban struct {
+	Subnet  string    // in CIDR notation, a single address being a /32, or a /128 for IPv6
+	Reason  string    // why it was banned
+	Created time.Time // when it was banned
+	Expires time.Time // zero for a ban that never expires
+}
+
+func (b *Ban) expired(now time.Time) bool {
+	return !b.Expires.IsZero() && now.After(b.Expires)
+}
+
+// bans holds the bans by subnet
+var bans = map[string]Ban{}
+var bansMutex sync.RWMutex
+
+// ParseSubnet reads an IP address or subnet in CIDR notation, eg 1.2.3.4, 1.2.3.0/24 or
+// 2001:db8::/64
+func ParseSubnet(address string) (*net.IPNet, error) {
+	cidr := strings.TrimSpace(address)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if nil == ip {
+			return nil, fmt.Errorf("%s is not an IP address or subnet", address)
+		}
+		cidr = subnetOf(ip, 32, 128)
+	}
+	_, subnet, err := net.ParseCIDR(cidr)
+	if nil != err {
+		return nil, fmt.Errorf("%s is not an IP address or subnet", address)
+	}
+	return subnet, nil
+}
+
+// banSubnet is what is banned for a peer at the IP address: the address itself for IPv4, and its
+// /64 for IPv6, as a host is given a whole /64 and can dial from any address of it
+func banSubnet(ip net.IP) string {
+	return subnetOf(ip, 32, 64)
+}
+
+// subnetOf returns the subnet of the IP address with the prefix length of its family, in CIDR
+// notation.  It returns an empty string, which ParseSubnet refuses, if ip is not an IP address.
+func subnetOf(ip net.IP, ipv4Bits, ipv6Bits int) string {
+	if ipv4 := ip.To4(); nil != ipv4 {
+		subnet := net.IPNet{IP: ipv4.Mask(net.CIDRMask(ipv4Bits, 32)), Mask: net.CIDRMask(ipv4Bits, 32)}
+		return subnet.String()
+	}
+	if len(ip) != net.IPv6len {
+		return ""
+	}
+	subnet := net.IPNet{IP: ip.Mask(net.CIDRMask(ipv6Bits, 128)), Mask: net.CIDRMask(ipv6Bits, 128)}
+	return subnet.String()
+}
+
+// BanAddress bans an address or subnet for the duration, for good if the duration is 0
+func BanAddress(address string, duration time.Duration, reason string) (Ban, error) {
+	subnet, err := ParseSubnet(address)
+	if nil != err {
+		return Ban{}, err
+	}
+	now := time.Now()
+	ban := Ban{Subnet: subnet.String(), Reason: reason, Created: now}
+	until := "for good"
+	if 0 < duration {
+		ban.Expires = now.Add(duration)
+		until = "until " + ban.Expires.String()
+	}
+	bansMutex.Lock()
+	bans[ban.Subnet] = ban
+	bansMutex.Unlock()
+	significant("reputation", "Banned %s %s: %s", ban.Subnet, until, reason)
+	return ban, nil
+}
+
+// Unban lifts the ban of an address or subnet, returning false if it was not banned.  Only
+// the ban given is lifted, not the bans of subnets holding the address.
+func Unban(address string) (bool, error) {
+	subnet, err := ParseSubnet(address)
+	if nil != err {
+		return false, err
+	}
+	bansMutex.Lock()
+	_, present := bans[subnet.String()]
+	delete(bans, subnet.String())
+	bansMutex.Unlock()
+	if present {
+		significant("reputation", "Unbanned %s", subnet.String())
+	}
+	return present, nil
+}
+
+// IsBanned returns the ban covering the address of a peer, if there is one.  Only IP addresses
+// are banned: hostnames are not looked up here, the connections check the IP address a hostname
+// resolved to when they dialled it.
+func IsBanned(address string) (Ban, bool) {
+	ip := net.ParseIP(address)
+	if nil == ip {
+		return Ban{}, false
+	}
+	now := time.Now()
+	bansMutex.RLock()
+	defer bansMutex.RUnlock()
+	for _, ban := range bans {
+		_, subnet, err := net.ParseCIDR(ban.Subnet)
+		if nil != err || ban.expired(now) {
+			continue
+		}
+		if subnet.Contains(ip) {
+			return ban, true
+		}
+	}
+	return Ban{}, false
+}
+
+// Bans returns the bans in force, sorted by subnet.  Expired bans are dropped.
+func Bans() []Ban {
+	now := time.Now()
+	current := []Ban{}
+	bansMutex.Lock()
+	for subnet, ban := range bans {
+		if ban.expired(now) {
+			delete(bans, subnet)
+			continue
+		}
+		current = append(current, ban)
+	}
+	bansMutex.Unlock()
+	sort.Slice(current, func(i, j int) bool { return current[i].Subnet < current[j].Subnet })
+	return current
+}
+
+// restoreBans puts back the bans saved in the peers file
+func restoreBans(saved []Ban) {
+	now := time.Now()
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+	for _, ban := range saved {
+		subnet, err := ParseSubnet(ban.Subnet)
+		if nil != err {
+			logerror("reputation", "restoreBans() dropping ban: %v", err)
+			continue
+		}
+		if ban.expired(now) {
+			continue
+		}
+		ban.Subnet = subnet.String()
+		bans[ban.Subnet] = ban
+	}
+}
@@ -0,0 +1,166 @@
+package p2p_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/FactomProject/factomd/p2p"
+)
+
+func TestParseSubnet(t *testing.T) {
+	for address, expected := range map[string]string{
+		"1.2.3.4":      "1.2.3.4/32",
+		" 1.2.3.4 ":    "1.2.3.4/32",
+		"1.2.3.4/24":   "1.2.3.0/24",
+		"10.0.0.0/8":   "10.0.0.0/8",
+		"0.0.0.0/0":    "0.0.0.0/0",
+		"127.0.0.1/32": "127.0.0.1/32",
+		"::1":          "::1/128",
+		"fe80::1/64":   "fe80::/64",
+	} {
+		subnet, err := ParseSubnet(address)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		if subnet.String() != expected {
+			t.Errorf("%s parsed as %s, not %s", address, subnet, expected)
+		}
+	}
+	for _, address := range []string{"", "1.2.3", "1.2.3.4/33", "example.com", "fe80::/129"} {
+		_, err := ParseSubnet(address)
+		if err == nil {
+			t.Errorf("Parsed %q", address)
+		}
+	}
+}
+
+func TestBanAddress(t *testing.T) {
+	_, err := BanAddress("10.1.0.0/16", 0, "test")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer Unban("10.1.0.0/16")
+
+	ban, banned := IsBanned("10.1.2.3")
+	if !banned || ban.Reason != "test" || !ban.Expires.IsZero() {
+		t.Errorf("Address in a banned subnet not banned: %+v", ban)
+	}
+	if _, banned := IsBanned("10.2.0.1"); banned {
+		t.Errorf("Address outside the banned subnet banned")
+	}
+	_, err = BanAddress("2001:db8:1:2::/64", 0, "test")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer Unban("2001:db8:1:2::/64")
+	if _, banned := IsBanned("2001:db8:1:2:abcd::1"); !banned {
+		t.Errorf("IPv6 address in a banned /64 not banned")
+	}
+	if _, banned := IsBanned("2001:db8:1:3::1"); banned {
+		t.Errorf("IPv6 address outside the banned /64 banned")
+	}
+	if _, banned := IsBanned("not an address"); banned {
+		t.Errorf("Banned something that is not an address")
+	}
+
+	// Hostnames are not looked up, the connections check the address they dialled
+	_, err = BanAddress("127.0.0.1", 0, "test")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, banned := IsBanned("localhost"); banned {
+		t.Errorf("Looked up a hostname")
+	}
+	Unban("127.0.0.1")
+
+	// Lifting the ban of an address doesn't lift the ban of its subnet
+	present, err := Unban("10.1.2.3")
+	if err != nil || present {
+		t.Errorf("Unbanned an address that was not banned: %v", err)
+	}
+	if _, banned := IsBanned("10.1.2.3"); !banned {
+		t.Errorf("Address unbanned with its subnet still banned")
+	}
+	present, err = Unban("10.1.0.0/16")
+	if err != nil || !present {
+		t.Errorf("Did not unban the subnet: %v", err)
+	}
+	if _, banned := IsBanned("10.1.2.3"); banned {
+		t.Errorf("Address still banned after its subnet was unbanned")
+	}
+}
+
+func TestBanExpires(t *testing.T) {
+	_, err := BanAddress("10.3.0.1", time.Nanosecond, "test")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, banned := IsBanned("10.3.0.1"); banned {
+		t.Errorf("Ban did not expire")
+	}
+	for _, ban := range Bans() {
+		if ban.Subnet == "10.3.0.1/32" {
+			t.Errorf("Expired ban listed")
+		}
+	}
+}
+
+// TestBansSaved checks the bans are saved in the peers file and restored from it
+func TestBansSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peers")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peers.json")
+
+	// A peers file from before bans were saved
+	peer := new(Peer).Init("10.4.0.1", "8108", 0, RegularPeer, 0)
+	peer.LastContact = time.Now()
+	old, err := json.Marshal(map[string]Peer{peer.AddressPort(): *peer})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = ioutil.WriteFile(path, old, 0600)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	discovery := new(Discovery).Init(path, "")
+	discovery.LoadPeers()
+	_, err = BanAddress("10.5.0.0/16", time.Hour, "saved")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	discovery.SavePeers()
+	Unban("10.5.0.0/16")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var saved struct {
+		Peers map[string]Peer
+		Bans  []Ban
+	}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, present := saved.Peers[peer.AddressPort()]; !present {
+		t.Errorf("Peer from the old peers file not saved: %s", data)
+	}
+
+	new(Discovery).Init(path, "")
+	defer Unban("10.5.0.0/16")
+	ban, banned := IsBanned("10.5.1.1")
+	if !banned || ban.Reason != "saved" || ban.Expires.IsZero() {
+		t.Errorf("Ban not restored from the peers file: %+v", ban)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/FactomProject/factomd/receipts"
+)
+
+func main() {
+	fmt.Println("Usage:")
+	fmt.Println("ReceiptVerifier TrustedDBlocksFile ReceiptFileOrDirectory [ReceiptFileOrDirectory...]")
+	fmt.Println("TrustedDBlocksFile is a JSON list of DBlock KeyMRs or anchor records")
+	fmt.Println("Directories are searched for receipt files, such as the ones ReceiptGenerator saves")
+	if len(os.Args) < 3 {
+		fmt.Println("\nNot enough arguments passed")
+		os.Exit(1)
+	}
+
+	trusted, err := LoadTrustedDBlocks(os.Args[1])
+	if err != nil {
+		fmt.Printf("\nError loading the trusted DBlocks - %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nLoaded %v trusted DBlocks\n", len(trusted.DBlocks))
+
+	files := []string{}
+	for _, arg := range os.Args[2:] {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fmt.Printf("Error reading %v - %v\n", arg, err)
+			os.Exit(1)
+		}
+		if info.IsDir() == false {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() == false {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error reading %v - %v\n", arg, err)
+			os.Exit(1)
+		}
+	}
+
+	failed := 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err == nil {
+			err = VerifyReceiptString(string(data), trusted)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %v - %v\n", file, err)
+		} else {
+			fmt.Printf("OK   %v\n", file)
+		}
+	}
+
+	fmt.Printf("\nVerified %v receipts, %v failed\n", len(files), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -67,19 +67,17 @@ func ExportEntryReceipt(entryID string, dbo interfaces.DBOverlaySimple) error {
 }
 
 func ExportAllEntryReceipts(dbo interfaces.DBOverlay) error {
-	entryIDs, err := dbo.FetchAllEntryIDs()
-	if err != nil {
-		return err
-	}
-	for i, entryID := range entryIDs {
-		err = ExportEntryReceipt(entryID.String(), dbo)
+	i := 0
+	return dbo.ForEachEntryID(func(entryID interfaces.IHash) error {
+		err := ExportEntryReceipt(entryID.String(), dbo)
 		if err != nil {
 			if err.Error() != "dirBlockInfo not found" {
 				return err
 			} else {
-				fmt.Printf("dirBlockInfo not found for entry %v/%v - %v\n", i, len(entryIDs), entryID)
+				fmt.Printf("dirBlockInfo not found for entry %v - %v\n", i, entryID)
 			}
 		}
-	}
-	return nil
+		i++
+		return nil
+	})
 }
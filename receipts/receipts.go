@@ -20,13 +20,44 @@ type Receipt struct {
 	DirectoryBlockKeyMR    *primitives.Hash         `json:"directoryblockkeymr,omitempty"`
 	BitcoinTransactionHash *primitives.Hash         `json:"bitcointransactionhash,omitempty"`
 	BitcoinBlockHash       *primitives.Hash         `json:"bitcoinblockhash,omitempty"`
+
+	// Set instead of Entry and EntryBlockKeyMR for factoid and entry credit transactions
+	Transaction          *Transaction     `json:"transaction,omitempty"`
+	FactoidBlockKeyMR    *primitives.Hash `json:"factoidblockkeymr,omitempty"`
+	EntryCreditBlockHash *primitives.Hash `json:"entrycreditblockhash,omitempty"`
+	// The entry credit block body is hashed rather than Merkle rooted, so the whole block is needed to prove a commit
+	EntryCreditBlock string `json:"entrycreditblock,omitempty"`
+}
+
+// branchStart returns the hash the Merkle branch of the receipt starts from, and the KeyMR of
+// the block the branch has to pass through before reaching the directory block
+func (e *Receipt) branchStart() (interfaces.IHash, interfaces.IHash, error) {
+	if e.Transaction != nil {
+		return e.Transaction.branchStart(e)
+	}
+
+	if e.Entry == nil {
+		return nil, nil, fmt.Errorf("Receipt has no entry")
+	}
+	if e.EntryBlockKeyMR == nil {
+		return nil, nil, fmt.Errorf("Receipt has no EntryBlockKeyMR")
+	}
+	entryHash, err := primitives.NewShaHashFromStr(e.Entry.EntryHash)
+	//TODO: validate entry hashes into EntryHash
+	if err != nil {
+		return nil, nil, err
+	}
+	return entryHash, e.EntryBlockKeyMR, nil
 }
 
 func (e *Receipt) TrimReceipt() {
 	if e == nil {
 		return
 	}
-	entry, _ := primitives.NewShaHashFromStr(e.Entry.EntryHash)
+	entry, _, err := e.branchStart()
+	if err != nil {
+		return
+	}
 	for i := range e.MerkleBranch {
 		if entry.IsSameAs(e.MerkleBranch[i].Left) {
 			e.MerkleBranch[i].Left = nil
@@ -44,21 +75,13 @@ func (e *Receipt) Validate() error {
 	if e == nil {
 		return fmt.Errorf("No receipt provided")
 	}
-	if e.Entry == nil {
-		return fmt.Errorf("Receipt has no entry")
-	}
 	if e.MerkleBranch == nil {
 		return fmt.Errorf("Receipt has no MerkleBranch")
 	}
-	if e.EntryBlockKeyMR == nil {
-		return fmt.Errorf("Receipt has no EntryBlockKeyMR")
-	}
 	if e.DirectoryBlockKeyMR == nil {
 		return fmt.Errorf("Receipt has no DirectoryBlockKeyMR")
 	}
-	entryHash, err := primitives.NewShaHashFromStr(e.Entry.EntryHash)
-	//TODO: validate entry hashes into EntryHash
-
+	entryHash, blockKeyMR, err := e.branchStart()
 	if err != nil {
 		return err
 	}
@@ -66,7 +89,8 @@ func (e *Receipt) Validate() error {
 	var right interfaces.IHash
 	var currentEntry interfaces.IHash
 	currentEntry = entryHash
-	eBlockFound := false
+	// An entry credit block is itself the start of its branch
+	eBlockFound := entryHash.IsSameAs(blockKeyMR)
 	dBlockFound := false
 	for i, node := range e.MerkleBranch {
 		if node.Left == nil {
@@ -92,7 +116,7 @@ func (e *Receipt) Validate() error {
 				return fmt.Errorf("Derived top %v is not the same as saved top in node %v/%v", top, i, len(e.MerkleBranch))
 			}
 		}
-		if top.IsSameAs(e.EntryBlockKeyMR) == true {
+		if top.IsSameAs(blockKeyMR) == true {
 			eBlockFound = true
 		}
 		if top.IsSameAs(e.DirectoryBlockKeyMR) == true {
@@ -102,6 +126,9 @@ func (e *Receipt) Validate() error {
 	}
 
 	if eBlockFound == false {
+		if e.Transaction != nil {
+			return fmt.Errorf("Transaction block not found in branch")
+		}
 		return fmt.Errorf("EntryBlockKeyMR not found in branch")
 	}
 
@@ -202,6 +229,40 @@ func (e *Receipt) IsSameAs(r *Receipt) bool {
 		}
 	}
 
+	if e.Transaction == nil {
+		if r.Transaction != nil {
+			return false
+		}
+	} else {
+		if e.Transaction.IsSameAs(r.Transaction) == false {
+			return false
+		}
+	}
+
+	if e.FactoidBlockKeyMR == nil {
+		if r.FactoidBlockKeyMR != nil {
+			return false
+		}
+	} else {
+		if e.FactoidBlockKeyMR.IsSameAs(r.FactoidBlockKeyMR) == false {
+			return false
+		}
+	}
+
+	if e.EntryCreditBlockHash == nil {
+		if r.EntryCreditBlockHash != nil {
+			return false
+		}
+	} else {
+		if e.EntryCreditBlockHash.IsSameAs(r.EntryCreditBlockHash) == false {
+			return false
+		}
+	}
+
+	if e.EntryCreditBlock != r.EntryCreditBlock {
+		return false
+	}
+
 	return true
 }
 
@@ -308,6 +369,14 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 	}
 
 	if eBlock == nil {
+		// Factoid transactions and entry credit commits are included in their own blocks
+		txReceipt, err := createTransactionReceipt(dbo, entryID, hash)
+		if err != nil {
+			return nil, err
+		}
+		if txReceipt != nil {
+			return txReceipt, nil
+		}
 		return nil, fmt.Errorf("EBlock not found")
 	}
 
@@ -355,17 +424,30 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 	//str, _ = dBlock.JSONString()
 	//fmt.Printf("dBlock - %v\n\n", str)
 
-	entries = dBlock.GetEntryHashesForBranch()
+	err = addDBlockToReceipt(dbo, receipt, dBlock, receipt.EntryBlockKeyMR)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// addDBlockToReceipt extends the receipt's Merkle branch from a block's KeyMR up to the
+// directory block it is in, and adds the bitcoin anchor of the directory block if there is one
+func addDBlockToReceipt(dbo interfaces.DBOverlaySimple, receipt *Receipt, dBlock interfaces.IDirectoryBlock, blockKeyMR interfaces.IHash) error {
+	entries := dBlock.GetEntryHashesForBranch()
 	//fmt.Printf("dBlock entries - %v\n\n", entries)
 
 	//merkleTree := primitives.BuildMerkleTreeStore(entries)
 	//fmt.Printf("dBlock merkleTree - %v\n\n", merkleTree)
 
-	branch = primitives.BuildMerkleBranchForEntryHash(entries, receipt.EntryBlockKeyMR, true)
-	blockNode = new(primitives.MerkleNode)
-	left, err = dBlock.HeaderHash()
+	hash := dBlock.DatabasePrimaryIndex()
+
+	branch := primitives.BuildMerkleBranchForEntryHash(entries, blockKeyMR, true)
+	blockNode := new(primitives.MerkleNode)
+	left, err := dBlock.HeaderHash()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	blockNode.Left = left.(*primitives.Hash)
 	blockNode.Right = dBlock.BodyKeyMR().(*primitives.Hash)
@@ -376,12 +458,11 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 
 	//DirBlockInfo
 
-	hash = dBlock.DatabasePrimaryIndex()
 	receipt.DirectoryBlockKeyMR = hash.(*primitives.Hash)
 
 	dirBlockInfo, err := dbo.FetchDirBlockInfoByKeyMR(hash)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if dirBlockInfo != nil {
@@ -391,7 +472,7 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 		receipt.BitcoinBlockHash = dbi.BTCBlockHash.(*primitives.Hash)
 	}
 
-	return receipt, nil
+	return nil
 }
 
 func VerifyFullReceipt(dbo interfaces.DBOverlaySimple, receiptStr string) error {
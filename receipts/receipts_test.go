@@ -96,3 +96,74 @@ func TestDecodeReceiptString(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTransactionReceipts(t *testing.T) {
+	dbo := CreateAndPopulateTestDatabaseOverlay()
+	blocks := CreateFullTestBlockSet()
+
+	trusted := NewTrustedDBlocks()
+	for _, block := range blocks {
+		trusted.AddKeyMR(block.DBlock.DatabasePrimaryIndex())
+	}
+
+	for _, block := range blocks[:len(blocks)-2] {
+		txIDs := []string{}
+		for _, tx := range block.FBlock.GetTransactions() {
+			txIDs = append(txIDs, tx.GetSigHash().String())
+		}
+		for _, entry := range block.ECBlock.GetEntries() {
+			if entry.GetSigHash() != nil {
+				txIDs = append(txIDs, entry.GetSigHash().String())
+			}
+		}
+
+		for _, txID := range txIDs {
+			h, err := primitives.NewShaHashFromStr(txID)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			receipt, err := CreateFullReceipt(dbo, h)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if receipt.Transaction == nil || receipt.Transaction.TxID != txID {
+				t.Fatalf("Receipt is not for transaction %v", txID)
+			}
+
+			err = VerifyFullReceipt(dbo, receipt.CustomMarshalString())
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			err = VerifyReceipt(receipt, trusted)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			receipt.TrimReceipt()
+			err = VerifyMinimalReceipt(dbo, receipt.CustomMarshalString())
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			err = VerifyReceipt(receipt, trusted)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			if receipt.Transaction.Type == TransactionTypeFactoid {
+				raw := receipt.Transaction.Raw
+				receipt.Transaction.Raw = ""
+				err = VerifyReceipt(receipt, trusted)
+				if err == nil {
+					t.Errorf("Factoid receipt without the raw transaction verified")
+				}
+				receipt.Transaction.Raw = raw
+			}
+
+			receipt.Transaction.TxID = primitives.NewZeroHash().String()
+			err = VerifyReceipt(receipt, trusted)
+			if err == nil {
+				t.Errorf("Receipt for the wrong transaction verified")
+			}
+		}
+	}
+}
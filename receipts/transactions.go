@@ -0,0 +1,249 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package receipts
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+const (
+	TransactionTypeFactoid     = "factoid"
+	TransactionTypeEntryCredit = "entrycredit"
+)
+
+// Transaction is the factoid transaction or entry credit commit a receipt is for
+type Transaction struct {
+	Type string `json:"type"`
+	TxID string `json:"txid"`
+	// Hash is the leaf of the factoid block's Merkle tree, the hash of the transaction with its signatures
+	Hash string `json:"hash,omitempty"`
+	// Raw is the marshalled factoid transaction, which ties the TxID to the Hash. A factoid
+	// receipt without it does not verify.
+	Raw string `json:"raw,omitempty"`
+}
+
+func (e *Transaction) IsSameAs(r *Transaction) bool {
+	if r == nil {
+		return false
+	}
+	if e.Type != r.Type {
+		return false
+	}
+	if e.TxID != r.TxID {
+		return false
+	}
+	if e.Hash != r.Hash {
+		return false
+	}
+	if e.Raw != r.Raw {
+		return false
+	}
+	return true
+}
+
+func (e *Transaction) branchStart(receipt *Receipt) (interfaces.IHash, interfaces.IHash, error) {
+	txID, err := primitives.NewShaHashFromStr(e.TxID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch e.Type {
+	case TransactionTypeFactoid:
+		if receipt.FactoidBlockKeyMR == nil {
+			return nil, nil, fmt.Errorf("Receipt has no FactoidBlockKeyMR")
+		}
+		hash, err := primitives.NewShaHashFromStr(e.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Without the transaction, nothing ties the TxID to the leaf the branch proves
+		if e.Raw == "" {
+			return nil, nil, fmt.Errorf("Receipt has no raw factoid transaction")
+		}
+		data, err := hex.DecodeString(e.Raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		tx := new(factoid.Transaction)
+		err = tx.UnmarshalBinary(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tx.GetSigHash().IsSameAs(txID) == false || tx.GetHash().IsSameAs(hash) == false {
+			return nil, nil, fmt.Errorf("Raw transaction does not match the TxID and Hash")
+		}
+		return hash, receipt.FactoidBlockKeyMR, nil
+
+	case TransactionTypeEntryCredit:
+		if receipt.EntryCreditBlockHash == nil {
+			return nil, nil, fmt.Errorf("Receipt has no EntryCreditBlockHash")
+		}
+		data, err := hex.DecodeString(receipt.EntryCreditBlock)
+		if err != nil {
+			return nil, nil, err
+		}
+		block, err := entryCreditBlock.UnmarshalECBlock(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		headerHash, err := block.HeaderHash()
+		if err != nil {
+			return nil, nil, err
+		}
+		if headerHash.IsSameAs(receipt.EntryCreditBlockHash) == false {
+			return nil, nil, fmt.Errorf("EntryCreditBlock hashes to %v instead of the EntryCreditBlockHash", headerHash)
+		}
+		if block.GetEntryByHash(txID) == nil {
+			return nil, nil, fmt.Errorf("Transaction not found in EntryCreditBlock")
+		}
+		return headerHash, headerHash, nil
+	}
+
+	return nil, nil, fmt.Errorf("Unknown transaction type %v", e.Type)
+}
+
+// CreateTransactionReceipt creates a receipt proving a factoid transaction or an entry credit
+// commit is in its block, and that block is in a directory block
+func CreateTransactionReceipt(dbo interfaces.DBOverlaySimple, txID interfaces.IHash) (*Receipt, error) {
+	hash, err := dbo.FetchIncludedIn(txID)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		return nil, fmt.Errorf("Block containing transaction not found")
+	}
+
+	receipt, err := createTransactionReceipt(dbo, txID, hash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("Block containing transaction not found")
+	}
+	return receipt, nil
+}
+
+// createTransactionReceipt returns nil if the block is neither a factoid nor an entry credit block
+func createTransactionReceipt(dbo interfaces.DBOverlaySimple, txID interfaces.IHash, blockHash interfaces.IHash) (*Receipt, error) {
+	receipt := new(Receipt)
+	receipt.Transaction = new(Transaction)
+
+	var dbHeight uint32
+	var blockKeyMR interfaces.IHash
+
+	fBlock, err := dbo.FetchFBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if fBlock != nil {
+		tx, err := findFactoidTransaction(fBlock, txID)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		receipt.Transaction.Type = TransactionTypeFactoid
+		receipt.Transaction.TxID = tx.GetSigHash().String()
+		receipt.Transaction.Hash = tx.GetHash().String()
+		receipt.Transaction.Raw = hex.EncodeToString(raw)
+
+		blockKeyMR = fBlock.DatabasePrimaryIndex()
+		receipt.FactoidBlockKeyMR = primitives.NewHash(blockKeyMR.Bytes()).(*primitives.Hash)
+
+		// The KeyMR is the hash of the header hash and the body Merkle root
+		branch := primitives.BuildMerkleBranchForEntryHash(fBlockMerkleLeaves(fBlock), tx.GetHash(), true)
+		header, err := fBlock.MarshalHeader()
+		if err != nil {
+			return nil, err
+		}
+		blockNode := new(primitives.MerkleNode)
+		blockNode.Left = primitives.Sha(header).(*primitives.Hash)
+		blockNode.Right = primitives.NewHash(fBlock.GetBodyMR().Bytes()).(*primitives.Hash)
+		blockNode.Top = receipt.FactoidBlockKeyMR
+		branch = append(branch, blockNode)
+		receipt.MerkleBranch = append(receipt.MerkleBranch, branch...)
+
+		dbHeight = fBlock.GetDatabaseHeight()
+	} else {
+		ecBlock, err := dbo.FetchECBlock(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		if ecBlock == nil {
+			return nil, nil
+		}
+		entry := ecBlock.GetEntryByHash(txID)
+		if entry == nil {
+			return nil, fmt.Errorf("Transaction not found in EntryCreditBlock")
+		}
+		raw, err := ecBlock.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		receipt.Transaction.Type = TransactionTypeEntryCredit
+		receipt.Transaction.TxID = txID.String()
+		receipt.EntryCreditBlock = hex.EncodeToString(raw)
+
+		blockKeyMR = ecBlock.DatabasePrimaryIndex()
+		receipt.EntryCreditBlockHash = primitives.NewHash(blockKeyMR.Bytes()).(*primitives.Hash)
+
+		dbHeight = ecBlock.GetDatabaseHeight()
+	}
+
+	dBlock, err := dbo.FetchDBlockByHeight(dbHeight)
+	if err != nil {
+		return nil, err
+	}
+	if dBlock == nil {
+		return nil, fmt.Errorf("DBlock not found")
+	}
+
+	err = addDBlockToReceipt(dbo, receipt, dBlock, blockKeyMR)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func findFactoidTransaction(fBlock interfaces.IFBlock, txID interfaces.IHash) (interfaces.ITransaction, error) {
+	for _, tx := range fBlock.GetTransactions() {
+		if tx.GetSigHash().IsSameAs(txID) || tx.GetHash().IsSameAs(txID) {
+			return tx, nil
+		}
+	}
+	return nil, fmt.Errorf("Transaction not found in FactoidBlock")
+}
+
+// fBlockMerkleLeaves returns the leaves of the factoid block's body Merkle tree, the transaction
+// hashes with a marker at the end of every minute, the same way FBlock.GetBodyMR builds them
+func fBlockMerkleLeaves(fBlock interfaces.IFBlock) []interfaces.IHash {
+	txs := fBlock.GetTransactions()
+	endOfPeriod := fBlock.GetEndOfPeriod()
+	hashes := make([]interfaces.IHash, 0, len(txs)+len(endOfPeriod))
+	marker := 0
+	for i, tx := range txs {
+		for marker < len(endOfPeriod) && i != 0 && i == endOfPeriod[marker] {
+			marker++
+			hashes = append(hashes, primitives.Sha(constants.ZERO))
+		}
+		hashes = append(hashes, tx.GetHash())
+	}
+	// Add any lagging markers
+	for marker < len(endOfPeriod) {
+		marker++
+		hashes = append(hashes, primitives.Sha(constants.ZERO))
+	}
+	return hashes
+}
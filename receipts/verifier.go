@@ -0,0 +1,248 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package receipts
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/FactomProject/factomd/anchor"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// TrustedDBlock is a directory block the verifier already trusts, either because it was
+// checked against a node or because it was read from an anchor record. The bitcoin hashes
+// are optional, when they are set a receipt has to carry the same ones.
+type TrustedDBlock struct {
+	KeyMR                  *primitives.Hash `json:"keymr"`
+	DBHeight               uint32           `json:"dbheight,omitempty"`
+	BitcoinTransactionHash *primitives.Hash `json:"bitcointransactionhash,omitempty"`
+	BitcoinBlockHash       *primitives.Hash `json:"bitcoinblockhash,omitempty"`
+}
+
+// TrustedDBlocks is the set of directory blocks receipts are verified against, no database needed
+type TrustedDBlocks struct {
+	DBlocks map[[32]byte]*TrustedDBlock
+}
+
+func NewTrustedDBlocks() *TrustedDBlocks {
+	t := new(TrustedDBlocks)
+	t.DBlocks = map[[32]byte]*TrustedDBlock{}
+	return t
+}
+
+func (t *TrustedDBlocks) Add(dBlock *TrustedDBlock) error {
+	if dBlock == nil || dBlock.KeyMR == nil {
+		return fmt.Errorf("Trusted DBlock has no KeyMR")
+	}
+	t.DBlocks[dBlock.KeyMR.Fixed()] = dBlock
+	return nil
+}
+
+func (t *TrustedDBlocks) AddKeyMR(keyMR interfaces.IHash) error {
+	dBlock := new(TrustedDBlock)
+	dBlock.KeyMR = primitives.NewHash(keyMR.Bytes()).(*primitives.Hash)
+	return t.Add(dBlock)
+}
+
+// AddAnchorRecord trusts the directory block of an anchor record along with its bitcoin anchor
+func (t *TrustedDBlocks) AddAnchorRecord(ar *anchor.AnchorRecord) error {
+	var err error
+	dBlock := new(TrustedDBlock)
+	dBlock.KeyMR, err = primitives.NewShaHashFromStr(ar.KeyMR)
+	if err != nil {
+		return err
+	}
+	dBlock.DBHeight = ar.DBHeight
+	if ar.Bitcoin != nil {
+		dBlock.BitcoinTransactionHash, err = primitives.NewShaHashFromStr(ar.Bitcoin.TXID)
+		if err != nil {
+			return err
+		}
+		dBlock.BitcoinBlockHash, err = primitives.NewShaHashFromStr(ar.Bitcoin.BlockHash)
+		if err != nil {
+			return err
+		}
+	}
+	return t.Add(dBlock)
+}
+
+func (t *TrustedDBlocks) Get(keyMR interfaces.IHash) *TrustedDBlock {
+	return t.DBlocks[keyMR.Fixed()]
+}
+
+// LoadTrustedDBlocks reads a JSON file holding a list of either directory blocks
+// ({"keymr": ..., "bitcointransactionhash": ...}), anchor records or bare KeyMR strings
+func LoadTrustedDBlocks(filename string) (*TrustedDBlocks, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []json.RawMessage{}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTrustedDBlocks()
+	for i, raw := range list {
+		keyMR := ""
+		if json.Unmarshal(raw, &keyMR) == nil {
+			h, err := primitives.NewShaHashFromStr(keyMR)
+			if err != nil {
+				return nil, fmt.Errorf("Trusted DBlock %v/%v - %v", i, len(list), err)
+			}
+			err = t.AddKeyMR(h)
+			if err != nil {
+				return nil, fmt.Errorf("Trusted DBlock %v/%v - %v", i, len(list), err)
+			}
+			continue
+		}
+
+		// Anchor records have no json tags, so tell them apart by their version and bitcoin fields
+		ar := new(anchor.AnchorRecord)
+		if json.Unmarshal(raw, ar) == nil && (ar.AnchorRecordVer != 0 || ar.Bitcoin != nil) {
+			err = t.AddAnchorRecord(ar)
+			if err != nil {
+				return nil, fmt.Errorf("Trusted DBlock %v/%v - %v", i, len(list), err)
+			}
+			continue
+		}
+
+		dBlock := new(TrustedDBlock)
+		err = json.Unmarshal(raw, dBlock)
+		if err != nil {
+			return nil, fmt.Errorf("Trusted DBlock %v/%v - %v", i, len(list), err)
+		}
+		err = t.Add(dBlock)
+		if err != nil {
+			return nil, fmt.Errorf("Trusted DBlock %v/%v - %v", i, len(list), err)
+		}
+	}
+	return t, nil
+}
+
+// VerifyReceipt checks a full or minimal receipt without a database. The Merkle branch is hashed
+// from the entry or transaction hash up, every node has to contain the hash below it, the branch
+// has to pass through the entry, factoid or entry credit block and end at the DirectoryBlockKeyMR,
+// and that directory block has to be trusted.
+func VerifyReceipt(receipt *Receipt, trusted *TrustedDBlocks) error {
+	if receipt == nil {
+		return fmt.Errorf("No receipt provided")
+	}
+	if len(receipt.MerkleBranch) == 0 {
+		return fmt.Errorf("Receipt has no MerkleBranch")
+	}
+	if receipt.DirectoryBlockKeyMR == nil {
+		return fmt.Errorf("Receipt has no DirectoryBlockKeyMR")
+	}
+
+	// For transactions this also checks the raw transaction or entry credit block
+	leaf, blockKeyMR, err := receipt.branchStart()
+	if err != nil {
+		return err
+	}
+	if receipt.Transaction == nil && receipt.Entry.Raw != "" {
+		err = verifyRawEntry(receipt.Entry.Raw, leaf)
+		if err != nil {
+			return err
+		}
+	}
+
+	current := leaf
+	blockFound := leaf.IsSameAs(blockKeyMR)
+	for i, node := range receipt.MerkleBranch {
+		var left, right interfaces.IHash
+		switch {
+		case node.Left == nil && node.Right == nil:
+			return fmt.Errorf("Node %v/%v has two nil sides", i, len(receipt.MerkleBranch))
+		case node.Left == nil:
+			left = current
+			right = node.Right
+		case node.Right == nil:
+			left = node.Left
+			right = current
+		default:
+			left = node.Left
+			right = node.Right
+			if left.IsSameAs(current) == false && right.IsSameAs(current) == false {
+				return fmt.Errorf("Hash %v not found in node %v/%v", current, i, len(receipt.MerkleBranch))
+			}
+		}
+
+		top := primitives.HashMerkleBranches(left, right)
+		if node.Top != nil && top.IsSameAs(node.Top) == false {
+			return fmt.Errorf("Derived top %v is not the same as saved top in node %v/%v", top, i, len(receipt.MerkleBranch))
+		}
+		if top.IsSameAs(blockKeyMR) {
+			blockFound = true
+		}
+		current = top
+	}
+
+	if blockFound == false {
+		return fmt.Errorf("Block KeyMR %v not found in branch", blockKeyMR)
+	}
+	if current.IsSameAs(receipt.DirectoryBlockKeyMR) == false {
+		return fmt.Errorf("Branch ends at %v instead of the DirectoryBlockKeyMR", current)
+	}
+
+	if trusted == nil {
+		return fmt.Errorf("No trusted DBlocks provided")
+	}
+	dBlock := trusted.Get(receipt.DirectoryBlockKeyMR)
+	if dBlock == nil {
+		return fmt.Errorf("DirectoryBlockKeyMR %v is not trusted", receipt.DirectoryBlockKeyMR)
+	}
+	if dBlock.BitcoinTransactionHash != nil {
+		if receipt.BitcoinTransactionHash == nil {
+			return fmt.Errorf("Receipt has no BitcoinTransactionHash, but the DBlock is anchored")
+		}
+		if dBlock.BitcoinTransactionHash.IsSameAs(receipt.BitcoinTransactionHash) == false {
+			return fmt.Errorf("BitcoinTransactionHash does not match the anchor of the DBlock")
+		}
+	}
+	if dBlock.BitcoinBlockHash != nil {
+		if receipt.BitcoinBlockHash == nil {
+			return fmt.Errorf("Receipt has no BitcoinBlockHash, but the DBlock is anchored")
+		}
+		if dBlock.BitcoinBlockHash.IsSameAs(receipt.BitcoinBlockHash) == false {
+			return fmt.Errorf("BitcoinBlockHash does not match the anchor of the DBlock")
+		}
+	}
+
+	return nil
+}
+
+// VerifyReceiptString decodes a JSON receipt and verifies it against the trusted directory blocks
+func VerifyReceiptString(receiptStr string, trusted *TrustedDBlocks) error {
+	receipt, err := DecodeReceiptString(receiptStr)
+	if err != nil {
+		return err
+	}
+	return VerifyReceipt(receipt, trusted)
+}
+
+// verifyRawEntry checks that the hex encoded entry hashes to the entry hash of the receipt
+func verifyRawEntry(raw string, entryHash interfaces.IHash) error {
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return err
+	}
+	entry := entryBlock.NewEntry()
+	err = entry.UnmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	if entry.GetHash().IsSameAs(entryHash) == false {
+		return fmt.Errorf("Raw entry hashes to %v instead of %v", entry.GetHash(), entryHash)
+	}
+	return nil
+}
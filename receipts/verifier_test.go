@@ -0,0 +1,135 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package receipts_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/receipts"
+	. "github.com/FactomProject/factomd/testHelper"
+)
+
+func TestVerifyReceipt(t *testing.T) {
+	dbo := CreateAndPopulateTestDatabaseOverlay()
+	blocks := CreateFullTestBlockSet()
+
+	trusted := NewTrustedDBlocks()
+	for _, block := range blocks {
+		err := trusted.AddKeyMR(block.DBlock.DatabasePrimaryIndex())
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	for _, block := range blocks[:len(blocks)-2] {
+		for _, entry := range block.Entries {
+			receipt, err := CreateFullReceipt(dbo, entry.DatabasePrimaryIndex())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			err = VerifyReceiptString(receipt.CustomMarshalString(), trusted)
+			if err != nil {
+				t.Errorf("Full receipt - %v", err)
+			}
+
+			err = VerifyReceipt(receipt, NewTrustedDBlocks())
+			if err == nil {
+				t.Errorf("Receipt verified against an untrusted DBlock")
+			}
+
+			receipt.TrimReceipt()
+			err = VerifyReceiptString(receipt.CustomMarshalString(), trusted)
+			if err != nil {
+				t.Errorf("Minimal receipt - %v", err)
+			}
+
+			receipt.Entry.EntryHash = primitives.NewZeroHash().String()
+			err = VerifyReceipt(receipt, trusted)
+			if err == nil {
+				t.Errorf("Receipt for the wrong entry verified")
+			}
+		}
+	}
+}
+
+func TestVerifyReceiptBitcoinHashes(t *testing.T) {
+	dbo := CreateAndPopulateTestDatabaseOverlay()
+	blocks := CreateFullTestBlockSet()
+
+	receipt, err := CreateFullReceipt(dbo, blocks[0].Entries[0].DatabasePrimaryIndex())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	txid := primitives.Sha([]byte("txid")).(*primitives.Hash)
+	blockHash := primitives.Sha([]byte("block")).(*primitives.Hash)
+
+	trusted := NewTrustedDBlocks()
+	err = trusted.Add(&TrustedDBlock{KeyMR: receipt.DirectoryBlockKeyMR, BitcoinTransactionHash: txid, BitcoinBlockHash: blockHash})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	receipt.BitcoinTransactionHash = nil
+	receipt.BitcoinBlockHash = nil
+	err = VerifyReceipt(receipt, trusted)
+	if err == nil {
+		t.Errorf("Receipt without the bitcoin hashes of an anchored DBlock verified")
+	}
+
+	receipt.BitcoinTransactionHash = blockHash
+	receipt.BitcoinBlockHash = txid
+	err = VerifyReceipt(receipt, trusted)
+	if err == nil {
+		t.Errorf("Receipt with the wrong bitcoin hashes verified")
+	}
+
+	receipt.BitcoinTransactionHash = txid
+	receipt.BitcoinBlockHash = blockHash
+	err = VerifyReceipt(receipt, trusted)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestLoadTrustedDBlocks(t *testing.T) {
+	keyMR := "bdadd16c5335c369a1b784212f80764e1f47805c89d39141bd40d05153edcdf5"
+	anchored := "4d8ed632f7852a07055a0592c341b957815bdd46e82d2da7bdf58be54fc60bf9"
+	txid := "9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5"
+	blockHash := "00000000000000000cc14eacfc7057300aea87bed6fee904fd8e1c1f3dc008d4"
+	data := fmt.Sprintf(`["%v", {"AnchorRecordVer":1,"DBHeight":5,"KeyMR":"%v","Bitcoin":{"TXID":"%v","BlockHash":"%v"}}]`, keyMR, anchored, txid, blockHash)
+
+	file, err := ioutil.TempFile("", "trusted")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(data)
+	file.Close()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	trusted, err := LoadTrustedDBlocks(file.Name())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(trusted.DBlocks) != 2 {
+		t.Fatalf("Expected 2 trusted DBlocks, found %v", len(trusted.DBlocks))
+	}
+
+	h, _ := primitives.NewShaHashFromStr(anchored)
+	dBlock := trusted.Get(h)
+	if dBlock == nil {
+		t.Fatalf("Anchored DBlock not found")
+	}
+	if dBlock.DBHeight != 5 || dBlock.BitcoinTransactionHash.String() != txid || dBlock.BitcoinBlockHash.String() != blockHash {
+		t.Errorf("Anchor record was not loaded - %v", dBlock)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/database/archive"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// ImportArchive adds the blocks of the bootstrap archive the database does not have yet, but for the
+// last one, which nothing in the archive signs and the node syncs from its peers. Once it is
+// imported, a start only reads the start of every chunk of the archive to find it has nothing new,
+// and the archive can be removed from the config.
+func (s *State) ImportArchive() error {
+	dbo, ok := s.DB.(*databaseOverlay.Overlay)
+	if ok == false {
+		return fmt.Errorf("The database can't import a block archive")
+	}
+
+	s.Println("Importing the block archive", s.BootstrapArchive)
+	count, err := archive.Import(dbo, s.BootstrapArchive, func(height uint32) {
+		if height%1000 == 0 {
+			s.Println("Imported directory block", height)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.Println("Imported", count, "directory blocks from the block archive")
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/database/boltdb"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/leveldb"
+	"github.com/FactomProject/factomd/database/securedb"
+)
+
+const (
+	// DBKeyEnvVar holds the database key when DBEncryptionKeyFile is not set
+	DBKeyEnvVar = "FACTOMD_DB_KEY"
+	// DBNewKeyEnvVar holds the key to re-encrypt the database with when DBEncryptionNewKeyFile is
+	// not set
+	DBNewKeyEnvVar = "FACTOMD_DB_NEW_KEY"
+)
+
+func (s *State) InitEncryptedDB() error {
+	if s.DB != nil {
+		return nil
+	}
+
+	dbase, err := s.openEncryptedDB(s.DBType)
+	if err != nil {
+		return err
+	}
+
+	s.DB = databaseOverlay.NewOverlay(dbase)
+	return nil
+}
+
+// openEncryptedDB opens an EncryptedLDB or EncryptedBolt database. If a new key is given, the
+// database is re-encrypted with it before it is used.
+func (s *State) openEncryptedDB(dbType string) (interfaces.IDatabase, error) {
+	secret, err := securedb.ReadSecret(s.DBEncryptionKeyFile, DBKeyEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	newSecret := ""
+	if s.DBEncryptionNewKeyFile != "" || os.Getenv(DBNewKeyEnvVar) != "" {
+		newSecret, err = securedb.ReadSecret(s.DBEncryptionNewKeyFile, DBNewKeyEnvVar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dbase interfaces.IDatabase
+	switch dbType {
+	case "EncryptedLDB":
+		path := s.LdbPath + "/" + s.Network + "/" + "factoid_level_encrypted.db"
+		s.Println("Database:", path)
+		dbase, err = leveldb.NewLevelDB(path, true)
+		if err != nil {
+			return nil, err
+		}
+	case "EncryptedBolt":
+		path := s.BoltDBPath + "/" + s.Network + "/"
+		s.Println("Database Path for", s.FactomNodeName, "is", path)
+		os.MkdirAll(path, 0777)
+		dbase = boltdb.NewBoltDB(nil, path+"FactomBoltEncrypted.db")
+	default:
+		return nil, fmt.Errorf("%v is not an encrypted database type", dbType)
+	}
+
+	var edb *securedb.EncryptedDB
+	if newSecret != "" {
+		s.Println("Re-encrypting the database with the new key, this takes a while")
+		edb, err = securedb.RotateKey(dbase, secret, newSecret)
+		if err == nil {
+			s.Println("The database was re-encrypted, the new key is now the database key")
+		}
+	} else {
+		edb, err = securedb.OpenEncryptedDB(dbase, secret)
+	}
+	if err != nil {
+		dbase.Close()
+		return nil, err
+	}
+	return edb, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/migration"
+)
+
+// openDatabase opens the database of a DBType at its configured path
+func (s *State) openDatabase(dbType string) (interfaces.IDatabase, error) {
+	switch dbType {
+	case "LDB":
+		return s.openLevelDB()
+	case "Bolt":
+		return s.openBoltDB(), nil
+	case "Badger":
+		return s.openBadgerDB()
+	case "EncryptedLDB", "EncryptedBolt":
+		return s.openEncryptedDB(dbType)
+	}
+	return nil, fmt.Errorf("Can't migrate the database to %v, it must be LDB, Bolt, Badger, EncryptedLDB or EncryptedBolt", dbType)
+}
+
+// initDBMigration copies the database to MigrateDBType while the node runs on it. The writes of
+// the node go to both databases, and once the copy is verified the node switches to the new
+// database on its next start.
+func (s *State) initDBMigration() error {
+	if s.MigrateDBType == s.DBType {
+		return nil
+	}
+	overlay, ok := s.DB.(*databaseOverlay.Overlay)
+	if ok == false {
+		return fmt.Errorf("Can't migrate a database of type %T", s.DB)
+	}
+	source := overlay.DB
+	target, err := s.openDatabase(s.MigrateDBType)
+	if err != nil {
+		return err
+	}
+
+	switchOver, err := migration.CanSwitch(source, target)
+	if err != nil {
+		return err
+	}
+	if switchOver {
+		err = migration.MarkSwitched(target)
+		if err != nil {
+			return err
+		}
+		source.Close()
+		s.Println("\nThe database was migrated from", s.DBType, "to", s.MigrateDBType, "- set DBType to", s.MigrateDBType, "and remove MigrateDBType")
+		s.DB = databaseOverlay.NewOverlay(target)
+		s.DBType = s.MigrateDBType
+		return nil
+	}
+
+	mirror := migration.NewMirrorDB(source, target)
+	s.DB = databaseOverlay.NewOverlay(mirror)
+	migrator := migration.NewMigrator(mirror, s.DBType, s.MigrateDBType)
+	s.Println("\nMigrating the database from", s.DBType, "to", s.MigrateDBType)
+	go func() {
+		err := migrator.Run()
+		if err != nil {
+			s.Println("Database migration failed, it starts again on the next start:", err)
+			return
+		}
+		s.Println("Database migration to", s.MigrateDBType, "is complete, restart the node to switch to it")
+	}()
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// BlockHeadCheckDepth is the number of directory blocks below the head whose entry chain heads are
+// checked when the node starts
+const BlockHeadCheckDepth = 1000
+
+// RecoverDatabase finishes the block save a crash interrupted, and checks the chain heads agree
+// with the saved blocks, repairing them if not
+func (s *State) RecoverDatabase() error {
+	rolledForward, err := s.DB.RecoverMultiBatch()
+	if err != nil {
+		return err
+	}
+	if rolledForward {
+		s.Println("Finished writing the blocks a crash interrupted")
+	}
+
+	repaired, err := s.DB.CheckBlockHeads(BlockHeadCheckDepth)
+	if err == databaseOverlay.ErrNoCompleteBlockSet {
+		// The blocks are synced again, there is nothing to repair the heads from
+		s.Println("The database has no complete block set, its chain heads were not checked")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if repaired > 0 {
+		s.Println("Repaired", repaired, "chain heads of the database")
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	// "github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/directoryBlock/dbInfo"
 	"github.com/FactomProject/factomd/common/entryBlock"
 	"github.com/FactomProject/factomd/common/entryCreditBlock"
 	"github.com/FactomProject/factomd/common/factoid"
@@ -1256,6 +1257,9 @@ func (list *DBStateList) SaveDBStateToDB(d *DBState) (progress bool) {
 
 	pl := list.State.ProcessLists.Get(uint32(dbheight))
 
+	// Entry blocks written in this batch, so subscribers can be told about them once it is committed
+	var savedEBlocks []interfaces.IEntryBlock
+
 	allowedEBlocks := make(map[[32]byte]struct{})
 	allowedEntries := make(map[[32]byte]struct{})
 
@@ -1293,6 +1297,7 @@ func (list *DBStateList) SaveDBStateToDB(d *DBState) (progress bool) {
 				if err := list.State.DB.ProcessEBlockMultiBatch(eb, true); err != nil {
 					panic(err.Error())
 				}
+				savedEBlocks = append(savedEBlocks, eb)
 			} else {
 				list.State.Logf("error", "Error saving eblock from dbstate, eblock not allowed")
 			}
@@ -1320,6 +1325,7 @@ func (list *DBStateList) SaveDBStateToDB(d *DBState) (progress bool) {
 				if err := list.State.DB.ProcessEBlockMultiBatch(eb, true); err != nil {
 					panic(err.Error())
 				}
+				savedEBlocks = append(savedEBlocks, eb)
 
 				for _, e := range eb.GetBody().GetEBEntries() {
 					if _, ok := allowedEntries[e.Fixed()]; ok {
@@ -1398,6 +1404,14 @@ func (list *DBStateList) SaveDBStateToDB(d *DBState) (progress bool) {
 	d.ReadyToSave = false
 	d.Saved = true
 
+	if sink := list.State.getEventSink(); sink != nil {
+		sink.NotifyNewDBlock(list.State, d.DirectoryBlock, savedEBlocks)
+	}
+
+	if list.State.Anchor != nil {
+		list.State.Anchor.UpdateDirBlockInfoMap(dbInfo.NewDirBlockInfoFromDirBlock(d.DirectoryBlock))
+	}
+
 	return
 }
 
@@ -0,0 +1,71 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+)
+
+// MinEntryPruneDepth keeps the entries a node needs to answer for recent blocks, a lower
+// EntryPruneDepth is raised to it
+const MinEntryPruneDepth = 1000
+
+// GetEntryPruneHeight returns the height below which the node does not keep the content of
+// entries, or 0 if it keeps every entry
+func (s *State) GetEntryPruneHeight() uint32 {
+	if s.EntryPruneDepth == 0 {
+		return 0
+	}
+	depth := s.EntryPruneDepth
+	if depth < MinEntryPruneDepth {
+		depth = MinEntryPruneDepth
+	}
+	highest := s.GetHighestSavedBlk()
+	if highest <= depth {
+		return 0
+	}
+	return highest - depth
+}
+
+// keepEntries returns true for the chains the node reads entries from when it starts: the
+// identity chains, which all start with 888888, the exchange rate chain and the anchor chain
+func (s *State) keepEntries(chainID interfaces.IHash) bool {
+	if bytes.HasPrefix(chainID.Bytes(), []byte{0x88, 0x88, 0x88}) {
+		return true
+	}
+	id := chainID.String()
+	return id == s.FERChainId || id == databaseOverlay.AnchorBlockID
+}
+
+// entryPruned returns true if the entries of the chain at the given height are below the prune
+// height and not kept by keepEntries
+func (s *State) entryPruned(chainID interfaces.IHash, dbheight uint32, pruneHeight uint32) bool {
+	return dbheight < pruneHeight && !s.keepEntries(chainID)
+}
+
+// PruneEntries deletes the content of the entries below the prune height, from where the last
+// call stopped
+func (s *State) PruneEntries() error {
+	_, err := s.DB.PruneEntries(s.GetEntryPruneHeight(), s.keepEntries)
+	return err
+}
+
+// GoPruneEntries prunes entries every so often, if EntryPruneDepth is set
+func (s *State) GoPruneEntries() {
+	if s.EntryPruneDepth == 0 {
+		return
+	}
+	for {
+		err := s.PruneEntries()
+		if err != nil {
+			s.Println("Error pruning entries:", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
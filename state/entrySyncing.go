@@ -49,8 +49,13 @@ func (s *State) MakeMissingEntryRequests() {
 		avg := 0
 		highest := 0
 
-		// Look through our map, and remove any entries we now have in our database.
+		// Look through our map, and remove any entries we now have in our database, or no longer keep.
+		pruneHeight := s.GetEntryPruneHeight()
 		for k := range MissingEntryMap {
+			if s.entryPruned(MissingEntryMap[k].ChainID, MissingEntryMap[k].DBHeight, pruneHeight) {
+				delete(MissingEntryMap, k)
+				continue
+			}
 			if has(s, MissingEntryMap[k].EntryHash) {
 				found++
 				delete(MissingEntryMap, k)
@@ -77,6 +82,9 @@ func (s *State) MakeMissingEntryRequests() {
 		for len(MissingEntryMap) < 3000 {
 			select {
 			case et := <-s.MissingEntries:
+				if s.entryPruned(et.ChainID, et.DBHeight, pruneHeight) {
+					continue
+				}
 				missing++
 				MissingEntryMap[et.EntryHash.Fixed()] = et
 			default:
@@ -187,6 +195,8 @@ func (s *State) GoSyncEntries() {
 
 		entryMissing = 0
 
+		pruneHeight := s.GetEntryPruneHeight()
+
 		for k := range missingMap {
 			if has(s, missingMap[k]) {
 				delete(missingMap, k)
@@ -231,6 +241,9 @@ func (s *State) GoSyncEntries() {
 					eBlock, _ = s.DB.FetchEBlock(ebKeyMR)
 				}
 
+				// Entries pruned from this chain are not kept, so they are never missing
+				pruned := s.entryPruned(eBlock.GetHeader().GetChainID(), eBlock.GetHeader().GetDBHeight(), pruneHeight)
+
 				// Go through all the entry hashes.
 				for _, entryhash := range eBlock.GetEntryHashes() {
 					if entryhash.IsMinuteMarker() {
@@ -245,8 +258,8 @@ func (s *State) GoSyncEntries() {
 						s.UpdateEntryHash <- ueh
 					}
 
-					// If I have the entry, then remove it from the Missing Entries list.
-					if has(s, entryhash) {
+					// If I have the entry, or prune it, then remove it from the Missing Entries list.
+					if pruned || has(s, entryhash) {
 						delete(missingMap, entryhash.Fixed())
 						continue
 					}
@@ -270,6 +283,7 @@ func (s *State) GoSyncEntries() {
 						v.DBHeight = eBlock.GetHeader().GetDBHeight()
 						v.EntryHash = entryhash
 						v.EBHash = ebKeyMR
+						v.ChainID = eBlock.GetHeader().GetChainID()
 						entryMissing++
 						missingMap[entryhash.Fixed()] = entryhash
 						s.MissingEntries <- &v
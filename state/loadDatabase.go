@@ -10,14 +10,9 @@ import (
 
 	"os"
 
-	"github.com/FactomProject/factomd/common/adminBlock"
-	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/directoryBlock"
-	"github.com/FactomProject/factomd/common/entryCreditBlock"
-	"github.com/FactomProject/factomd/common/factoid"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
-	"github.com/FactomProject/factomd/common/primitives"
 )
 
 var _ = fmt.Print
@@ -88,32 +83,5 @@ func LoadDatabase(s *State) {
 }
 
 func GenerateGenesisBlocks(networkID uint32) (interfaces.IDirectoryBlock, interfaces.IAdminBlock, interfaces.IFBlock, interfaces.IEntryCreditBlock) {
-	dblk := directoryBlock.NewDirectoryBlock(nil)
-	ablk := adminBlock.NewAdminBlock(nil)
-	fblk := factoid.GetGenesisFBlock(networkID)
-	ecblk := entryCreditBlock.NewECBlock()
-
-	if networkID != constants.MAIN_NETWORK_ID {
-		if networkID == constants.TEST_NETWORK_ID {
-			ablk.AddFedServer(primitives.NewZeroHash())
-		} else {
-			ablk.AddFedServer(primitives.Sha([]byte("FNode0")))
-		}
-	} else {
-		ecblk.GetBody().AddEntry(entryCreditBlock.NewServerIndexNumber())
-		for i := 1; i < 11; i++ {
-			minute := entryCreditBlock.NewMinuteNumber(uint8(i))
-			ecblk.GetBody().AddEntry(minute)
-		}
-	}
-
-	dblk.SetABlockHash(ablk)
-	dblk.SetECBlockHash(ecblk)
-	dblk.SetFBlockHash(fblk)
-	dblk.GetHeader().SetNetworkID(networkID)
-
-	dblk.GetHeader().SetTimestamp(primitives.NewTimestampFromMinutes(24018960))
-	dblk.BuildBodyMR()
-
-	return dblk, ablk, fblk, ecblk
+	return directoryBlock.GenerateGenesisBlocks(networkID)
 }
@@ -78,6 +78,7 @@ type MissingEntry struct {
 	LastTime  time.Time
 	EBHash    interfaces.IHash
 	EntryHash interfaces.IHash
+	ChainID   interfaces.IHash
 	DBHeight  uint32
 }
 
@@ -89,6 +90,7 @@ func RandomMissingEntry() *MissingEntry {
 	me.LastTime = time.Unix(random.RandInt64Between(0, 1000000), random.RandInt64Between(0, 1000000))
 	me.EBHash = primitives.RandomHash()
 	me.EntryHash = primitives.RandomHash()
+	me.ChainID = primitives.RandomHash()
 	me.DBHeight = random.RandUInt32()
 	return me
 }
@@ -100,6 +102,9 @@ func (s *MissingEntry) IsSameAs(b *MissingEntry) bool {
 	if s.EntryHash.IsSameAs(b.EntryHash) == false {
 		return false
 	}
+	if s.ChainID.IsSameAs(b.ChainID) == false {
+		return false
+	}
 	if s.Cnt != b.Cnt {
 		return false
 	}
@@ -128,6 +133,10 @@ func (s *MissingEntry) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = buf.PushBinaryMarshallable(s.ChainID)
+	if err != nil {
+		return nil, err
+	}
 	err = buf.PushUInt32(s.DBHeight)
 	if err != nil {
 		return nil, err
@@ -139,6 +148,7 @@ func (s *MissingEntry) MarshalBinary() ([]byte, error) {
 func (s *MissingEntry) UnmarshalBinaryData(p []byte) (newData []byte, err error) {
 	s.EBHash = primitives.NewZeroHash()
 	s.EntryHash = primitives.NewZeroHash()
+	s.ChainID = primitives.NewZeroHash()
 
 	newData = p
 	buf := primitives.NewBuffer(p)
@@ -162,6 +172,10 @@ func (s *MissingEntry) UnmarshalBinaryData(p []byte) (newData []byte, err error)
 	if err != nil {
 		return
 	}
+	err = buf.PopBinaryMarshallable(s.ChainID)
+	if err != nil {
+		return
+	}
 
 	s.DBHeight, err = buf.PopUInt32()
 	if err != nil {
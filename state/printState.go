@@ -38,13 +38,27 @@ func PrintState(state *State) {
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LogPath", state.LogPath)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LdbPath", state.LdbPath)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "BoltDBPath", state.BoltDBPath)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "BadgerDBPath", state.BadgerDBPath)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LogLevel", state.LogLevel)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "ConsoleLogLevel", state.ConsoleLogLevel)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "NodeMode", state.NodeMode)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "DBType", state.DBType)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "CloneDBType", state.CloneDBType)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "MigrateDBType", state.MigrateDBType)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "ExportData", state.ExportData)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "ExportDataSubpath", state.ExportDataSubpath)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AddressIndex", state.AddressIndex)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "EntryPruneDepth", state.EntryPruneDepth)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "DBEncryptionKeyFile", state.DBEncryptionKeyFile)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "DBEncryptionNewKeyFile", state.DBEncryptionNewKeyFile)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "DBSlowOperationThreshold", state.DBSlowOperationThreshold)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "DBBlockCacheSize", state.DBBlockCacheSize)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "BootstrapArchive", state.BootstrapArchive)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AnchorChain", state.AnchorChain)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AnchorMockChainFile", state.AnchorMockChainFile)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AnchorConfirmations", state.AnchorConfirmations)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AnchorSigningKey", state.AnchorSigningKey)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AnchorECPrivateKey", state.AnchorECPrivateKey)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LocalServerPrivKey", state.LocalServerPrivKey)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "DirectoryBlockInSeconds", state.DirectoryBlockInSeconds)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "PortNumber", state.PortNumber)
@@ -65,6 +79,12 @@ func PrintState(state *State) {
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LocalNetworkPort", state.LocalNetworkPort)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LocalSeedURL", state.LocalSeedURL)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "LocalSpecialPeers", state.LocalSpecialPeers)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "NodeKeyFile", state.NodeKeyFile)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "AllowPlaintextPeers", state.AllowPlaintextPeers)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "PeerSendBytesPerSecond", state.PeerSendBytesPerSecond)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "PeerSendMessagesPerSecond", state.PeerSendMessagesPerSecond)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "PeerReceiveBytesPerSecond", state.PeerReceiveBytesPerSecond)
+	str = fmt.Sprintf("%s %35s = %+v\n", str, "PeerReceiveMessagesPerSecond", state.PeerReceiveMessagesPerSecond)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "CustomNetworkID", state.CustomNetworkID)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "IdentityChainID", state.IdentityChainID)
 	str = fmt.Sprintf("%s %35s = %+v\n", str, "Identities", state.Identities)
@@ -18,12 +18,16 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 
+	"github.com/FactomProject/factomd/anchor"
 	"github.com/FactomProject/factomd/common/adminBlock"
 	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
 	. "github.com/FactomProject/factomd/common/identity"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
 	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/badgerdb"
 	"github.com/FactomProject/factomd/database/boltdb"
 	"github.com/FactomProject/factomd/database/databaseOverlay"
 	"github.com/FactomProject/factomd/database/leveldb"
@@ -58,13 +62,33 @@ type State struct {
 	LogPath           string
 	LdbPath           string
 	BoltDBPath        string
+	BadgerDBPath      string
 	LogLevel          string
 	ConsoleLogLevel   string
 	NodeMode          string
 	DBType            string
 	CloneDBType       string
+	MigrateDBType     string
 	ExportData        bool
 	ExportDataSubpath string
+	AddressIndex      bool
+	EntryPruneDepth   uint32
+
+	DBEncryptionKeyFile    string
+	DBEncryptionNewKeyFile string
+
+	// DBSlowOperationThreshold logs the database operations that take longer, 0 logs none
+	DBSlowOperationThreshold time.Duration
+	// DBBlockCacheSize is the number of blocks and entries the database keeps, 0 keeps none
+	DBBlockCacheSize int
+	// BootstrapArchive is a block archive imported on start
+	BootstrapArchive string
+
+	AnchorChain         string
+	AnchorMockChainFile string
+	AnchorConfirmations int
+	AnchorSigningKey    string
+	AnchorECPrivateKey  string
 
 	LogBits int64 // Bit zero is for logging the Directory Block on DBSig [5]
 
@@ -96,10 +120,18 @@ type State struct {
 	LocalNetworkPort        string
 	LocalSeedURL            string
 	LocalSpecialPeers       string
+	NodeKeyFile             string // Encrypts and authenticates peer connections when set
+	AllowPlaintextPeers     bool   // Lets a node with a node key talk to peers without one
 	CustomNetworkID         []byte
 	CustomBootstrapIdentity string
 	CustomBootstrapKey      string
 
+	// Per peer rate limits, 0 is unlimited
+	PeerSendBytesPerSecond       int
+	PeerSendMessagesPerSecond    int
+	PeerReceiveBytesPerSecond    int
+	PeerReceiveMessagesPerSecond int
+
 	IdentityChainID      interfaces.IHash // If this node has an identity, this is it
 	Identities           []*Identity      // Identities of all servers in management chain
 	Authorities          []*Authority     // Identities of all servers in management chain
@@ -258,6 +290,10 @@ type State struct {
 	DB     interfaces.DBOverlaySimple
 	Anchor interfaces.IAnchor
 
+	// Told of new blocks, acks and minutes, eg by the API for its subscribers
+	eventSink      interfaces.IEventSink
+	eventSinkMutex sync.RWMutex
+
 	// Directory Block State
 	DBStates *DBStateList // Holds all DBStates not yet processed.
 
@@ -400,6 +436,7 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.JournalFile = s.LogPath + "/journal" + number + ".log"
 	newState.Journaling = s.Journaling
 	newState.BoltDBPath = s.BoltDBPath + "/Sim" + number
+	newState.BadgerDBPath = s.BadgerDBPath + "/Sim" + number
 	newState.LogLevel = s.LogLevel
 	newState.ConsoleLogLevel = s.ConsoleLogLevel
 	newState.NodeMode = "FULL"
@@ -407,6 +444,19 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.DBType = s.CloneDBType
 	newState.ExportData = s.ExportData
 	newState.ExportDataSubpath = s.ExportDataSubpath + "sim-" + number
+	newState.AddressIndex = s.AddressIndex
+	newState.EntryPruneDepth = s.EntryPruneDepth
+	newState.DBEncryptionKeyFile = s.DBEncryptionKeyFile
+	newState.DBEncryptionNewKeyFile = s.DBEncryptionNewKeyFile
+	newState.DBSlowOperationThreshold = s.DBSlowOperationThreshold
+	newState.DBBlockCacheSize = s.DBBlockCacheSize
+	// Simulated nodes sync from the original
+	newState.BootstrapArchive = ""
+	newState.AnchorChain = "none" // Only the original node anchors
+	newState.AnchorMockChainFile = s.AnchorMockChainFile
+	newState.AnchorConfirmations = s.AnchorConfirmations
+	newState.AnchorSigningKey = s.AnchorSigningKey
+	newState.AnchorECPrivateKey = s.AnchorECPrivateKey
 	newState.Network = s.Network
 	newState.MainNetworkPort = s.MainNetworkPort
 	newState.PeersFile = s.PeersFile
@@ -418,6 +468,12 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.LocalNetworkPort = s.LocalNetworkPort
 	newState.LocalSeedURL = s.LocalSeedURL
 	newState.LocalSpecialPeers = s.LocalSpecialPeers
+	newState.NodeKeyFile = s.NodeKeyFile
+	newState.AllowPlaintextPeers = s.AllowPlaintextPeers
+	newState.PeerSendBytesPerSecond = s.PeerSendBytesPerSecond
+	newState.PeerSendMessagesPerSecond = s.PeerSendMessagesPerSecond
+	newState.PeerReceiveBytesPerSecond = s.PeerReceiveBytesPerSecond
+	newState.PeerReceiveMessagesPerSecond = s.PeerReceiveMessagesPerSecond
 	newState.StartDelayLimit = s.StartDelayLimit
 	newState.CustomNetworkID = s.CustomNetworkID
 
@@ -465,14 +521,18 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.FactomdLocations = s.FactomdLocations
 
 	switch newState.DBType {
-	case "LDB":
+	case "LDB", "EncryptedLDB":
 		newState.StateSaverStruct.FastBoot = s.StateSaverStruct.FastBoot
 		newState.StateSaverStruct.FastBootLocation = newState.LdbPath
 		break
-	case "Bolt":
+	case "Bolt", "EncryptedBolt":
 		newState.StateSaverStruct.FastBoot = s.StateSaverStruct.FastBoot
 		newState.StateSaverStruct.FastBootLocation = newState.BoltDBPath
 		break
+	case "Badger":
+		newState.StateSaverStruct.FastBoot = s.StateSaverStruct.FastBoot
+		newState.StateSaverStruct.FastBootLocation = newState.BadgerDBPath
+		break
 	}
 
 	return newState
@@ -623,21 +683,42 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		// TODO: improve the paths after milestone 1
 		cfg.App.LdbPath = cfg.App.HomeDir + networkName + cfg.App.LdbPath
 		cfg.App.BoltDBPath = cfg.App.HomeDir + networkName + cfg.App.BoltDBPath
+		cfg.App.BadgerDBPath = cfg.App.HomeDir + networkName + cfg.App.BadgerDBPath
 		cfg.App.DataStorePath = cfg.App.HomeDir + networkName + cfg.App.DataStorePath
 		cfg.Log.LogPath = cfg.App.HomeDir + networkName + cfg.Log.LogPath
 		cfg.App.ExportDataSubpath = cfg.App.HomeDir + networkName + cfg.App.ExportDataSubpath
 		cfg.App.PeersFile = cfg.App.HomeDir + networkName + cfg.App.PeersFile
+		if cfg.App.NodeKeyFile != "" {
+			cfg.App.NodeKeyFile = cfg.App.HomeDir + networkName + cfg.App.NodeKeyFile
+		}
+		cfg.App.AnchorMockChainFile = cfg.App.HomeDir + networkName + cfg.App.AnchorMockChainFile
 		cfg.App.ControlPanelFilesPath = cfg.App.HomeDir + cfg.App.ControlPanelFilesPath
 
 		s.LogPath = cfg.Log.LogPath + s.Prefix
 		s.LdbPath = cfg.App.LdbPath + s.Prefix
 		s.BoltDBPath = cfg.App.BoltDBPath + s.Prefix
+		s.BadgerDBPath = cfg.App.BadgerDBPath + s.Prefix
 		s.LogLevel = cfg.Log.LogLevel
 		s.ConsoleLogLevel = cfg.Log.ConsoleLogLevel
 		s.NodeMode = cfg.App.NodeMode
 		s.DBType = cfg.App.DBType
+		s.MigrateDBType = cfg.App.MigrateDBType
 		s.ExportData = cfg.App.ExportData // bool
 		s.ExportDataSubpath = cfg.App.ExportDataSubpath
+		s.AddressIndex = cfg.App.AddressIndex
+		if cfg.App.EntryPruneDepth > 0 {
+			s.EntryPruneDepth = uint32(cfg.App.EntryPruneDepth)
+		}
+		s.DBEncryptionKeyFile = cfg.App.DBEncryptionKeyFile
+		s.DBEncryptionNewKeyFile = cfg.App.DBEncryptionNewKeyFile
+		s.DBSlowOperationThreshold = time.Duration(cfg.App.DBSlowOperationThreshold) * time.Millisecond
+		s.DBBlockCacheSize = cfg.App.DBBlockCacheSize
+		s.BootstrapArchive = cfg.App.BootstrapArchive
+		s.AnchorChain = cfg.App.AnchorChain
+		s.AnchorMockChainFile = cfg.App.AnchorMockChainFile
+		s.AnchorConfirmations = cfg.App.AnchorConfirmations
+		s.AnchorSigningKey = cfg.App.AnchorSigningKey
+		s.AnchorECPrivateKey = cfg.App.AnchorECPrivateKey
 		s.MainNetworkPort = cfg.App.MainNetworkPort
 		s.PeersFile = cfg.App.PeersFile
 		s.MainSeedURL = cfg.App.MainSeedURL
@@ -650,6 +731,12 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		s.LocalNetworkPort = cfg.App.LocalNetworkPort
 		s.LocalSeedURL = cfg.App.LocalSeedURL
 		s.LocalSpecialPeers = cfg.App.LocalSpecialPeers
+		s.NodeKeyFile = cfg.App.NodeKeyFile
+		s.AllowPlaintextPeers = cfg.App.AllowPlaintextPeers
+		s.PeerSendBytesPerSecond = cfg.App.PeerSendBytesPerSecond
+		s.PeerSendMessagesPerSecond = cfg.App.PeerSendMessagesPerSecond
+		s.PeerReceiveBytesPerSecond = cfg.App.PeerReceiveBytesPerSecond
+		s.PeerReceiveMessagesPerSecond = cfg.App.PeerReceiveMessagesPerSecond
 		s.LocalServerPrivKey = cfg.App.LocalServerPrivKey
 		s.FactoshisPerEC = cfg.App.ExchangeRate
 		s.DirectoryBlockInSeconds = cfg.App.DirectoryBlockInSeconds
@@ -696,12 +783,26 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		s.LogPath = "database/"
 		s.LdbPath = "database/ldb"
 		s.BoltDBPath = "database/bolt"
+		s.BadgerDBPath = "database/badger"
 		s.LogLevel = "none"
 		s.ConsoleLogLevel = "standard"
 		s.NodeMode = "SERVER"
 		s.DBType = "Map"
+		s.MigrateDBType = ""
 		s.ExportData = false
 		s.ExportDataSubpath = "data/export"
+		s.AddressIndex = false
+		s.EntryPruneDepth = 0
+		s.DBEncryptionKeyFile = ""
+		s.DBEncryptionNewKeyFile = ""
+		s.DBSlowOperationThreshold = 0
+		s.DBBlockCacheSize = 0
+		s.BootstrapArchive = ""
+		s.AnchorChain = "none"
+		s.AnchorMockChainFile = "database/anchor/mockchain.json"
+		s.AnchorConfirmations = 6
+		s.AnchorSigningKey = ""
+		s.AnchorECPrivateKey = ""
 		s.Network = "TEST"
 		s.MainNetworkPort = "8108"
 		s.PeersFile = "peers.json"
@@ -713,6 +814,12 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		s.LocalNetworkPort = "8110"
 		s.LocalSeedURL = "https://raw.githubusercontent.com/FactomProject/factomproject.github.io/master/seed/localseed.txt"
 		s.LocalSpecialPeers = ""
+		s.NodeKeyFile = ""
+		s.AllowPlaintextPeers = false
+		s.PeerSendBytesPerSecond = 0
+		s.PeerSendMessagesPerSecond = 0
+		s.PeerReceiveBytesPerSecond = 0
+		s.PeerReceiveMessagesPerSecond = 0
 
 		s.LocalServerPrivKey = "4c38c72fc5cdad68f13b74674d3ffb1f3d63a112710868c9b08946553448d26d"
 		s.FactoshisPerEC = 006666
@@ -846,6 +953,9 @@ func (s *State) Init() {
 	}
 
 	//Database
+	if s.DBSlowOperationThreshold > 0 {
+		databaseOverlay.SlowOperationThreshold = s.DBSlowOperationThreshold
+	}
 	switch s.DBType {
 	case "LDB":
 		if err := s.InitLevelDB(); err != nil {
@@ -859,13 +969,48 @@ func (s *State) Init() {
 		if err := s.InitMapDB(); err != nil {
 			panic(fmt.Sprintf("Error initializing the database: %v", err))
 		}
+	case "Badger":
+		if err := s.InitBadgerDB(); err != nil {
+			panic(fmt.Sprintf("Error initializing the database: %v", err))
+		}
+	case "EncryptedLDB", "EncryptedBolt":
+		if err := s.InitEncryptedDB(); err != nil {
+			panic(fmt.Sprintf("Error initializing the database: %v", err))
+		}
 	default:
 		panic("No Database type specified")
 	}
-
+	if s.MigrateDBType != "" {
+		if err := s.initDBMigration(); err != nil {
+			panic(fmt.Sprintf("Error initializing the database migration: %v", err))
+		}
+	}
+	if err := s.RecoverDatabase(); err != nil {
+		panic(fmt.Sprintf("Error recovering the database: %v", err))
+	}
+	// The blocks imported from the archive are indexed and exported as the blocks the node saves
 	if s.ExportData {
 		s.DB.SetExportData(s.ExportDataSubpath)
 	}
+	if s.AddressIndex {
+		s.DB.SetAddressIndex(true)
+	}
+	if s.DBBlockCacheSize > 0 {
+		s.DB.SetBlockCacheSize(s.DBBlockCacheSize)
+	}
+	if s.BootstrapArchive != "" {
+		if err := s.ImportArchive(); err != nil {
+			panic(fmt.Sprintf("Error importing the block archive: %v", err))
+		}
+	}
+	// Indexing the anchor chain of an older database takes a while, the node does not wait for
+	// it. It starts once the archive is imported and the overlay is set up, so it does not
+	// overlap their writes.
+	go func() {
+		if err := s.DB.InitAnchorRecordIndex(); err != nil {
+			s.Println("Error indexing the anchor records:", err)
+		}
+	}()
 
 	//Network
 	switch s.Network {
@@ -890,6 +1035,9 @@ func (s *State) Init() {
 
 	s.initServerKeys()
 	s.AuthorityServerCount = 0
+	if err := s.initAnchorer(); err != nil {
+		s.Println("\nAnchoring is disabled:", err)
+	}
 
 	//LoadIdentityCache(s)
 	//StubIdentityCache(s)
@@ -1013,6 +1161,18 @@ func (s *State) GetAndLockDB() interfaces.DBOverlaySimple {
 func (s *State) UnlockDB() {
 }
 
+func (s *State) GetDBSnapshot() (interfaces.DBOverlaySimple, error) {
+	dbo, ok := s.DB.(*databaseOverlay.Overlay)
+	if ok == false {
+		return nil, fmt.Errorf("The database does not support snapshots")
+	}
+	view, err := dbo.NewSnapshotView()
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
 // Checks ChainIDs to determine if we need their entries to process entries and transactions.
 func (s *State) Needed(eb interfaces.IEntryBlock) bool {
 	id := []byte{0x88, 0x88, 0x88}
@@ -1862,6 +2022,26 @@ func (s *State) GetAnchor() interfaces.IAnchor {
 	return s.Anchor
 }
 
+func (s *State) GetNetworkController() interfaces.INetworkController {
+	if nil == s.NetworkControler {
+		return nil
+	}
+	return s.NetworkControler
+}
+
+func (s *State) SetEventSink(sink interfaces.IEventSink) {
+	s.eventSinkMutex.Lock()
+	defer s.eventSinkMutex.Unlock()
+	s.eventSink = sink
+}
+
+// getEventSink returns the sink events are sent to, nil if there is none
+func (s *State) getEventSink() interfaces.IEventSink {
+	s.eventSinkMutex.RLock()
+	defer s.eventSinkMutex.RUnlock()
+	return s.eventSink
+}
+
 func (s *State) TallySent(msgType int) {
 	s.MessageTalliesSent[msgType]++
 }
@@ -1891,6 +2071,151 @@ func (s *State) initServerKeys() {
 	s.serverPubKey = s.serverPrivKey.Pub
 }
 
+// initAnchorer sets up anchoring the directory blocks in process, if an anchor chain is configured.
+// Signed anchor records are saved as the DirBlockInfo of their directory block, which is what
+// receipts are built from, and committed as entries to the anchor chain for the other nodes.
+func (s *State) initAnchorer() error {
+	var chain anchor.AnchorChain
+	mock := false
+	switch s.AnchorChain {
+	case "", "none":
+		return nil
+	case "mock-bitcoin":
+		mockChain := anchor.NewMockChain(anchor.ChainBitcoin, s.AnchorMockChainFile)
+		mockChain.AutoMine = true
+		chain = mockChain
+		mock = true
+	case "mock-ethereum":
+		mockChain := anchor.NewMockChain(anchor.ChainEthereum, s.AnchorMockChainFile)
+		mockChain.AutoMine = true
+		chain = mockChain
+		mock = true
+	default:
+		return fmt.Errorf("Bad value for AnchorChain in factomd.conf: %v", s.AnchorChain)
+	}
+
+	signer, err := primitives.NewPrivateKeyFromHex(s.AnchorSigningKey)
+	if err != nil {
+		return fmt.Errorf("Cannot parse AnchorSigningKey in factomd.conf: %v", err)
+	}
+	known := false
+	for _, pub := range databaseOverlay.AnchorSigPublicKeys {
+		if pub.String() == signer.Pub.String() {
+			known = true
+			break
+		}
+	}
+	if !known {
+		if !mock {
+			return fmt.Errorf("AnchorSigningKey does not match any of the anchor public keys")
+		}
+		s.Println("\nAnchorSigningKey does not match any of the anchor public keys, other nodes will not accept its anchor records")
+	}
+
+	ecKey, err := primitives.HumanReadableECPrivateKeyToPrivateKey(s.AnchorECPrivateKey)
+	if err != nil {
+		return fmt.Errorf("Cannot parse AnchorECPrivateKey in factomd.conf: %v", err)
+	}
+
+	store, ok := s.DB.(anchor.JobStore)
+	if !ok {
+		return fmt.Errorf("The database can not keep anchor jobs")
+	}
+	anchorer := anchor.NewAnchorer(store, signer, chain)
+	if s.AnchorConfirmations > 0 {
+		anchorer.Confirmations = int64(s.AnchorConfirmations)
+	}
+	anchorer.Publish = func(record *anchor.AnchorRecord, data []byte, sig []byte) error {
+		if record.Bitcoin == nil && record.Ethereum == nil {
+			return fmt.Errorf("Anchor record of block %d has no anchor", record.DBHeight)
+		}
+		if record.Ethereum != nil {
+			err := s.DB.SaveEthereumAnchorRecord(record.DBHeight, record)
+			if err != nil {
+				return err
+			}
+		}
+		if record.Bitcoin != nil {
+			dbi, err := databaseOverlay.AnchorRecordToDirBlockInfo(record)
+			if err != nil {
+				return err
+			}
+			err = s.DB.SaveDirBlockInfo(dbi)
+			if err != nil {
+				return err
+			}
+		}
+		return s.commitAnchorEntry(data, sig, ecKey)
+	}
+	anchorer.IsPublished = func(data []byte, sig []byte) (bool, error) {
+		entry, err := anchorEntry(data, sig)
+		if err != nil {
+			return false, err
+		}
+		eBlock, err := s.DB.FetchIncludedIn(entry.GetHash())
+		return eBlock != nil, err
+	}
+	if err := anchorer.InitRPCClient(); err != nil {
+		return fmt.Errorf("Error initializing the anchorer: %v", err)
+	}
+	anchorer.Start(10 * time.Second)
+	s.Anchor = anchorer
+	return nil
+}
+
+// anchorEntry returns the entry in the anchor chain holding a signed V2 anchor record, with its
+// signature as the external ID
+func anchorEntry(data []byte, sig []byte) (*entryBlock.Entry, error) {
+	chainID, err := primitives.NewShaHashFromStr(databaseOverlay.AnchorBlockID)
+	if err != nil {
+		return nil, err
+	}
+	entry := entryBlock.NewEntry()
+	entry.ChainID = chainID
+	entry.ExtIDs = []primitives.ByteSlice{{Bytes: sig}}
+	entry.Content = primitives.ByteSlice{Bytes: data}
+	return entry, nil
+}
+
+// commitAnchorEntry commits and reveals the anchor entry of a signed V2 anchor record, paid with
+// the given entry credit key
+func (s *State) commitAnchorEntry(data []byte, sig []byte, ecKey []byte) error {
+	entry, err := anchorEntry(data, sig)
+	if err != nil {
+		return err
+	}
+	bin, err := entry.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	cost, err := util.EntryCost(bin)
+	if err != nil {
+		return err
+	}
+
+	commit := entryCreditBlock.NewCommitEntry()
+	milli := make([]byte, 8)
+	binary.BigEndian.PutUint64(milli, uint64(time.Now().UnixNano()/1e6))
+	copy(commit.MilliTime[:], milli[2:])
+	commit.EntryHash = entry.GetHash()
+	commit.Credits = cost
+	err = commit.Sign(ecKey)
+	if err != nil {
+		return err
+	}
+
+	commitMsg := messages.NewCommitEntryMsg()
+	commitMsg.CommitEntry = commit
+	s.APIQueue().Enqueue(commitMsg)
+	s.IncECommits()
+
+	reveal := messages.NewRevealEntryMsg()
+	reveal.Entry = entry
+	reveal.Timestamp = s.GetTimestamp()
+	s.APIQueue().Enqueue(reveal)
+	return nil
+}
+
 func (s *State) Log(level string, message string) {
 	packageLogger.WithFields(s.Logger.Data).Info(message)
 }
@@ -2150,6 +2475,16 @@ func (s *State) InitLevelDB() error {
 		return nil
 	}
 
+	dbase, err := s.openLevelDB()
+	if err != nil {
+		return err
+	}
+
+	s.DB = databaseOverlay.NewOverlay(dbase)
+	return nil
+}
+
+func (s *State) openLevelDB() (interfaces.IDatabase, error) {
 	path := s.LdbPath + "/" + s.Network + "/" + "factoid_level.db"
 
 	s.Println("Database:", path)
@@ -2159,12 +2494,10 @@ func (s *State) InitLevelDB() error {
 	if err != nil || dbase == nil {
 		dbase, err = leveldb.NewLevelDB(path, true)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-
-	s.DB = databaseOverlay.NewOverlay(dbase)
-	return nil
+	return dbase, nil
 }
 
 func (s *State) InitBoltDB() error {
@@ -2172,6 +2505,11 @@ func (s *State) InitBoltDB() error {
 		return nil
 	}
 
+	s.DB = databaseOverlay.NewOverlay(s.openBoltDB())
+	return nil
+}
+
+func (s *State) openBoltDB() interfaces.IDatabase {
 	path := s.BoltDBPath + "/" + s.Network + "/"
 
 	s.Println("Database Path for", s.FactomNodeName, "is", path)
@@ -2179,10 +2517,35 @@ func (s *State) InitBoltDB() error {
 
 	dbase := new(boltdb.BoltDB)
 	dbase.Init(nil, path+"FactomBolt.db")
+	return dbase
+}
+
+func (s *State) InitBadgerDB() error {
+	if s.DB != nil {
+		return nil
+	}
+
+	dbase, err := s.openBadgerDB()
+	if err != nil {
+		return err
+	}
+
 	s.DB = databaseOverlay.NewOverlay(dbase)
 	return nil
 }
 
+func (s *State) openBadgerDB() (interfaces.IDatabase, error) {
+	path := s.BadgerDBPath + "/" + s.Network + "/" + "factoid_badger.db"
+
+	s.Println("Database:", path)
+
+	dbase, err := badgerdb.NewBadgerDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return dbase, nil
+}
+
 func (s *State) InitMapDB() error {
 	if s.DB != nil {
 		return nil
@@ -1550,6 +1550,10 @@ func (s *State) ProcessEOM(dbheight uint32, msg interfaces.IMsg) bool {
 			s.Saving = true
 		}
 
+		if sink := s.getEventSink(); sink != nil {
+			sink.NotifyMinute(s, s.LLeaderHeight, s.CurrentMinute)
+		}
+
 		s.Commits.RemoveExpired(s)
 		// for k, v := range s.Commits {
 		// 	if v != nil {
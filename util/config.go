@@ -24,10 +24,24 @@ type FactomdConfig struct {
 		DBType                                 string
 		LdbPath                                string
 		BoltDBPath                             string
+		BadgerDBPath                           string
+		MigrateDBType                          string
+		DBEncryptionKeyFile                    string
+		DBEncryptionNewKeyFile                 string
+		DBSlowOperationThreshold               int
+		DBBlockCacheSize                       int
+		BootstrapArchive                       string
 		DataStorePath                          string
 		DirectoryBlockInSeconds                int
 		ExportData                             bool
 		ExportDataSubpath                      string
+		AddressIndex                           bool
+		EntryPruneDepth                        int
+		AnchorChain                            string
+		AnchorMockChainFile                    string
+		AnchorConfirmations                    int
+		AnchorSigningKey                       string
+		AnchorECPrivateKey                     string
 		FastBoot                               bool
 		FastBootLocation                       string
 		NodeMode                               string
@@ -53,6 +67,8 @@ type FactomdConfig struct {
 		LocalNetworkPort        string
 		LocalSeedURL            string
 		LocalSpecialPeers       string
+		NodeKeyFile             string
+		AllowPlaintextPeers     bool
 		CustomBootstrapIdentity string
 		CustomBootstrapKey      string
 		FactomdTlsEnabled       bool
@@ -61,6 +77,12 @@ type FactomdConfig struct {
 		FactomdRpcUser          string
 		FactomdRpcPass          string
 
+		// Peer rate limits, 0 is unlimited
+		PeerSendBytesPerSecond       int
+		PeerSendMessagesPerSecond    int
+		PeerReceiveBytesPerSecond    int
+		PeerReceiveMessagesPerSecond int
+
 		ChangeAcksHeight uint32
 	}
 	Peer struct {
@@ -108,14 +130,39 @@ HomeDir                               = ""
 ; --------------- ControlPanel disabled | readonly | readwrite
 ControlPanelSetting                   = readonly
 ControlPanelPort                      = 8090
-; --------------- DBType: LDB | Bolt | Map
+; --------------- DBType: LDB | Bolt | Map | Badger | EncryptedLDB | EncryptedBolt
 DBType                                = "LDB"
 LdbPath                               = "database/ldb"
 BoltDBPath                            = "database/bolt"
+BadgerDBPath                          = "database/badger"
+; --------------- MigrateDBType: copies the database to this DBType while the node runs, and switches to it on the next start once verified
+MigrateDBType                         = ""
+; --------------- Key file of EncryptedLDB and EncryptedBolt, readable by its owner only. If empty the key is read from FACTOMD_DB_KEY
+DBEncryptionKeyFile                   = ""
+; --------------- Re-encrypts the database with this key file, or FACTOMD_DB_NEW_KEY, on start. Make it the key file once done
+DBEncryptionNewKeyFile                = ""
+; --------------- Logs the database operations taking at least this many milliseconds, 0 logs none
+DBSlowOperationThreshold              = 0
+; --------------- Number of decoded directory blocks, entry blocks and entries kept in memory, 0 caches none. Helps nodes serving the API
+DBBlockCacheSize                      = 0
+; --------------- Block archive to import on start, the blocks the database does not have yet are validated and added
+BootstrapArchive                      = ""
 DataStorePath                         = "data/export"
 DirectoryBlockInSeconds               = 6
 ExportData                            = false
 ExportDataSubpath                     = "database/export/"
+; --------------- Index factoid and entry credit transactions by address, for the address-transactions API
+AddressIndex                          = false
+; --------------- Delete the content of entries older than this many directory blocks, 0 keeps every entry
+EntryPruneDepth                       = 0
+; --------------- Anchor directory blocks in process instead of by an external anchor service: none | mock-bitcoin | mock-ethereum
+AnchorChain                           = "none"
+AnchorMockChainFile                   = "database/anchor/mockchain.json"
+AnchorConfirmations                   = 6
+; --------------- Private key the anchor records are signed with, it has to match one of the anchor public keys the network trusts
+AnchorSigningKey                      = ""
+; --------------- Entry credit private key (Es...) paying for the anchor record entries
+AnchorECPrivateKey                    = ""
 FastBoot                              = true
 FastBootLocation                      = ""
 ; --------------- Network: MAIN | TEST | LOCAL
@@ -130,6 +177,15 @@ TestSpecialPeers     = ""
 LocalNetworkPort     = 8110
 LocalSeedURL         = "https://raw.githubusercontent.com/FactomProject/factomproject.github.io/master/seed/localseed.txt"
 LocalSpecialPeers    = ""
+; --------------- Node key file; when set, peer connections are encrypted and authenticated with the node key, and special peers may be pinned as nodekey@ip:port
+; --------------- A node with a node key only connects to peers with one, unless plaintext peers are allowed while the node keys are rolled out
+NodeKeyFile          = ""
+AllowPlaintextPeers  = false
+; --------------- Limits on the bytes and messages per second sent to and read from each peer, 0 is unlimited. Special peers are never limited
+PeerSendBytesPerSecond = 0
+PeerSendMessagesPerSecond = 0
+PeerReceiveBytesPerSecond = 0
+PeerReceiveMessagesPerSecond = 0
 CustomBootstrapIdentity     = 38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9
 CustomBootstrapKey          = cc1985cdfae4e32b5a454dfda8ce5e1361558482684f3367649c3ad852c8e31a
 ; --------------- NodeMode: FULL | SERVER ----------------
@@ -204,10 +260,24 @@ func (s *FactomdConfig) String() string {
 	out.WriteString(fmt.Sprintf("\n    DBType                  %v", s.App.DBType))
 	out.WriteString(fmt.Sprintf("\n    LdbPath                 %v", s.App.LdbPath))
 	out.WriteString(fmt.Sprintf("\n    BoltDBPath              %v", s.App.BoltDBPath))
+	out.WriteString(fmt.Sprintf("\n    BadgerDBPath            %v", s.App.BadgerDBPath))
+	out.WriteString(fmt.Sprintf("\n    MigrateDBType           %v", s.App.MigrateDBType))
+	out.WriteString(fmt.Sprintf("\n    DBEncryptionKeyFile     %v", s.App.DBEncryptionKeyFile))
+	out.WriteString(fmt.Sprintf("\n    DBEncryptionNewKeyFile  %v", s.App.DBEncryptionNewKeyFile))
+	out.WriteString(fmt.Sprintf("\n    DBSlowOperationThreshold %v", s.App.DBSlowOperationThreshold))
+	out.WriteString(fmt.Sprintf("\n    DBBlockCacheSize        %v", s.App.DBBlockCacheSize))
+	out.WriteString(fmt.Sprintf("\n    BootstrapArchive        %v", s.App.BootstrapArchive))
 	out.WriteString(fmt.Sprintf("\n    DataStorePath           %v", s.App.DataStorePath))
 	out.WriteString(fmt.Sprintf("\n    DirectoryBlockInSeconds %v", s.App.DirectoryBlockInSeconds))
 	out.WriteString(fmt.Sprintf("\n    ExportData              %v", s.App.ExportData))
 	out.WriteString(fmt.Sprintf("\n    ExportDataSubpath       %v", s.App.ExportDataSubpath))
+	out.WriteString(fmt.Sprintf("\n    AddressIndex            %v", s.App.AddressIndex))
+	out.WriteString(fmt.Sprintf("\n    EntryPruneDepth         %v", s.App.EntryPruneDepth))
+	out.WriteString(fmt.Sprintf("\n    AnchorChain             %v", s.App.AnchorChain))
+	out.WriteString(fmt.Sprintf("\n    AnchorMockChainFile     %v", s.App.AnchorMockChainFile))
+	out.WriteString(fmt.Sprintf("\n    AnchorConfirmations     %v", s.App.AnchorConfirmations))
+	out.WriteString(fmt.Sprintf("\n    AnchorSigningKey        %v", s.App.AnchorSigningKey))
+	out.WriteString(fmt.Sprintf("\n    AnchorECPrivateKey      %v", s.App.AnchorECPrivateKey))
 	out.WriteString(fmt.Sprintf("\n    Network                 %v", s.App.Network))
 	out.WriteString(fmt.Sprintf("\n    MainNetworkPort         %v", s.App.MainNetworkPort))
 	out.WriteString(fmt.Sprintf("\n    PeersFile               %v", s.App.PeersFile))
@@ -219,6 +289,12 @@ func (s *FactomdConfig) String() string {
 	out.WriteString(fmt.Sprintf("\n    LocalNetworkPort        %v", s.App.LocalNetworkPort))
 	out.WriteString(fmt.Sprintf("\n    LocalSeedURL            %v", s.App.LocalSeedURL))
 	out.WriteString(fmt.Sprintf("\n    LocalSpecialPeers       %v", s.App.LocalSpecialPeers))
+	out.WriteString(fmt.Sprintf("\n    NodeKeyFile             %v", s.App.NodeKeyFile))
+	out.WriteString(fmt.Sprintf("\n    AllowPlaintextPeers     %v", s.App.AllowPlaintextPeers))
+	out.WriteString(fmt.Sprintf("\n    PeerSendBytesPerSecond  %v", s.App.PeerSendBytesPerSecond))
+	out.WriteString(fmt.Sprintf("\n    PeerSendMessagesPerSecond %v", s.App.PeerSendMessagesPerSecond))
+	out.WriteString(fmt.Sprintf("\n    PeerReceiveBytesPerSecond %v", s.App.PeerReceiveBytesPerSecond))
+	out.WriteString(fmt.Sprintf("\n    PeerReceiveMessagesPerSecond %v", s.App.PeerReceiveMessagesPerSecond))
 	out.WriteString(fmt.Sprintf("\n    CustomBootstrapIdentity %v", s.App.CustomBootstrapIdentity))
 	out.WriteString(fmt.Sprintf("\n    CustomBootstrapKey      %v", s.App.CustomBootstrapKey))
 	out.WriteString(fmt.Sprintf("\n    NodeMode                %v", s.App.NodeMode))
@@ -8,11 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/p2p"
 	"github.com/FactomProject/factomd/util"
 	"github.com/FactomProject/web"
 )
@@ -81,6 +84,15 @@ func HandleDebugRequest(
 	case "authorities":
 		resp, jsonError = HandleAuthorities(state, params)
 		break
+	case "bans":
+		resp, jsonError = HandleBans(state, params)
+		break
+	case "ban":
+		resp, jsonError = HandleBan(state, params)
+		break
+	case "unban":
+		resp, jsonError = HandleUnban(state, params)
+		break
 	case "configuration":
 		resp, jsonError = HandleConfig(state, params)
 		break
@@ -111,6 +123,27 @@ func HandleDebugRequest(
 	case "network-info":
 		resp, jsonError = HandleNetworkInfo(state, params)
 		break
+	case "peers":
+		resp, jsonError = HandlePeers(state, params)
+		break
+	case "dial-peer":
+		resp, jsonError = HandleDialPeer(state, params)
+		break
+	case "disconnect-peer":
+		resp, jsonError = HandleDisconnectPeer(state, params)
+		break
+	case "ban-peer":
+		resp, jsonError = HandleBanPeer(state, params)
+		break
+	case "adjust-peer-quality":
+		resp, jsonError = HandleAdjustPeerQuality(state, params)
+		break
+	case "add-special-peer":
+		resp, jsonError = HandleAddSpecialPeer(state, params)
+		break
+	case "remove-special-peer":
+		resp, jsonError = HandleRemoveSpecialPeer(state, params)
+		break
 	case "summary":
 		resp, jsonError = HandleSummary(state, params)
 		break
@@ -171,6 +204,83 @@ func HandleAuthorities(
 	return r, nil
 }
 
+func HandleBans(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Bans []p2p.Ban
+	}
+	r := new(ret)
+
+	r.Bans = p2p.Bans()
+	return r, nil
+}
+
+func HandleBan(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Ban p2p.Ban
+	}
+	r := new(ret)
+
+	ban := new(BanRequest)
+	err := MapToObject(params, ban)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+	if ban.Reason == "" {
+		ban.Reason = "banned by an operator"
+	}
+
+	r.Ban, err = controller.BanAddress(ban.Address, time.Duration(ban.Duration)*time.Second, ban.Reason)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return r, nil
+}
+
+func HandleUnban(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Unbanned bool
+	}
+	r := new(ret)
+
+	unban := new(UnbanRequest)
+	err := MapToObject(params, unban)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	r.Unbanned, err = controller.Unban(unban.Address)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return r, nil
+}
+
 func HandleConfig(
 	state interfaces.IState,
 	params interface{},
@@ -346,6 +456,161 @@ func HandleNetworkInfo(
 	return r, nil
 }
 
+func HandlePeers(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Peers map[string]p2p.ConnectionMetrics // by peer hash
+	}
+	r := new(ret)
+
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+	r.Peers = controller.GetConnectionMetrics()
+	return r, nil
+}
+
+func HandleDialPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	dial := new(DialPeerRequest)
+	err := MapToObject(params, dial)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+	address, port, err := net.SplitHostPort(dial.Address)
+	if err != nil || net.ParseIP(address) == nil {
+		return nil, NewCustomInvalidParamsError("The address must be in the form 127.0.0.1:8108")
+	}
+
+	peer := new(p2p.Peer).Init(address, port, 0, p2p.RegularPeer, 0)
+	peer.Source["API"] = time.Now()
+	controller.DialPeer(*peer, dial.Persistent)
+	return dial, nil
+}
+
+func HandleDisconnectPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	peer := new(PeerRequest)
+	err := MapToObject(params, peer)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := connectedPeer(state, peer.Hash)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	controller.Disconnect(peer.Hash)
+	return peer, nil
+}
+
+func HandleBanPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	peer := new(PeerRequest)
+	err := MapToObject(params, peer)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := connectedPeer(state, peer.Hash)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	controller.Ban(peer.Hash)
+	return peer, nil
+}
+
+func HandleAdjustPeerQuality(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	adjust := new(AdjustPeerQualityRequest)
+	err := MapToObject(params, adjust)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := connectedPeer(state, adjust.Hash)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	controller.AdjustPeerQuality(adjust.Hash, adjust.Adjustment)
+	return adjust, nil
+}
+
+func HandleAddSpecialPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	special := new(SpecialPeerRequest)
+	err := MapToObject(params, special)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	err = controller.AddSpecialPeer(special.Address)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return special, nil
+}
+
+func HandleRemoveSpecialPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	special := new(SpecialPeerRequest)
+	err := MapToObject(params, special)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	controller.RemoveSpecialPeer(special.Address)
+	return special, nil
+}
+
 func HandleSummary(
 	state interfaces.IState,
 	params interface{},
@@ -405,6 +670,28 @@ func HandleReloadConfig(
 	return state.GetCfg(), nil
 }
 
+// networkController returns the p2p controller of the node, or an error if it is not on the network
+func networkController(state interfaces.IState) (*p2p.Controller, *primitives.JSONError) {
+	controller, ok := state.GetNetworkController().(*p2p.Controller)
+	if !ok {
+		return nil, NewCustomInternalError("The node is not on the network")
+	}
+	return controller, nil
+}
+
+// connectedPeer returns the p2p controller of the node, or an error if it has no connection to
+// the peer with the hash
+func connectedPeer(state interfaces.IState, hash string) (*p2p.Controller, *primitives.JSONError) {
+	controller, jsonError := networkController(state)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+	if !controller.IsConnected(hash) {
+		return nil, NewCustomInvalidParamsError("No connection to the peer " + hash)
+	}
+	return controller, nil
+}
+
 type SetDelayRequest struct {
 	Delay int64 `json:"delay"`
 }
@@ -412,3 +699,31 @@ type SetDelayRequest struct {
 type SetDropRateRequest struct {
 	DropRate int `json:"droprate"`
 }
+
+type BanRequest struct {
+	Address  string `json:"address"`  // an address or subnet, eg 1.2.3.4 or 1.2.3.0/24
+	Duration int64  `json:"duration"` // seconds, 0 bans for good
+	Reason   string `json:"reason"`
+}
+
+type UnbanRequest struct {
+	Address string `json:"address"`
+}
+
+type PeerRequest struct {
+	Hash string `json:"hash"` // as listed by the peers method
+}
+
+type DialPeerRequest struct {
+	Address    string `json:"address"` // eg 1.2.3.4:8108
+	Persistent bool   `json:"persistent"`
+}
+
+type AdjustPeerQualityRequest struct {
+	Hash       string `json:"hash"`
+	Adjustment int32  `json:"adjustment"`
+}
+
+type SpecialPeerRequest struct {
+	Address string `json:"address"` // eg 1.2.3.4:8108, or nodekey@1.2.3.4:8108 to pin the node key
+}
@@ -97,3 +97,12 @@ func NewReceiptError() *primitives.JSONError {
 func NewRepeatCommitError(data interface{}) *primitives.JSONError {
 	return primitives.NewJSONError(-32011, "Repeated Commit", data)
 }
+func NewEntryPrunedError() *primitives.JSONError {
+	return primitives.NewJSONError(-32012, "Entry pruned", "This node does not keep the content of old entries")
+}
+func NewBalanceHistoryNotBuiltError() *primitives.JSONError {
+	return primitives.NewJSONError(-32013, "Balance history not built yet", "The balances at this height are still being rebuilt, try again later")
+}
+func NewAuthenticationRequiredError() *primitives.JSONError {
+	return primitives.NewJSONError(-32014, "Authentication required", "Set an RPC user and password to use this method")
+}
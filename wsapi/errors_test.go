@@ -133,6 +133,16 @@ func TestErrors(t *testing.T) {
 		t.Error("Code or message is wrong for NewReceiptError")
 	}
 
+	je = NewEntryPrunedError()
+	if je.Code != -32012 || je.Message != "Entry pruned" {
+		t.Error("Code or message is wrong for NewEntryPrunedError")
+	}
+
+	je = NewAuthenticationRequiredError()
+	if je.Code != -32014 || je.Message != "Authentication required" {
+		t.Error("Code or message is wrong for NewAuthenticationRequiredError")
+	}
+
 	fmt.Println(getResp(je))
 
 }
@@ -15,11 +15,26 @@ var (
 		Help: "Time it takes to compelete a call",
 	})
 
+	HandleV2APICallBatch = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_batch_ns",
+		Help: "Time it takes to compelete a batch",
+	})
+
 	HandleV2APICallChainHead = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "factomd_wsapi_v2_api_call_chainhead_ns",
 		Help: "Time it takes to compelete a chainhead",
 	})
 
+	HandleV2APICallChainEBlocks = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_chaineblocks_ns",
+		Help: "Time it takes to compelete a chaineblocks",
+	})
+
+	HandleV2APICallChainEntries = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_chainentries_ns",
+		Help: "Time it takes to compelete a chainentries",
+	})
+
 	HandleV2APICallCommitChain = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "factomd_wsapi_v2_api_call_commitchain_ns",
 		Help: "Time it takes to compelete a commithcain",
@@ -50,6 +65,21 @@ var (
 		Help: "Time it takes to compelete an entry",
 	})
 
+	HandleV2APICallEntryLocation = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_entrylocation_ns",
+		Help: "Time it takes to compelete an entrylocation",
+	})
+
+	HandleV2APICallAddressTransactions = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_addresstransactions_ns",
+		Help: "Time it takes to compelete an addresstransactions",
+	})
+
+	HandleV2APICallAnchors = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_anchors_ns",
+		Help: "Time it takes to compelete an anchors",
+	})
+
 	HandleV2APICallECBal = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "factomd_wsapi_v2_api_call_ecbal_ns",
 		Help: "Time it takes to compelete a ecbal",
@@ -164,6 +194,21 @@ var (
 		Name: "factomd_wsapi_v2_api_call_tpsrate_ns",
 		Help: "Time it takes to compelete a tpsrate",
 	})
+
+	SubscriptionClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "factomd_wsapi_v2_subscription_clients",
+		Help: "Number of clients connected to the subscription api",
+	})
+
+	SubscriptionNotificationsSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_wsapi_v2_subscription_notifications_sent_total",
+		Help: "Number of notifications queued for subscription clients",
+	})
+
+	SubscriptionNotificationsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_wsapi_v2_subscription_notifications_dropped_total",
+		Help: "Number of notifications dropped because a subscription client fell behind",
+	})
 )
 
 var registered = false
@@ -178,13 +223,19 @@ func RegisterPrometheus() {
 
 	prometheus.MustRegister(GensisFblockCall)
 	prometheus.MustRegister(HandleV2APICallGeneral)
+	prometheus.MustRegister(HandleV2APICallBatch)
 	prometheus.MustRegister(HandleV2APICallChainHead)
+	prometheus.MustRegister(HandleV2APICallChainEBlocks)
+	prometheus.MustRegister(HandleV2APICallChainEntries)
 	prometheus.MustRegister(HandleV2APICallCommitChain)
 	prometheus.MustRegister(HandleV2APICallCommitEntry)
 	prometheus.MustRegister(HandleV2APICallDBlock)
 	prometheus.MustRegister(HandleV2APICallDBlockHead)
 	prometheus.MustRegister(HandleV2APICallEblock)
 	prometheus.MustRegister(HandleV2APICallEntry)
+	prometheus.MustRegister(HandleV2APICallEntryLocation)
+	prometheus.MustRegister(HandleV2APICallAddressTransactions)
+	prometheus.MustRegister(HandleV2APICallAnchors)
 	prometheus.MustRegister(HandleV2APICallECBal)
 	prometheus.MustRegister(HandleV2APICallECRate)
 	prometheus.MustRegister(HandleV2APICallFABal)
@@ -207,4 +258,7 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(HandleV2APICallABlockByHeight)
 	prometheus.MustRegister(HandleV2APICallAuthorities)
 	prometheus.MustRegister(HandleV2APICallTpsRate)
+	prometheus.MustRegister(SubscriptionClients)
+	prometheus.MustRegister(SubscriptionNotificationsSent)
+	prometheus.MustRegister(SubscriptionNotificationsDropped)
 }
@@ -0,0 +1,387 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package wsapi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/web"
+	"golang.org/x/net/websocket"
+)
+
+// Topics a client can subscribe to over /v2/subscribe
+const (
+	TopicNewDBlocks = "new-dblocks"
+	TopicNewEntries = "new-entries"
+	TopicAcks       = "acks"
+	TopicMinute     = "minute"
+)
+
+// subscriberQueueSize is the number of notifications buffered per client. A client that
+// falls further behind than this will miss notifications rather than stall the node.
+const subscriberQueueSize = 1000
+
+type SubscribeRequest struct {
+	Topic   string `json:"topic"`
+	ChainID string `json:"chainid,omitempty"`
+}
+
+type UnsubscribeRequest struct {
+	Subscription int64 `json:"subscription"`
+}
+
+type SubscribeResponse struct {
+	Subscription int64  `json:"subscription"`
+	Topic        string `json:"topic"`
+}
+
+type UnsubscribeResponse struct {
+	Subscription int64 `json:"subscription"`
+	Success      bool  `json:"success"`
+}
+
+// SubscriptionNotification is sent to the client as the params of a "subscription" JSON-RPC notification
+type SubscriptionNotification struct {
+	Subscription int64       `json:"subscription"`
+	Topic        string      `json:"topic"`
+	Result       interface{} `json:"result"`
+}
+
+type NewDBlockNotification struct {
+	KeyMR     string `json:"keymr"`
+	Height    int64  `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type NewEntryNotification struct {
+	ChainID     string `json:"chainid"`
+	EntryHash   string `json:"entryhash"`
+	EBlockKeyMR string `json:"eblockkeymr"`
+	DBHeight    int64  `json:"dbheight"`
+}
+
+type AckNotification struct {
+	TxID     string `json:"txid"`
+	Type     string `json:"type"`
+	DBHeight int64  `json:"dbheight"`
+	Minute   int64  `json:"minute"`
+	Status   string `json:"status"`
+}
+
+type MinuteNotification struct {
+	LeaderHeight int64 `json:"leaderheight"`
+	Minute       int64 `json:"minute"`
+}
+
+type subscription struct {
+	ID      int64
+	Topic   string
+	ChainID string
+}
+
+// Subscriber is a single connected client and the subscriptions it holds
+type Subscriber struct {
+	State         interfaces.IState
+	Subscriptions map[int64]*subscription
+	Out           chan interface{}
+}
+
+// SubscriptionHub tracks the clients connected to /v2/subscribe and fans out the events
+// published by the state to them.
+type SubscriptionHub struct {
+	mutex       sync.Mutex
+	nextID      int64
+	subscribers map[*Subscriber]struct{}
+}
+
+var Subscriptions = NewSubscriptionHub()
+
+var _ interfaces.IEventSink = (*SubscriptionHub)(nil)
+
+func NewSubscriptionHub() *SubscriptionHub {
+	h := new(SubscriptionHub)
+	h.subscribers = make(map[*Subscriber]struct{})
+	return h
+}
+
+func (h *SubscriptionHub) AddSubscriber(state interfaces.IState) *Subscriber {
+	s := new(Subscriber)
+	s.State = state
+	s.Subscriptions = make(map[int64]*subscription)
+	s.Out = make(chan interface{}, subscriberQueueSize)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.subscribers[s] = struct{}{}
+	SubscriptionClients.Set(float64(len(h.subscribers)))
+	return s
+}
+
+func (h *SubscriptionHub) RemoveSubscriber(s *Subscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.subscribers, s)
+	SubscriptionClients.Set(float64(len(h.subscribers)))
+}
+
+func (h *SubscriptionHub) Subscribe(s *Subscriber, req *SubscribeRequest) (int64, error) {
+	switch req.Topic {
+	case TopicNewDBlocks, TopicAcks, TopicMinute:
+	case TopicNewEntries:
+		if req.ChainID != "" {
+			if _, err := primitives.HexToHash(req.ChainID); err != nil {
+				return 0, fmt.Errorf("Invalid chainid %s", req.ChainID)
+			}
+		}
+	default:
+		return 0, fmt.Errorf("Unknown topic %s", req.Topic)
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.nextID++
+	sub := new(subscription)
+	sub.ID = h.nextID
+	sub.Topic = req.Topic
+	sub.ChainID = strings.ToLower(req.ChainID)
+	s.Subscriptions[sub.ID] = sub
+	return sub.ID, nil
+}
+
+func (h *SubscriptionHub) Unsubscribe(s *Subscriber, id int64) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := s.Subscriptions[id]; !ok {
+		return false
+	}
+	delete(s.Subscriptions, id)
+	return true
+}
+
+// Publish sends the result to every subscription of the given topic held by clients of the
+// given state. Subscriptions that did not ask for a chainid receive every notification of the topic.
+func (h *SubscriptionHub) Publish(state interfaces.IState, topic string, chainid string, result interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for s := range h.subscribers {
+		if s.State != state {
+			continue
+		}
+		for _, sub := range s.Subscriptions {
+			if sub.Topic != topic {
+				continue
+			}
+			if sub.ChainID != "" && sub.ChainID != chainid {
+				continue
+			}
+
+			n := new(SubscriptionNotification)
+			n.Subscription = sub.ID
+			n.Topic = topic
+			n.Result = result
+			j := primitives.NewJSON2Request("subscription", nil, n)
+
+			select {
+			case s.Out <- j:
+				SubscriptionNotificationsSent.Inc()
+			default:
+				// The client is not keeping up, drop rather than block the state
+				SubscriptionNotificationsDropped.Inc()
+			}
+		}
+	}
+}
+
+// HasSubscribers lets the publishers skip building notifications nobody is listening for
+func (h *SubscriptionHub) HasSubscribers() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.subscribers) > 0
+}
+
+// NotifyNewDBlock is called by the state once a directory block and its entry blocks have been
+// written to the database.
+func (h *SubscriptionHub) NotifyNewDBlock(state interfaces.IState, dblock interfaces.IDirectoryBlock, eblocks []interfaces.IEntryBlock) {
+	if dblock == nil || !h.HasSubscribers() {
+		return
+	}
+
+	d := new(NewDBlockNotification)
+	d.KeyMR = dblock.GetKeyMR().String()
+	d.Height = int64(dblock.GetDatabaseHeight())
+	d.Timestamp = dblock.GetHeader().GetTimestamp().GetTimeSeconds()
+	h.Publish(state, TopicNewDBlocks, "", d)
+
+	for _, eb := range eblocks {
+		keymr, err := eb.KeyMR()
+		if err != nil {
+			continue
+		}
+		chainid := eb.GetChainID().String()
+		for _, e := range eb.GetEntryHashes() {
+			if e.IsMinuteMarker() {
+				continue
+			}
+			n := new(NewEntryNotification)
+			n.ChainID = chainid
+			n.EntryHash = e.String()
+			n.EBlockKeyMR = keymr.String()
+			n.DBHeight = d.Height
+			h.Publish(state, TopicNewEntries, chainid, n)
+		}
+	}
+}
+
+// NotifyAck is called by the process list when a transaction message has been acknowledged
+func (h *SubscriptionHub) NotifyAck(state interfaces.IState, ackMsg interfaces.IMsg, m interfaces.IMsg) {
+	ack, ok := ackMsg.(*messages.Ack)
+	if !ok || m == nil || !h.HasSubscribers() {
+		return
+	}
+
+	a := new(AckNotification)
+	switch msg := m.(type) {
+	case *messages.CommitChainMsg:
+		a.TxID = msg.CommitChain.GetSigHash().String()
+		a.Type = "commit-chain"
+	case *messages.CommitEntryMsg:
+		a.TxID = msg.CommitEntry.GetSigHash().String()
+		a.Type = "commit-entry"
+	case *messages.RevealEntryMsg:
+		a.TxID = msg.Entry.GetHash().String()
+		a.Type = "reveal-entry"
+	case *messages.FactoidTransaction:
+		a.TxID = msg.Transaction.GetSigHash().String()
+		a.Type = "factoid"
+	default:
+		return
+	}
+	a.DBHeight = int64(ack.DBHeight)
+	a.Minute = int64(ack.Minute)
+	a.Status = constants.AckStatusString(constants.AckStatusACK)
+	h.Publish(state, TopicAcks, "", a)
+}
+
+// NotifyMinute is called by the state when it moves to a new minute
+func (h *SubscriptionHub) NotifyMinute(state interfaces.IState, leaderHeight uint32, minute int) {
+	if !h.HasSubscribers() {
+		return
+	}
+
+	n := new(MinuteNotification)
+	n.LeaderHeight = int64(leaderHeight)
+	n.Minute = int64(minute)
+	h.Publish(state, TopicMinute, "", n)
+}
+
+// HandleV2Subscribe returns the websocket handler for /v2/subscribe. Clients send JSON-RPC 2.0
+// "subscribe" and "unsubscribe" requests and receive "subscription" notifications.
+func HandleV2Subscribe(server *web.Server) websocket.Handler {
+	return func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		ServersMutex.Lock()
+		state := server.Env["state"].(interfaces.IState)
+		ServersMutex.Unlock()
+
+		if err := checkAuthHeader(state, ws.Request()); err != nil {
+			remoteIP := ""
+			remoteIP += strings.Split(ws.Request().RemoteAddr, ":")[0]
+			fmt.Printf("Unauthorized V2 API subscription attempt from %s\n", remoteIP)
+			return
+		}
+
+		s := Subscriptions.AddSubscriber(state)
+		defer Subscriptions.RemoveSubscriber(s)
+
+		done := make(chan struct{})
+		defer close(done)
+
+		// Writer, so notifications and responses never interleave on the connection
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case j := <-s.Out:
+					if err := websocket.JSON.Send(ws, j); err != nil {
+						ws.Close()
+						return
+					}
+				}
+			}
+		}()
+
+		for {
+			j := new(primitives.JSON2Request)
+			if err := websocket.JSON.Receive(ws, j); err != nil {
+				return
+			}
+
+			jsonResp, jsonError := HandleV2SubscribeRequest(s, j)
+			if jsonError != nil {
+				jsonResp = primitives.NewJSON2Response()
+				jsonResp.ID = j.ID
+				jsonResp.Error = jsonError
+			}
+
+			// Responses share the queue with notifications so they are sent in order
+			select {
+			case s.Out <- jsonResp:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func HandleV2SubscribeRequest(s *Subscriber, j *primitives.JSON2Request) (*primitives.JSON2Response, *primitives.JSONError) {
+	var resp interface{}
+	var jsonError *primitives.JSONError
+	params := j.Params
+	switch j.Method {
+	case "subscribe":
+		req := new(SubscribeRequest)
+		err := MapToObject(params, req)
+		if err != nil {
+			return nil, NewInvalidParamsError()
+		}
+		id, err := Subscriptions.Subscribe(s, req)
+		if err != nil {
+			return nil, NewCustomInvalidParamsError(err.Error())
+		}
+		r := new(SubscribeResponse)
+		r.Subscription = id
+		r.Topic = req.Topic
+		resp = r
+	case "unsubscribe":
+		req := new(UnsubscribeRequest)
+		err := MapToObject(params, req)
+		if err != nil {
+			return nil, NewInvalidParamsError()
+		}
+		r := new(UnsubscribeResponse)
+		r.Subscription = req.Subscription
+		r.Success = Subscriptions.Unsubscribe(s, req.Subscription)
+		resp = r
+	default:
+		jsonError = NewMethodNotFoundError()
+	}
+	if jsonError != nil {
+		return nil, jsonError
+	}
+
+	jsonResp := primitives.NewJSON2Response()
+	jsonResp.ID = j.ID
+	jsonResp.Result = resp
+	return jsonResp, nil
+}
@@ -0,0 +1,74 @@
+package wsapi_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/testHelper"
+	. "github.com/FactomProject/factomd/wsapi"
+)
+
+func TestSubscriptionHub(t *testing.T) {
+	state := testHelper.CreateEmptyTestState()
+	other := testHelper.CreateEmptyTestState()
+	hub := NewSubscriptionHub()
+
+	if hub.HasSubscribers() {
+		t.Errorf("Empty hub has subscribers")
+	}
+
+	s := hub.AddSubscriber(state)
+	if !hub.HasSubscribers() {
+		t.Errorf("Hub has no subscribers")
+	}
+
+	if _, err := hub.Subscribe(s, &SubscribeRequest{Topic: "not-a-topic"}); err == nil {
+		t.Errorf("Subscribed to an unknown topic")
+	}
+	if _, err := hub.Subscribe(s, &SubscribeRequest{Topic: TopicNewEntries, ChainID: "zz"}); err == nil {
+		t.Errorf("Subscribed with an invalid chainid")
+	}
+
+	chainid := "888888f0b7e308974afc34b2c7f703f25ed2699cb05f818e84e8745644896c55"
+	dblocks, err := hub.Subscribe(s, &SubscribeRequest{Topic: TopicNewDBlocks})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	entries, err := hub.Subscribe(s, &SubscribeRequest{Topic: TopicNewEntries, ChainID: chainid})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	hub.Publish(state, TopicNewDBlocks, "", "dblock")
+	hub.Publish(state, TopicNewEntries, chainid, "entry")
+	hub.Publish(state, TopicNewEntries, "0000000000000000000000000000000000000000000000000000000000000000", "other entry")
+	hub.Publish(other, TopicNewDBlocks, "", "other node")
+	hub.Publish(state, TopicMinute, "", "minute")
+
+	if len(s.Out) != 2 {
+		t.Fatalf("Expected 2 notifications, found %d", len(s.Out))
+	}
+	for _, id := range []int64{dblocks, entries} {
+		j := (<-s.Out).(*primitives.JSON2Request)
+		n := j.Params.(*SubscriptionNotification)
+		if n.Subscription != id {
+			t.Errorf("Expected subscription %d, found %d", id, n.Subscription)
+		}
+	}
+
+	if !hub.Unsubscribe(s, dblocks) {
+		t.Errorf("Failed to unsubscribe")
+	}
+	if hub.Unsubscribe(s, dblocks) {
+		t.Errorf("Unsubscribed twice")
+	}
+	hub.Publish(state, TopicNewDBlocks, "", "dblock")
+	if len(s.Out) != 0 {
+		t.Errorf("Received a notification after unsubscribing")
+	}
+
+	hub.RemoveSubscriber(s)
+	if hub.HasSubscribers() {
+		t.Errorf("Hub still has subscribers")
+	}
+}
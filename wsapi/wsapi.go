@@ -36,6 +36,7 @@ var ServersMutex sync.Mutex
 
 func Start(state interfaces.IState) {
 	RegisterPrometheus()
+	state.SetEventSink(Subscriptions)
 	var server *web.Server
 
 	ServersMutex.Lock()
@@ -85,6 +86,7 @@ func Start(state interfaces.IState) {
 
 		server.Post("/v2", HandleV2)
 		server.Get("/v2", HandleV2)
+		server.Websocket("/v2/subscribe", HandleV2Subscribe(server))
 
 		// start the debugging api if we are not on the main network
 		if state.GetNetworkName() != "MAIN" {
@@ -119,6 +121,7 @@ func Start(state interfaces.IState) {
 }
 
 func SetState(state interfaces.IState) {
+	state.SetEventSink(Subscriptions)
 	wait := func() {
 		ServersMutex.Lock()
 		defer ServersMutex.Unlock()
@@ -96,6 +96,79 @@ type EntryBlockResponse struct {
 	EntryList []EntryAddr `json:"entrylist"`
 }
 
+type ChainEBlock struct {
+	KeyMR string `json:"keymr"`
+	*EntryBlockResponse
+}
+
+type ChainEBlocksResponse struct {
+	EntryBlocks []ChainEBlock `json:"entryblocks"`
+	NextCursor  string        `json:"nextcursor,omitempty"`
+}
+
+type ChainEntry struct {
+	EntryHash   string   `json:"entryhash"`
+	EBlockKeyMR string   `json:"eblockkeymr"`
+	DBHeight    int64    `json:"dbheight"`
+	Timestamp   int64    `json:"timestamp"`
+	Content     string   `json:"content"`
+	ExtIDs      []string `json:"extids"`
+}
+
+type ChainEntriesResponse struct {
+	Entries    []ChainEntry `json:"entries"`
+	NextCursor string       `json:"nextcursor,omitempty"`
+}
+
+type AddressTransaction struct {
+	TxID     string `json:"txid"`
+	Type     string `json:"type"`
+	DBHeight int64  `json:"dbheight"`
+}
+
+type AddressTransactionsResponse struct {
+	Transactions []AddressTransaction `json:"transactions"`
+	NextCursor   string               `json:"nextcursor,omitempty"`
+}
+
+type AnchorsResponse struct {
+	DBHeight int64           `json:"dbheight"`
+	KeyMR    string          `json:"keymr"`
+	Anchors  []AnchorInfo    `json:"anchors"`
+	Jobs     []AnchorJobInfo `json:"jobs,omitempty"`
+}
+
+// AnchorInfo is an anchor record found in the anchor chain for the directory block
+type AnchorInfo struct {
+	EntryHash    string         `json:"entryhash"`
+	Version      int            `json:"version"`
+	Valid        bool           `json:"valid"`
+	KeyMRMatches bool           `json:"keymrmatches"`
+	RecordHeight int64          `json:"recordheight"`
+	Bitcoin      *AnchorPayload `json:"bitcoin,omitempty"`
+	Ethereum     *AnchorPayload `json:"ethereum,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// AnchorPayload is where the anchor ended up in the target chain
+type AnchorPayload struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	BlockHeight int64  `json:"blockheight"`
+	BlockHash   string `json:"blockhash"`
+	Offset      int64  `json:"offset"`
+}
+
+// AnchorJobInfo is the progress of anchoring the directory block by this node
+type AnchorJobInfo struct {
+	Chain         string `json:"chain"`
+	Status        string `json:"status"`
+	Attempts      int64  `json:"attempts"`
+	TxID          string `json:"txid,omitempty"`
+	Confirmations int64  `json:"confirmations"`
+	LastError     string `json:"lasterror,omitempty"`
+}
+
 type EntryCreditBlockResponse struct {
 	ECBlock struct {
 		Header     interfaces.IECBlockHeader `json:"header"`
@@ -107,9 +180,20 @@ type EntryCreditBlockResponse struct {
 }
 
 type EntryResponse struct {
-	ChainID string   `json:"chainid"`
-	Content string   `json:"content"`
-	ExtIDs  []string `json:"extids"`
+	ChainID  string                 `json:"chainid"`
+	Content  string                 `json:"content"`
+	ExtIDs   []string               `json:"extids"`
+	Location *EntryLocationResponse `json:"location,omitempty"`
+}
+
+type EntryLocationResponse struct {
+	EntryHash            string `json:"entryhash"`
+	ChainID              string `json:"chainid"`
+	EntryBlockKeyMR      string `json:"entryblockkeymr"`
+	DirectoryBlockKeyMR  string `json:"directoryblockkeymr"`
+	DirectoryBlockHeight int64  `json:"directoryblockheight"`
+	Minute               int64  `json:"minute"`
+	Timestamp            int64  `json:"timestamp"`
 }
 
 type ChainHeadResponse struct {
@@ -237,6 +321,11 @@ type AddressRequest struct {
 	Address string `json:"address"`
 }
 
+type BalanceRequest struct {
+	Address string `json:"address"`
+	Height  *int64 `json:"height,omitempty"`
+}
+
 type HeightRequest struct {
 	Height int64 `json:"height"`
 }
@@ -245,6 +334,26 @@ type ChainIDRequest struct {
 	ChainID string `json:"chainid"`
 }
 
+type AnchorsRequest struct {
+	Height *int64 `json:"height,omitempty"`
+	KeyMR  string `json:"keymr,omitempty"`
+}
+
+type AddressTransactionsRequest struct {
+	Address     string `json:"address"`
+	StartHeight int64  `json:"startheight"`
+	Cursor      string `json:"cursor,omitempty"`
+	Limit       int64  `json:"limit,omitempty"`
+}
+
+type ChainRangeRequest struct {
+	ChainID     string `json:"chainid"`
+	StartHeight int64  `json:"startheight"`
+	EndHeight   *int64 `json:"endheight,omitempty"`
+	Cursor      string `json:"cursor,omitempty"`
+	Limit       int64  `json:"limit,omitempty"`
+}
+
 type EntryRequest struct {
 	Entry string `json:"entry"`
 }
@@ -253,6 +362,11 @@ type HashRequest struct {
 	Hash string `json:"hash"`
 }
 
+type EntryHashRequest struct {
+	Hash            string `json:"hash"`
+	IncludeLocation bool   `json:"includelocation,omitempty"`
+}
+
 type KeyMRRequest struct {
 	KeyMR string `json:"keymr"`
 }
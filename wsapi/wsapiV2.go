@@ -5,15 +5,18 @@
 package wsapi
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/FactomProject/factomd/anchor"
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/entryBlock"
 	"github.com/FactomProject/factomd/common/entryCreditBlock"
@@ -21,6 +24,7 @@ import (
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
 	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
 	"github.com/FactomProject/factomd/receipts"
 	"github.com/FactomProject/web"
 )
@@ -50,6 +54,24 @@ func HandleV2(ctx *web.Context) {
 		return
 	}
 
+	if isBatchRequest(body) {
+		batchResp, jsonError := HandleV2BatchRequest(state, body)
+		if jsonError != nil {
+			HandleV2Error(ctx, nil, jsonError)
+			return
+		}
+		if len(batchResp) == 0 {
+			return // a batch of notifications is answered with nothing at all
+		}
+		b, err := json.Marshal(batchResp)
+		if err != nil {
+			HandleV2Error(ctx, nil, NewInternalError())
+			return
+		}
+		ctx.Write(b)
+		return
+	}
+
 	j, err := primitives.ParseJSON2Request(string(body))
 	if err != nil {
 		HandleV2Error(ctx, nil, NewInvalidRequestError())
@@ -66,6 +88,67 @@ func HandleV2(ctx *web.Context) {
 	ctx.Write([]byte(jsonResp.String()))
 }
 
+// MaxBatchRequestSize is the largest number of calls accepted in a single JSON-RPC batch
+const MaxBatchRequestSize = 1000
+
+// isBatchRequest reports whether the body is a JSON-RPC batch, i.e. a JSON array
+func isBatchRequest(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// isNotification reports whether a call of a batch is a JSON-RPC notification, one without an id
+func isNotification(raw json.RawMessage) bool {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return false
+	}
+	_, hasID := members["id"]
+	return !hasID
+}
+
+// HandleV2BatchRequest dispatches every element of a JSON-RPC 2.0 batch through HandleV2Request.
+// An error in one element is returned in that element's response and does not fail the batch.
+// Notifications are carried out but get no response, so a batch of them returns none.
+func HandleV2BatchRequest(state interfaces.IState, body []byte) ([]*primitives.JSON2Response, *primitives.JSONError) {
+	n := time.Now()
+	defer func() {
+		HandleV2APICallBatch.Observe(float64(time.Since(n).Nanoseconds()))
+	}()
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, NewParseError()
+	}
+	if len(batch) == 0 || len(batch) > MaxBatchRequestSize {
+		return nil, NewInvalidRequestError()
+	}
+
+	responses := make([]*primitives.JSON2Response, 0, len(batch))
+	for _, raw := range batch {
+		j, err := primitives.ParseJSON2Request(string(raw))
+		if err != nil {
+			resp := primitives.NewJSON2Response()
+			resp.Error = NewInvalidRequestError()
+			responses = append(responses, resp)
+			continue
+		}
+
+		resp, jsonError := HandleV2Request(state, j)
+		if isNotification(raw) {
+			continue
+		}
+		if jsonError != nil {
+			resp = primitives.NewJSON2Response()
+			resp.ID = j.ID
+			resp.Error = jsonError
+		}
+		responses = append(responses, resp)
+	}
+
+	return responses, nil
+}
+
 func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*primitives.JSON2Response, *primitives.JSONError) {
 	var resp interface{}
 	var jsonError *primitives.JSONError
@@ -74,6 +157,12 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	case "chain-head":
 		resp, jsonError = HandleV2ChainHead(state, params)
 		break
+	case "chain-eblocks":
+		resp, jsonError = HandleV2ChainEBlocks(state, params)
+		break
+	case "chain-entries":
+		resp, jsonError = HandleV2ChainEntries(state, params)
+		break
 	case "commit-chain":
 		resp, jsonError = HandleV2CommitChain(state, params)
 		break
@@ -92,6 +181,12 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	case "entry-block":
 		resp, jsonError = HandleV2EntryBlock(state, params)
 		break
+	case "address-transactions":
+		resp, jsonError = HandleV2AddressTransactions(state, params)
+		break
+	case "anchors":
+		resp, jsonError = HandleV2Anchors(state, params)
+		break
 	case "admin-block":
 		resp, jsonError = HandleV2AdminBlock(state, params)
 		break
@@ -104,6 +199,9 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	case "entry":
 		resp, jsonError = HandleV2Entry(state, params)
 		break
+	case "entry-location":
+		resp, jsonError = HandleV2EntryLocation(state, params)
+		break
 	case "entry-credit-balance":
 		resp, jsonError = HandleV2EntryCreditBalance(state, params)
 		break
@@ -170,6 +268,15 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 		resp, jsonError = HandleV2TransactionRate(state, params)
 	case "ack":
 		resp, jsonError = HandleV2ACKWithChain(state, params)
+	case "bans":
+		resp, jsonError = HandleBans(state, params)
+	case "unban":
+		// Only behind a password, so the banned peers can not lift their own bans
+		if state.GetRpcUser() == "" {
+			jsonError = NewAuthenticationRequiredError()
+			break
+		}
+		resp, jsonError = HandleUnban(state, params)
 	default:
 		jsonError = NewMethodNotFoundError()
 		break
@@ -185,6 +292,16 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	return jsonResp, nil
 }
 
+// getDBSnapshot returns a read only view of the database for handlers that make several reads, so
+// they all see the same height even if a block set is saved in between. The view has to be closed.
+func getDBSnapshot(state interfaces.IState) (interfaces.DBOverlaySimple, *primitives.JSONError) {
+	dbase, err := state.GetDBSnapshot()
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	return dbase, nil
+}
+
 func HandleV2DBlockByHeight(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallDBlockByHeight.Observe(float64(time.Since(n).Nanoseconds()))
@@ -726,6 +843,7 @@ func HandleV2RawData(state interfaces.IState, params interface{}) (interface{},
 	return d, nil
 }
 
+// HandleV2Receipt returns a receipt for an entry hash, a factoid transaction id or an entry credit commit id
 func HandleV2Receipt(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallReceipt.Observe(float64(time.Since(n).Nanoseconds()))
@@ -741,8 +859,11 @@ func HandleV2Receipt(state interfaces.IState, params interface{}) (interface{},
 		return nil, NewInvalidHashError()
 	}
 
-	dbase := state.GetAndLockDB()
-	defer state.UnlockDB()
+	dbase, jErr := getDBSnapshot(state)
+	if jErr != nil {
+		return nil, jErr
+	}
+	defer dbase.Close()
 
 	receipt, err := receipts.CreateFullReceipt(dbase, h)
 	if err != nil {
@@ -803,7 +924,6 @@ func HandleV2EntryBlock(state interfaces.IState, params interface{}) (interface{
 	if err != nil {
 		return nil, NewInvalidParamsError()
 	}
-	e := new(EntryBlockResponse)
 
 	h, err := primitives.HexToHash(keymr.KeyMR)
 	if err != nil {
@@ -827,12 +947,18 @@ func HandleV2EntryBlock(state interfaces.IState, params interface{}) (interface{
 		}
 	}
 
+	return eBlockToResp(dbase, block), nil
+}
+
+func eBlockToResp(dbase interfaces.DBOverlaySimple, block interfaces.IEntryBlock) *EntryBlockResponse {
+	e := new(EntryBlockResponse)
+
 	e.Header.BlockSequenceNumber = int64(block.GetHeader().GetEBSequence())
 	e.Header.ChainID = block.GetHeader().GetChainID().String()
 	e.Header.PrevKeyMR = block.GetHeader().GetPrevKeyMR().String()
 	e.Header.DBHeight = int64(block.GetHeader().GetDBHeight())
 
-	if dblock, err := dbase.FetchDBlockByHeight(block.GetHeader().GetDBHeight()); err == nil {
+	if dblock, err := dbase.FetchDBlockByHeight(block.GetHeader().GetDBHeight()); err == nil && dblock != nil {
 		e.Header.Timestamp = dblock.GetHeader().GetTimestamp().GetTimeSeconds()
 	}
 
@@ -863,14 +989,14 @@ func HandleV2EntryBlock(state interfaces.IState, params interface{}) (interface{
 		}
 	}
 
-	return e, nil
+	return e
 }
 
 func HandleV2Entry(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallEntry.Observe(float64(time.Since(n).Nanoseconds()))
 
-	hashkey := new(HashRequest)
+	hashkey := new(EntryHashRequest)
 	err := MapToObject(params, hashkey)
 	if err != nil {
 		return nil, NewInvalidParamsError()
@@ -895,6 +1021,13 @@ func HandleV2Entry(state interfaces.IState, params interface{}) (interface{}, *p
 			return nil, NewInvalidHashError()
 		}
 		if entry == nil {
+			pruned, err := dbase.IsEntryPruned(h)
+			if err != nil {
+				return nil, NewInternalDatabaseError()
+			}
+			if pruned {
+				return nil, NewEntryPrunedError()
+			}
 			return nil, NewEntryNotFoundError()
 		}
 	}
@@ -905,9 +1038,108 @@ func HandleV2Entry(state interfaces.IState, params interface{}) (interface{}, *p
 		e.ExtIDs = append(e.ExtIDs, hex.EncodeToString(v))
 	}
 
+	if hashkey.IncludeLocation {
+		dbase, jErr := getDBSnapshot(state)
+		if jErr != nil {
+			return nil, jErr
+		}
+		defer dbase.Close()
+
+		// An entry that is only in the process list, or whose blocks are not all saved yet, has
+		// no location yet
+		loc, jErr := entryLocation(dbase, h)
+		if jErr != nil && jErr.Code != NewEntryNotFoundError().Code && jErr.Code != NewBlockNotFoundError().Code {
+			return nil, jErr
+		}
+		e.Location = loc
+	}
+
 	return e, nil
 }
 
+func HandleV2EntryLocation(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallEntryLocation.Observe(float64(time.Since(n).Nanoseconds()))
+
+	hashkey := new(HashRequest)
+	err := MapToObject(params, hashkey)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+
+	h, err := primitives.HexToHash(hashkey.Hash)
+	if err != nil {
+		return nil, NewInvalidHashError()
+	}
+
+	dbase, jErr := getDBSnapshot(state)
+	if jErr != nil {
+		return nil, jErr
+	}
+	defer dbase.Close()
+
+	return entryLocation(dbase, h)
+}
+
+// entryLocation finds the entry block and directory block an entry was saved in, and the
+// minute of the block it was written in.
+func entryLocation(dbase interfaces.DBOverlaySimple, h interfaces.IHash) (*EntryLocationResponse, *primitives.JSONError) {
+	ebKeyMR, err := dbase.FetchIncludedIn(h)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	if ebKeyMR == nil {
+		return nil, NewEntryNotFoundError()
+	}
+
+	eblock, err := dbase.FetchEBlock(ebKeyMR)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	if eblock == nil {
+		// Included in a block that is not an entry block, so it is not an entry
+		return nil, NewEntryNotFoundError()
+	}
+
+	dbKeyMR, err := dbase.FetchIncludedIn(ebKeyMR)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	if dbKeyMR == nil {
+		return nil, NewBlockNotFoundError()
+	}
+	dblock, err := dbase.FetchDBlock(dbKeyMR)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	if dblock == nil {
+		return nil, NewBlockNotFoundError()
+	}
+
+	loc := new(EntryLocationResponse)
+	loc.EntryHash = h.String()
+	loc.ChainID = eblock.GetChainID().String()
+	loc.EntryBlockKeyMR = ebKeyMR.String()
+	loc.DirectoryBlockKeyMR = dbKeyMR.String()
+	loc.DirectoryBlockHeight = int64(dblock.GetDatabaseHeight())
+
+	// The entry belongs to the minute of the first minute marker that follows it
+	found := false
+	for _, v := range eblock.GetBody().GetEBEntries() {
+		if v.IsSameAs(h) {
+			found = true
+			continue
+		}
+		if found && v.IsMinuteMarker() {
+			loc.Minute = int64(v.Bytes()[len(v.Bytes())-1])
+			break
+		}
+	}
+	loc.Timestamp = dblock.GetHeader().GetTimestamp().GetTimeSeconds() + 60*loc.Minute
+
+	return loc, nil
+}
+
 func HandleV2ChainHead(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallChainHead.Observe(float64(time.Since(n).Nanoseconds()))
@@ -975,7 +1207,7 @@ func HandleV2EntryCreditBalance(state interfaces.IState, params interface{}) (in
 	n := time.Now()
 	defer HandleV2APICallECBal.Observe(float64(time.Since(n).Nanoseconds()))
 
-	ecadr := new(AddressRequest)
+	ecadr := new(BalanceRequest)
 	err := MapToObject(params, ecadr)
 	if err != nil {
 		return nil, NewInvalidParamsError()
@@ -1004,7 +1236,25 @@ func HandleV2EntryCreditBalance(state interfaces.IState, params interface{}) (in
 		return nil, NewInvalidAddressError()
 	}
 	resp := new(EntryCreditBalanceResponse)
-	resp.Balance = state.GetFactoidState().GetECBalance(address.Fixed())
+	if ecadr.Height == nil {
+		resp.Balance = state.GetFactoidState().GetECBalance(address.Fixed())
+		return resp, nil
+	}
+
+	height, jErr := balanceHeight(state, *ecadr.Height)
+	if jErr != nil {
+		return nil, jErr
+	}
+	dbase := state.GetAndLockDB()
+	defer state.UnlockDB()
+
+	resp.Balance, err = dbase.FetchECBalanceAtHeight(address.Fixed(), height)
+	if err == databaseOverlay.ErrBalancesNotBuilt {
+		return nil, NewBalanceHistoryNotBuiltError()
+	}
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
 	return resp, nil
 }
 
@@ -1055,7 +1305,7 @@ func HandleV2FactoidBalance(state interfaces.IState, params interface{}) (interf
 	n := time.Now()
 	defer HandleV2APICallFABal.Observe(float64(time.Since(n).Nanoseconds()))
 
-	fadr := new(AddressRequest)
+	fadr := new(BalanceRequest)
 	err := MapToObject(params, fadr)
 	if err != nil {
 		return nil, NewInvalidParamsError()
@@ -1080,10 +1330,40 @@ func HandleV2FactoidBalance(state interfaces.IState, params interface{}) (interf
 	}
 
 	resp := new(FactoidBalanceResponse)
-	resp.Balance = state.GetFactoidState().GetFactoidBalance(factoid.NewAddress(adr).Fixed())
+	if fadr.Height == nil {
+		resp.Balance = state.GetFactoidState().GetFactoidBalance(factoid.NewAddress(adr).Fixed())
+		return resp, nil
+	}
+
+	height, jErr := balanceHeight(state, *fadr.Height)
+	if jErr != nil {
+		return nil, jErr
+	}
+	dbase := state.GetAndLockDB()
+	defer state.UnlockDB()
+
+	resp.Balance, err = dbase.FetchFactoidBalanceAtHeight(factoid.NewAddress(adr).Fixed(), height)
+	if err == databaseOverlay.ErrBalancesNotBuilt {
+		return nil, NewBalanceHistoryNotBuiltError()
+	}
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
 	return resp, nil
 }
 
+// balanceHeight checks the height of a historical balance request, which has to be a block
+// this node has saved
+func balanceHeight(state interfaces.IState, height int64) (uint32, *primitives.JSONError) {
+	if height < 0 {
+		return 0, NewCustomInvalidParamsError("height must not be negative")
+	}
+	if height > int64(state.GetHighestSavedBlk()) {
+		return 0, NewCustomInvalidParamsError("height is above the highest saved block")
+	}
+	return uint32(height), nil
+}
+
 func HandleV2Heights(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallHeights.Observe(float64(time.Since(n).Nanoseconds()))
@@ -1200,8 +1480,11 @@ func HandleV2GetTranasction(state interfaces.IState, params interface{}) (interf
 		return nil, NewInternalError()
 	}
 
-	dbase := state.GetAndLockDB()
-	defer state.UnlockDB()
+	dbase, jErr := getDBSnapshot(state)
+	if jErr != nil {
+		return nil, jErr
+	}
+	defer dbase.Close()
 
 	if fTx == nil {
 		fTx, err = dbase.FetchFactoidTransaction(h)
@@ -1275,3 +1558,346 @@ func HandleV2TransactionRate(state interfaces.IState, params interface{}) (inter
 	r.InstantTransactionRate = instant
 	return r, nil
 }
+
+// MaxChainRangeLimit is the most entry blocks or entries returned by a single chain range call
+const MaxChainRangeLimit = 100
+
+// parseChainRangeRequest validates a chain range request and returns the chain, the height and
+// entry index to resume from, the last height to include and the page size.
+func parseChainRangeRequest(params interface{}) (interfaces.IHash, uint32, int, uint32, int, *primitives.JSONError) {
+	r := new(ChainRangeRequest)
+	err := MapToObject(params, r)
+	if err != nil {
+		return nil, 0, 0, 0, 0, NewInvalidParamsError()
+	}
+
+	chainID, err := primitives.HexToHash(r.ChainID)
+	if err != nil {
+		return nil, 0, 0, 0, 0, NewInvalidHashError()
+	}
+
+	if r.StartHeight < 0 {
+		return nil, 0, 0, 0, 0, NewCustomInvalidParamsError("startheight must not be negative")
+	}
+	start := uint32(r.StartHeight)
+	index := 0
+	if r.Cursor != "" {
+		var h int64
+		if _, err := fmt.Sscanf(r.Cursor, "%d:%d", &h, &index); err != nil || h < 0 || index < 0 {
+			return nil, 0, 0, 0, 0, NewCustomInvalidParamsError("Invalid cursor")
+		}
+		start = uint32(h)
+	}
+
+	end := uint32(math.MaxUint32)
+	if r.EndHeight != nil {
+		if *r.EndHeight < 0 {
+			return nil, 0, 0, 0, 0, NewCustomInvalidParamsError("endheight must not be negative")
+		}
+		end = uint32(*r.EndHeight)
+	}
+
+	limit := int(r.Limit)
+	if limit <= 0 || limit > MaxChainRangeLimit {
+		limit = MaxChainRangeLimit
+	}
+
+	return chainID, start, index, end, limit, nil
+}
+
+func chainRangeCursor(height uint32, index int) string {
+	return fmt.Sprintf("%d:%d", height, index)
+}
+
+// HandleV2ChainEBlocks returns the entry blocks of a chain in ascending height order, starting at
+// startheight (or the cursor of a previous call) and ending at endheight.
+func HandleV2ChainEBlocks(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallChainEBlocks.Observe(float64(time.Since(n).Nanoseconds()))
+
+	chainID, start, _, end, limit, jErr := parseChainRangeRequest(params)
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	dbase, jErr := getDBSnapshot(state)
+	if jErr != nil {
+		return nil, jErr
+	}
+	defer dbase.Close()
+
+	// Ask for one more than the page so we know if there is another page
+	blocks, err := dbase.FetchEBlocksByChainInHeightRange(chainID, start, end, limit+1)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+
+	resp := new(ChainEBlocksResponse)
+	resp.EntryBlocks = make([]ChainEBlock, 0, len(blocks))
+	for i, block := range blocks {
+		if i == limit {
+			resp.NextCursor = chainRangeCursor(block.GetDatabaseHeight(), 0)
+			break
+		}
+		keymr, err := block.KeyMR()
+		if err != nil {
+			return nil, NewInternalError()
+		}
+		resp.EntryBlocks = append(resp.EntryBlocks, ChainEBlock{KeyMR: keymr.String(), EntryBlockResponse: eBlockToResp(dbase, block)})
+	}
+
+	return resp, nil
+}
+
+// HandleV2ChainEntries returns the entries of a chain in the order they were written, starting at
+// startheight (or the cursor of a previous call) and ending at endheight.
+func HandleV2ChainEntries(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallChainEntries.Observe(float64(time.Since(n).Nanoseconds()))
+
+	chainID, start, index, end, limit, jErr := parseChainRangeRequest(params)
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	dbase, jErr := getDBSnapshot(state)
+	if jErr != nil {
+		return nil, jErr
+	}
+	defer dbase.Close()
+
+	// Every entry block holds at least one entry, so limit+1 blocks always fill the page
+	blocks, err := dbase.FetchEBlocksByChainInHeightRange(chainID, start, end, limit+1)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+
+	resp := new(ChainEntriesResponse)
+	resp.Entries = make([]ChainEntry, 0, limit)
+	for _, block := range blocks {
+		keymr, err := block.KeyMR()
+		if err != nil {
+			return nil, NewInternalError()
+		}
+		eb := eBlockToResp(dbase, block)
+
+		first := 0
+		if block.GetDatabaseHeight() == start {
+			first = index
+		}
+		for i := first; i < len(eb.EntryList); i++ {
+			if len(resp.Entries) == limit {
+				resp.NextCursor = chainRangeCursor(block.GetDatabaseHeight(), i)
+				return resp, nil
+			}
+
+			c := ChainEntry{}
+			c.EntryHash = eb.EntryList[i].EntryHash
+			c.EBlockKeyMR = keymr.String()
+			c.DBHeight = eb.Header.DBHeight
+			c.Timestamp = eb.EntryList[i].Timestamp
+
+			h, err := primitives.HexToHash(c.EntryHash)
+			if err != nil {
+				return nil, NewInternalError()
+			}
+			entry, err := dbase.FetchEntry(h)
+			if err != nil {
+				return nil, NewInternalDatabaseError()
+			}
+			// A node that is still syncing entries may not have the content yet
+			if entry != nil {
+				c.Content = hex.EncodeToString(entry.GetContent())
+				for _, v := range entry.ExternalIDs() {
+					c.ExtIDs = append(c.ExtIDs, hex.EncodeToString(v))
+				}
+			}
+			resp.Entries = append(resp.Entries, c)
+		}
+	}
+
+	return resp, nil
+}
+
+// HandleV2AddressTransactions returns the factoid transactions and entry credit commits that involved
+// an address, oldest first. The address index has to be turned on in the node's configuration.
+func HandleV2AddressTransactions(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallAddressTransactions.Observe(float64(time.Since(n).Nanoseconds()))
+
+	r := new(AddressTransactionsRequest)
+	err := MapToObject(params, r)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+
+	var adr []byte
+	if primitives.ValidateFUserStr(r.Address) || primitives.ValidateECUserStr(r.Address) {
+		adr = primitives.ConvertUserStrToAddress(r.Address)
+	} else {
+		adr, err = hex.DecodeString(r.Address)
+		if err != nil {
+			return nil, NewInvalidAddressError()
+		}
+	}
+	if len(adr) != constants.HASH_LENGTH {
+		return nil, NewInvalidAddressError()
+	}
+
+	if r.StartHeight < 0 {
+		return nil, NewCustomInvalidParamsError("startheight must not be negative")
+	}
+	var cursor []byte
+	if r.Cursor != "" {
+		cursor, err = hex.DecodeString(r.Cursor)
+		if err != nil {
+			return nil, NewCustomInvalidParamsError("Invalid cursor")
+		}
+	}
+
+	limit := int(r.Limit)
+	if limit <= 0 || limit > MaxChainRangeLimit {
+		limit = MaxChainRangeLimit
+	}
+
+	dbase := state.GetAndLockDB()
+	defer state.UnlockDB()
+
+	if !dbase.GetAddressIndex() {
+		return nil, NewCustomInternalError("The address index is not enabled on this node")
+	}
+
+	txs, next, err := dbase.FetchAddressTransactions(adr, uint32(r.StartHeight), cursor, limit)
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+
+	resp := new(AddressTransactionsResponse)
+	resp.Transactions = make([]AddressTransaction, 0, len(txs))
+	for _, tx := range txs {
+		t := AddressTransaction{}
+		t.TxID = tx.TxID.String()
+		t.Type = "factoid"
+		if tx.EntryCredit {
+			t.Type = "entrycredit"
+		}
+		t.DBHeight = int64(tx.DBHeight)
+		resp.Transactions = append(resp.Transactions, t)
+	}
+	if next != nil {
+		resp.NextCursor = hex.EncodeToString(next)
+	}
+
+	return resp, nil
+}
+
+// HandleV2Anchors returns the anchor records in the anchor chain for a directory block, given by
+// height or KeyMR, whether they are signed by a known anchor key, and where they were anchored.
+// If this node anchors in process, the progress of its own anchors is included.
+func HandleV2Anchors(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallAnchors.Observe(float64(time.Since(n).Nanoseconds()))
+
+	r := new(AnchorsRequest)
+	err := MapToObject(params, r)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	if (r.Height == nil) == (r.KeyMR == "") {
+		return nil, NewCustomInvalidParamsError("Either height or keymr is required")
+	}
+
+	dbase := state.GetAndLockDB()
+	defer state.UnlockDB()
+
+	var dBlock interfaces.IDirectoryBlock
+	if r.Height != nil {
+		if *r.Height < 0 {
+			return nil, NewCustomInvalidParamsError("height must not be negative")
+		}
+		dBlock, err = dbase.FetchDBlockByHeight(uint32(*r.Height))
+	} else {
+		var h interfaces.IHash
+		h, err = primitives.HexToHash(r.KeyMR)
+		if err != nil {
+			return nil, NewInvalidHashError()
+		}
+		dBlock, err = dbase.FetchDBlock(h)
+	}
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	if dBlock == nil {
+		return nil, NewBlockNotFoundError()
+	}
+
+	resp := new(AnchorsResponse)
+	resp.DBHeight = int64(dBlock.GetDatabaseHeight())
+	resp.KeyMR = dBlock.GetKeyMR().String()
+
+	entries, err := dbase.FetchAnchorEntriesByDBHeight(dBlock.GetDatabaseHeight())
+	if err != nil {
+		return nil, NewInternalDatabaseError()
+	}
+	resp.Anchors = make([]AnchorInfo, 0, len(entries))
+	for _, e := range entries {
+		info := AnchorInfo{}
+		info.EntryHash = e.Entry.GetHash().String()
+		info.Valid = e.Valid
+		// Version 2 records carry their signature in the only external ID
+		info.Version = 1
+		if len(e.Entry.ExternalIDs()) == 1 {
+			info.Version = 2
+		}
+
+		ar, err := anchor.UnmarshalAnchorRecord(e.Entry.GetContent())
+		if err != nil {
+			info.Error = err.Error()
+			resp.Anchors = append(resp.Anchors, info)
+			continue
+		}
+		info.KeyMRMatches = ar.KeyMR == resp.KeyMR
+		info.RecordHeight = int64(ar.RecordHeight)
+		if ar.Bitcoin != nil {
+			info.Bitcoin = new(AnchorPayload)
+			info.Bitcoin.Address = ar.Bitcoin.Address
+			info.Bitcoin.TxID = ar.Bitcoin.TXID
+			info.Bitcoin.BlockHeight = int64(ar.Bitcoin.BlockHeight)
+			info.Bitcoin.BlockHash = ar.Bitcoin.BlockHash
+			info.Bitcoin.Offset = int64(ar.Bitcoin.Offset)
+		}
+		if ar.Ethereum != nil {
+			info.Ethereum = new(AnchorPayload)
+			info.Ethereum.Address = ar.Ethereum.Address
+			info.Ethereum.TxID = ar.Ethereum.TXID
+			info.Ethereum.BlockHeight = ar.Ethereum.BlockHeight
+			info.Ethereum.BlockHash = ar.Ethereum.BlockHash
+			info.Ethereum.Offset = ar.Ethereum.Offset
+		}
+		resp.Anchors = append(resp.Anchors, info)
+	}
+
+	if anchorer, ok := state.GetAnchor().(*anchor.Anchorer); ok {
+		for _, chain := range anchorer.Chains {
+			job, err := anchorer.GetJob(chain.Name(), dBlock.GetDatabaseHeight())
+			if err != nil {
+				return nil, NewInternalDatabaseError()
+			}
+			if job == nil {
+				continue
+			}
+			info := AnchorJobInfo{}
+			info.Chain = job.Chain
+			info.Status = job.StatusName()
+			info.Attempts = int64(job.Attempts)
+			if job.Transaction != nil {
+				info.TxID = job.Transaction.TXID
+				info.Confirmations = job.Transaction.Confirmations
+			}
+			info.LastError = job.LastError
+			resp.Jobs = append(resp.Jobs, info)
+		}
+	}
+
+	return resp, nil
+}
@@ -481,3 +481,186 @@ func Test_ecBlockToResp(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleV2BatchRequest(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+
+	batch := `[
+		{"jsonrpc": "2.0", "id": 1, "method": "properties"},
+		{"jsonrpc": "2.0", "id": 2, "method": "not-a-method"},
+		{"jsonrpc": "1.0", "id": 3, "method": "properties"},
+		{"jsonrpc": "2.0", "id": 4, "method": "entry-credit-rate"}
+	]`
+
+	resp, jErr := HandleV2BatchRequest(state, []byte(batch))
+	if jErr != nil {
+		t.Fatalf("%v", jErr)
+	}
+	if len(resp) != 4 {
+		t.Fatalf("Expected 4 responses, got %d", len(resp))
+	}
+	if resp[0].Error != nil || resp[0].Result == nil {
+		t.Errorf("Expected a result for the first call - %v", resp[0])
+	}
+	if resp[1].Error == nil || resp[1].Error.Code != NewMethodNotFoundError().Code {
+		t.Errorf("Expected a method not found error for the second call - %v", resp[1])
+	}
+	if resp[2].Error == nil || resp[2].Error.Code != NewInvalidRequestError().Code {
+		t.Errorf("Expected an invalid request error for the third call - %v", resp[2])
+	}
+	if resp[3].Error != nil || resp[3].ID != float64(4) {
+		t.Errorf("Expected a result with id 4 for the fourth call - %v", resp[3])
+	}
+
+	// Notifications, calls without an id, get no response
+	notifications := `[
+		{"jsonrpc": "2.0", "method": "properties"},
+		{"jsonrpc": "2.0", "id": null, "method": "properties"},
+		{"jsonrpc": "2.0", "method": "not-a-method"}
+	]`
+	resp, jErr = HandleV2BatchRequest(state, []byte(notifications))
+	if jErr != nil {
+		t.Fatalf("%v", jErr)
+	}
+	if len(resp) != 1 || resp[0].ID != nil || resp[0].Result == nil {
+		t.Errorf("Expected only the response to the call with a null id - %v", resp)
+	}
+
+	if _, jErr := HandleV2BatchRequest(state, []byte("[]")); jErr == nil {
+		t.Errorf("Empty batch was accepted")
+	}
+	if _, jErr := HandleV2BatchRequest(state, []byte("[{")); jErr == nil {
+		t.Errorf("Malformed batch was accepted")
+	}
+}
+
+func TestHandleV2EntryLocation(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+	blocks := testHelper.CreateFullTestBlockSet()
+
+	for _, block := range blocks {
+		for _, entry := range block.Entries {
+			hashkey := new(HashRequest)
+			hashkey.Hash = entry.GetHash().String()
+
+			resp, jErr := HandleV2EntryLocation(state, hashkey)
+			if jErr != nil {
+				t.Errorf("%v", jErr)
+				continue
+			}
+			loc := resp.(*EntryLocationResponse)
+			if loc.EntryBlockKeyMR != block.EBlock.DatabasePrimaryIndex().String() &&
+				loc.EntryBlockKeyMR != block.AnchorEBlock.DatabasePrimaryIndex().String() {
+				t.Errorf("Wrong entry block for entry %v", hashkey.Hash)
+			}
+			if loc.DirectoryBlockKeyMR != block.DBlock.GetKeyMR().String() {
+				t.Errorf("Wrong directory block for entry %v", hashkey.Hash)
+			}
+			if loc.DirectoryBlockHeight != int64(block.Height) {
+				t.Errorf("Wrong directory block height for entry %v - %v vs %v", hashkey.Hash, loc.DirectoryBlockHeight, block.Height)
+			}
+			if loc.Minute < 1 || loc.Minute > 10 {
+				t.Errorf("Invalid minute %v for entry %v", loc.Minute, hashkey.Hash)
+			}
+		}
+	}
+
+	hashkey := new(HashRequest)
+	hashkey.Hash = "0000000000000000000000000000000000000000000000000000000000000123"
+	if _, jErr := HandleV2EntryLocation(state, hashkey); jErr == nil {
+		t.Errorf("Found a location for an unknown entry")
+	}
+}
+
+func TestHandleV2EntryIncludeLocation(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+	blocks := testHelper.CreateFullTestBlockSet()
+
+	block := blocks[len(blocks)-1]
+	hashkey := new(EntryHashRequest)
+	hashkey.Hash = block.Entries[0].GetHash().String()
+	hashkey.IncludeLocation = true
+	resp, jErr := HandleV2Entry(state, hashkey)
+	if jErr != nil {
+		t.Fatalf("%v", jErr)
+	}
+	loc := resp.(*EntryResponse).Location
+	if loc == nil {
+		t.Fatalf("No location for entry %v", hashkey.Hash)
+	}
+	if loc.DirectoryBlockKeyMR != block.DBlock.GetKeyMR().String() || loc.DirectoryBlockHeight != int64(block.Height) {
+		t.Errorf("Wrong directory block for entry %v", hashkey.Hash)
+	}
+
+	// An entry whose directory block is not saved yet is returned without a location
+	eBlock, entries := testHelper.CreateTestEntryBlock(block.EBlock)
+	state.DB.StartMultiBatch()
+	for _, entry := range entries {
+		err := state.DB.InsertEntryMultiBatch(entry)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	err := state.DB.ProcessEBlockMultiBatch(eBlock, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = state.DB.ExecuteMultiBatch()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	hashkey.Hash = entries[0].GetHash().String()
+	resp, jErr = HandleV2Entry(state, hashkey)
+	if jErr != nil {
+		t.Fatalf("%v", jErr)
+	}
+	if resp.(*EntryResponse).Location != nil {
+		t.Errorf("Found a location for an entry without a directory block")
+	}
+}
+
+func TestHandleV2Anchors(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+	blocks := testHelper.CreateFullTestBlockSet()
+
+	// The anchor of every directory block is in the next block set
+	for _, block := range blocks[:len(blocks)-1] {
+		height := int64(block.Height)
+		req := new(AnchorsRequest)
+		req.Height = &height
+
+		resp, jErr := HandleV2Anchors(state, req)
+		if jErr != nil {
+			t.Errorf("%v", jErr)
+			continue
+		}
+		anchors := resp.(*AnchorsResponse)
+		if anchors.KeyMR != block.DBlock.GetKeyMR().String() {
+			t.Errorf("Wrong KeyMR at height %v", height)
+		}
+		if len(anchors.Anchors) != 1 {
+			t.Errorf("Expected 1 anchor at height %v, got %v", height, len(anchors.Anchors))
+			continue
+		}
+		a := anchors.Anchors[0]
+		if a.Valid == false || a.KeyMRMatches == false || a.Bitcoin == nil {
+			t.Errorf("Wrong anchor at height %v - %v", height, a)
+		}
+
+		// The same anchors are found by KeyMR
+		req = new(AnchorsRequest)
+		req.KeyMR = anchors.KeyMR
+		resp, jErr = HandleV2Anchors(state, req)
+		if jErr != nil {
+			t.Errorf("%v", jErr)
+			continue
+		}
+		if resp.(*AnchorsResponse).DBHeight != height {
+			t.Errorf("Wrong height for KeyMR %v", req.KeyMR)
+		}
+	}
+
+	if _, jErr := HandleV2Anchors(state, new(AnchorsRequest)); jErr == nil {
+		t.Errorf("No error without a height or KeyMR")
+	}
+}